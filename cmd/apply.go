@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/output"
+	"maker/internal/provider"
+	"maker/internal/spec"
+	"maker/internal/userdata"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "creates the objects listed in a lab spec",
+	Long: `Used to create every object listed in a YAML lab spec that doesn't already exist
+Objects are created per provider, buckets and databases first, then clusters and VMs
+Use --provider to only apply the part of the spec for one provider`,
+	Example: "maker apply -f lab.yaml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, _ := cmd.Flags().GetString("file")
+		lab, err := spec.Load(path)
+		if err != nil {
+			return errs.Wrap(err, "Failed to load lab spec")
+		}
+		names, err := labProviders(cmd, lab)
+		if err != nil {
+			return err
+		}
+
+		// aliases are resolved up front too, so one missing for a provider doesn't leave the lab half built
+		aliases, err := loadAliases()
+		if err != nil {
+			return err
+		}
+		resolved := map[string]spec.ProviderSpec{}
+		for _, name := range names {
+			if resolved[name], err = resolveLabAliases(aliases, name, lab.Providers[name]); err != nil {
+				return err
+			}
+		}
+
+		var created []provider.Resource
+		for _, name := range names {
+			p, err := provider.Get(name, providerOptions(cmd))
+			if err != nil {
+				return errs.Wrap(err, "Failed to load provider")
+			}
+			ps := resolved[name]
+
+			for _, kind := range spec.CreateOrder {
+				wanted := ps.Names(kind)
+				if len(wanted) == 0 {
+					continue
+				}
+				existing, err := existingNames(p, labKinds[kind].list)
+				if err != nil {
+					return errs.Wrap(err, "Failed to list objects")
+				}
+
+				for i, objName := range wanted {
+					if existing[objName] {
+						fmt.Fprintf(os.Stderr, "%s %s already exists on %s, skipping\n", kind, objName, name)
+						continue
+					}
+					fmt.Fprintf(os.Stderr, "Creating %s %s on %s\n", kind, objName, name)
+					resource, err := labKinds[kind].create(p, ps, i)
+					if err != nil {
+						return errs.Wrapf(err, "Failed to create %s %s", kind, objName)
+					}
+					recordResource(name, kind, resource, map[string]string{"file": path})
+					created = append(created, *resource)
+				}
+			}
+		}
+		err = output.PrintResources(os.Stdout, outputFormat(cmd), created)
+		return errs.Wrap(err, "Failed to print output")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	addWaitFlags(applyCmd)
+
+	applyCmd.Flags().StringP("file", "f", "", "path to the lab spec")
+	applyCmd.MarkFlagRequired("file")
+}
+
+// labKind ties a kind to the provider methods apply and destroy use to manage it
+type labKind struct {
+	list   listFunc
+	create func(p provider.Provider, ps spec.ProviderSpec, i int) (*provider.Resource, error)
+	delete func(p provider.Provider, name string) error
+}
+
+var labKinds = map[string]labKind{
+	provider.KindVM: {
+		list: provider.Provider.ListVMs,
+		create: func(p provider.Provider, ps spec.ProviderSpec, i int) (*provider.Resource, error) {
+			return p.CreateVM(ps.VMs[i])
+		},
+		delete: provider.Provider.DeleteVM,
+	},
+	provider.KindCluster: {
+		list: provider.Provider.ListClusters,
+		create: func(p provider.Provider, ps spec.ProviderSpec, i int) (*provider.Resource, error) {
+			return p.CreateCluster(ps.Clusters[i])
+		},
+		delete: provider.Provider.DeleteCluster,
+	},
+	provider.KindBucket: {
+		list: provider.Provider.ListBuckets,
+		create: func(p provider.Provider, ps spec.ProviderSpec, i int) (*provider.Resource, error) {
+			return p.CreateBucket(ps.Buckets[i].Name)
+		},
+		delete: provider.Provider.DeleteBucket,
+	},
+	provider.KindDB: {
+		list: provider.Provider.ListDBs,
+		create: func(p provider.Provider, ps spec.ProviderSpec, i int) (*provider.Resource, error) {
+			return p.CreateDB(ps.DBs[i])
+		},
+		delete: provider.Provider.DeleteDB,
+	},
+}
+
+// resolveLabAliases returns a provider's part of a lab spec with its size and image aliases
+// replaced by the values the provider takes, and user data naming a template by its cloud-config
+func resolveLabAliases(aliases provider.Aliases, providerName string, ps spec.ProviderSpec) (spec.ProviderSpec, error) {
+	var err error
+	vms := make([]provider.VMOptions, len(ps.VMs))
+	for i, vm := range ps.VMs {
+		if vm.Size, err = aliases.Resolve(provider.CatalogSizes, providerName, vm.Size); err != nil {
+			return ps, err
+		}
+		if vm.Image, err = aliases.Resolve(provider.CatalogImages, providerName, vm.Image); err != nil {
+			return ps, err
+		}
+		vm.UserData = userdata.Expand(vm.UserData)
+		if err := userdata.Validate(vm.UserData); err != nil {
+			return ps, errs.Wrapf(err, "Invalid user data for VM %s", vm.Name)
+		}
+		vms[i] = vm
+	}
+	clusters := make([]provider.ClusterOptions, len(ps.Clusters))
+	for i, cluster := range ps.Clusters {
+		if cluster.NodeSize, err = aliases.Resolve(provider.CatalogSizes, providerName, cluster.NodeSize); err != nil {
+			return ps, err
+		}
+		clusters[i] = cluster
+	}
+	ps.VMs, ps.Clusters = vms, clusters
+	return ps, nil
+}
+
+// labProviders returns the providers of the lab to act on, narrowed down by --provider when it is set.
+// Every provider is checked up front so a typo doesn't leave a lab half built.
+func labProviders(cmd *cobra.Command, lab *spec.Spec) ([]string, error) {
+	names := lab.ProviderNames()
+	only, _ := cmd.Flags().GetString("provider")
+	if only != "" && only != "all" {
+		if _, ok := lab.Providers[only]; !ok {
+			return nil, errs.Errorf(errs.Usage, "Invalid provider: %s isn't listed in the lab spec", only)
+		}
+		names = []string{only}
+	}
+	for _, name := range names {
+		if _, err := provider.Lookup(name); err != nil {
+			return nil, errs.Wrap(err, "Invalid lab spec")
+		}
+	}
+	return names, nil
+}
+
+// existingNames returns the names of the objects a provider already has
+func existingNames(p provider.Provider, list listFunc) (map[string]bool, error) {
+	resources, err := list(p)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, resource := range resources {
+		names[resource.Name] = true
+	}
+	return names, nil
+}
@@ -1,13 +1,12 @@
 package cmd
 
 import (
-	"fmt"
-	"maker/internal/aws"
-	"maker/internal/do"
-	"maker/internal/gcp"
-	"maker/internal/utils"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"os"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // authCmd represents the auth command
@@ -16,25 +15,69 @@ var authCmd = &cobra.Command{
 	Short: "configures authentication to specified provider",
 	Long: `auth is used to set the required config files needed to
 authenticate and communicate with a cloud provider
-Required settings will be prompted based on provider`,
-	Example: "maker auth --provider {do|aws|gcp}",
-	Run: func(cmd *cobra.Command, args []string) {
-		switch provider, _ := cmd.Flags().GetString("provider"); provider {
-		case "do":
-			err := do.SetupConfig()
-			utils.HandleErr("Failed to setup configuration files:", err)
-		case "aws":
-			err := aws.Configure()
-			utils.HandleErr("Failed to setup configuration files:", err)
-		case "gcp":
-			err := gcp.Configure()
-			utils.HandleErr("Failed to setup configuration files:", err)
-		default:
-			fmt.Printf("Unknown Provder -- %s", provider)
+Required settings will be prompted based on provider, unless they are given by flags
+or the standard environment variables of each provider:
+  do:  DIGITALOCEAN_TOKEN, SPACES_ACCESS_KEY_ID, SPACES_SECRET_ACCESS_KEY
+  aws: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_REGION
+  gcp: GOOGLE_APPLICATION_CREDENTIALS, GOOGLE_CLOUD_PROJECT, CLOUDSDK_COMPUTE_ZONE
+Use --non-interactive in pipelines to fail on missing settings instead of prompting`,
+	Example: `maker auth --provider {do|aws|gcp}
+  maker auth --provider do --token-env DO_TOKEN --region nyc3
+  maker auth --provider gcp --project lab --region us-east1-b --non-interactive
+  maker auth --provider gcp --credential-chain --impersonate lab@PROJECT.iam.gserviceaccount.com --region us-east1-b
+  maker auth --provider aws --credential-chain --aws-profile lab-sso --role-arn arn:aws:iam::123456789012:role/lab`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := providerName(cmd)
+		if err != nil {
+			return err
 		}
+		reg, err := provider.Lookup(name)
+		if err != nil {
+			return errs.Wrap(err, "Failed to setup configuration files")
+		}
+
+		err = reg.Configure(authOptions(cmd))
+		return errs.Wrap(err, "Failed to setup configuration files")
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(authCmd)
+
+	authCmd.Flags().String("token-env", "", "name of the environment variable holding the PAT token, secret access key or key file path")
+	authCmd.Flags().String("region", "", "sets the default region, or zone for gcp")
+	authCmd.Flags().String("project", "", "sets the gcp project, defaults to the project of the key file")
+	authCmd.Flags().Bool("credential-chain", false, "uses the sdk credential chain instead of storing keys, for aws the environment, ~/.aws/config, SSO and instance roles, for gcp Application Default Credentials")
+	authCmd.Flags().String("aws-profile", "", "sets the ~/.aws/config profile used with --credential-chain")
+	authCmd.Flags().String("role-arn", "", "sets an aws role to assume with the stored keys or the credential chain")
+	authCmd.Flags().String("external-id", "", "sets the external ID required to assume --role-arn")
+	authCmd.Flags().String("mfa-serial", "", "sets the MFA device needed to assume --role-arn, the code is prompted for or read from MAKER_MFA_TOKEN")
+	authCmd.Flags().String("impersonate", "", "sets a gcp service account to act as, using the key file or Application Default Credentials")
+	authCmd.Flags().Bool("non-interactive", false, "fails on missing settings instead of prompting, the default when stdin isn't a terminal")
+}
+
+// authOptions returns the settings given to the auth command
+func authOptions(cmd *cobra.Command) provider.AuthOptions {
+	tokenEnv, _ := cmd.Flags().GetString("token-env")
+	region, _ := cmd.Flags().GetString("region")
+	project, _ := cmd.Flags().GetString("project")
+	nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
+	chain, _ := cmd.Flags().GetBool("credential-chain")
+	sharedProfile, _ := cmd.Flags().GetString("aws-profile")
+	roleARN, _ := cmd.Flags().GetString("role-arn")
+	externalID, _ := cmd.Flags().GetString("external-id")
+	mfaSerial, _ := cmd.Flags().GetString("mfa-serial")
+	impersonate, _ := cmd.Flags().GetString("impersonate")
+	return provider.AuthOptions{
+		NonInteractive:  nonInteractive || !terminal.IsTerminal(int(os.Stdin.Fd())),
+		TokenEnv:        tokenEnv,
+		Region:          region,
+		Project:         project,
+		CredentialChain: chain,
+		SharedProfile:   sharedProfile,
+		RoleARN:         roleARN,
+		ExternalID:      externalID,
+		MFASerial:       mfaSerial,
+		Impersonate:     impersonate,
+	}
 }
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/output"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// verifyResult is how a provider is shown by 'maker auth verify'
+type verifyResult struct {
+	provider.Identity `yaml:",inline"`
+	Status            string `json:"status" yaml:"status"`
+	Error             string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// authVerifyCmd represents the auth verify command
+var authVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "checks the credentials of the specified provider",
+	Long: `Used to check that the configured credentials work by making a cheap authenticated call
+and showing who they belong to, the account or project and the default region
+Use '--provider all' to check every provider configured in the profile`,
+	Example: "maker auth verify --provider {do|aws|gcp|all}",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := providerName(cmd)
+		if err != nil {
+			return err
+		}
+		names := []string{name}
+		if name == "all" {
+			names = configuredProviders()
+			if len(names) == 0 {
+				return errs.Errorf(errs.AuthFailed, "No providers are configured in profile %s -- run 'maker auth'", profile.Current())
+			}
+		}
+
+		results := []verifyResult{}
+		var rows [][]string
+		var failed []string
+		var class errs.Class
+		for _, name := range names {
+			result := verifyResult{Identity: provider.Identity{Provider: name}, Status: "ok"}
+			identity, err := verifyProvider(cmd, name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to verify %s: %v\n", name, err)
+				if len(failed) == 0 {
+					class = errs.ClassOf(err)
+				}
+				failed = append(failed, name)
+				result.Status, result.Error = "failed", err.Error()
+			} else {
+				result.Identity = *identity
+			}
+			results = append(results, result)
+			rows = append(rows, []string{name, result.Status, orDash(result.User), orDash(result.Account), orDash(result.Region)})
+		}
+		err = output.PrintRows(os.Stdout, outputFormat(cmd), results, []string{"PROVIDER", "STATUS", "USER", "ACCOUNT", "REGION"}, rows)
+		if err != nil {
+			return errs.Wrap(err, "Failed to print output")
+		}
+		if len(failed) > 0 {
+			return errs.Errorf(class, "Failed to verify credentials for %s", strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+func init() {
+	authCmd.AddCommand(authVerifyCmd)
+}
+
+// verifyProvider loads a provider and checks its credentials
+func verifyProvider(cmd *cobra.Command, name string) (*provider.Identity, error) {
+	p, err := provider.Get(name, providerOptions(cmd))
+	if err != nil {
+		return nil, err
+	}
+	return p.Verify()
+}
+
+// configuredProviders returns the providers with a config file in the active profile
+func configuredProviders() []string {
+	var names []string
+	for _, name := range provider.Names() {
+		reg, _ := provider.Lookup(name)
+		if len(profile.Files(profile.Current(), reg.ConfigFile)) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// orDash shows empty table cells as a dash
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"maker/internal/cache"
+	"maker/internal/errs"
+	"maker/internal/output"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogCmd represents the catalog command
+var catalogCmd = &cobra.Command{
+	Use:   "catalog [kind]",
+	Short: "lists the sizes, images, regions and Kubernetes versions a provider offers",
+	Long: `Used to look up the values create commands accept for --size, --node-size, --image and --version,
+and the regions a provider can be configured with
+Answers are cached per profile, provider and region for --cache-ttl, use --refresh to fetch them again`,
+}
+
+func init() {
+	rootCmd.AddCommand(catalogCmd)
+
+	catalogCmd.PersistentFlags().Bool("refresh", false, "fetches the catalog from the provider even if a cached copy is fresh")
+	catalogCmd.PersistentFlags().Duration("cache-ttl", 24*time.Hour, "how long a cached catalog is used before it is fetched again")
+}
+
+// runCatalog fetches a catalog, from the cache while it is fresh, and prints the entries passing filter
+func runCatalog(cmd *cobra.Command, kind string, filter provider.CatalogFilter) error {
+	name, err := providerName(cmd)
+	if err != nil {
+		return err
+	}
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	ttl, _ := cmd.Flags().GetDuration("cache-ttl")
+
+	// loading the provider doesn't call its API, it only reads the region the answers depend on
+	p, err := loadProvider(cmd)
+	if err != nil {
+		return err
+	}
+	key := "catalog-" + profile.Current() + "-" + name + "-" + p.Region() + "-" + kind
+	var entries []provider.CatalogEntry
+	if refresh || !cache.Load(key, ttl, &entries) {
+		entries, err = p.Catalog(kind)
+		if err != nil {
+			return errs.Wrap(err, "Failed to list "+kind)
+		}
+		if err := cache.Save(key, entries); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: catalog fetched but not cached:", err)
+		}
+	}
+	return printCatalog(cmd, kind, provider.FilterCatalog(entries, filter))
+}
+
+// printCatalog prints catalog entries with the columns that matter for their kind
+func printCatalog(cmd *cobra.Command, kind string, entries []provider.CatalogEntry) error {
+	header := []string{"SLUG", "DESCRIPTION"}
+	switch kind {
+	case provider.CatalogSizes:
+		header = []string{"SLUG", "VCPUS", "MEMORY", "DESCRIPTION"}
+	case provider.CatalogImages:
+		header = []string{"SLUG", "DISTRO", "DESCRIPTION"}
+	}
+	var rows [][]string
+	for _, entry := range entries {
+		switch kind {
+		case provider.CatalogSizes:
+			rows = append(rows, []string{entry.Slug, strconv.Itoa(entry.VCPUs), formatMemory(entry.MemoryMB), entry.Description})
+		case provider.CatalogImages:
+			rows = append(rows, []string{entry.Slug, entry.Distro, entry.Description})
+		default:
+			rows = append(rows, []string{entry.Slug, entry.Description})
+		}
+	}
+	err := output.PrintRows(os.Stdout, outputFormat(cmd), entries, header, rows)
+	return errs.Wrap(err, "Failed to print output")
+}
+
+// formatMemory writes an amount of memory in whole GB where it divides evenly
+func formatMemory(mb int) string {
+	if mb >= 1024 && mb%1024 == 0 {
+		return strconv.Itoa(mb/1024) + "GB"
+	}
+	return strconv.Itoa(mb) + "MB"
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogImagesCmd represents the catalogImages command
+var catalogImagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "lists the public VM images of a provider",
+	Long: `Used to list the distribution images of DigitalOcean, the newest AMI of the common distributions
+on AWS, or the current images of the public GCP image projects`,
+	Example: "maker catalog images --provider {do|aws|gcp} --distro ubuntu",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		distro, _ := cmd.Flags().GetString("distro")
+		return runCatalog(cmd, provider.CatalogImages, provider.CatalogFilter{Distro: distro})
+	},
+}
+
+func init() {
+	catalogCmd.AddCommand(catalogImagesCmd)
+
+	catalogImagesCmd.Flags().String("distro", "", "only lists images of a distribution, e.g. ubuntu or debian")
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogRegionsCmd represents the catalogRegions command
+var catalogRegionsCmd = &cobra.Command{
+	Use:     "regions",
+	Short:   "lists the regions of a provider",
+	Long:    `Used to list the regions of DigitalOcean and AWS, or the zones of GCP, that objects can be created in`,
+	Example: "maker catalog regions --provider {do|aws|gcp}",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCatalog(cmd, provider.CatalogRegions, provider.CatalogFilter{})
+	},
+}
+
+func init() {
+	catalogCmd.AddCommand(catalogRegionsCmd)
+}
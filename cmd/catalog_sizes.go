@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogSizesCmd represents the catalogSizes command
+var catalogSizesCmd = &cobra.Command{
+	Use:     "sizes",
+	Short:   "lists the VM and node sizes of a provider",
+	Long:    `Used to list the droplet sizes, EC2 instance types or GCE machine types of the configured region or zone`,
+	Example: "maker catalog sizes --provider {do|aws|gcp} --min-vcpus 2 --min-memory 4",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		minVCPUs, _ := cmd.Flags().GetInt("min-vcpus")
+		minMemory, _ := cmd.Flags().GetFloat64("min-memory")
+		return runCatalog(cmd, provider.CatalogSizes, provider.CatalogFilter{MinVCPUs: minVCPUs, MinMemoryMB: int(minMemory * 1024)})
+	},
+}
+
+func init() {
+	catalogCmd.AddCommand(catalogSizesCmd)
+
+	catalogSizesCmd.Flags().Int("min-vcpus", 0, "only lists sizes with at least this many vCPUs")
+	catalogSizesCmd.Flags().Float64("min-memory", 0, "only lists sizes with at least this much memory in GB")
+}
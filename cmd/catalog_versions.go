@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// catalogVersionsCmd represents the catalogVersions command
+var catalogVersionsCmd = &cobra.Command{
+	Use:     "versions",
+	Short:   "lists the Kubernetes versions of a provider",
+	Long:    `Used to list the Kubernetes versions new clusters can be created with`,
+	Example: "maker catalog versions --provider {do|aws|gcp}",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCatalog(cmd, provider.CatalogVersions, provider.CatalogFilter{})
+	},
+}
+
+func init() {
+	catalogCmd.AddCommand(catalogVersionsCmd)
+}
@@ -8,9 +8,12 @@ import (
 var createCmd = &cobra.Command{
 	Use:   "create [object]",
 	Short: "creates the specified object on the specified platform",
-	Long:  `Used to create various objects on the cloud provider specified`,
+	Long: `Used to create various objects on the cloud provider specified
+Flags that aren't given are read from MAKER_<PROVIDER>_<KIND>_<FLAG> environment variables,
+then from <provider>.<kind>.<flag> in the config file, e.g. MAKER_DO_VM_SIZE or do.vm.size`,
 }
 
 func init() {
 	rootCmd.AddCommand(createCmd)
+	addWaitFlags(createCmd)
 }
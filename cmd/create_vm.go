@@ -1,11 +1,10 @@
 package cmd
 
 import (
-	"fmt"
-	"maker/internal/aws"
-	"maker/internal/do"
-	"maker/internal/gcp"
-	"maker/internal/utils"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"maker/internal/userdata"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -15,44 +14,39 @@ var createVMCmd = &cobra.Command{
 	Use:   "vm",
 	Short: "creates a VM",
 	Long: `Used to create a VM object on the specified provider
-Sizes and Image names are provider specific! GCP requires images in 'project/image-name' format`,
-	Example: "maker create vm --provider {do|aws|gcp} --size SIZE --image IMAGE-NAME --name NAME",
-	Run: func(cmd *cobra.Command, args []string) {
+Sizes and images take the provider's own names, or an alias such as small or ubuntu-22.04 that works on every provider
+GCP requires its own images in 'project/image-name' or 'project/family/family-name' format
+User data is run by cloud-init on first boot, GCP passes scripts as a startup-script instead
+Without --ssh-key the only key of the account is used, or ~/.ssh/id_ed25519.pub, id_ecdsa.pub or id_rsa.pub is uploaded when it has none
+GCP adds the local key to the instance unless it is a project key already`,
+	Example: "maker create vm --provider {do|aws|gcp} --size SIZE --image IMAGE-NAME --name NAME --cloud-init docker",
+	PreRunE: applyDefaults(provider.KindVM),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name, _ := cmd.Flags().GetString("name")
 		size, _ := cmd.Flags().GetString("size")
 		image, _ := cmd.Flags().GetString("image")
+		sshKey, _ := cmd.Flags().GetString("ssh-key")
+		userData, err := vmUserData(cmd)
+		if err != nil {
+			return err
+		}
 
-		switch provider, _ := cmd.Flags().GetString("provider"); provider {
-		case "do":
-			config, err := do.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			patToken, defaultRegion := config.PatToken, config.DefaultRegion
-			client := do.CreateDoClient(patToken, defaultRegion)
-
-			err = do.CreateDoDroplet(client, name, defaultRegion, size, image)
-			utils.HandleErr("Failed to create droplet:", err)
-		case "aws":
-			defaultRegion, err := aws.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			session, err := aws.CreateAwsSession(aws.CredsPath, defaultRegion)
-			utils.HandleErr("Failed to setup AWS Session:", err)
-
-			err = aws.CreateEc2Instance(session, name, defaultRegion, size, image)
-			utils.HandleErr("Failed to create EC2 instance:", err)
-		case "gcp":
-			keyfile, defaultZone, gcpProject, err := gcp.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			service, err := gcp.CreateGceService(keyfile)
-			utils.HandleErr("Failed to create a Compute Service:", err)
-
-			err = gcp.CreateGceInstance(service, name, gcpProject, defaultZone, size, image)
-			utils.HandleErr("Failed to create GCE instance:", err)
-		default:
-			fmt.Printf("Unknown Provder -- %s", provider)
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		if size, err = resolveAlias(cmd, provider.CatalogSizes, size); err != nil {
+			return err
+		}
+		if image, err = resolveAlias(cmd, provider.CatalogImages, image); err != nil {
+			return err
 		}
+		resource, err := p.CreateVM(provider.VMOptions{Name: name, Size: size, Image: image, UserData: userData, SSHKey: sshKey})
+		if err != nil {
+			return errs.Wrap(err, "Failed to create VM")
+		}
+		recordCreate(cmd, provider.KindVM, resource)
+		return nil
 	},
 }
 
@@ -62,8 +56,26 @@ func init() {
 	// Local flags which will only run when this command
 	createVMCmd.Flags().StringP("name", "n", "", "name of the VM")
 	createVMCmd.MarkFlagRequired("name")
-	createVMCmd.Flags().StringP("size", "s", "", "sets the VM size/Instance type")
+	createVMCmd.Flags().StringP("size", "s", "", "sets the VM size/Instance type, or one of the aliases "+strings.Join(provider.DefaultAliases.Names(provider.CatalogSizes), ", "))
 	createVMCmd.MarkFlagRequired("size")
-	createVMCmd.Flags().StringP("image", "i", "", "sets the OS/Disk Image to use")
+	createVMCmd.Flags().StringP("image", "i", "", "sets the OS/Disk Image to use, or one of the aliases "+strings.Join(provider.DefaultAliases.Names(provider.CatalogImages), ", "))
 	createVMCmd.MarkFlagRequired("image")
+	createVMCmd.Flags().String("ssh-key", "", "name, ID or fingerprint of an SSH key of the provider, or the path of a public key to upload")
+	createVMCmd.Flags().String("user-data", "", "file with a script or cloud-config to run on first boot")
+	createVMCmd.Flags().String("cloud-init", "", "cloud-config file to run on first boot, or one of the templates "+strings.Join(userdata.TemplateNames(), ", "))
+}
+
+// vmUserData reads the user data set with --user-data or --cloud-init
+func vmUserData(cmd *cobra.Command) (string, error) {
+	file, _ := cmd.Flags().GetString("user-data")
+	cloudInit, _ := cmd.Flags().GetString("cloud-init")
+	switch {
+	case file != "" && cloudInit != "":
+		return "", errs.New(errs.Usage, "Set either --user-data or --cloud-init, not both")
+	case file != "":
+		return userdata.ReadFile(file)
+	case cloudInit != "":
+		return userdata.ReadCloudConfig(cloudInit)
+	}
+	return "", nil
 }
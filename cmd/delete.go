@@ -13,4 +13,5 @@ var deleteCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(deleteCmd)
+	addWaitFlags(deleteCmd)
 }
@@ -1,65 +1,32 @@
 package cmd
 
 import (
-	"fmt"
-	"maker/internal/aws"
-	"maker/internal/do"
-	"maker/internal/gcp"
-	"maker/internal/utils"
+	"maker/internal/errs"
+	"maker/internal/provider"
 
 	"github.com/spf13/cobra"
 )
 
 // deleteBucketCmd represents the deleteBucket command
 var deleteBucketCmd = &cobra.Command{
-	Use:     "bucket",
-	Short:   "deletes a storage bucket",
-	Long:    `Used to delete a storage bucket on the specified provider`,
+	Use:   "bucket",
+	Short: "deletes a storage bucket",
+	Long: `Used to delete a storage bucket on the specified provider
+A bucket holding objects is only emptied and deleted once confirmed, use --yes to skip the question`,
 	Example: "maker delete bucket --provider {do|aws|gcp} --name BUCKET-NAME",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name, _ := cmd.Flags().GetString("name")
 
-		switch provider, _ := cmd.Flags().GetString("provider"); provider {
-		case "do":
-			config, err := do.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-			accessKey := config.SpacesAccessKey
-			secretKey := config.SpacesSecretKey
-			endpoint := config.SpacesDefaultEndpoint
-
-			client := do.CreateDoSpacesClient(accessKey, secretKey, endpoint)
-			err = do.DeleteSpaceObjects(client, name)
-			utils.HandleErr("Failed to delete Space:", err)
-
-			err = do.DeleteDoSpace(client, name)
-			utils.HandleErr("Failed to delete Space:", err)
-		case "aws":
-			defaultRegion, err := aws.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			client, err := aws.CreateS3Client(aws.CredsPath, defaultRegion)
-			utils.HandleErr("Failed to create sessions", err)
-
-			err = aws.DeleteS3Objects(client, name)
-			utils.HandleErr("Failed to delete bucket", err)
-
-			err = aws.DeleteS3Bucket(client, name)
-			utils.HandleErr("Failed to deletes S3 bucket:", err)
-		case "gcp":
-			keyfile, _, gcpProject, err := gcp.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			client, err := gcp.CreateStorageClient(keyfile)
-			utils.HandleErr("Failed to create a Storage client:", err)
-
-			err = gcp.DeleteStorageObjects(client, name, gcpProject)
-			utils.HandleErr("Failed to delete objects in bucket", err)
-
-			err = gcp.DeleteStorageBucket(client, name, gcpProject)
-			utils.HandleErr("Failed to delete Storage bucket:", err)
-		default:
-			fmt.Printf("Unknown Provder -- %s", provider)
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		err = p.DeleteBucket(name)
+		if err != nil {
+			return errs.Wrap(err, "Failed to delete bucket")
 		}
+		recordDelete(cmd, provider.KindBucket, name)
+		return nil
 	},
 }
 
@@ -68,4 +35,5 @@ func init() {
 
 	deleteBucketCmd.Flags().StringP("name", "n", "", "name of the bucket")
 	deleteBucketCmd.MarkFlagRequired("name")
+	deleteBucketCmd.Flags().BoolP("yes", "y", false, "delete the objects in the bucket without asking")
 }
@@ -1,11 +1,8 @@
 package cmd
 
 import (
-	"fmt"
-	"maker/internal/aws"
-	"maker/internal/do"
-	"maker/internal/gcp"
-	"maker/internal/utils"
+	"maker/internal/errs"
+	"maker/internal/provider"
 
 	"github.com/spf13/cobra"
 )
@@ -16,44 +13,19 @@ var deleteClusterCmd = &cobra.Command{
 	Short:   "deletes a Kubernetes cluster",
 	Long:    `Deletes a Kubernetes cluster on the specified provider`,
 	Example: "maker status cluster --provider {do|aws|gcp} --name CLUSTER-NAME",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name, _ := cmd.Flags().GetString("name")
 
-		switch provider, _ := cmd.Flags().GetString("provider"); provider {
-		case "do":
-			config, err := do.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			patToken, defaultRegion := config.PatToken, config.DefaultRegion
-			client := do.CreateDoClient(patToken, defaultRegion)
-			utils.HandleErr("Failed to authenticate:", err)
-
-			clusterID, err := do.GetDoCluster(client, name)
-			err = do.DeleteDoCluster(client, clusterID, name)
-		case "aws":
-			defaultRegion, err := aws.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			session, err := aws.CreateAwsSession(aws.CredsPath, defaultRegion)
-			utils.HandleErr("Failed to setup AWS Session:", err)
-
-			err = aws.DeleteEksNodeGroup(session, name, name+"-nodegroup")
-			utils.HandleErr("Failed to delete the node group:", err)
-
-			err = aws.DeleteEksCluster(session, name, name+"-nodegroup")
-			utils.HandleErr("Failed to delete the cluster", err)
-		case "gcp":
-			keyfile, defaultZone, gcpProject, err := gcp.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			client, err := gcp.CreateGkeClient(keyfile)
-			utils.HandleErr("Failed to create a Compute Service:", err)
-
-			err = gcp.DeleteGkeCluster(client, name, gcpProject, defaultZone)
-			utils.HandleErr("Failed to create GCE instance:", err)
-		default:
-			fmt.Printf("Unknown Provder -- %s", provider)
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		err = p.DeleteCluster(name)
+		if err != nil {
+			return errs.Wrap(err, "Failed to delete cluster")
 		}
+		recordDelete(cmd, provider.KindCluster, name)
+		return nil
 	},
 }
 
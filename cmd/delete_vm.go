@@ -1,11 +1,8 @@
 package cmd
 
 import (
-	"fmt"
-	"maker/internal/aws"
-	"maker/internal/do"
-	"maker/internal/gcp"
-	"maker/internal/utils"
+	"maker/internal/errs"
+	"maker/internal/provider"
 
 	"github.com/spf13/cobra"
 )
@@ -16,47 +13,19 @@ var deleteVMCmd = &cobra.Command{
 	Short:   "deletes a VM",
 	Long:    `Used to delete a VM object on the specified provider`,
 	Example: "maker delete vm --provider {do|aws|gcp} --name NAME",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name, _ := cmd.Flags().GetString("name")
 
-		switch provider, _ := cmd.Flags().GetString("provider"); provider {
-		case "do":
-			config, err := do.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			patToken, defaultRegion := config.PatToken, config.DefaultRegion
-
-			client := do.CreateDoClient(patToken, defaultRegion)
-
-			dropletID, err := do.GetDoDroplet(client, name)
-			utils.HandleErr("Faiiled to fetch droplet ID:", err)
-
-			err = do.DeleteDoDroplet(client, dropletID, name)
-			utils.HandleErr("Failed to delete droplet:", err)
-		case "aws":
-			defaultRegion, err := aws.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			session, err := aws.CreateAwsSession(aws.CredsPath, defaultRegion)
-			utils.HandleErr("Failed to setup AWS Session:", err)
-
-			instanceID, err := aws.GetInstanceID(session, name)
-			utils.HandleErr("Failed to fetch EC2 instance ID:", err)
-
-			err = aws.DeleteEc2Instance(session, instanceID)
-			utils.HandleErr("Failed to delete EC2 instance ID:", err)
-		case "gcp":
-			keyfile, defaultZone, gcpProject, err := gcp.LoadConfig()
-			utils.HandleErr("Failed to load config:", err)
-
-			service, err := gcp.CreateGceService(keyfile)
-			utils.HandleErr("Failed to create a Compute Service:", err)
-
-			err = gcp.DeleteGceInstance(service, name, gcpProject, defaultZone)
-			utils.HandleErr("Failed to create GCE instance:", err)
-		default:
-			fmt.Printf("Unknown Provder -- %s", provider)
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		err = p.DeleteVM(name)
+		if err != nil {
+			return errs.Wrap(err, "Failed to delete VM")
 		}
+		recordDelete(cmd, provider.KindVM, name)
+		return nil
 	},
 }
 
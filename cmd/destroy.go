@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"maker/internal/spec"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// destroyCmd represents the destroy command
+var destroyCmd = &cobra.Command{
+	Use:   "destroy",
+	Short: "deletes the objects listed in a lab spec",
+	Long: `Used to delete every object listed in a YAML lab spec
+Objects are deleted per provider, VMs and clusters first, then databases and buckets
+Use --provider to only destroy the part of the spec for one provider
+Destroy asks once before deleting anything, buckets included with their objects. Use --yes in scripts`,
+	Example: "maker destroy -f lab.yaml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, _ := cmd.Flags().GetString("file")
+		lab, err := spec.Load(path)
+		if err != nil {
+			return errs.Wrap(err, "Failed to load lab spec")
+		}
+		names, err := labProviders(cmd, lab)
+		if err != nil {
+			return err
+		}
+		// one answer covers the whole lab, so teardown never stops halfway to ask about a bucket
+		opts := providerOptions(cmd)
+		if !dryRun && !opts.Yes {
+			if err := confirmDestroy(path, names); err != nil {
+				return err
+			}
+			opts.Yes = true
+		}
+
+		for _, name := range names {
+			p, err := provider.Get(name, opts)
+			if err != nil {
+				return errs.Wrap(err, "Failed to load provider")
+			}
+			ps := lab.Providers[name]
+
+			for i := len(spec.CreateOrder) - 1; i >= 0; i-- {
+				kind := spec.CreateOrder[i]
+				wanted := ps.Names(kind)
+				if len(wanted) == 0 {
+					continue
+				}
+				existing, err := existingNames(p, labKinds[kind].list)
+				if err != nil {
+					return errs.Wrap(err, "Failed to list objects")
+				}
+
+				for _, objName := range wanted {
+					if !existing[objName] {
+						fmt.Fprintf(os.Stderr, "%s %s doesn't exist on %s, skipping\n", kind, objName, name)
+						forgetResource(name, kind, objName)
+						continue
+					}
+					fmt.Fprintf(os.Stderr, "Deleting %s %s on %s\n", kind, objName, name)
+					if err := labKinds[kind].delete(p, objName); err != nil {
+						return errs.Wrapf(err, "Failed to delete %s %s", kind, objName)
+					}
+					forgetResource(name, kind, objName)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// confirmDestroy asks before a lab is destroyed, refusing without a terminal to ask on
+func confirmDestroy(path string, names []string) error {
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return errs.New(errs.Usage, "Destroy needs confirming -- pass --yes to delete the lab without asking")
+	}
+	fmt.Fprintf(os.Stderr, "Delete every object of %s on %s, including the objects in its buckets? (y/N): ", path, strings.Join(names, ", "))
+	var answer string
+	fmt.Fscanln(os.Stdin, &answer)
+	if strings.ToLower(answer) != "y" {
+		return errs.New(errs.Usage, "Destroy cancelled")
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(destroyCmd)
+	addWaitFlags(destroyCmd)
+
+	destroyCmd.Flags().StringP("file", "f", "", "path to the lab spec")
+	destroyCmd.MarkFlagRequired("file")
+	destroyCmd.Flags().BoolP("yes", "y", false, "destroy without asking, emptying buckets that hold objects")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"encoding/json"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// kubeTokenCmd represents the kube-token command
+var kubeTokenCmd = &cobra.Command{
+	Use:   "kube-token",
+	Short: "prints a token for a Kubernetes cluster for kubectl",
+	Long: `Used by the kubeconfigs Maker writes to authenticate kubectl with the credentials of the profile,
+so the AWS CLI or gcloud don't need to be installed. Prints a ` + kubeconfig.ExecAPIVersion + ` ExecCredential`,
+	Example: "maker kube-token --provider {aws|gcp} --cluster CLUSTER-NAME",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, _ := cmd.Flags().GetString("cluster")
+
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		issuer, ok := p.(provider.KubeTokenIssuer)
+		if !ok {
+			name, _ := providerName(cmd)
+			return errs.Errorf(errs.Usage, "Provider %s doesn't issue cluster tokens, its kubeconfigs hold their own credentials", name)
+		}
+		token, err := issuer.KubeToken(cluster)
+		if err != nil {
+			return errs.Wrap(err, "Failed to get cluster token")
+		}
+		err = json.NewEncoder(os.Stdout).Encode(kubeconfig.NewExecCredential(token.Token, token.Expiry))
+		return errs.Wrap(err, "Failed to print token")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(kubeTokenCmd)
+
+	kubeTokenCmd.Flags().StringP("cluster", "n", "", "name of the cluster")
+	kubeTokenCmd.MarkFlagRequired("cluster")
+}
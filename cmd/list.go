@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/output"
+	"maker/internal/provider"
+	"maker/internal/state"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list [object]",
+	Short: "lists the objects of a type on the specified platform",
+	Long: `Used to list every object of a type on the cloud provider specified
+Use '--provider all' to list across every configured provider
+Use '--local' to list the objects Maker recorded in its state file without calling the provider
+Use '--drift' to show objects that are recorded but gone from the provider, or present but untracked`,
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.PersistentFlags().Bool("local", false, "list objects recorded in the local state file instead of querying the provider")
+	listCmd.PersistentFlags().Bool("drift", false, "only show objects that differ between the local state file and the provider")
+}
+
+// listFunc fetches one kind of object from a provider
+type listFunc func(p provider.Provider) ([]provider.Resource, error)
+
+// listResources runs list against the provider set with --provider and prints the results
+func listResources(cmd *cobra.Command, kind string, list listFunc) error {
+	format := outputFormat(cmd)
+	local, _ := cmd.Flags().GetBool("local")
+	drift, _ := cmd.Flags().GetBool("drift")
+	name, err := providerName(cmd)
+	if err != nil {
+		return err
+	}
+	names := []string{name}
+	if name == "all" {
+		names = provider.Names()
+	}
+
+	var st *state.State
+	if local || drift {
+		st, err = state.Load()
+		if err != nil {
+			return errs.Wrap(err, "Failed to load state")
+		}
+	}
+
+	var resources []provider.Resource
+	for _, name := range names {
+		if local {
+			resources = append(resources, stateResources(st.List(name, kind))...)
+			continue
+		}
+
+		p, err := provider.Get(name, providerOptions(cmd))
+		if err == nil {
+			var found []provider.Resource
+			found, err = list(p)
+			if drift {
+				found = driftResources(st.List(name, kind), found)
+			}
+			resources = append(resources, found...)
+		}
+		if err != nil && len(names) > 1 {
+			// one unconfigured provider shouldn't hide the others
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", name, err)
+			continue
+		}
+		if err != nil {
+			return errs.Wrap(err, "Failed to list objects")
+		}
+	}
+	err = output.PrintResources(os.Stdout, format, resources)
+	return errs.Wrap(err, "Failed to print output")
+}
+
+// stateResources converts state entries into resources so they can be printed like provider results
+func stateResources(entries []state.Entry) []provider.Resource {
+	var resources []provider.Resource
+	for _, entry := range entries {
+		resources = append(resources, entryResource(entry, "recorded"))
+	}
+	return resources
+}
+
+func entryResource(entry state.Entry, status string) provider.Resource {
+	return provider.Resource{
+		Name:     entry.Name,
+		ID:       entry.ID,
+		Provider: entry.Provider,
+		Region:   entry.Region,
+		Status:   status,
+		Created:  entry.Created,
+	}
+}
+
+// driftResources compares recorded entries with what the provider reports.
+// Entries the provider no longer has are marked missing, objects the state
+// file doesn't know about are marked untracked, and matches are dropped.
+func driftResources(entries []state.Entry, found []provider.Resource) []provider.Resource {
+	matched := make([]bool, len(found))
+	var resources []provider.Resource
+	for _, entry := range entries {
+		present := false
+		for i, resource := range found {
+			if (entry.ID != "" && entry.ID == resource.ID) || entry.Name == resource.Name {
+				matched[i] = true
+				present = true
+			}
+		}
+		if !present {
+			resources = append(resources, entryResource(entry, "missing"))
+		}
+	}
+	for i, resource := range found {
+		if !matched[i] {
+			resource.Status = "untracked"
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
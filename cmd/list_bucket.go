@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// listBucketCmd represents the list bucket command
+var listBucketCmd = &cobra.Command{
+	Use:     "bucket",
+	Short:   "lists storage buckets",
+	Long:    `Used to list all storage buckets on the specified provider`,
+	Example: "maker list bucket --provider {do|aws|gcp|all}",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listResources(cmd, provider.KindBucket, provider.Provider.ListBuckets)
+	},
+}
+
+func init() {
+	listCmd.AddCommand(listBucketCmd)
+}
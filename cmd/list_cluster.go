@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// listClusterCmd represents the list cluster command
+var listClusterCmd = &cobra.Command{
+	Use:     "cluster",
+	Short:   "lists Kubernetes clusters",
+	Long:    `Used to list all Kubernetes clusters on the specified provider`,
+	Example: "maker list cluster --provider {do|aws|gcp|all}",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listResources(cmd, provider.KindCluster, provider.Provider.ListClusters)
+	},
+}
+
+func init() {
+	listCmd.AddCommand(listClusterCmd)
+}
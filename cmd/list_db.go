@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// listDbCmd represents the list db command
+var listDbCmd = &cobra.Command{
+	Use:     "db",
+	Short:   "lists databases",
+	Long:    `Used to list all databases on the specified provider`,
+	Example: "maker list db --provider {do|aws|gcp|all}",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listResources(cmd, provider.KindDB, provider.Provider.ListDBs)
+	},
+}
+
+func init() {
+	listCmd.AddCommand(listDbCmd)
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// listVMCmd represents the list vm command
+var listVMCmd = &cobra.Command{
+	Use:     "vm",
+	Short:   "lists VMs",
+	Long:    `Used to list all VMs on the specified provider`,
+	Example: "maker list vm --provider {do|aws|gcp|all}",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listResources(cmd, provider.KindVM, provider.Provider.ListVMs)
+	},
+}
+
+func init() {
+	listCmd.AddCommand(listVMCmd)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+	"maker/internal/output"
+	"maker/internal/provider"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// nodepoolCmd represents the nodepool command
+var nodepoolCmd = &cobra.Command{
+	Use:   "nodepool [command]",
+	Short: "manages the node pools of a Kubernetes cluster",
+	Long: `Used to add, list, scale and delete groups of identical nodes in a Kubernetes cluster,
+e.g. a second high memory pool next to the one the cluster was created with.
+A pool is sized with --node-count, or autoscaled between --min-nodes and --max-nodes`,
+}
+
+func init() {
+	rootCmd.AddCommand(nodepoolCmd)
+
+	nodepoolCmd.PersistentFlags().String("cluster", "", "name of the cluster")
+	nodepoolCmd.MarkPersistentFlagRequired("cluster")
+}
+
+// addScaleFlags adds the flags sizing a node pool to a command
+func addScaleFlags(cmd *cobra.Command) {
+	cmd.Flags().IntP("node-count", "c", 0, "sets the number of nodes, or the starting number when autoscaling")
+	cmd.Flags().Int("min-nodes", 0, "sets the fewest nodes the autoscaler scales down to")
+	cmd.Flags().Int("max-nodes", 0, "sets the most nodes the autoscaler scales up to, enables autoscaling")
+}
+
+// nodeScale reads the flags added by addScaleFlags
+func nodeScale(cmd *cobra.Command) provider.NodeScale {
+	nodeCount, _ := cmd.Flags().GetInt("node-count")
+	minNodes, _ := cmd.Flags().GetInt("min-nodes")
+	maxNodes, _ := cmd.Flags().GetInt("max-nodes")
+	return provider.NodeScale{NodeCount: nodeCount, MinNodes: minNodes, MaxNodes: maxNodes}
+}
+
+// printNodePools prints node pools as a table, or JSON or YAML with --output
+func printNodePools(cmd *cobra.Command, pools []provider.NodePool) error {
+	var rows [][]string
+	for _, pool := range pools {
+		autoscale := ""
+		if pool.Autoscaled() {
+			autoscale = strconv.Itoa(pool.MinNodes) + "-" + strconv.Itoa(pool.MaxNodes)
+		}
+		var taints []string
+		for _, taint := range pool.Taints {
+			taints = append(taints, taint.String())
+		}
+		rows = append(rows, []string{pool.Provider, pool.Cluster, pool.Name, pool.Size, strconv.Itoa(pool.NodeCount),
+			autoscale, pool.Status, provider.FormatLabels(pool.Labels), strings.Join(taints, ",")})
+	}
+	if pools == nil {
+		pools = []provider.NodePool{}
+	}
+	err := output.PrintRows(os.Stdout, outputFormat(cmd), pools,
+		[]string{"PROVIDER", "CLUSTER", "NAME", "SIZE", "NODES", "AUTOSCALE", "STATUS", "LABELS", "TAINTS"}, rows)
+	return errs.Wrap(err, "Failed to print output")
+}
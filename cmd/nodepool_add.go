@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// nodepoolAddCmd represents the nodepool add command
+var nodepoolAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "adds a node pool to a Kubernetes cluster",
+	Long: `Used to add a node pool to a Kubernetes cluster on the specified provider
+Labels and taints are applied to every node of the pool, taints are written like kubectl's key=value:Effect`,
+	Example: "maker nodepool add --provider {do|aws|gcp} --cluster CLUSTER-NAME --name POOL-NAME --node-size SIZE --min-nodes 1 --max-nodes 3 --labels workload=test --taints dedicated=test:NoSchedule",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		name, _ := cmd.Flags().GetString("name")
+		nodeSize, _ := cmd.Flags().GetString("node-size")
+		labels, _ := cmd.Flags().GetStringToString("labels")
+		taintFlags, _ := cmd.Flags().GetStringSlice("taints")
+
+		opts := provider.NodePoolOptions{
+			Cluster:   cluster,
+			Name:      name,
+			NodeSize:  nodeSize,
+			NodeScale: nodeScale(cmd),
+			Labels:    labels,
+		}
+		for _, flag := range taintFlags {
+			taint, err := provider.ParseTaint(flag)
+			if err != nil {
+				return err
+			}
+			opts.Taints = append(opts.Taints, taint)
+		}
+		if err := opts.Validate(); err != nil {
+			return err
+		}
+
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		if opts.NodeSize, err = resolveAlias(cmd, provider.CatalogSizes, opts.NodeSize); err != nil {
+			return err
+		}
+		pool, err := p.AddNodePool(opts)
+		if err != nil {
+			return errs.Wrap(err, "Failed to add node pool")
+		}
+		return printNodePools(cmd, []provider.NodePool{*pool})
+	},
+}
+
+func init() {
+	nodepoolCmd.AddCommand(nodepoolAddCmd)
+	addWaitFlags(nodepoolAddCmd)
+
+	nodepoolAddCmd.Flags().StringP("name", "n", "", "name of the node pool")
+	nodepoolAddCmd.MarkFlagRequired("name")
+	nodepoolAddCmd.Flags().StringP("node-size", "s", "", "sets the node VM size/Instance type, or a size alias such as medium")
+	nodepoolAddCmd.MarkFlagRequired("node-size")
+	addScaleFlags(nodepoolAddCmd)
+	nodepoolAddCmd.Flags().StringToString("labels", nil, "comma separated key=value Kubernetes labels for the nodes")
+	nodepoolAddCmd.Flags().StringSlice("taints", nil, "comma separated key=value:Effect taints for the nodes, Effect is NoSchedule, PreferNoSchedule or NoExecute")
+}
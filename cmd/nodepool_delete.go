@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+
+	"github.com/spf13/cobra"
+)
+
+// nodepoolDeleteCmd represents the nodepool delete command
+var nodepoolDeleteCmd = &cobra.Command{
+	Use:     "delete",
+	Short:   "deletes a node pool of a Kubernetes cluster",
+	Long:    `Used to delete a node pool and its nodes, the pods on them are rescheduled onto the other pools`,
+	Example: "maker nodepool delete --provider {do|aws|gcp} --cluster CLUSTER-NAME --name POOL-NAME",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		name, _ := cmd.Flags().GetString("name")
+
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		err = p.DeleteNodePool(cluster, name)
+		return errs.Wrap(err, "Failed to delete node pool")
+	},
+}
+
+func init() {
+	nodepoolCmd.AddCommand(nodepoolDeleteCmd)
+	addWaitFlags(nodepoolDeleteCmd)
+
+	nodepoolDeleteCmd.Flags().StringP("name", "n", "", "name of the node pool")
+	nodepoolDeleteCmd.MarkFlagRequired("name")
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+
+	"github.com/spf13/cobra"
+)
+
+// nodepoolListCmd represents the nodepool list command
+var nodepoolListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "lists the node pools of a Kubernetes cluster",
+	Long:    `Used to list the node pools of a Kubernetes cluster with their size, node count, autoscaling range, labels and taints`,
+	Example: "maker nodepool list --provider {do|aws|gcp} --cluster CLUSTER-NAME",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, _ := cmd.Flags().GetString("cluster")
+
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		pools, err := p.ListNodePools(cluster)
+		if err != nil {
+			return errs.Wrap(err, "Failed to list node pools")
+		}
+		return printNodePools(cmd, pools)
+	},
+}
+
+func init() {
+	nodepoolCmd.AddCommand(nodepoolListCmd)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// nodepoolScaleCmd represents the nodepool scale command
+var nodepoolScaleCmd = &cobra.Command{
+	Use:   "scale",
+	Short: "resizes a node pool of a Kubernetes cluster",
+	Long: `Used to set the number of nodes of a node pool, or the range the autoscaler keeps it in
+Setting only --node-count turns autoscaling off`,
+	Example: "maker nodepool scale --provider {do|aws|gcp} --cluster CLUSTER-NAME --name POOL-NAME --node-count 3",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		name, _ := cmd.Flags().GetString("name")
+		scale := nodeScale(cmd)
+		if err := scale.Validate(); err != nil {
+			return err
+		}
+
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		pool, err := p.ScaleNodePool(cluster, name, scale)
+		if err != nil {
+			return errs.Wrap(err, "Failed to scale node pool")
+		}
+		return printNodePools(cmd, []provider.NodePool{*pool})
+	},
+}
+
+func init() {
+	nodepoolCmd.AddCommand(nodepoolScaleCmd)
+	addWaitFlags(nodepoolScaleCmd)
+
+	nodepoolScaleCmd.Flags().StringP("name", "n", "", "name of the node pool")
+	nodepoolScaleCmd.MarkFlagRequired("name")
+	addScaleFlags(nodepoolScaleCmd)
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile [command]",
+	Short: "manages named profiles of provider config files",
+	Long: `Profiles keep a separate set of provider config files per account, side by side under $HOME/.maker/profiles
+The default profile uses the files directly in $HOME/.maker
+Create a profile with 'maker auth --profile NAME', then pick it per command with --profile,
+with the MAKER_PROFILE environment variable, or for every command with 'maker profile use'`,
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"maker/internal/secrets"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// profileDeleteCmd represents the profile delete command
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "deletes a profile",
+	Long: `Used to delete a named profile and its config files
+Secrets the profile keeps in the OS keyring or encrypted file are deleted too
+Objects created with the profile are left alone, delete them first if they are no longer needed`,
+	Example: "maker profile delete staging",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// the secrets the config files point at would be orphaned otherwise
+		if profile.Validate(args[0]) == nil && args[0] != profile.Default {
+			for _, name := range provider.Names() {
+				reg, _ := provider.Lookup(name)
+				for _, key := range reg.Secrets {
+					if err := secrets.Delete(args[0] + "/" + key); err != nil {
+						return errs.Wrap(err, "Failed to delete profile secrets")
+					}
+				}
+				data, err := ioutil.ReadFile(filepath.Join(profile.Dir(args[0]), reg.ConfigFile))
+				if err != nil {
+					continue
+				}
+				if err := secrets.RemoveAll(data); err != nil {
+					return errs.Wrap(err, "Failed to delete profile secrets")
+				}
+			}
+		}
+		if err := profile.Delete(args[0]); err != nil {
+			return errs.Wrap(err, "Failed to delete profile")
+		}
+		fmt.Println("Deleted profile", args[0])
+		return nil
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileDeleteCmd)
+}
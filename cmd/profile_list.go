@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+	"maker/internal/output"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// profileSummary is how a profile is shown by 'maker profile list'
+type profileSummary struct {
+	Name      string   `json:"name" yaml:"name"`
+	Current   bool     `json:"current" yaml:"current"`
+	Providers []string `json:"providers" yaml:"providers"`
+}
+
+// profileListCmd represents the profile list command
+var profileListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "lists profiles",
+	Long:    `Used to list every profile, the providers configured in each and which one is current`,
+	Example: "maker profile list",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := profile.List()
+		if err != nil {
+			return errs.Wrap(err, "Failed to list profiles")
+		}
+		current := profile.Current()
+
+		summaries := []profileSummary{}
+		var rows [][]string
+		for _, name := range names {
+			summary := profileSummary{Name: name, Current: name == current, Providers: []string{}}
+			for _, providerName := range provider.Names() {
+				reg, _ := provider.Lookup(providerName)
+				if len(profile.Files(name, reg.ConfigFile)) > 0 {
+					summary.Providers = append(summary.Providers, providerName)
+				}
+			}
+			marker := ""
+			if summary.Current {
+				marker = "*"
+			}
+			summaries = append(summaries, summary)
+			rows = append(rows, []string{marker, name, strings.Join(summary.Providers, ",")})
+		}
+		err = output.PrintRows(os.Stdout, outputFormat(cmd), summaries, []string{"CURRENT", "NAME", "PROVIDERS"}, rows)
+		return errs.Wrap(err, "Failed to print output")
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd)
+}
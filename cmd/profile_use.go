@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/profile"
+
+	"github.com/spf13/cobra"
+)
+
+// profileUseCmd represents the profile use command
+var profileUseCmd = &cobra.Command{
+	Use:     "use NAME",
+	Short:   "sets the current profile",
+	Long:    `Used to make a profile the one every later command uses, unless --profile or MAKER_PROFILE say otherwise`,
+	Example: "maker profile use staging",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := profile.Use(args[0]); err != nil {
+			return errs.Wrap(err, "Failed to switch profile")
+		}
+		fmt.Println("Using profile", args[0])
+		return nil
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileUseCmd)
+}
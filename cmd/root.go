@@ -2,12 +2,28 @@ package cmd
 
 import (
 	"fmt"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/output"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"maker/internal/settings"
+	"maker/internal/state"
 	"os"
+	"strings"
+	"time"
+
+	// register the supported providers
+	_ "maker/internal/aws"
+	_ "maker/internal/do"
+	_ "maker/internal/gcp"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-var cfgFile string
+// dryRun is set by the --dry-run flag and stops create and delete requests from being sent
+var dryRun bool
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -16,50 +32,225 @@ var rootCmd = &cobra.Command{
 	Long: `Maker can be used to create various types of services in various cloud providers such as VM's,
 K8s clusters, storage buckets, etc. Its not meant to be a full replacement for each 
 providers own CLI's or clients. Handy for spinning up and down infra for labs and devlopment work kinda thing.`,
+	// errors are printed once by Execute, usage only for bad flags and arguments
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// catch a bad format or profile before any objects get created or fetched
+		if err := profile.Validate(profile.Current()); err != nil {
+			return err
+		}
+		return errs.Wrap(output.Validate(outputFormat(cmd)), "Invalid output")
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Failures exit with a code per error class so scripts can tell them apart.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
+	}
+	if errs.ClassOf(err) == errs.Unknown && isUsageError(err) {
+		// cobra reports bad flags and arguments as plain errors
+		err = &errs.Error{Class: errs.Usage, Err: err}
+		fmt.Fprintln(os.Stderr, cmd.UsageString())
 	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(errs.ExitCode(err))
 }
 
-func init() {
-	//cobra.OnInitialize(initConfig)
+// isUsageError reports whether err came from cobra parsing the command line
+func isUsageError(err error) bool {
+	message := err.Error()
+	for _, prefix := range []string{"unknown command", "unknown flag", "unknown shorthand flag", "required flag", "invalid argument", "flag needs an argument", "accepts ", "requires "} {
+		if strings.HasPrefix(message, prefix) {
+			return true
+		}
+	}
+	return false
+}
 
+func init() {
+	// not marked required since commands like apply read the providers from a file
 	rootCmd.PersistentFlags().StringP("provider", "p", "", "sets the cloud provider")
-	rootCmd.MarkPersistentFlagRequired("provider")
+	rootCmd.PersistentFlags().StringP("output", "o", output.Table, "sets the output format of status and list commands {table|json|yaml}")
+	rootCmd.PersistentFlags().StringVar(&settings.Path, "config", "", "sets the file holding flag defaults (default $HOME/.maker/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile.Name, "profile", "", "sets the profile whose config files are used, overrides "+profile.EnvVar)
+	rootCmd.PersistentFlags().StringVar(&kubeconfig.Path, "kubeconfig", "", "sets the kubeconfig clusters are merged into (default $KUBECONFIG or $HOME/.kube/config)")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "prints the create and delete requests that would be sent without sending them")
 
 	//rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-// initConfig reads in config file and ENV variables if set.
-/*
-func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
+// providerName returns the provider set with the --provider flag, failing if it is missing
+func providerName(cmd *cobra.Command) (string, error) {
+	name, _ := cmd.Flags().GetString("provider")
+	if name == "" {
+		return "", errs.New(errs.Usage, `required flag "provider" not set`)
+	}
+	return name, nil
+}
+
+// loadProvider loads the provider set with the --provider flag
+func loadProvider(cmd *cobra.Command) (provider.Provider, error) {
+	name, err := providerName(cmd)
+	if err != nil {
+		return nil, err
+	}
+	p, err := provider.Get(name, providerOptions(cmd))
+	return p, errs.Wrap(err, "Failed to load provider")
+}
+
+// providerOptions returns the options providers are loaded with, set by global flags and
+// the --wait, --timeout and --yes flags of commands that create or delete objects
+func providerOptions(cmd *cobra.Command) provider.Options {
+	wait, _ := cmd.Flags().GetBool("wait")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	yes, _ := cmd.Flags().GetBool("yes")
+	return provider.Options{
+		DryRun:   dryRun,
+		Plan:     os.Stdout,
+		Wait:     wait,
+		Timeout:  timeout,
+		Progress: os.Stderr,
+		Yes:      yes,
+	}
+}
+
+// applyDefaults fills the flags of a create command that weren't given from the MAKER_*
+// environment variables and the config file, keyed by provider, kind and flag name.
+// Flags left unset keep their built-in defaults.
+func applyDefaults(kind string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("provider")
+		if name == "" {
+			// the missing provider is reported by the command itself
+			return nil
+		}
+		defaults, err := settings.Load()
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return err
 		}
+		var setErr error
+		cmd.NonInheritedFlags().VisitAll(func(f *pflag.Flag) {
+			if f.Changed || setErr != nil {
+				return
+			}
+			if value, ok := defaults.Lookup(name, kind, f.Name); ok {
+				if err := cmd.Flags().Set(f.Name, value); err != nil {
+					setErr = errs.WrapAs(errs.Usage, err, "Invalid default for "+settings.Key(name, kind, f.Name))
+				}
+			}
+		})
+		return setErr
+	}
+}
+
+// resolveAlias returns the value a size or image alias stands for on the provider of a command,
+// from the config file or the built-in aliases. Values that aren't aliases are returned unchanged.
+func resolveAlias(cmd *cobra.Command, kind, value string) (string, error) {
+	name, err := providerName(cmd)
+	if err != nil {
+		return "", err
+	}
+	aliases, err := loadAliases()
+	if err != nil {
+		return "", err
+	}
+	return aliases.Resolve(kind, name, value)
+}
+
+// loadAliases returns the built-in size and image aliases with those of the config file laid over them
+func loadAliases() (provider.Aliases, error) {
+	s, err := settings.Load()
+	if err != nil {
+		return nil, err
+	}
+	return s.Aliases()
+}
+
+// addWaitFlags adds the --wait and --timeout flags to a command and its children
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool("wait", false, "blocks until created objects are ready or deleted objects are gone")
+	cmd.PersistentFlags().Duration("timeout", 30*time.Minute, "how long to wait for objects before failing, 0 waits forever")
+}
+
+// outputFormat returns the format set with the --output flag
+func outputFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("output")
+	return format
+}
+
+// printResource renders a single resource using the --output format
+func printResource(cmd *cobra.Command, resource *provider.Resource) error {
+	err := output.PrintResource(os.Stdout, outputFormat(cmd), resource)
+	return errs.Wrap(err, "Failed to print output")
+}
 
-		// Search config in home directory with name ".maker" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".maker")
+// recordCreate adds a newly created object to the local state file
+func recordCreate(cmd *cobra.Command, kind string, resource *provider.Resource) {
+	flags := map[string]string{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	name, _ := cmd.Flags().GetString("provider")
+	recordResource(name, kind, resource, flags)
+}
+
+// recordResource adds an object to the local state file, warning rather than failing
+// since the object already exists on the provider
+func recordResource(providerName, kind string, resource *provider.Resource, flags map[string]string) {
+	if dryRun {
+		return
+	}
+	created := resource.Created
+	if created.IsZero() {
+		created = time.Now().UTC()
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	err := updateState(func(st *state.State) {
+		st.Add(state.Entry{
+			Provider: providerName,
+			Kind:     kind,
+			Name:     resource.Name,
+			ID:       resource.ID,
+			Region:   resource.Region,
+			Created:  created,
+			Flags:    flags,
+		})
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: object created but not recorded in state:", err)
+	}
+}
+
+// recordDelete removes a deleted object from the local state file
+func recordDelete(cmd *cobra.Command, kind, name string) {
+	p, _ := cmd.Flags().GetString("provider")
+	forgetResource(p, kind, name)
+}
+
+// forgetResource removes an object from the local state file
+func forgetResource(providerName, kind, name string) {
+	if dryRun {
+		return
+	}
+	err := updateState(func(st *state.State) {
+		st.Remove(providerName, kind, name)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: object deleted but state not updated:", err)
+	}
+}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+// updateState loads the state file, applies update and saves it back
+func updateState(update func(st *state.State)) error {
+	st, err := state.Load()
+	if err != nil {
+		return err
 	}
+	update(st)
+	return st.Save()
 }
-*/
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+	"maker/internal/output"
+	"maker/internal/provider"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// sshKeyCmd represents the ssh-key command
+var sshKeyCmd = &cobra.Command{
+	Use:   "ssh-key [command]",
+	Short: "manages the SSH keys new VMs are created with",
+	Long: `Used to list, add and remove the SSH keys of a provider: DigitalOcean account keys, EC2 key pairs
+of the configured region, or the project wide ssh-keys metadata of GCP`,
+}
+
+func init() {
+	rootCmd.AddCommand(sshKeyCmd)
+}
+
+// printSSHKeys renders SSH keys using the --output format
+func printSSHKeys(cmd *cobra.Command, keys []provider.SSHKey) error {
+	var rows [][]string
+	for _, key := range keys {
+		rows = append(rows, []string{key.Provider, key.Name, key.ID, key.Fingerprint})
+	}
+	if keys == nil {
+		keys = []provider.SSHKey{}
+	}
+	err := output.PrintRows(os.Stdout, outputFormat(cmd), keys, []string{"PROVIDER", "NAME", "ID", "FINGERPRINT"}, rows)
+	return errs.Wrap(err, "Failed to print output")
+}
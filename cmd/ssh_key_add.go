@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+	"maker/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// sshKeyAddCmd represents the ssh-key add command
+var sshKeyAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "uploads a local SSH public key to a provider",
+	Long: `Used to upload a public key so new VMs can be logged in to with it
+Keys are named after their comment unless --name is set, GCP logs in as the user of a user@host comment`,
+	Example: "maker ssh-key add --provider {do|aws|gcp} --public-key ~/.ssh/id_ed25519.pub --name laptop",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, _ := cmd.Flags().GetString("public-key")
+		name, _ := cmd.Flags().GetString("name")
+
+		var key *provider.PublicKey
+		var err error
+		if path == "" {
+			key, err = provider.DefaultPublicKey()
+		} else {
+			key, err = provider.ReadPublicKey(path)
+		}
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			key.Name = name
+		}
+
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		added, err := p.AddSSHKey(*key)
+		if err != nil {
+			return errs.Wrap(err, "Failed to add SSH key")
+		}
+		return printSSHKeys(cmd, []provider.SSHKey{*added})
+	},
+}
+
+func init() {
+	sshKeyCmd.AddCommand(sshKeyAddCmd)
+
+	sshKeyAddCmd.Flags().StringP("public-key", "k", "", "path of the public key (default the first of ~/.ssh/id_ed25519.pub, id_ecdsa.pub and id_rsa.pub)")
+	sshKeyAddCmd.Flags().StringP("name", "n", "", "name of the key on the provider (default the key comment)")
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+
+	"github.com/spf13/cobra"
+)
+
+// sshKeyListCmd represents the ssh-key list command
+var sshKeyListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "lists the SSH keys of a provider",
+	Long:    `Used to list the SSH keys a VM can be created with, by the name, ID or fingerprint shown`,
+	Example: "maker ssh-key list --provider {do|aws|gcp}",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		keys, err := p.ListSSHKeys()
+		if err != nil {
+			return errs.Wrap(err, "Failed to list SSH keys")
+		}
+		return printSSHKeys(cmd, keys)
+	},
+}
+
+func init() {
+	sshKeyCmd.AddCommand(sshKeyListCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+
+	"github.com/spf13/cobra"
+)
+
+// sshKeyRemoveCmd represents the ssh-key remove command
+var sshKeyRemoveCmd = &cobra.Command{
+	Use:     "remove",
+	Short:   "removes an SSH key from a provider",
+	Long:    `Used to remove an SSH key by its name, ID or fingerprint. VMs created with the key keep it`,
+	Example: "maker ssh-key remove --provider {do|aws|gcp} --name laptop",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		err = p.RemoveSSHKey(name)
+		return errs.Wrap(err, "Failed to remove SSH key")
+	},
+}
+
+func init() {
+	sshKeyCmd.AddCommand(sshKeyRemoveCmd)
+
+	sshKeyRemoveCmd.Flags().StringP("name", "n", "", "name, ID or fingerprint of the key")
+	sshKeyRemoveCmd.MarkFlagRequired("name")
+}
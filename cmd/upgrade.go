@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [object]",
+	Short: "upgrades the specified object on the specified platform",
+	Long: `Used to move an existing object to a newer version on the cloud provider specified
+Upgrades are always waited on, since each step can only start once the one before it is done`,
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.PersistentFlags().Duration("timeout", time.Hour, "how long to wait for each step of the upgrade before failing, 0 waits forever")
+}
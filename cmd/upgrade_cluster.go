@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"maker/internal/errs"
+	"maker/internal/output"
+	"maker/internal/provider"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// upgradeClusterCmd represents the upgradeCluster command
+var upgradeClusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "upgrades the Kubernetes version of a cluster",
+	Long: `Used to upgrade the control plane of a Kubernetes cluster and then its node pools
+Without --version the versions the cluster can be upgraded to are listed. A version can be
+given in full or as a minor version such as 1.20, which picks the newest available patch.
+EKS and GKE move the control plane one minor version at a time, rerunning with the current
+version upgrades node pools an interrupted upgrade left behind`,
+	Example: "maker upgrade cluster --provider {do|aws|gcp} --name CLUSTER-NAME --version VERSION",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		version, _ := cmd.Flags().GetString("version")
+
+		p, err := loadProvider(cmd)
+		if err != nil {
+			return err
+		}
+		if version == "" {
+			versions, err := p.ClusterUpgrades(name)
+			if err != nil {
+				return errs.Wrap(err, "Failed to list cluster upgrades")
+			}
+			return printClusterVersions(cmd, versions)
+		}
+		resource, err := p.UpgradeCluster(name, version)
+		if err != nil {
+			return errs.Wrap(err, "Failed to upgrade cluster")
+		}
+		return printResource(cmd, resource)
+	},
+}
+
+// printClusterVersions prints the versions a cluster can be upgraded to using the --output format
+func printClusterVersions(cmd *cobra.Command, versions *provider.ClusterVersions) error {
+	rows := [][]string{{versions.Provider, versions.Cluster, versions.Current, strings.Join(versions.Available, ",")}}
+	err := output.PrintRows(os.Stdout, outputFormat(cmd), versions, []string{"PROVIDER", "CLUSTER", "CURRENT", "AVAILABLE"}, rows)
+	return errs.Wrap(err, "Failed to print output")
+}
+
+func init() {
+	upgradeCmd.AddCommand(upgradeClusterCmd)
+
+	upgradeClusterCmd.Flags().StringP("name", "n", "", "name of the cluster")
+	upgradeClusterCmd.MarkFlagRequired("name")
+	upgradeClusterCmd.Flags().StringP("version", "v", "", "sets the Kubernetes version to upgrade to, lists the available ones when left out")
+}
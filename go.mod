@@ -5,9 +5,10 @@ go 1.15
 require (
 	cloud.google.com/go v0.77.0
 	cloud.google.com/go/storage v1.13.0
-	github.com/aws/aws-sdk-go v1.37.15
+	github.com/aws/aws-sdk-go v1.38.37
 	github.com/digitalocean/godo v1.58.0
 	github.com/fsnotify/fsnotify v1.4.9 // indirect
+	github.com/googleapis/gax-go/v2 v2.0.5
 	github.com/kr/pretty v0.2.0 // indirect
 	github.com/magiconair/properties v1.8.4 // indirect
 	//github.com/mitchellh/go-homedir v1.1.0
@@ -18,17 +19,21 @@ require (
 	github.com/spf13/cast v1.3.1 // indirect
 	github.com/spf13/cobra v1.1.3
 	github.com/spf13/jwalterweatherman v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.1
+	github.com/zalando/go-keyring v0.1.1
 	go.opencensus.io v0.22.6 // indirect
 	golang.org/x/crypto v0.0.0-20210220033148-5ea612d1eb83
 	golang.org/x/net v0.0.0-20210220033124-5f55cee0dc0d
-	golang.org/x/oauth2 v0.0.0-20210220000619-9bb904979d93 // indirect
+	golang.org/x/oauth2 v0.0.0-20210220000619-9bb904979d93
 	golang.org/x/sys v0.0.0-20210220050731-9a76102bfb43 // indirect
 	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d // indirect
 	google.golang.org/api v0.40.0
 	google.golang.org/genproto v0.0.0-20210219173056-d891e3cb3b5b
+	google.golang.org/grpc v1.35.0
+	google.golang.org/protobuf v1.25.0
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 	gopkg.in/ini.v1 v1.62.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776 // indirect
-
 )
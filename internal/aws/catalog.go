@@ -0,0 +1,143 @@
+package aws
+
+import (
+	"maker/internal/provider"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/pkg/errors"
+)
+
+// imageSeries are the public AMIs listed as images, new builds are published every few
+// weeks so only the newest of each series is shown
+var imageSeries = []struct {
+	distro, owner, name string
+}{
+	{"Amazon Linux 2", "amazon", "amzn2-ami-hvm-*-x86_64-gp2"},
+	{"Ubuntu 20.04", "099720109477", "ubuntu/images/hvm-ssd/ubuntu-focal-20.04-amd64-server-*"},
+	{"Ubuntu 18.04", "099720109477", "ubuntu/images/hvm-ssd/ubuntu-bionic-18.04-amd64-server-*"},
+	{"Debian 10", "136693071363", "debian-10-amd64-*"},
+}
+
+// ListEc2InstanceTypes fetches the current generation instance types of the region
+func ListEc2InstanceTypes(svc ec2iface.EC2API) ([]provider.CatalogEntry, error) {
+	input := &ec2.DescribeInstanceTypesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("current-generation"), Values: aws.StringSlice([]string{"true"})}},
+	}
+	var entries []provider.CatalogEntry
+	err := svc.DescribeInstanceTypesPages(input, func(page *ec2.DescribeInstanceTypesOutput, lastPage bool) bool {
+		for _, info := range page.InstanceTypes {
+			entry := provider.CatalogEntry{Slug: aws.StringValue(info.InstanceType)}
+			if info.VCpuInfo != nil {
+				entry.VCPUs = int(aws.Int64Value(info.VCpuInfo.DefaultVCpus))
+			}
+			if info.MemoryInfo != nil {
+				entry.MemoryMB = int(aws.Int64Value(info.MemoryInfo.SizeInMiB))
+			}
+			if info.ProcessorInfo != nil {
+				entry.Description = strings.Join(aws.StringValueSlice(info.ProcessorInfo.SupportedArchitectures), ",")
+			}
+			entries = append(entries, entry)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list instance types")
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Slug < entries[j].Slug })
+	return entries, nil
+}
+
+// ListEc2Images fetches the newest AMI of each image series in the region
+func ListEc2Images(svc ec2iface.EC2API) ([]provider.CatalogEntry, error) {
+	input := &ec2.DescribeImagesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("state"), Values: aws.StringSlice([]string{"available"})}},
+	}
+	owners := map[string]bool{}
+	var names []string
+	for _, series := range imageSeries {
+		if !owners[series.owner] {
+			owners[series.owner] = true
+			input.Owners = append(input.Owners, aws.String(series.owner))
+		}
+		names = append(names, series.name)
+	}
+	input.Filters = append(input.Filters, &ec2.Filter{Name: aws.String("name"), Values: aws.StringSlice(names)})
+
+	result, err := svc.DescribeImages(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list images")
+	}
+	newest := make([]*ec2.Image, len(imageSeries))
+	for _, image := range result.Images {
+		for i, series := range imageSeries {
+			if matched, _ := path.Match(series.name, aws.StringValue(image.Name)); !matched {
+				continue
+			}
+			if newest[i] == nil || aws.StringValue(image.CreationDate) > aws.StringValue(newest[i].CreationDate) {
+				newest[i] = image
+			}
+		}
+	}
+	var entries []provider.CatalogEntry
+	for i, image := range newest {
+		if image != nil {
+			entries = append(entries, provider.CatalogEntry{
+				Slug:        aws.StringValue(image.ImageId),
+				Description: aws.StringValue(image.Name),
+				Distro:      imageSeries[i].distro,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// ListEc2Regions fetches the regions enabled for the account
+func ListEc2Regions(svc ec2iface.EC2API) ([]provider.CatalogEntry, error) {
+	result, err := svc.DescribeRegions(&ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list regions")
+	}
+	var entries []provider.CatalogEntry
+	for _, region := range result.Regions {
+		entries = append(entries, provider.CatalogEntry{Slug: aws.StringValue(region.RegionName), Description: aws.StringValue(region.Endpoint)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Slug < entries[j].Slug })
+	return entries, nil
+}
+
+// ListEksVersions finds the Kubernetes versions EKS supports, EKS has no call listing them
+// so they are gathered from the cluster versions its add-ons are compatible with
+func ListEksVersions(svc eksiface.EKSAPI) ([]provider.CatalogEntry, error) {
+	seen := map[string]bool{}
+	var versions []string
+	err := svc.DescribeAddonVersionsPages(&eks.DescribeAddonVersionsInput{}, func(page *eks.DescribeAddonVersionsOutput, lastPage bool) bool {
+		for _, addon := range page.Addons {
+			for _, addonVersion := range addon.AddonVersions {
+				for _, compatibility := range addonVersion.Compatibilities {
+					version := aws.StringValue(compatibility.ClusterVersion)
+					if version != "" && !seen[version] {
+						seen[version] = true
+						versions = append(versions, version)
+					}
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list EKS versions")
+	}
+	sort.Slice(versions, func(i, j int) bool { return provider.CompareVersions(versions[i], versions[j]) > 0 })
+	var entries []provider.CatalogEntry
+	for _, version := range versions {
+		entries = append(entries, provider.CatalogEntry{Slug: version, Description: "Kubernetes " + version})
+	}
+	return entries, nil
+}
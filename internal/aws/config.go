@@ -2,116 +2,222 @@ package aws
 
 import (
 	"fmt"
-	"io/ioutil"
-	"maker/internal/utils"
+	"maker/internal/errs"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"maker/internal/secrets"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/pkg/errors"
-	"github.com/spf13/viper"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 // CredsFile makes up the required settings in a AWS config file
 type CredsFile struct {
+	// CredentialSource is SourceKeys or SourceChain, files written before it existed use keys
+	CredentialSource string
+	// SharedProfile is the ~/.aws/config profile the credential chain uses, empty for AWS_PROFILE or default
+	SharedProfile   string
 	AccessKeyID     string
 	SecretAccessKey string
 	DefaultRegion   string
+	// RoleARN is assumed with the credentials above when set
+	RoleARN    string
+	ExternalID string
+	MFASerial  string
 }
 
 // CredsName is the name of the config file used by Maker
 var CredsName = "aws_credentials"
 
-// CredsPath is the full path to the ConfigFile
-var CredsPath = filepath.Join(utils.ConfigFolderPath, CredsName)
+// CredsPath returns the full path to the config file of the active profile
+func CredsPath() string {
+	return profile.Path(CredsName)
+}
+
+// credsSchema lists the settings in the credentials file, kept in the sections the AWS CLI uses
+var credsSchema = provider.ConfigSchema{
+	Path: CredsPath,
+	Type: "toml",
+	Keys: []string{
+		"default.credential_source", "default.aws_profile", "default.aws_access_key_id", "default.aws_secret_access_key",
+		"region.default_region", "role.role_arn", "role.external_id", "role.mfa_serial",
+	},
+}
+
+// Environment variables checked for credentials, the same ones the AWS CLI uses
+var (
+	AccessKeyEnvs = []string{"AWS_ACCESS_KEY_ID"}
+	SecretKeyEnvs = []string{"AWS_SECRET_ACCESS_KEY"}
+	RegionEnvs    = []string{"AWS_REGION", "AWS_DEFAULT_REGION"}
+)
 
 // Configure sets up the aws credentials file needed to auth with AWS
-func Configure() error {
-	// check that .maker exists
-	_, err := os.Stat(utils.ConfigFolderPath)
-	if os.IsNotExist(err) {
-		err := os.Mkdir(utils.ConfigFolderPath, 0755)
-		if err != nil {
-			return errors.Wrapf(err, "Failed to creds folder %s:", utils.ConfigFolder)
+func Configure(opts provider.AuthOptions) error {
+	// check that the profile folder exists
+	if _, err := profile.EnsureDir(); err != nil {
+		return err
+	}
+
+	// everything needed came from flags and the environment, nothing to ask
+	complete := provider.Env(AccessKeyEnvs...) != "" && opts.Token(SecretKeyEnvs...) != "" && authRegion(opts) != ""
+	if opts.NonInteractive || opts.CredentialChain || complete {
+		opts.NonInteractive = true
+		if err := CreateCredsFile(&CredsFile{}, opts); err != nil {
+			return errors.Wrapf(err, "Failed to create creds file %s", CredsName)
 		}
+		fmt.Println("Creds file generated at", CredsPath())
+		return nil
 	}
 
 	// check if config exists to create, or to verify
-	_, err = os.Stat(CredsPath)
+	_, err := os.Stat(CredsPath())
 	if os.IsNotExist(err) {
 		creds := &CredsFile{}
-		err = CreateCredsFile(creds)
+		err = CreateCredsFile(creds, opts)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to create creds file %s:", CredsName)
+			return errors.Wrapf(err, "Failed to create creds file %s", CredsName)
 		}
 	}
-	fmt.Println("Creds file generated at", CredsPath)
-	ShowCurrentCreds()
-	return nil
+	fmt.Println("Creds file generated at", CredsPath())
+	return ShowCurrentCreds(opts)
 }
 
-// ShowCurrentCreds prints out the current credentials file
-func ShowCurrentCreds() error {
-	data, err := ioutil.ReadFile(CredsPath)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to read file %s:", CredsPath)
+// authRegion returns the region set with --region or in the environment
+func authRegion(opts provider.AuthOptions) string {
+	if opts.Region != "" {
+		return opts.Region
 	}
-	fmt.Printf("\nCurrent Credentials:\n\n%s", string(data))
+	return provider.Env(RegionEnvs...)
+}
 
-	var confirmation string
-	fmt.Print("Is this info accurate? (Y/n): ")
-	fmt.Scanln(&confirmation)
-	confirmation = strings.ToLower(string(confirmation))
-	println()
+// ShowCurrentCreds prints out the current credentials file with the secret key redacted
+func ShowCurrentCreds(opts provider.AuthOptions) error {
+	creds, err := readCreds()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nCurrent Credentials:\n\n")
+	fmt.Printf("credential_source: %s\n", creds.CredentialSource)
+	if creds.CredentialSource == SourceChain {
+		fmt.Printf("aws_profile: %s\n", creds.SharedProfile)
+	} else {
+		fmt.Printf("aws_access_key_id: %s\n", creds.AccessKeyID)
+		fmt.Printf("aws_secret_access_key: %s\n", secrets.Redact(creds.SecretAccessKey))
+	}
+	fmt.Printf("default_region: %s\n", creds.DefaultRegion)
+	if creds.RoleARN != "" {
+		fmt.Printf("role_arn: %s\n", creds.RoleARN)
+		fmt.Printf("external_id: %s\n", secrets.Redact(creds.ExternalID))
+		fmt.Printf("mfa_serial: %s\n", creds.MFASerial)
+	}
+	fmt.Println()
 
-	if confirmation != "y" {
+	if !opts.Confirm("Is this info accurate? (Y/n): ") {
 		creds := &CredsFile{}
-		err = CreateCredsFile(creds)
+		err = CreateCredsFile(creds, opts)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to create creds file %s:", CredsName)
+			return errors.Wrapf(err, "Failed to create creds file %s", CredsName)
 		}
-		fmt.Println("Credentials file generated at", CredsPath)
+		fmt.Println("Credentials file generated at", CredsPath())
 	}
 	return nil
 }
 
-// CreateCredsFile makes the credentials file to use in all AWS commands
-func CreateCredsFile(creds *CredsFile) error {
-	// ask for access key
-	var accessKey string
-	fmt.Print("Enter AWS Access Key ID: ")
-	fmt.Scanln(&accessKey)
-	creds.AccessKeyID = string(accessKey)
+// CreateCredsFile makes the credentials file to use in all AWS commands, prompting
+// for whatever the AWS_* environment variables and --region don't give.
+// With the credential chain no keys are stored and the region may come from ~/.aws/config.
+func CreateCredsFile(creds *CredsFile, opts provider.AuthOptions) error {
+	var err error
+	if opts.CredentialChain {
+		creds.CredentialSource = SourceChain
+		creds.SharedProfile = opts.SharedProfile
+		creds.DefaultRegion = authRegion(opts)
+	} else {
+		creds.CredentialSource = SourceKeys
+		creds.AccessKeyID, err = opts.Ask("access key ID", "AWS_ACCESS_KEY_ID", "Enter AWS Access Key ID: ", provider.Env(AccessKeyEnvs...), false)
+		if err != nil {
+			return err
+		}
+		creds.SecretAccessKey, err = opts.Ask("secret access key", "--token-env or AWS_SECRET_ACCESS_KEY", "Enter AWS Secret Key ID: ", opts.Token(SecretKeyEnvs...), true)
+		if err != nil {
+			return err
+		}
+		creds.DefaultRegion, err = opts.Ask("default region", "--region or AWS_REGION", "Enter Default Region: ", authRegion(opts), false)
+		if err != nil {
+			return err
+		}
+	}
+	creds.RoleARN, creds.ExternalID, creds.MFASerial = opts.RoleARN, opts.ExternalID, opts.MFASerial
+	if creds.RoleARN == "" && (creds.ExternalID != "" || creds.MFASerial != "") {
+		return errs.New(errs.Usage, "An external ID or MFA serial needs a role to assume -- set --role-arn")
+	}
 
-	fmt.Print("Enter AWS Secret Key ID: ")
-	pass, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	// the secret key goes to the secret store, the file only holds a reference to it
+	secretRef, err := secrets.Save(profile.Current()+"/aws/aws_secret_access_key", creds.SecretAccessKey)
 	if err != nil {
-		return errors.Errorf("Failed to capture password:", err)
+		return err
 	}
-	creds.SecretAccessKey = string(pass)
-	println()
 
-	var region string
-	fmt.Print("Enter Default Region: ")
-	fmt.Scanln(&region)
-	creds.DefaultRegion = string(region)
-
-	viper.SetConfigType("toml")
-	viper.Set("default.aws_access_key_id", creds.AccessKeyID)
-	viper.Set("default.aws_secret_access_key", creds.SecretAccessKey)
-	viper.Set("region.default_region", creds.DefaultRegion)
-	return viper.WriteConfigAs(CredsPath)
+	return credsSchema.Write(map[string]string{
+		"default.credential_source":     creds.CredentialSource,
+		"default.aws_profile":           creds.SharedProfile,
+		"default.aws_access_key_id":     creds.AccessKeyID,
+		"default.aws_secret_access_key": secretRef,
+		"region.default_region":         creds.DefaultRegion,
+		"role.role_arn":                 creds.RoleARN,
+		"role.external_id":              creds.ExternalID,
+		"role.mfa_serial":               creds.MFASerial,
+	})
 }
 
-// LoadConfig parses the viper config file and loads into a struct
-func LoadConfig() (string, error) {
-	// This all needs to be cleaned up using vars but for now...
-	viper.SetConfigFile(CredsPath)
-	viper.SetConfigType("toml")
-	if err := viper.ReadInConfig(); err != nil {
-		return "", errors.Errorf("Error reading creds file %s:", CredsPath, err)
+// LoadConfig parses the credentials file and loads into a struct, fetching the secret key from the secret store
+func LoadConfig() (*CredsFile, error) {
+	creds, err := readCreds()
+	if err != nil {
+		return nil, err
+	}
+	switch creds.CredentialSource {
+	case SourceChain:
+		return creds, nil
+	case SourceKeys:
+	default:
+		return nil, errs.Errorf(errs.AuthFailed, "Unknown credential_source %s in %s -- must be %s or %s", creds.CredentialSource, CredsPath(), SourceKeys, SourceChain)
+	}
+	required := []struct{ key, value string }{
+		{"aws_access_key_id", creds.AccessKeyID},
+		{"aws_secret_access_key", creds.SecretAccessKey},
+		{"default_region", creds.DefaultRegion},
 	}
+	for _, setting := range required {
+		if setting.value == "" {
+			return nil, errs.Errorf(errs.AuthFailed, "Config file %s is missing %s -- run 'maker auth' again", CredsPath(), setting.key)
+		}
+	}
+	creds.SecretAccessKey, err = secrets.Resolve(creds.SecretAccessKey)
+	if err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
 
-	return viper.GetString("region.default_region"), nil
+// readCreds parses the credentials file, leaving a reference to the stored secret key as it is
+func readCreds() (*CredsFile, error) {
+	v, err := credsSchema.Read()
+	if err != nil {
+		return nil, err
+	}
+	creds := &CredsFile{
+		CredentialSource: v.GetString("default.credential_source"),
+		SharedProfile:    v.GetString("default.aws_profile"),
+		AccessKeyID:      v.GetString("default.aws_access_key_id"),
+		SecretAccessKey:  v.GetString("default.aws_secret_access_key"),
+		DefaultRegion:    v.GetString("region.default_region"),
+		RoleARN:          v.GetString("role.role_arn"),
+		ExternalID:       v.GetString("role.external_id"),
+		MFASerial:        v.GetString("role.mfa_serial"),
+	}
+	if creds.CredentialSource == "" {
+		creds.CredentialSource = SourceKeys
+	}
+	return creds, nil
 }
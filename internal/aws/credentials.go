@@ -0,0 +1,151 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/profile"
+	"maker/internal/secrets"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Credential sources of the credentials file
+const (
+	// SourceKeys uses the access keys stored by 'maker auth'
+	SourceKeys = "keys"
+	// SourceChain uses the SDK default credential chain: environment variables, ~/.aws/config
+	// and ~/.aws/credentials including SSO profiles, then container and instance roles
+	SourceChain = "chain"
+)
+
+// MFATokenEnv holds the MFA code used to assume a role so it isn't prompted for
+const MFATokenEnv = "MAKER_MFA_TOKEN"
+
+// RoleCacheSecret is the secret in each profile caching the temporary credentials of assumed roles
+const RoleCacheSecret = "aws/role_cache"
+
+// expiryWindow is how long before they expire cached credentials are refreshed
+const expiryWindow = 5 * time.Minute
+
+// assumeRole returns credentials for the role in the credentials file, assumed with the
+// credentials of sess and cached in the profile until they are about to expire
+func assumeRole(sess client.ConfigProvider, creds *CredsFile) *credentials.Credentials {
+	assume := stscreds.NewCredentials(sess, creds.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = "maker-" + profile.Current()
+		p.ExpiryWindow = expiryWindow
+		if creds.ExternalID != "" {
+			p.ExternalID = &creds.ExternalID
+		}
+		if creds.MFASerial != "" {
+			p.SerialNumber = &creds.MFASerial
+			p.TokenProvider = mfaToken
+		}
+	})
+	return credentials.NewCredentials(&cachingProvider{
+		key:    creds.RoleARN + "|" + creds.ExternalID,
+		secret: profile.Current() + "/" + RoleCacheSecret,
+		assume: assume,
+	})
+}
+
+// mfaToken reads the MFA code from the environment, or from the terminal when there is one
+func mfaToken() (string, error) {
+	if token := os.Getenv(MFATokenEnv); token != "" {
+		return token, nil
+	}
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errs.Errorf(errs.AuthFailed, "Missing MFA token to assume role -- set %s", MFATokenEnv)
+	}
+	fmt.Fprint(os.Stderr, "Enter MFA token: ")
+	var token string
+	fmt.Fscanln(os.Stdin, &token)
+	return token, nil
+}
+
+// cachedCreds are temporary credentials saved to the role cache
+type cachedCreds struct {
+	AccessKeyID     string    `json:"access_key_id"`
+	SecretAccessKey string    `json:"secret_access_key"`
+	SessionToken    string    `json:"session_token"`
+	Expires         time.Time `json:"expires"`
+}
+
+// cachingProvider serves assumed role credentials from the secret store, only calling STS,
+// and asking for an MFA token, when there are none or they are about to expire
+type cachingProvider struct {
+	key    string
+	secret string
+	assume *credentials.Credentials
+
+	expires time.Time
+}
+
+func (c *cachingProvider) Retrieve() (credentials.Value, error) {
+	cache := readRoleCache(c.secret)
+	if cached, ok := cache[c.key]; ok && time.Until(cached.Expires) > expiryWindow {
+		c.expires = cached.Expires
+		return credentials.Value{
+			AccessKeyID:     cached.AccessKeyID,
+			SecretAccessKey: cached.SecretAccessKey,
+			SessionToken:    cached.SessionToken,
+			ProviderName:    "MakerRoleCache",
+		}, nil
+	}
+
+	value, err := c.assume.Get()
+	if err != nil {
+		return value, errs.Wrap(err, "Failed to assume role")
+	}
+	c.expires, err = c.assume.ExpiresAt()
+	if err != nil {
+		// without an expiry the credentials can't be cached
+		c.expires = time.Now()
+		return value, nil
+	}
+	cache[c.key] = cachedCreds{
+		AccessKeyID:     value.AccessKeyID,
+		SecretAccessKey: value.SecretAccessKey,
+		SessionToken:    value.SessionToken,
+		Expires:         c.expires,
+	}
+	if err := writeRoleCache(c.secret, cache); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: role credentials not cached:", err)
+	}
+	return value, nil
+}
+
+func (c *cachingProvider) IsExpired() bool {
+	return time.Until(c.expires) < expiryWindow
+}
+
+// readRoleCache loads the role cache, a missing or unreadable cache is empty
+func readRoleCache(secret string) map[string]cachedCreds {
+	cache := map[string]cachedCreds{}
+	data, err := secrets.Load(secret)
+	if err == nil {
+		json.Unmarshal([]byte(data), &cache)
+	}
+	return cache
+}
+
+// writeRoleCache saves the role cache to the secret store, dropping expired credentials
+func writeRoleCache(secret string, cache map[string]cachedCreds) error {
+	for key, cached := range cache {
+		if time.Now().After(cached.Expires) {
+			delete(cache, key)
+		}
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal role cache")
+	}
+	_, err = secrets.Save(secret, string(data))
+	return err
+}
@@ -0,0 +1,156 @@
+package aws
+
+import (
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"maker/internal/secrets"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// useTempConfig points the config files at a temporary directory
+func useTempConfig(t *testing.T) string {
+	dir := t.TempDir()
+	oldFolder, oldProfile := utils.ConfigFolderPath, profile.Name
+	utils.ConfigFolderPath, profile.Name = dir, ""
+	t.Cleanup(func() {
+		utils.ConfigFolderPath, profile.Name = oldFolder, oldProfile
+	})
+	setenv(t, profile.EnvVar, "")
+	setenv(t, secrets.StoreEnv, secrets.File)
+	setenv(t, secrets.PassphraseEnv, "test")
+	return dir
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// fakeAssume hands out numbered temporary credentials, counting the calls to STS
+type fakeAssume struct {
+	credentials.Expiry
+	calls int
+	ttl   time.Duration
+}
+
+func (f *fakeAssume) Retrieve() (credentials.Value, error) {
+	f.calls++
+	f.SetExpiration(time.Now().Add(f.ttl), 0)
+	return credentials.Value{AccessKeyID: "ASIA" + strconv.Itoa(f.calls), SecretAccessKey: "wJalrSecretKey", SessionToken: "FwoGSessionToken"}, nil
+}
+
+func TestRoleCredentialsAreCached(t *testing.T) {
+	dir := useTempConfig(t)
+	assume := &fakeAssume{ttl: time.Hour}
+	newCreds := func() *credentials.Credentials {
+		return credentials.NewCredentials(&cachingProvider{
+			key:    "arn:aws:iam::123456789012:role/lab|",
+			secret: "default/" + RoleCacheSecret,
+			assume: credentials.NewCredentials(assume),
+		})
+	}
+
+	first, err := newCreds().Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	// a later command reads the cache instead of assuming the role again
+	second, err := newCreds().Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if assume.calls != 1 || second.AccessKeyID != first.AccessKeyID || second.SessionToken != "FwoGSessionToken" {
+		t.Errorf("expected cached credentials, got %d calls and %+v", assume.calls, second)
+	}
+	// the cache lives in the encrypted store, nothing under the config folder holds the credentials in the clear
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, secret := range []string{first.SecretAccessKey, first.SessionToken} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s holds role credentials in plaintext", path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoleCredentialsRefreshNearExpiry(t *testing.T) {
+	useTempConfig(t)
+	assume := &fakeAssume{ttl: time.Minute}
+	for i := 0; i < 2; i++ {
+		creds := credentials.NewCredentials(&cachingProvider{
+			key:    "role",
+			secret: "default/" + RoleCacheSecret,
+			assume: credentials.NewCredentials(assume),
+		})
+		if _, err := creds.Get(); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	}
+	if assume.calls != 2 {
+		t.Errorf("credentials about to expire should be refreshed, got %d calls", assume.calls)
+	}
+}
+
+func TestCredentialChainConfig(t *testing.T) {
+	useTempConfig(t)
+	opts := provider.AuthOptions{
+		NonInteractive:  true,
+		CredentialChain: true,
+		SharedProfile:   "lab-sso",
+		RoleARN:         "arn:aws:iam::123456789012:role/lab",
+		MFASerial:       "arn:aws:iam::123456789012:mfa/me",
+	}
+	if err := Configure(opts); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	creds, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	want := CredsFile{CredentialSource: SourceChain, SharedProfile: "lab-sso", RoleARN: opts.RoleARN, MFASerial: opts.MFASerial}
+	if *creds != want {
+		t.Errorf("creds = %+v, want %+v", *creds, want)
+	}
+
+	opts = provider.AuthOptions{NonInteractive: true, CredentialChain: true, ExternalID: "lab"}
+	if err := Configure(opts); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error for an external ID without a role, got %v", err)
+	}
+}
+
+func TestKeysConfigRequiresKeys(t *testing.T) {
+	useTempConfig(t)
+	if err := credsSchema.Write(map[string]string{"region.default_region": "us-east-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); errs.ClassOf(err) != errs.AuthFailed {
+		t.Errorf("expected an AuthFailed error for missing keys, got %v", err)
+	}
+}
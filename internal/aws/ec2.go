@@ -1,90 +1,122 @@
 package aws
 
 import (
+	"encoding/base64"
 	"fmt"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/pkg/errors"
 )
 
-// CreateAwsSession sets up a new session using the config file
-func CreateAwsSession(credentialsFile, defaultRegion string) (*session.Session, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(defaultRegion),
-		Credentials: credentials.NewSharedCredentials(credentialsFile, "default")},
-	)
+// CreateAwsSession sets up a new session using the keys from the credentials file, or the
+// SDK default credential chain, then assumes the configured role if there is one
+func CreateAwsSession(creds *CredsFile) (*session.Session, error) {
+	opts := session.Options{}
+	if creds.DefaultRegion != "" {
+		opts.Config.Region = aws.String(creds.DefaultRegion)
+	}
+	if creds.CredentialSource == SourceChain {
+		// profiles in ~/.aws/config may assume roles with MFA or use SSO
+		opts.SharedConfigState = session.SharedConfigEnable
+		opts.Profile = creds.SharedProfile
+		opts.AssumeRoleTokenProvider = mfaToken
+	} else {
+		opts.Config.Credentials = credentials.NewStaticCredentials(creds.AccessKeyID, creds.SecretAccessKey, "")
+	}
+	sess, err := session.NewSessionWithOptions(opts)
 	if err != nil {
-		return nil, errors.Errorf("Failed to create session:", err)
+		return nil, errors.Wrap(err, "Failed to create session")
+	}
+	if creds.RoleARN != "" {
+		sess = sess.Copy(&aws.Config{Credentials: assumeRole(sess, creds)})
 	}
 	return sess, nil
 }
 
-// CreateEc2Instance creates an ec2 instance with provided specs
-func CreateEc2Instance(sess *session.Session, name, region, instanceType, ami string) error {
-	// Create the instance
-	svc := ec2.New(sess)
-	var sshkeyName *string
-	keys, err := svc.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{})
-	if err != nil {
-		return errors.Errorf("Failed to check keypairs:", err)
-	}
-	if len(keys.KeyPairs) < 1 {
-		fmt.Println("To access an EC2 instance, an SSH Key is required")
-		fmt.Println("Create an SSH Key and Upload and try again")
-		fmt.Println("https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/ec2-key-pairs.html#prepare-key-pair")
-		return errors.Errorf("failed to create instance: SSH Key required and none are avaiable")
-	}
-	if len(keys.KeyPairs) > 1 {
-		fmt.Println("Multiple SSH Keys found -- Which would you like to use?")
-		for _, sshkeys := range keys.KeyPairs {
-			fmt.Printf("Name: %s  ID: %s\n", *sshkeys.KeyName, *sshkeys.KeyPairId)
-		}
-		fmt.Printf("Enter a Key Name: ")
-		var key string
-		fmt.Scanln(&key)
-		sshkeyName = &key
-	} else {
-		fmt.Printf("Using SSH Key %v\n", *keys.KeyPairs[0].KeyName)
-		sshkeyName = keys.KeyPairs[0].KeyName
-	}
-	result, err := svc.RunInstances(&ec2.RunInstancesInput{
+// Ec2RunInput builds the request to create a named ec2 instance that the key pair is added to
+func Ec2RunInput(name, instanceType, ami, keyName string) *ec2.RunInstancesInput {
+	return &ec2.RunInstancesInput{
 		ImageId:      aws.String(ami),
 		InstanceType: aws.String(instanceType),
 		MinCount:     aws.Int64(1),
 		MaxCount:     aws.Int64(1),
-		KeyName:      sshkeyName,
-	})
+		KeyName:      aws.String(keyName),
+		// tag on launch so the instance is never around without its name
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeInstance),
+				Tags: []*ec2.Tag{
+					{
+						Key:   aws.String("Name"),
+						Value: aws.String(name),
+					},
+				},
+			},
+		},
+	}
+}
 
-	if err != nil {
-		return errors.Wrapf(err, "Failed to create EC2 instance %s:", name)
+// Ec2UserData encodes user data the way RunInstances takes it, nil when there is none
+func Ec2UserData(data string) *string {
+	if data == "" {
+		return nil
 	}
-	fmt.Println("Created instance", *result.Instances[0].InstanceId)
+	return aws.String(base64.StdEncoding.EncodeToString([]byte(data)))
+}
 
-	// Add tags to the created instance
-	_, err = svc.CreateTags(&ec2.CreateTagsInput{
-		Resources: []*string{result.Instances[0].InstanceId},
-		Tags: []*ec2.Tag{
-			{
-				Key:   aws.String("Name"),
-				Value: aws.String(name),
-			},
+// ResolveEc2Image returns the AMI to launch. AMI IDs are used as they are, 'OWNER/NAME-PATTERN'
+// as the image aliases use is looked up as the newest available AMI of that owner in the region.
+func ResolveEc2Image(svc ec2iface.EC2API, image string) (string, error) {
+	i := strings.Index(image, "/")
+	if strings.HasPrefix(image, "ami-") || i < 0 {
+		return image, nil
+	}
+	owner, pattern := image[:i], image[i+1:]
+	result, err := svc.DescribeImages(&ec2.DescribeImagesInput{
+		Owners: aws.StringSlice([]string{owner}),
+		Filters: []*ec2.Filter{
+			{Name: aws.String("name"), Values: aws.StringSlice([]string{pattern})},
+			{Name: aws.String("state"), Values: aws.StringSlice([]string{"available"})},
 		},
 	})
 	if err != nil {
-		return errors.Wrapf(err, "Failed to tag instance %s with name %s:",
-			*result.Instances[0].InstanceId, name)
+		return "", errors.Wrap(err, "Failed to look up AMI")
 	}
-	fmt.Println("Successfully tagged instance")
-	return nil
+	var newest *ec2.Image
+	for _, candidate := range result.Images {
+		if newest == nil || aws.StringValue(candidate.CreationDate) > aws.StringValue(newest.CreationDate) {
+			newest = candidate
+		}
+	}
+	if newest == nil {
+		return "", errs.Errorf(errs.NotFound, "No AMI of owner %s matches %s in this region", owner, pattern)
+	}
+	fmt.Fprintf(os.Stderr, "Using AMI %s (%s)\n", aws.StringValue(newest.ImageId), aws.StringValue(newest.Name))
+	return aws.StringValue(newest.ImageId), nil
+}
+
+// CreateEc2Instance creates an ec2 instance from the provided request
+func CreateEc2Instance(svc ec2iface.EC2API, input *ec2.RunInstancesInput) (*ec2.Instance, error) {
+	result, err := svc.RunInstances(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create EC2 instance")
+	}
+	fmt.Fprintln(os.Stderr, "Created instance", *result.Instances[0].InstanceId)
+	return result.Instances[0], nil
 }
 
 // GetInstanceID fetches the EC2 Instance ID for status or deleting
-func GetInstanceID(sess *session.Session, name string) (string, error) {
-	svc := ec2.New(sess)
+func GetInstanceID(svc ec2iface.EC2API, name string) (string, error) {
 	input := &ec2.DescribeInstancesInput{
 		Filters: []*ec2.Filter{
 			{
@@ -93,6 +125,11 @@ func GetInstanceID(sess *session.Session, name string) (string, error) {
 					aws.String(name),
 				},
 			},
+			{
+				// terminated instances keep their tags for a while, skip them
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{"pending", "running", "stopping", "stopped"}),
+			},
 		},
 	}
 
@@ -102,29 +139,84 @@ func GetInstanceID(sess *session.Session, name string) (string, error) {
 			switch aerr.Code() {
 			default:
 				return "", errors.Wrapf(
-					errors.New(aerr.Error()), "Failed to describe instance %s:", name)
+					aerr, "Failed to describe instance %s:", name)
 			}
 		} else {
 			// Print the error, cast err to awserr.Error to get the Code and
 			// Message from an error.
 			return "", errors.Wrapf(
-				errors.New(err.Error()), "Failed to describe instance %s:", name)
+				err, "Failed to describe instance %s:", name)
 		}
 	}
+	if len(result.Reservations) < 1 {
+		return "", errs.Errorf(errs.NotFound, "Could not find instance with name %s", name)
+	}
 	return *result.Reservations[0].Instances[0].InstanceId, nil
 }
 
-// PrintEc2Status outputs ec2 instance info
-func PrintEc2Status(sess *session.Session, name string) {
-	svc := ec2.New(sess)
+// ListEc2Instances fetches all EC2 instances in the provider region
+func ListEc2Instances(svc ec2iface.EC2API) ([]*ec2.Instance, error) {
+	var instances []*ec2.Instance
+	err := svc.DescribeInstancesPages(&ec2.DescribeInstancesInput{},
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				instances = append(instances, reservation.Instances...)
+			}
+			return true
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list instances")
+	}
+	return instances, nil
+}
+
+// InstanceName returns the value of the Name tag Maker sets on instances
+func InstanceName(instance *ec2.Instance) string {
+	for _, tag := range instance.Tags {
+		if aws.StringValue(tag.Key) == "Name" {
+			return aws.StringValue(tag.Value)
+		}
+	}
+	return ""
+}
+
+// Ec2State reports the state of an instance for waiting on
+func Ec2State(svc ec2iface.EC2API, id string) waiter.Condition {
+	return func() (string, error) {
+		result, err := svc.DescribeInstances(&ec2.DescribeInstancesInput{
+			InstanceIds: []*string{aws.String(id)},
+		})
+		// new instances can take a moment to show up
+		if awsErrorCode(err) == "InvalidInstanceID.NotFound" {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, reservation := range result.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.State != nil {
+					return aws.StringValue(instance.State.Name), nil
+				}
+			}
+		}
+		return "", nil
+	}
+}
+
+// awsErrorCode returns the code of an AWS API error, or an empty string for any other error
+func awsErrorCode(err error) string {
+	if aerr, ok := errors.Cause(err).(awserr.Error); ok {
+		return aerr.Code()
+	}
+	return ""
+}
+
+// GetEc2Status fetches an ec2 instance by ID and summarizes it
+func GetEc2Status(svc ec2iface.EC2API, id string) (*provider.Resource, error) {
 	input := &ec2.DescribeInstancesInput{
-		Filters: []*ec2.Filter{
-			{
-				Name: aws.String("tag:Name"),
-				Values: []*string{
-					aws.String(name),
-				},
-			},
+		InstanceIds: []*string{
+			aws.String(id),
 		},
 	}
 
@@ -133,53 +225,79 @@ func PrintEc2Status(sess *session.Session, name string) {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
 			default:
-				fmt.Printf("Failed to describe instance %s -- %s:", name, aerr.Error())
+				return nil, errors.Wrapf(
+					aerr, "Failed to describe instance %s:", id)
 			}
 		} else {
 			// Print the error, cast err to awserr.Error to get the Code and
 			// Message from an error.
-			fmt.Printf("Failed to describe instance %s -- %s:", name, err.Error())
+			return nil, errors.Wrapf(
+				err, "Failed to describe instance %s:", id)
 		}
 	}
+	if len(result.Reservations) < 1 {
+		return nil, errs.Errorf(errs.NotFound, "Could not find instance with ID %s", id)
+	}
+	resource := Ec2Resource(result.Reservations[0].Instances[0])
+	return &resource, nil
+}
 
-	fmt.Printf(
-		"Name: %s\nID: %s\n\nAMI: %s\nInstance Type: %s\n\nPublic IP: %s\nPublic DNS: %s\nRegion: %s\nStatus: %s\n",
-		*result.Reservations[0].Instances[0].Tags[0].Value,
-		*result.Reservations[0].Instances[0].InstanceId,
-		*result.Reservations[0].Instances[0].ImageId,
-		*result.Reservations[0].Instances[0].InstanceType,
-		*result.Reservations[0].Instances[0].NetworkInterfaces[0].Association.PublicIp,
-		*result.Reservations[0].Instances[0].NetworkInterfaces[0].Association.PublicDnsName,
-		*result.Reservations[0].Instances[0].Placement.AvailabilityZone,
-		*result.Reservations[0].Instances[0].State.Name,
-	)
+// Ec2Resource converts an ec2 instance into the common resource summary
+func Ec2Resource(instance *ec2.Instance) provider.Resource {
+	resource := provider.Resource{
+		Name:     InstanceName(instance),
+		ID:       aws.StringValue(instance.InstanceId),
+		Provider: "aws",
+		Size:     aws.StringValue(instance.InstanceType),
+		Endpoint: aws.StringValue(instance.PublicDnsName),
+		Created:  aws.TimeValue(instance.LaunchTime),
+		Details: map[string]string{
+			"ami": aws.StringValue(instance.ImageId),
+		},
+	}
+	if instance.Placement != nil {
+		resource.Region = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+	if instance.State != nil {
+		resource.Status = aws.StringValue(instance.State.Name)
+	}
+	for _, ip := range []*string{instance.PublicIpAddress, instance.PrivateIpAddress} {
+		if ip != nil {
+			resource.Addresses = append(resource.Addresses, *ip)
+		}
+	}
+	return resource
 }
 
-// DeleteEc2Instance destroys an instance
-func DeleteEc2Instance(sess *session.Session, id string) error {
-	svc := ec2.New(sess)
-	input := &ec2.TerminateInstancesInput{
+// Ec2TerminateInput builds the request to destroy an instance
+func Ec2TerminateInput(id string) *ec2.TerminateInstancesInput {
+	return &ec2.TerminateInstancesInput{
 		InstanceIds: []*string{
 			aws.String(id),
 		},
 	}
+}
+
+// DeleteEc2Instance destroys an instance
+func DeleteEc2Instance(svc ec2iface.EC2API, id string) error {
+	input := Ec2TerminateInput(id)
 	result, err := svc.TerminateInstances(input)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
 			default:
 				return errors.Wrapf(
-					errors.New(aerr.Error()), "Failed to terminate instance %s:", id)
+					aerr, "Failed to terminate instance %s:", id)
 			}
 		} else {
 			// Print the error, cast err to awserr.Error to get the Code and
 			// Message from an error.
 			return errors.Wrapf(
-				errors.New(err.Error()), "Failed to describe instance %s:", id)
+				err, "Failed to describe instance %s:", id)
 		}
 
 	}
-	fmt.Printf("Success: %s is %s\n",
+	fmt.Fprintf(os.Stderr, "Success: %s is %s\n",
 		*result.TerminatingInstances[0].InstanceId,
 		*result.TerminatingInstances[0].CurrentState.Name,
 	)
@@ -1,51 +1,49 @@
 package aws
 
 import (
-	"crypto/md5"
+	"encoding/base64"
 	"fmt"
-	"io/ioutil"
-	"maker/internal/utils"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
 	"github.com/pkg/errors"
 )
 
 // GetExistingRoleARN checks if a role exists for AWSServiceRoleForAmazonEKS and returns its ARN
-func GetExistingRoleARN(sess *session.Session) (string, error) {
-	svc := iam.New(sess)
+func GetExistingRoleARN(svc iamiface.IAMAPI) (string, error) {
 	roleInput := &iam.GetRoleInput{
 		RoleName: aws.String("EKSClusterRole"),
 	}
 
 	eksArn, err := svc.GetRole(roleInput)
 	if err != nil {
-		return "", errors.Errorf("Failed", err)
+		return "", errors.Wrap(err, "Failed")
 	}
 	return string(*eksArn.Role.Arn), nil
 }
 
-// CreateEksClusterRole does stuff
-func CreateEksClusterRole(sess *session.Session) (string, error) {
+// EksRoleInputs builds the requests to create the role used by EKS clusters and node groups
+func EksRoleInputs() (*iam.CreateRoleInput, []*iam.AttachRolePolicyInput) {
 	policy := `{ "Version": "2012-10-17", "Statement": [{ "Effect": "Allow", "Principal": { "AWS": "arn:aws:iam::898425707596:root" }, "Action": "sts:AssumeRole" }, { "Effect": "Allow", "Principal": { "Service": "ec2.amazonaws.com", "Service": "eks.amazonaws.com" }, "Action": "sts:AssumeRole" }]}`
-	rolesvc := iam.New(sess)
 	roleInput := &iam.CreateRoleInput{
 		AssumeRolePolicyDocument: aws.String(policy),
 		Description:              aws.String("EKS Role with Cluster and Node policies"),
 		RoleName:                 aws.String("EKSClusterRole"),
 	}
 
-	roleResult, err := rolesvc.CreateRole(roleInput)
-	if err != nil {
-		return "", errors.Errorf("Failed to create role", err)
-	}
-
-	// add policy
-	policysvc := iam.New(sess)
 	policies := []*iam.AttachRolePolicyInput{
 		{
 			PolicyArn: aws.String("arn:aws:iam::aws:policy/AmazonEKSClusterPolicy"),
@@ -64,26 +62,31 @@ func CreateEksClusterRole(sess *session.Session) (string, error) {
 			RoleName:  aws.String("EKSClusterRole"),
 		},
 	}
+	return roleInput, policies
+}
+
+// CreateEksClusterRole creates the role used by EKS clusters and node groups, returning its ARN
+func CreateEksClusterRole(svc iamiface.IAMAPI) (string, error) {
+	roleInput, policies := EksRoleInputs()
+	roleResult, err := svc.CreateRole(roleInput)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create role")
+	}
 
+	// add policy
 	for _, policyInput := range policies {
-		_, err = policysvc.AttachRolePolicy(policyInput)
+		_, err = svc.AttachRolePolicy(policyInput)
 		if err != nil {
-			return "", errors.Errorf("Failed to add policy %s to role:", *policyInput.PolicyArn, err)
+			return "", errors.Wrapf(err, "Failed to add policy %s to role", *policyInput.PolicyArn)
 		}
 	}
 	return string(*roleResult.Role.Arn), nil
 }
 
-// CreateEksCluster creates an EKS cluster with provided specs
-func CreateEksCluster(sess *session.Session, name, arn, version string, subnets []string) error {
-	digest := md5.New()
-	digest.Write([]byte(name))
-	hash := digest.Sum(nil)
-
-	svc := eks.New(sess)
-	input := &eks.CreateClusterInput{
-		ClientRequestToken: aws.String(name + "-" + string(hash)),
-		Name:               aws.String(name),
+// EksClusterInput builds the request to create an EKS cluster with provided specs, the SDK fills in a fresh request token for each call
+func EksClusterInput(name, arn, version string, subnets []string) *eks.CreateClusterInput {
+	return &eks.CreateClusterInput{
+		Name: aws.String(name),
 		ResourcesVpcConfig: &eks.VpcConfigRequest{
 			SubnetIds: []*string{
 				aws.String(subnets[0]),
@@ -93,46 +96,26 @@ func CreateEksCluster(sess *session.Session, name, arn, version string, subnets
 		RoleArn: aws.String(arn),
 		Version: &version,
 	}
+}
 
+// CreateEksCluster creates an EKS cluster from the provided request
+func CreateEksCluster(svc eksiface.EKSAPI, input *eks.CreateClusterInput) error {
 	_, err := svc.CreateCluster(input)
 	if err != nil {
-		return errors.Errorf("Failed to create cluser:", err)
+		return errors.Wrap(err, "Failed to create cluser")
 	}
-	fmt.Println("Creating", name, "cluster. This can take up to 10-15 minutes...")
+	fmt.Fprintln(os.Stderr, "Creating", aws.StringValue(input.Name), "cluster. This can take up to 10-15 minutes...")
 	return nil
 }
 
-// CreateEksNodeGroup creates workers for the EKS cluster just created
-func CreateEksNodeGroup(sess *session.Session, name, arn, nodeSize string, nodeCount int, subnets []string) error {
-	// pre-check
-
-	for {
-		if status, err := GetClusterStatus(sess, name); status != "FAILED" {
-			if err != nil {
-				return errors.Errorf("Failed to get cluster status to create node group:", err)
-			}
-			if status == "ACTIVE" {
-				fmt.Println("Cluster completed!")
-				fmt.Printf("Current status: %s\n\n", status)
-				break
-			}
-			fmt.Println("Waiting for cluster completion to create node group -- Current status:", status)
-			time.Sleep(1 * time.Minute)
-		}
-	}
-
-	digest := md5.New()
-	digest.Write([]byte(name))
-	hash := digest.Sum(nil)
-
-	svc := eks.New(sess)
-	input := &eks.CreateNodegroupInput{
-		CapacityType:       aws.String("ON_DEMAND"),
-		ClientRequestToken: aws.String(name + "-nodegroup-" + string(hash)),
-		ClusterName:        aws.String(name),
-		InstanceTypes:      aws.StringSlice([]string{nodeSize}),
-		NodeRole:           aws.String(arn),
-		NodegroupName:      aws.String(name + "-nodegroup"),
+// EksNodegroupInput builds the request to create the node group for an EKS cluster
+func EksNodegroupInput(name, arn, nodeSize string, nodeCount int, subnets []string) *eks.CreateNodegroupInput {
+	return &eks.CreateNodegroupInput{
+		CapacityType:  aws.String("ON_DEMAND"),
+		ClusterName:   aws.String(name),
+		InstanceTypes: aws.StringSlice([]string{nodeSize}),
+		NodeRole:      aws.String(arn),
+		NodegroupName: aws.String(name + "-nodegroup"),
 		ScalingConfig: &eks.NodegroupScalingConfig{
 			DesiredSize: aws.Int64(int64(nodeCount)),
 			MaxSize:     aws.Int64(int64(nodeCount)),
@@ -140,85 +123,128 @@ func CreateEksNodeGroup(sess *session.Session, name, arn, nodeSize string, nodeC
 		},
 		Subnets: aws.StringSlice(subnets),
 	}
+}
 
+// CreateEksNodeGroup creates workers for an EKS cluster, which must be ACTIVE first
+func CreateEksNodeGroup(svc eksiface.EKSAPI, input *eks.CreateNodegroupInput) error {
 	_, err := svc.CreateNodegroup(input)
 	if err != nil {
-		return errors.Errorf("Failed to create cluser:", err)
+		return errors.Wrap(err, "Failed to create cluser")
 	}
-	fmt.Println("Node group", *input.NodegroupName, "creating")
+	fmt.Fprintln(os.Stderr, "Node group", *input.NodegroupName, "creating")
 	return nil
 }
 
-// CreateKubeconfig creates a kubeconfig needed to access the cluster
+// CreateKubeconfig merges the cluster into the kubeconfig file, kubectl gets tokens from 'maker kube-token'
 func CreateKubeconfig(endpoint, caData, name string) error {
-	configString := `apiVersion: v1
-clusters:
-- cluster:
-    server: ` + endpoint + `
-    certificate-authority-data: ` + caData + `
-  name: kubernetes
-contexts:
-- context:
-    cluster: kubernetes
-    user: aws
-  name: aws
-current-context: aws
-kind: Config
-preferences: {}
-users:
-- name: aws
-  user:
-    exec:
-      apiVersion: client.authentication.k8s.io/v1alpha1
-      command: aws
-      args:
-        - "eks"
-        - "get-token"
-        - "--cluster-name"
-        - "` + name + `"
-`
-	linesToWrite := configString
-	err := ioutil.WriteFile(utils.ConfigFolderPath+"/aws_kubeconfig", []byte(linesToWrite), 0755)
+	return kubeconfig.Merge(kubeconfig.Entry{
+		Provider: "aws",
+		Name:     name,
+		Cluster: map[string]interface{}{
+			"server":                     endpoint,
+			"certificate-authority-data": caData,
+		},
+		User: kubeconfig.ExecUser("aws", name),
+	})
+}
+
+// EKS tokens are presigned STS GetCallerIdentity URLs naming the cluster, the same as 'aws eks get-token'
+const (
+	eksTokenPrefix     = "k8s-aws-v1."
+	eksClusterIDHeader = "x-k8s-aws-id"
+	// eksTokenLifetime is how long EKS accepts a presigned URL, less a minute for clock skew
+	eksTokenLifetime = 14 * time.Minute
+)
+
+// EksToken returns a bearer token for an EKS cluster signed with the credentials of svc
+func EksToken(svc stsiface.STSAPI, cluster string) (*provider.KubeToken, error) {
+	req, _ := svc.GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	req.HTTPRequest.Header.Add(eksClusterIDHeader, cluster)
+	expiry := time.Now().Add(eksTokenLifetime)
+	// EKS enforces its own lifetime from the signing time, this only bounds the URL
+	url, err := req.Presign(time.Minute)
 	if err != nil {
-		return errors.Errorf("Failed to write kubeconfig:", err)
+		return nil, errs.Wrap(err, "Failed to sign token")
 	}
-	fmt.Println("Kubeconfig created at", utils.ConfigFolderPath+"/aws_kubeconfig")
-	fmt.Printf("To use the kubeconfig, be sure to run 'export KUBECONFIG=%s/aws_kubeconfig'\n", utils.ConfigFolderPath)
-	fmt.Println("AWS CLI is required and must be installed to auth for kubectl")
-	return nil
+	return &provider.KubeToken{
+		Token:  eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(url)),
+		Expiry: expiry,
+	}, nil
 }
 
 // GetCluster describes the cluster and returns cluster details needed for kubeconfig
-func GetCluster(sess *session.Session, name string) (*eks.DescribeClusterOutput, error) {
-	svc := eks.New(sess)
+func GetCluster(svc eksiface.EKSAPI, name string) (*eks.DescribeClusterOutput, error) {
 	input := &eks.DescribeClusterInput{
 		Name: aws.String(name),
 	}
 
 	result, err := svc.DescribeCluster(input)
 	if err != nil {
-		return nil, errors.Errorf("Failed to fetch cluster status:", err)
+		return nil, errors.Wrap(err, "Failed to fetch cluster status")
 	}
 	return result, nil
 }
 
+// ListEksClusters describes every EKS cluster in the provider region
+func ListEksClusters(svc eksiface.EKSAPI) ([]*eks.Cluster, error) {
+	var names []*string
+	err := svc.ListClustersPages(&eks.ListClustersInput{},
+		func(page *eks.ListClustersOutput, lastPage bool) bool {
+			names = append(names, page.Clusters...)
+			return true
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list clusters")
+	}
+
+	var clusters []*eks.Cluster
+	for _, name := range names {
+		result, err := GetCluster(svc, aws.StringValue(name))
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, result.Cluster)
+	}
+	return clusters, nil
+}
+
 // GetClusterStatus checks the state of the EKS Cluster before creating a node group
-func GetClusterStatus(sess *session.Session, name string) (string, error) {
-	svc := eks.New(sess)
+func GetClusterStatus(svc eksiface.EKSAPI, name string) (string, error) {
 	input := &eks.DescribeClusterInput{
 		Name: aws.String(name),
 	}
 
 	result, err := svc.DescribeCluster(input)
 	if err != nil {
-		return "", errors.Errorf("Failed to fetch cluster status:", err)
+		return "", errors.Wrap(err, "Failed to fetch cluster status")
 	}
 	return *result.Cluster.Status, nil
 }
 
+// EksClusterState reports the status of a cluster for waiting on, or gone once it is deleted
+func EksClusterState(svc eksiface.EKSAPI, name string) waiter.Condition {
+	return func() (string, error) {
+		status, err := GetClusterStatus(svc, name)
+		if awsErrorCode(err) == eks.ErrCodeResourceNotFoundException {
+			return waiter.StateGone, nil
+		}
+		return status, err
+	}
+}
+
+// EksNodeGroupState reports the status of a node group for waiting on, or gone once it is deleted
+func EksNodeGroupState(svc eksiface.EKSAPI, clusterName, nodeGroupName string) waiter.Condition {
+	return func() (string, error) {
+		status, err := GetNodeGroupStatus(svc, clusterName, nodeGroupName)
+		if awsErrorCode(err) == eks.ErrCodeResourceNotFoundException {
+			return waiter.StateGone, nil
+		}
+		return status, err
+	}
+}
+
 // GetNodeGroupStatus grabs the current state of the node group
-func GetNodeGroupStatus(sess *session.Session, clusterName, nodeGroupName string) (string, error) {
-	svc := eks.New(sess)
+func GetNodeGroupStatus(svc eksiface.EKSAPI, clusterName, nodeGroupName string) (string, error) {
 	input := &eks.DescribeNodegroupInput{
 		ClusterName:   aws.String(clusterName),
 		NodegroupName: aws.String(nodeGroupName),
@@ -226,98 +252,96 @@ func GetNodeGroupStatus(sess *session.Session, clusterName, nodeGroupName string
 
 	result, err := svc.DescribeNodegroup(input)
 	if err != nil {
-		return "", errors.Errorf("Failed to fetch cluster status:", err)
+		return "", errors.Wrap(err, "Failed to fetch cluster status")
 	}
 	return *result.Nodegroup.Status, nil
 }
 
-// PrintEksClusterStatus outputs EKS cluster info
-func PrintEksClusterStatus(sess *session.Session, clusterName, nodeGroupName string) error {
-	svc := eks.New(sess)
-	input := &eks.DescribeClusterInput{
-		Name: aws.String(clusterName),
+// GetEksClusterStatus fetches an EKS cluster and its node groups and summarizes them,
+// the details of the node group Maker created with the cluster are shown when it still exists
+func GetEksClusterStatus(svc eksiface.EKSAPI, region, clusterName, nodeGroupName string) (*provider.Resource, error) {
+	result, err := GetCluster(svc, clusterName)
+	if err != nil {
+		return nil, err
 	}
+	resource := EksClusterResource(result.Cluster, region)
 
-	result, err := svc.DescribeCluster(input)
+	nodegroups, err := ListEksNodegroups(svc, clusterName)
 	if err != nil {
-		return errors.Errorf("Failed to fetch cluster status:", err)
+		return nil, errors.Wrap(err, "Failed to fetch node group status")
 	}
-	fmt.Printf("\nCluster Info\n----------\n")
-	fmt.Printf("Name: %s\nARN: %s\n\nEndpoint: %s\nService IP: %s\n\nVersion: %s\nCreated: %s\nState: %s\n",
-		*result.Cluster.Name,
-		*result.Cluster.Arn,
-		*result.Cluster.Endpoint,
-		*result.Cluster.KubernetesNetworkConfig.ServiceIpv4Cidr,
-		*result.Cluster.Version,
-		*result.Cluster.CreatedAt,
-		*result.Cluster.Status,
-	)
-
-	nodeSvc := eks.New(sess)
-	nodeInput := &eks.DescribeNodegroupInput{
-		ClusterName:   aws.String(clusterName),
-		NodegroupName: aws.String(nodeGroupName),
+	var names []string
+	var count int64
+	for _, nodegroup := range nodegroups {
+		names = append(names, aws.StringValue(nodegroup.NodegroupName))
+		if nodegroup.ScalingConfig != nil {
+			count += aws.Int64Value(nodegroup.ScalingConfig.DesiredSize)
+		}
+		if resource.Size == "" && len(nodegroup.InstanceTypes) > 0 {
+			resource.Size = aws.StringValue(nodegroup.InstanceTypes[0])
+		}
+		if aws.StringValue(nodegroup.NodegroupName) == nodeGroupName {
+			resource.Details["nodegroup"] = nodeGroupName
+			resource.Details["nodegroup_ami"] = aws.StringValue(nodegroup.AmiType)
+			resource.Details["nodegroup_status"] = aws.StringValue(nodegroup.Status)
+		}
 	}
+	resource.Details["node_pools"] = strings.Join(names, ",")
+	resource.Details["node_count"] = strconv.FormatInt(count, 10)
+	return &resource, nil
+}
 
-	nodesResult, err := nodeSvc.DescribeNodegroup(nodeInput)
-	if err != nil {
-		return errors.Errorf("Failed to fetch node group status:", err)
+// EksClusterResource converts an EKS cluster into the common resource summary
+func EksClusterResource(cluster *eks.Cluster, region string) provider.Resource {
+	resource := provider.Resource{
+		Name:     aws.StringValue(cluster.Name),
+		ID:       aws.StringValue(cluster.Arn),
+		Provider: "aws",
+		Region:   region,
+		Status:   aws.StringValue(cluster.Status),
+		Endpoint: aws.StringValue(cluster.Endpoint),
+		Created:  aws.TimeValue(cluster.CreatedAt),
+		Details: map[string]string{
+			"version": aws.StringValue(cluster.Version),
+		},
 	}
-	fmt.Printf("\nNodegroup Info\n----------\n")
-	fmt.Printf("Name: %s\nARN: %s\nAMI: %s\nInstance Type: %s\nCreated At: %s\nStatus: %s\n\n",
-		*nodesResult.Nodegroup.NodegroupName,
-		*nodesResult.Nodegroup.NodegroupArn,
-		*nodesResult.Nodegroup.AmiType,
-		*nodesResult.Nodegroup.InstanceTypes[0],
-		*nodesResult.Nodegroup.CreatedAt,
-		*nodesResult.Nodegroup.Status,
-	)
-	return nil
+	if cluster.KubernetesNetworkConfig != nil {
+		resource.Details["service_cidr"] = aws.StringValue(cluster.KubernetesNetworkConfig.ServiceIpv4Cidr)
+	}
+	return resource
 }
 
-// DeleteEksNodeGroup deletes the node group before deleting the cluster
-func DeleteEksNodeGroup(sess *session.Session, clusterName, nodeGroupName string) error {
-	svc := eks.New(sess)
-	input := &eks.DeleteNodegroupInput{
+// EksNodegroupDeleteInput builds the request to delete a node group
+func EksNodegroupDeleteInput(clusterName, nodeGroupName string) *eks.DeleteNodegroupInput {
+	return &eks.DeleteNodegroupInput{
 		ClusterName:   aws.String(clusterName),
 		NodegroupName: aws.String(nodeGroupName),
 	}
+}
 
-	_, err := svc.DeleteNodegroup(input)
+// DeleteEksNodeGroup deletes the node group before deleting the cluster
+func DeleteEksNodeGroup(svc eksiface.EKSAPI, clusterName, nodeGroupName string) error {
+	_, err := svc.DeleteNodegroup(EksNodegroupDeleteInput(clusterName, nodeGroupName))
 	if err != nil {
-		return errors.Errorf("Failed to delete the node group:", err)
+		return errors.Wrap(err, "Failed to delete the node group")
 	}
-	fmt.Println("Node group", nodeGroupName, "deleted")
+	fmt.Fprintln(os.Stderr, "Node group", nodeGroupName, "deleted")
 	return nil
 }
 
-// DeleteEksCluster destroys an EKS cluster
-func DeleteEksCluster(sess *session.Session, name, nodeGroupName string) error {
-	// pre-check
-	for {
-		status, err := GetNodeGroupStatus(sess, name, nodeGroupName)
-		if err != nil {
-			if aerr, ok := err.(awserr.Error); ok {
-				fmt.Println("err is:", err)
-				fmt.Println("aerr is:", aerr)
-				fmt.Println("aerrCode is:", aerr.Code())
-				break
-			}
-			break
-		}
-		fmt.Println("Waiting for nodes to finish deleting -- Current status:", status)
-		time.Sleep(1 * time.Minute)
-	}
-
-	svc := eks.New(sess)
-	input := &eks.DeleteClusterInput{
+// EksClusterDeleteInput builds the request to delete an EKS cluster
+func EksClusterDeleteInput(name string) *eks.DeleteClusterInput {
+	return &eks.DeleteClusterInput{
 		Name: aws.String(name),
 	}
+}
 
-	_, err := svc.DeleteCluster(input)
+// DeleteEksCluster destroys an EKS cluster, its node groups must be gone first
+func DeleteEksCluster(svc eksiface.EKSAPI, name string) error {
+	_, err := svc.DeleteCluster(EksClusterDeleteInput(name))
 	if err != nil {
-		return errors.Errorf("Failed to delete the cluster:", err)
+		return errors.Wrap(err, "Failed to delete the cluster")
 	}
-	fmt.Println("Cluster", name, "deleted")
+	fmt.Fprintln(os.Stderr, "Cluster", name, "deleted")
 	return nil
 }
@@ -0,0 +1,604 @@
+package aws
+
+import (
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// nextState moves an object one step along its lifecycle each time it is described
+var nextState = map[string]string{
+	"pending":       "running",
+	"shutting-down": "terminated",
+	"CREATING":      "ACTIVE",
+	"UPDATING":      "ACTIVE",
+	"InProgress":    "Successful",
+	"creating":      "available",
+}
+
+// fakeEC2 keeps instances in memory
+type fakeEC2 struct {
+	ec2iface.EC2API
+	keys      []string
+	instances map[string]*ec2.Instance
+	launched  []*ec2.RunInstancesInput
+}
+
+func newFakeEC2(keys ...string) *fakeEC2 {
+	return &fakeEC2{keys: keys, instances: map[string]*ec2.Instance{}}
+}
+
+func (f *fakeEC2) DescribeKeyPairs(input *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error) {
+	output := &ec2.DescribeKeyPairsOutput{}
+	for i, key := range f.keys {
+		output.KeyPairs = append(output.KeyPairs, &ec2.KeyPairInfo{KeyName: aws.String(key), KeyPairId: aws.String("key-" + strconv.Itoa(i))})
+	}
+	return output, nil
+}
+
+func (f *fakeEC2) ImportKeyPair(input *ec2.ImportKeyPairInput) (*ec2.ImportKeyPairOutput, error) {
+	f.keys = append(f.keys, aws.StringValue(input.KeyName))
+	return &ec2.ImportKeyPairOutput{KeyName: input.KeyName, KeyPairId: aws.String("key-" + strconv.Itoa(len(f.keys)-1))}, nil
+}
+
+func (f *fakeEC2) DeleteKeyPair(input *ec2.DeleteKeyPairInput) (*ec2.DeleteKeyPairOutput, error) {
+	for i, key := range f.keys {
+		if key == aws.StringValue(input.KeyName) {
+			f.keys = append(f.keys[:i], f.keys[i+1:]...)
+			break
+		}
+	}
+	return &ec2.DeleteKeyPairOutput{}, nil
+}
+
+func (f *fakeEC2) RunInstances(input *ec2.RunInstancesInput) (*ec2.Reservation, error) {
+	f.launched = append(f.launched, input)
+	instance := &ec2.Instance{
+		InstanceId:   aws.String("i-" + strconv.Itoa(len(f.launched))),
+		InstanceType: input.InstanceType,
+		ImageId:      input.ImageId,
+		KeyName:      input.KeyName,
+		State:        &ec2.InstanceState{Name: aws.String("pending")},
+		Placement:    &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")},
+	}
+	for _, spec := range input.TagSpecifications {
+		instance.Tags = append(instance.Tags, spec.Tags...)
+	}
+	f.instances[aws.StringValue(instance.InstanceId)] = instance
+	copied := *instance
+	return &ec2.Reservation{Instances: []*ec2.Instance{&copied}}, nil
+}
+
+// DescribeInstances supports the instance ID, Name tag and state filters Maker uses
+func (f *fakeEC2) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	output := &ec2.DescribeInstancesOutput{}
+	for _, id := range input.InstanceIds {
+		if _, ok := f.instances[aws.StringValue(id)]; !ok {
+			return nil, awserr.New("InvalidInstanceID.NotFound", "no such instance", nil)
+		}
+	}
+	for id, instance := range f.instances {
+		if len(input.InstanceIds) > 0 && !contains(aws.StringValueSlice(input.InstanceIds), id) {
+			continue
+		}
+		if !matchesFilters(instance, input.Filters) {
+			continue
+		}
+		copied := *instance
+		output.Reservations = append(output.Reservations, &ec2.Reservation{Instances: []*ec2.Instance{&copied}})
+		if next, ok := nextState[aws.StringValue(instance.State.Name)]; ok {
+			instance.State = &ec2.InstanceState{Name: aws.String(next)}
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeEC2) DescribeInstancesPages(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	output, err := f.DescribeInstances(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeEC2) TerminateInstances(input *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+	output := &ec2.TerminateInstancesOutput{}
+	for _, id := range input.InstanceIds {
+		instance, ok := f.instances[aws.StringValue(id)]
+		if !ok {
+			return nil, awserr.New("InvalidInstanceID.NotFound", "no such instance", nil)
+		}
+		instance.State = &ec2.InstanceState{Name: aws.String("shutting-down")}
+		output.TerminatingInstances = append(output.TerminatingInstances, &ec2.InstanceStateChange{
+			InstanceId:   id,
+			CurrentState: instance.State,
+		})
+	}
+	return output, nil
+}
+
+var fakeImages = []*ec2.Image{
+	{ImageId: aws.String("ami-old"), Name: aws.String("ubuntu/images/hvm-ssd/ubuntu-focal-20.04-amd64-server-20210201"), CreationDate: aws.String("2021-02-01T00:00:00.000Z")},
+	{ImageId: aws.String("ami-new"), Name: aws.String("ubuntu/images/hvm-ssd/ubuntu-focal-20.04-amd64-server-20210301"), CreationDate: aws.String("2021-03-01T00:00:00.000Z")},
+	{ImageId: aws.String("ami-amzn"), Name: aws.String("amzn2-ami-hvm-2.0.20210219.0-x86_64-gp2"), CreationDate: aws.String("2021-02-19T00:00:00.000Z")},
+}
+
+func (f *fakeEC2) DescribeImages(input *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	output := &ec2.DescribeImagesOutput{}
+	for _, image := range fakeImages {
+		if matchesImageName(image, input.Filters) {
+			output.Images = append(output.Images, image)
+		}
+	}
+	return output, nil
+}
+
+func matchesImageName(image *ec2.Image, filters []*ec2.Filter) bool {
+	for _, filter := range filters {
+		if aws.StringValue(filter.Name) != "name" {
+			continue
+		}
+		for _, pattern := range aws.StringValueSlice(filter.Values) {
+			if matched, _ := path.Match(pattern, aws.StringValue(image.Name)); matched {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (f *fakeEC2) DescribeInstanceTypesPages(input *ec2.DescribeInstanceTypesInput, fn func(*ec2.DescribeInstanceTypesOutput, bool) bool) error {
+	fn(&ec2.DescribeInstanceTypesOutput{InstanceTypes: []*ec2.InstanceTypeInfo{
+		{InstanceType: aws.String("t3.micro"), VCpuInfo: &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)}, MemoryInfo: &ec2.MemoryInfo{SizeInMiB: aws.Int64(1024)}},
+	}}, false)
+	fn(&ec2.DescribeInstanceTypesOutput{InstanceTypes: []*ec2.InstanceTypeInfo{
+		{InstanceType: aws.String("m5.large"), VCpuInfo: &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)}, MemoryInfo: &ec2.MemoryInfo{SizeInMiB: aws.Int64(8192)}},
+	}}, true)
+	return nil
+}
+
+func matchesFilters(instance *ec2.Instance, filters []*ec2.Filter) bool {
+	for _, filter := range filters {
+		values := aws.StringValueSlice(filter.Values)
+		switch aws.StringValue(filter.Name) {
+		case "tag:Name":
+			if !contains(values, InstanceName(instance)) {
+				return false
+			}
+		case "instance-state-name":
+			if !contains(values, aws.StringValue(instance.State.Name)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// fakeEKS keeps clusters, their node groups and updates in memory
+type fakeEKS struct {
+	eksiface.EKSAPI
+	clusters   map[string]*eks.Cluster
+	nodegroups map[string]*eks.Nodegroup
+	updates    map[string]*eks.Update
+	tokens     map[string]*eks.Update
+	// failUpdate makes the next control plane upgrade fail
+	failUpdate bool
+}
+
+func newFakeEKS() *fakeEKS {
+	return &fakeEKS{clusters: map[string]*eks.Cluster{}, nodegroups: map[string]*eks.Nodegroup{}, updates: map[string]*eks.Update{}, tokens: map[string]*eks.Update{}}
+}
+
+// replayed reports whether a request token was seen before, returning the update it started.
+// EKS answers those with the earlier request's result instead of acting on them again.
+func (f *fakeEKS) replayed(token *string) (*eks.Update, bool) {
+	if token == nil {
+		return nil, false
+	}
+	update, seen := f.tokens[*token]
+	if !seen {
+		f.tokens[*token] = nil
+	}
+	return update, seen
+}
+
+// remember records the update started by a request token
+func (f *fakeEKS) remember(token *string, update *eks.Update) *eks.Update {
+	if token != nil {
+		f.tokens[*token] = update
+	}
+	return update
+}
+
+func eksNotFound() error {
+	return awserr.New(eks.ErrCodeResourceNotFoundException, "not found", nil)
+}
+
+func (f *fakeEKS) CreateCluster(input *eks.CreateClusterInput) (*eks.CreateClusterOutput, error) {
+	if _, ok := f.replayed(input.ClientRequestToken); ok {
+		return &eks.CreateClusterOutput{}, nil
+	}
+	cluster := &eks.Cluster{
+		Name:     input.Name,
+		Arn:      aws.String("arn:aws:eks:us-east-1:123456789012:cluster/" + aws.StringValue(input.Name)),
+		Version:  input.Version,
+		Status:   aws.String(eks.ClusterStatusCreating),
+		Endpoint: aws.String("https://example.eks.amazonaws.com"),
+		ResourcesVpcConfig: &eks.VpcConfigResponse{
+			SubnetIds: input.ResourcesVpcConfig.SubnetIds,
+		},
+		CertificateAuthority: &eks.Certificate{
+			Data: aws.String("Y2VydA=="),
+		},
+	}
+	f.clusters[aws.StringValue(input.Name)] = cluster
+	return &eks.CreateClusterOutput{Cluster: cluster}, nil
+}
+
+func (f *fakeEKS) DescribeCluster(input *eks.DescribeClusterInput) (*eks.DescribeClusterOutput, error) {
+	cluster, ok := f.clusters[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, eksNotFound()
+	}
+	copied := *cluster
+	if next, ok := nextState[aws.StringValue(cluster.Status)]; ok {
+		cluster.Status = aws.String(next)
+	}
+	return &eks.DescribeClusterOutput{Cluster: &copied}, nil
+}
+
+func (f *fakeEKS) ListClustersPages(input *eks.ListClustersInput, fn func(*eks.ListClustersOutput, bool) bool) error {
+	output := &eks.ListClustersOutput{}
+	for name := range f.clusters {
+		output.Clusters = append(output.Clusters, aws.String(name))
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeEKS) DeleteCluster(input *eks.DeleteClusterInput) (*eks.DeleteClusterOutput, error) {
+	name := aws.StringValue(input.Name)
+	if _, ok := f.clusters[name]; !ok {
+		return nil, eksNotFound()
+	}
+	for _, nodegroup := range f.nodegroups {
+		if aws.StringValue(nodegroup.ClusterName) == name {
+			return nil, awserr.New(eks.ErrCodeResourceInUseException, "cluster has node groups", nil)
+		}
+	}
+	delete(f.clusters, name)
+	return &eks.DeleteClusterOutput{}, nil
+}
+
+func (f *fakeEKS) CreateNodegroup(input *eks.CreateNodegroupInput) (*eks.CreateNodegroupOutput, error) {
+	if _, ok := f.replayed(input.ClientRequestToken); ok {
+		return &eks.CreateNodegroupOutput{}, nil
+	}
+	cluster, ok := f.clusters[aws.StringValue(input.ClusterName)]
+	if !ok {
+		return nil, eksNotFound()
+	}
+	if aws.StringValue(cluster.Status) != eks.ClusterStatusActive {
+		return nil, awserr.New(eks.ErrCodeInvalidRequestException, "cluster isn't active", nil)
+	}
+	nodegroup := &eks.Nodegroup{
+		ClusterName:   input.ClusterName,
+		NodegroupName: input.NodegroupName,
+		NodegroupArn:  aws.String("arn:aws:eks:us-east-1:123456789012:nodegroup/" + aws.StringValue(input.ClusterName) + "/" + aws.StringValue(input.NodegroupName)),
+		InstanceTypes: input.InstanceTypes,
+		ScalingConfig: input.ScalingConfig,
+		Labels:        input.Labels,
+		Taints:        input.Taints,
+		Subnets:       input.Subnets,
+		Version:       cluster.Version,
+		Status:        aws.String(eks.NodegroupStatusCreating),
+	}
+	f.nodegroups[aws.StringValue(input.NodegroupName)] = nodegroup
+	return &eks.CreateNodegroupOutput{Nodegroup: nodegroup}, nil
+}
+
+func (f *fakeEKS) DescribeNodegroup(input *eks.DescribeNodegroupInput) (*eks.DescribeNodegroupOutput, error) {
+	nodegroup, ok := f.nodegroups[aws.StringValue(input.NodegroupName)]
+	if !ok {
+		return nil, eksNotFound()
+	}
+	copied := *nodegroup
+	if next, ok := nextState[aws.StringValue(nodegroup.Status)]; ok {
+		nodegroup.Status = aws.String(next)
+	}
+	return &eks.DescribeNodegroupOutput{Nodegroup: &copied}, nil
+}
+
+func (f *fakeEKS) ListNodegroupsPages(input *eks.ListNodegroupsInput, fn func(*eks.ListNodegroupsOutput, bool) bool) error {
+	output := &eks.ListNodegroupsOutput{}
+	for name, nodegroup := range f.nodegroups {
+		if aws.StringValue(nodegroup.ClusterName) == aws.StringValue(input.ClusterName) {
+			output.Nodegroups = append(output.Nodegroups, aws.String(name))
+		}
+	}
+	sort.Sort(byValue(output.Nodegroups))
+	fn(output, true)
+	return nil
+}
+
+// byValue sorts names so listings don't follow map order
+type byValue []*string
+
+func (b byValue) Len() int           { return len(b) }
+func (b byValue) Less(i, j int) bool { return aws.StringValue(b[i]) < aws.StringValue(b[j]) }
+func (b byValue) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+func (f *fakeEKS) UpdateNodegroupConfig(input *eks.UpdateNodegroupConfigInput) (*eks.UpdateNodegroupConfigOutput, error) {
+	nodegroup, ok := f.nodegroups[aws.StringValue(input.NodegroupName)]
+	if !ok {
+		return nil, eksNotFound()
+	}
+	if _, ok := f.replayed(input.ClientRequestToken); ok {
+		return &eks.UpdateNodegroupConfigOutput{}, nil
+	}
+	nodegroup.ScalingConfig = input.ScalingConfig
+	nodegroup.Status = aws.String(eks.NodegroupStatusUpdating)
+	return &eks.UpdateNodegroupConfigOutput{}, nil
+}
+
+func (f *fakeEKS) DescribeAddonVersionsPages(input *eks.DescribeAddonVersionsInput, fn func(*eks.DescribeAddonVersionsOutput, bool) bool) error {
+	compatible := func(versions ...string) []*eks.Compatibility {
+		var compatibilities []*eks.Compatibility
+		for _, version := range versions {
+			compatibilities = append(compatibilities, &eks.Compatibility{ClusterVersion: aws.String(version)})
+		}
+		return compatibilities
+	}
+	fn(&eks.DescribeAddonVersionsOutput{Addons: []*eks.AddonInfo{
+		{AddonName: aws.String("vpc-cni"), AddonVersions: []*eks.AddonVersionInfo{
+			{AddonVersion: aws.String("v1.7.5"), Compatibilities: compatible("1.18", "1.19")},
+			{AddonVersion: aws.String("v1.7.9"), Compatibilities: compatible("1.9", "1.19")},
+		}},
+	}}, true)
+	return nil
+}
+
+// addUpdate records an update in progress and returns its ID
+func (f *fakeEKS) addUpdate(updateType string) *eks.Update {
+	update := &eks.Update{
+		Id:     aws.String("update-" + strconv.Itoa(len(f.updates)+1)),
+		Type:   aws.String(updateType),
+		Status: aws.String(eks.UpdateStatusInProgress),
+	}
+	f.updates[aws.StringValue(update.Id)] = update
+	return update
+}
+
+func (f *fakeEKS) UpdateClusterVersion(input *eks.UpdateClusterVersionInput) (*eks.UpdateClusterVersionOutput, error) {
+	cluster, ok := f.clusters[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, eksNotFound()
+	}
+	if update, ok := f.replayed(input.ClientRequestToken); ok {
+		return &eks.UpdateClusterVersionOutput{Update: update}, nil
+	}
+	update := f.remember(input.ClientRequestToken, f.addUpdate(eks.UpdateTypeVersionUpdate))
+	if f.failUpdate {
+		f.failUpdate = false
+		update.Status = aws.String(eks.UpdateStatusFailed)
+		return &eks.UpdateClusterVersionOutput{Update: update}, nil
+	}
+	cluster.Version = input.Version
+	return &eks.UpdateClusterVersionOutput{Update: update}, nil
+}
+
+func (f *fakeEKS) UpdateNodegroupVersion(input *eks.UpdateNodegroupVersionInput) (*eks.UpdateNodegroupVersionOutput, error) {
+	nodegroup, ok := f.nodegroups[aws.StringValue(input.NodegroupName)]
+	if !ok {
+		return nil, eksNotFound()
+	}
+	if cluster := f.clusters[aws.StringValue(input.ClusterName)]; aws.StringValue(cluster.Version) != aws.StringValue(input.Version) {
+		return nil, awserr.New(eks.ErrCodeInvalidParameterException, "node group version must match the control plane", nil)
+	}
+	if update, ok := f.replayed(input.ClientRequestToken); ok {
+		return &eks.UpdateNodegroupVersionOutput{Update: update}, nil
+	}
+	nodegroup.Version = input.Version
+	return &eks.UpdateNodegroupVersionOutput{Update: f.remember(input.ClientRequestToken, f.addUpdate(eks.UpdateTypeVersionUpdate))}, nil
+}
+
+func (f *fakeEKS) DescribeUpdate(input *eks.DescribeUpdateInput) (*eks.DescribeUpdateOutput, error) {
+	update, ok := f.updates[aws.StringValue(input.UpdateId)]
+	if !ok {
+		return nil, eksNotFound()
+	}
+	copied := *update
+	if next, ok := nextState[aws.StringValue(update.Status)]; ok {
+		update.Status = aws.String(next)
+	}
+	return &eks.DescribeUpdateOutput{Update: &copied}, nil
+}
+
+func (f *fakeEKS) DeleteNodegroup(input *eks.DeleteNodegroupInput) (*eks.DeleteNodegroupOutput, error) {
+	if _, ok := f.nodegroups[aws.StringValue(input.NodegroupName)]; !ok {
+		return nil, eksNotFound()
+	}
+	delete(f.nodegroups, aws.StringValue(input.NodegroupName))
+	return &eks.DeleteNodegroupOutput{}, nil
+}
+
+// fakeIAM keeps roles in memory along with the policies attached to them
+type fakeIAM struct {
+	iamiface.IAMAPI
+	roles map[string][]string
+}
+
+func newFakeIAM() *fakeIAM {
+	return &fakeIAM{roles: map[string][]string{}}
+}
+
+func roleARN(name string) *string {
+	return aws.String("arn:aws:iam::123456789012:role/" + name)
+}
+
+func (f *fakeIAM) GetRole(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+	name := aws.StringValue(input.RoleName)
+	if _, ok := f.roles[name]; !ok {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "no such role", nil)
+	}
+	return &iam.GetRoleOutput{Role: &iam.Role{RoleName: input.RoleName, Arn: roleARN(name)}}, nil
+}
+
+func (f *fakeIAM) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+	name := aws.StringValue(input.RoleName)
+	f.roles[name] = nil
+	return &iam.CreateRoleOutput{Role: &iam.Role{RoleName: input.RoleName, Arn: roleARN(name)}}, nil
+}
+
+func (f *fakeIAM) AttachRolePolicy(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error) {
+	name := aws.StringValue(input.RoleName)
+	f.roles[name] = append(f.roles[name], aws.StringValue(input.PolicyArn))
+	return &iam.AttachRolePolicyOutput{}, nil
+}
+
+// fakeRDS keeps DB instances in memory
+type fakeRDS struct {
+	rdsiface.RDSAPI
+	instances map[string]*rds.DBInstance
+}
+
+func newFakeRDS() *fakeRDS {
+	return &fakeRDS{instances: map[string]*rds.DBInstance{}}
+}
+
+func (f *fakeRDS) CreateDBInstance(input *rds.CreateDBInstanceInput) (*rds.CreateDBInstanceOutput, error) {
+	instance := &rds.DBInstance{
+		DBInstanceIdentifier: input.DBInstanceIdentifier,
+		DBInstanceClass:      input.DBInstanceClass,
+		Engine:               input.Engine,
+		DBInstanceStatus:     aws.String("creating"),
+	}
+	f.instances[aws.StringValue(input.DBInstanceIdentifier)] = instance
+	copied := *instance
+	return &rds.CreateDBInstanceOutput{DBInstance: &copied}, nil
+}
+
+func (f *fakeRDS) DescribeDBInstances(input *rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error) {
+	output := &rds.DescribeDBInstancesOutput{}
+	for name, instance := range f.instances {
+		if input.DBInstanceIdentifier != nil && aws.StringValue(input.DBInstanceIdentifier) != name {
+			continue
+		}
+		copied := *instance
+		output.DBInstances = append(output.DBInstances, &copied)
+		if next, ok := nextState[aws.StringValue(instance.DBInstanceStatus)]; ok {
+			instance.DBInstanceStatus = aws.String(next)
+		}
+	}
+	if input.DBInstanceIdentifier != nil && len(output.DBInstances) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "not found", nil)
+	}
+	return output, nil
+}
+
+func (f *fakeRDS) DescribeDBInstancesPages(input *rds.DescribeDBInstancesInput, fn func(*rds.DescribeDBInstancesOutput, bool) bool) error {
+	output, err := f.DescribeDBInstances(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeRDS) DeleteDBInstance(input *rds.DeleteDBInstanceInput) (*rds.DeleteDBInstanceOutput, error) {
+	if _, ok := f.instances[aws.StringValue(input.DBInstanceIdentifier)]; !ok {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "not found", nil)
+	}
+	delete(f.instances, aws.StringValue(input.DBInstanceIdentifier))
+	return &rds.DeleteDBInstanceOutput{}, nil
+}
+
+// fakeS3 keeps buckets and the keys of their objects in memory
+type fakeS3 struct {
+	s3iface.S3API
+	buckets map[string][]string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{buckets: map[string][]string{}}
+}
+
+func (f *fakeS3) CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	f.buckets[aws.StringValue(input.Bucket)] = nil
+	return &s3.CreateBucketOutput{}, nil
+}
+
+func (f *fakeS3) ListBuckets(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	output := &s3.ListBucketsOutput{}
+	for name := range f.buckets {
+		output.Buckets = append(output.Buckets, &s3.Bucket{Name: aws.String(name)})
+	}
+	return output, nil
+}
+
+func (f *fakeS3) GetBucketLocation(input *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	return &s3.GetBucketLocationOutput{LocationConstraint: aws.String("us-east-2")}, nil
+}
+
+func (f *fakeS3) ListObjects(input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	output := &s3.ListObjectsOutput{}
+	for _, key := range f.buckets[aws.StringValue(input.Bucket)] {
+		output.Contents = append(output.Contents, &s3.Object{Key: aws.String(key)})
+	}
+	return output, nil
+}
+
+func (f *fakeS3) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	name := aws.StringValue(input.Bucket)
+	var kept []string
+	for _, key := range f.buckets[name] {
+		if key != aws.StringValue(input.Key) {
+			kept = append(kept, key)
+		}
+	}
+	f.buckets[name] = kept
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) DeleteBucket(input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	delete(f.buckets, aws.StringValue(input.Bucket))
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeSTS answers who the credentials belong to
+type fakeSTS struct {
+	stsiface.STSAPI
+}
+
+func (f *fakeSTS) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String("123456789012"),
+		Arn:     aws.String("arn:aws:iam::123456789012:user/lab"),
+	}, nil
+}
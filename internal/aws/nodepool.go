@@ -0,0 +1,147 @@
+package aws
+
+import (
+	"fmt"
+	"maker/internal/provider"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/pkg/errors"
+)
+
+// eksTaintEffects maps Kubernetes taint effects to the EKS enum
+var eksTaintEffects = map[string]string{
+	provider.TaintNoSchedule:       eks.TaintEffectNoSchedule,
+	provider.TaintPreferNoSchedule: eks.TaintEffectPreferNoSchedule,
+	provider.TaintNoExecute:        eks.TaintEffectNoExecute,
+}
+
+// EksNodegroupPoolInput builds the request to add a node group to an existing cluster
+func EksNodegroupPoolInput(opts provider.NodePoolOptions, arn string, subnets []*string) *eks.CreateNodegroupInput {
+	input := &eks.CreateNodegroupInput{
+		CapacityType:  aws.String("ON_DEMAND"),
+		ClusterName:   aws.String(opts.Cluster),
+		InstanceTypes: aws.StringSlice([]string{opts.NodeSize}),
+		NodeRole:      aws.String(arn),
+		NodegroupName: aws.String(opts.Name),
+		ScalingConfig: eksScalingConfig(opts.NodeScale, int64(opts.InitialCount())),
+		Subnets:       subnets,
+	}
+	if len(opts.Labels) > 0 {
+		input.Labels = aws.StringMap(opts.Labels)
+	}
+	for _, taint := range opts.Taints {
+		input.Taints = append(input.Taints, &eks.Taint{Key: aws.String(taint.Key), Value: aws.String(taint.Value), Effect: aws.String(eksTaintEffects[taint.Effect])})
+	}
+	return input
+}
+
+// eksScalingConfig converts a node scale, node groups that aren't autoscaled have equal bounds
+func eksScalingConfig(scale provider.NodeScale, desired int64) *eks.NodegroupScalingConfig {
+	if !scale.Autoscaled() {
+		count := int64(scale.NodeCount)
+		return &eks.NodegroupScalingConfig{DesiredSize: aws.Int64(count), MinSize: aws.Int64(count), MaxSize: aws.Int64(count)}
+	}
+	min, max := int64(scale.MinNodes), int64(scale.MaxNodes)
+	if desired < min {
+		desired = min
+	}
+	if desired > max {
+		desired = max
+	}
+	return &eks.NodegroupScalingConfig{DesiredSize: aws.Int64(desired), MinSize: aws.Int64(min), MaxSize: aws.Int64(max)}
+}
+
+// EksNodegroupScaleInput builds the request to resize a node group. Autoscaled groups keep
+// their current size, within the new bounds, unless a count is given. The SDK fills in a
+// fresh request token, so scaling back to an earlier size isn't taken for a retry.
+func EksNodegroupScaleInput(nodegroup *eks.Nodegroup, scale provider.NodeScale) *eks.UpdateNodegroupConfigInput {
+	desired := int64(scale.NodeCount)
+	if desired == 0 && nodegroup.ScalingConfig != nil {
+		desired = aws.Int64Value(nodegroup.ScalingConfig.DesiredSize)
+	}
+	return &eks.UpdateNodegroupConfigInput{
+		ClusterName:   nodegroup.ClusterName,
+		NodegroupName: nodegroup.NodegroupName,
+		ScalingConfig: eksScalingConfig(scale, desired),
+	}
+}
+
+// DescribeEksNodegroup fetches a node group of a cluster
+func DescribeEksNodegroup(svc eksiface.EKSAPI, clusterName, name string) (*eks.Nodegroup, error) {
+	result, err := svc.DescribeNodegroup(&eks.DescribeNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(name),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to fetch node group %s", name)
+	}
+	return result.Nodegroup, nil
+}
+
+// ListEksNodegroups describes every node group of a cluster
+func ListEksNodegroups(svc eksiface.EKSAPI, clusterName string) ([]*eks.Nodegroup, error) {
+	var names []*string
+	err := svc.ListNodegroupsPages(&eks.ListNodegroupsInput{ClusterName: aws.String(clusterName)},
+		func(page *eks.ListNodegroupsOutput, lastPage bool) bool {
+			names = append(names, page.Nodegroups...)
+			return true
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list node groups")
+	}
+
+	var nodegroups []*eks.Nodegroup
+	for _, name := range names {
+		nodegroup, err := DescribeEksNodegroup(svc, clusterName, aws.StringValue(name))
+		if err != nil {
+			return nil, err
+		}
+		nodegroups = append(nodegroups, nodegroup)
+	}
+	return nodegroups, nil
+}
+
+// UpdateEksNodegroup resizes a node group
+func UpdateEksNodegroup(svc eksiface.EKSAPI, input *eks.UpdateNodegroupConfigInput) error {
+	_, err := svc.UpdateNodegroupConfig(input)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update node group")
+	}
+	fmt.Fprintln(os.Stderr, "Node group", aws.StringValue(input.NodegroupName), "scaling")
+	return nil
+}
+
+// EksNodegroupSummary converts a node group into the common node pool summary
+func EksNodegroupSummary(nodegroup *eks.Nodegroup) provider.NodePool {
+	pool := provider.NodePool{
+		Provider: "aws",
+		Cluster:  aws.StringValue(nodegroup.ClusterName),
+		Name:     aws.StringValue(nodegroup.NodegroupName),
+		ID:       aws.StringValue(nodegroup.NodegroupArn),
+		Status:   aws.StringValue(nodegroup.Status),
+		Labels:   aws.StringValueMap(nodegroup.Labels),
+	}
+	if len(nodegroup.InstanceTypes) > 0 {
+		pool.Size = aws.StringValue(nodegroup.InstanceTypes[0])
+	}
+	if config := nodegroup.ScalingConfig; config != nil {
+		pool.NodeCount = int(aws.Int64Value(config.DesiredSize))
+		if min, max := aws.Int64Value(config.MinSize), aws.Int64Value(config.MaxSize); min != max {
+			pool.MinNodes, pool.MaxNodes = int(min), int(max)
+		}
+	}
+	if len(pool.Labels) == 0 {
+		pool.Labels = nil
+	}
+	for _, taint := range nodegroup.Taints {
+		for effect, value := range eksTaintEffects {
+			if value == aws.StringValue(taint.Effect) {
+				pool.Taints = append(pool.Taints, provider.Taint{Key: aws.StringValue(taint.Key), Value: aws.StringValue(taint.Value), Effect: effect})
+			}
+		}
+	}
+	return pool
+}
@@ -0,0 +1,692 @@
+package aws
+
+import (
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"maker/internal/state"
+	"maker/internal/waiter"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/pkg/errors"
+)
+
+// Provider implements provider.Provider for AWS
+type Provider struct {
+	region string
+	ec2    ec2iface.EC2API
+	eks    eksiface.EKSAPI
+	iam    iamiface.IAMAPI
+	rds    rdsiface.RDSAPI
+	s3     s3iface.S3API
+	sts    stsiface.STSAPI
+	opts   provider.Options
+}
+
+func init() {
+	provider.Register("aws", provider.Registration{
+		Configure:  Configure,
+		New:        New,
+		ConfigFile: CredsName,
+		Secrets:    []string{RoleCacheSecret},
+	})
+}
+
+// New loads the AWS credentials file and creates the service clients to talk to AWS
+func New(opts provider.Options) (provider.Provider, error) {
+	creds, err := LoadConfig()
+	if err != nil {
+		// without a config there are no credentials to use
+		return nil, errs.WrapAs(errs.AuthFailed, err, "Failed to load config")
+	}
+	sess, err := CreateAwsSession(creds)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to setup AWS Session")
+	}
+	return &Provider{
+		region: aws.StringValue(sess.Config.Region),
+		ec2:    ec2.New(sess),
+		eks:    eks.New(sess),
+		iam:    iam.New(sess),
+		rds:    rds.New(sess),
+		s3:     s3.New(sess),
+		sts:    sts.New(sess),
+		opts:   opts,
+	}, nil
+}
+
+// Region returns the region objects are created in
+func (p *Provider) Region() string {
+	return p.region
+}
+
+// Verify checks the credentials by asking STS who they belong to
+func (p *Provider) Verify() (*provider.Identity, error) {
+	out, err := p.sts.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get caller identity")
+	}
+	return &provider.Identity{
+		Provider: "aws",
+		User:     aws.StringValue(out.Arn),
+		Account:  aws.StringValue(out.Account),
+		Region:   p.region,
+	}, nil
+}
+
+// Catalog lists the instance types, AMIs, regions or Kubernetes versions AWS offers
+func (p *Provider) Catalog(kind string) ([]provider.CatalogEntry, error) {
+	switch kind {
+	case provider.CatalogSizes:
+		return ListEc2InstanceTypes(p.ec2)
+	case provider.CatalogImages:
+		return ListEc2Images(p.ec2)
+	case provider.CatalogRegions:
+		return ListEc2Regions(p.ec2)
+	case provider.CatalogVersions:
+		return ListEksVersions(p.eks)
+	}
+	return nil, provider.UnknownCatalog(kind)
+}
+
+// nodeGroupName is the name of the node group Maker creates alongside a cluster
+func nodeGroupName(clusterName string) string {
+	return clusterName + "-nodegroup"
+}
+
+// ListSSHKeys fetches the key pairs of the region
+func (p *Provider) ListSSHKeys() ([]provider.SSHKey, error) {
+	pairs, err := ListEc2KeyPairs(p.ec2)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]provider.SSHKey, 0, len(pairs))
+	for _, pair := range pairs {
+		keys = append(keys, KeyPairSummary(pair))
+	}
+	return keys, nil
+}
+
+// AddSSHKey imports a public key as a key pair of the region
+func (p *Provider) AddSSHKey(key provider.PublicKey) (*provider.SSHKey, error) {
+	input := Ec2ImportKeyPairInput(key)
+	if p.opts.DryRun {
+		return &provider.SSHKey{Provider: "aws", Name: key.Name}, p.opts.PrintRequest("EC2.ImportKeyPair", input)
+	}
+	pair, err := ImportEc2KeyPair(p.ec2, input)
+	if err != nil {
+		return nil, err
+	}
+	summary := KeyPairSummary(pair)
+	return &summary, nil
+}
+
+// RemoveSSHKey deletes a key pair by name, ID or fingerprint
+func (p *Provider) RemoveSSHKey(name string) error {
+	keys, err := p.ListSSHKeys()
+	if err != nil {
+		return err
+	}
+	key, err := provider.MatchSSHKey(keys, name)
+	if err != nil {
+		return err
+	}
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("EC2.DeleteKeyPair", &ec2.DeleteKeyPairInput{KeyName: aws.String(key.Name)})
+	}
+	return DeleteEc2KeyPair(p.ec2, key.Name)
+}
+
+// instanceKey finds the key pair an instance is launched with, importing a local key the region doesn't have yet
+func (p *Provider) instanceKey(ref string) (*provider.SSHKey, error) {
+	keys, err := p.ListSSHKeys()
+	if err != nil {
+		return nil, err
+	}
+	key, local, err := provider.ChooseSSHKey(keys, ref)
+	if err != nil {
+		return nil, err
+	}
+	if local != nil {
+		return p.AddSSHKey(*local)
+	}
+	fmt.Fprintln(os.Stderr, "Using SSH Key", key.Name)
+	return key, nil
+}
+
+// instanceID prefers the ID recorded when Maker created the instance over a Name tag lookup
+func (p *Provider) instanceID(name string) (string, error) {
+	if id := state.LookupID("aws", provider.KindVM, name); id != "" {
+		return id, nil
+	}
+	return GetInstanceID(p.ec2, name)
+}
+
+// CreateVM creates an EC2 instance
+func (p *Provider) CreateVM(opts provider.VMOptions) (*provider.Resource, error) {
+	ami, err := ResolveEc2Image(p.ec2, opts.Image)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create EC2 instance")
+	}
+	key, err := p.instanceKey(opts.SSHKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create EC2 instance")
+	}
+	input := Ec2RunInput(opts.Name, opts.Size, ami, key.Name)
+	input.UserData = Ec2UserData(opts.UserData)
+	if p.opts.DryRun {
+		return provider.Planned("aws", opts.Name, p.region, opts.Size), p.opts.PrintRequest("EC2.RunInstances", input)
+	}
+	instance, err := CreateEc2Instance(p.ec2, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create EC2 instance")
+	}
+	if p.opts.Wait {
+		id := aws.StringValue(instance.InstanceId)
+		err = p.opts.Waiter().Until("instance "+opts.Name, Ec2State(p.ec2, id), []string{"running"}, []string{"shutting-down", "terminated"})
+		if err != nil {
+			return nil, err
+		}
+		return GetEc2Status(p.ec2, id)
+	}
+	resource := Ec2Resource(instance)
+	// the launch response doesn't always carry the tags
+	resource.Name = opts.Name
+	return &resource, nil
+}
+
+// GetVM fetches the status of an EC2 instance
+func (p *Provider) GetVM(name string) (*provider.Resource, error) {
+	instanceID, err := p.instanceID(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch EC2 instance ID")
+	}
+	return GetEc2Status(p.ec2, instanceID)
+}
+
+// ListVMs lists all EC2 instances
+func (p *Provider) ListVMs() ([]provider.Resource, error) {
+	instances, err := ListEc2Instances(p.ec2)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, instance := range instances {
+		resources = append(resources, Ec2Resource(instance))
+	}
+	return resources, nil
+}
+
+// DeleteVM terminates an EC2 instance
+func (p *Provider) DeleteVM(name string) error {
+	instanceID, err := p.instanceID(name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch EC2 instance ID")
+	}
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("EC2.TerminateInstances", Ec2TerminateInput(instanceID))
+	}
+	err = DeleteEc2Instance(p.ec2, instanceID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete EC2 instance")
+	}
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("instance "+name, Ec2State(p.ec2, instanceID), []string{"terminated"}, nil)
+	}
+	return nil
+}
+
+// CreateCluster creates an EKS cluster with a node group and writes its kubeconfig
+func (p *Provider) CreateCluster(opts provider.ClusterOptions) (*provider.Resource, error) {
+	if len(opts.Subnets) < 2 {
+		return nil, errors.New("Must provide two subnets to create cluster (-b)")
+	}
+
+	arn, _ := GetExistingRoleARN(p.iam)
+	if p.opts.DryRun {
+		return p.planCluster(opts, arn)
+	}
+	if arn == "" {
+		var err error
+		arn, err = CreateEksClusterRole(p.iam)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create EKS service linked role")
+		}
+	}
+	err := CreateEksCluster(p.eks, EksClusterInput(opts.Name, arn, opts.Version, opts.Subnets))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create EKS cluster")
+	}
+	// node groups can only be added to an active cluster
+	err = p.opts.Waiter().Until("cluster "+opts.Name, EksClusterState(p.eks, opts.Name),
+		[]string{eks.ClusterStatusActive}, []string{eks.ClusterStatusFailed, waiter.StateGone})
+	if err != nil {
+		return nil, err
+	}
+	err = CreateEksNodeGroup(p.eks, EksNodegroupInput(opts.Name, arn, opts.NodeSize, opts.NodeCount, opts.Subnets))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create EKS node group")
+	}
+	if p.opts.Wait {
+		err = p.opts.Waiter().Until("node group "+nodeGroupName(opts.Name), EksNodeGroupState(p.eks, opts.Name, nodeGroupName(opts.Name)),
+			[]string{eks.NodegroupStatusActive}, []string{eks.NodegroupStatusCreateFailed, waiter.StateGone})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = p.FetchKubeconfig(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetCluster(opts.Name)
+}
+
+// planCluster prints the requests CreateCluster would send, including the role it would create
+func (p *Provider) planCluster(opts provider.ClusterOptions, arn string) (*provider.Resource, error) {
+	if arn == "" {
+		roleInput, policies := EksRoleInputs()
+		if err := p.opts.PrintRequest("IAM.CreateRole", roleInput); err != nil {
+			return nil, err
+		}
+		for _, policyInput := range policies {
+			if err := p.opts.PrintRequest("IAM.AttachRolePolicy", policyInput); err != nil {
+				return nil, err
+			}
+		}
+		// the ARN isn't known until the role is created
+		arn = "<EKSClusterRole ARN>"
+	}
+	err := p.opts.PrintRequest("EKS.CreateCluster", EksClusterInput(opts.Name, arn, opts.Version, opts.Subnets))
+	if err != nil {
+		return nil, err
+	}
+	err = p.opts.PrintRequest("EKS.CreateNodegroup", EksNodegroupInput(opts.Name, arn, opts.NodeSize, opts.NodeCount, opts.Subnets))
+	return provider.Planned("aws", opts.Name, p.region, opts.NodeSize), err
+}
+
+// GetCluster fetches the status of an EKS cluster and its node group
+func (p *Provider) GetCluster(name string) (*provider.Resource, error) {
+	resource, err := GetEksClusterStatus(p.eks, p.region, name, nodeGroupName(name))
+	return resource, errors.Wrap(err, "Failed to get EKS cluster status")
+}
+
+// KubeToken returns a token for an EKS cluster signed with the provider's credentials
+func (p *Provider) KubeToken(cluster string) (*provider.KubeToken, error) {
+	return EksToken(p.sts, cluster)
+}
+
+// ListClusters lists all EKS clusters
+func (p *Provider) ListClusters() ([]provider.Resource, error) {
+	clusters, err := ListEksClusters(p.eks)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, cluster := range clusters {
+		resources = append(resources, EksClusterResource(cluster, p.region))
+	}
+	return resources, nil
+}
+
+// FetchKubeconfig writes the kubeconfig of an EKS cluster
+func (p *Provider) FetchKubeconfig(name string) error {
+	result, err := GetCluster(p.eks, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to grab cluster info")
+	}
+	cluster := result.Cluster
+	endpoint := aws.StringValue(cluster.Endpoint)
+	var caData string
+	if cluster.CertificateAuthority != nil {
+		caData = aws.StringValue(cluster.CertificateAuthority.Data)
+	}
+	// the endpoint and certificate are only filled in once the control plane is up
+	if endpoint == "" || caData == "" {
+		return errors.Wrapf(provider.ErrClusterNotReady, "Cluster %s has no endpoint yet -- current status: %s", name, aws.StringValue(cluster.Status))
+	}
+	err = CreateKubeconfig(endpoint, caData, name)
+	return errors.Wrap(err, "Failed to create kubeconfig")
+}
+
+// ClusterUpgrades lists the version an EKS cluster can be upgraded to
+func (p *Provider) ClusterUpgrades(name string) (*provider.ClusterVersions, error) {
+	result, err := GetCluster(p.eks, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to grab cluster info")
+	}
+	return EksClusterVersions(result.Cluster), nil
+}
+
+// UpgradeCluster upgrades the control plane of an EKS cluster and then its node groups one
+// at a time, waiting for each update as node groups can't be newer than their control plane
+func (p *Provider) UpgradeCluster(name, version string) (*provider.Resource, error) {
+	versions, err := p.ClusterUpgrades(name)
+	if err != nil {
+		return nil, err
+	}
+	version, err = versions.Resolve(version)
+	if err != nil {
+		return nil, err
+	}
+	nodegroups, err := ListEksNodegroups(p.eks, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list the node groups")
+	}
+	var inputs []*eks.UpdateNodegroupVersionInput
+	for _, nodegroup := range nodegroups {
+		if aws.StringValue(nodegroup.Version) != version {
+			inputs = append(inputs, EksNodegroupVersionInput(name, aws.StringValue(nodegroup.NodegroupName), version))
+		}
+	}
+
+	if p.opts.DryRun {
+		if version != versions.Current {
+			if err := p.opts.PrintRequest("EKS.UpdateClusterVersion", EksClusterVersionInput(name, version)); err != nil {
+				return nil, err
+			}
+		}
+		for _, input := range inputs {
+			if err := p.opts.PrintRequest("EKS.UpdateNodegroupVersion", input); err != nil {
+				return nil, err
+			}
+		}
+		resource, err := p.GetCluster(name)
+		if err != nil {
+			return nil, err
+		}
+		resource.Status = provider.StatusPlanned
+		return resource, nil
+	}
+
+	if version != versions.Current {
+		id, err := UpdateEksClusterVersion(p.eks, EksClusterVersionInput(name, version))
+		if err != nil {
+			return nil, err
+		}
+		err = p.opts.Waiter().Until("cluster "+name, EksUpdateState(p.eks, name, "", id),
+			[]string{eks.UpdateStatusSuccessful}, []string{eks.UpdateStatusFailed, eks.UpdateStatusCancelled})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, input := range inputs {
+		nodegroupName := aws.StringValue(input.NodegroupName)
+		id, err := UpdateEksNodegroupVersion(p.eks, input)
+		if err != nil {
+			return nil, err
+		}
+		err = p.opts.Waiter().Until("node group "+nodegroupName, EksUpdateState(p.eks, name, nodegroupName, id),
+			[]string{eks.UpdateStatusSuccessful}, []string{eks.UpdateStatusFailed, eks.UpdateStatusCancelled})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.GetCluster(name)
+}
+
+// DeleteCluster deletes the node groups and then the EKS cluster
+func (p *Provider) DeleteCluster(name string) error {
+	nodegroups, err := ListEksNodegroups(p.eks, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to list the node groups")
+	}
+	if p.opts.DryRun {
+		for _, nodegroup := range nodegroups {
+			err := p.opts.PrintRequest("EKS.DeleteNodegroup", EksNodegroupDeleteInput(name, aws.StringValue(nodegroup.NodegroupName)))
+			if err != nil {
+				return err
+			}
+		}
+		return p.opts.PrintRequest("EKS.DeleteCluster", EksClusterDeleteInput(name))
+	}
+	for _, nodegroup := range nodegroups {
+		err = DeleteEksNodeGroup(p.eks, name, aws.StringValue(nodegroup.NodegroupName))
+		if err != nil {
+			return errors.Wrap(err, "Failed to delete the node group")
+		}
+	}
+	// the cluster can't be deleted while it still has node groups
+	for _, nodegroup := range nodegroups {
+		nodegroupName := aws.StringValue(nodegroup.NodegroupName)
+		err = p.opts.Waiter().Until("node group "+nodegroupName, EksNodeGroupState(p.eks, name, nodegroupName),
+			[]string{waiter.StateGone}, []string{eks.NodegroupStatusDeleteFailed})
+		if err != nil {
+			return err
+		}
+	}
+	err = DeleteEksCluster(p.eks, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete the cluster")
+	}
+	kubeconfig.Forget("aws", name)
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("cluster "+name, EksClusterState(p.eks, name), []string{waiter.StateGone}, []string{eks.ClusterStatusFailed})
+	}
+	return nil
+}
+
+// AddNodePool adds a node group to an EKS cluster, in the cluster's subnets with the role Maker created it with
+func (p *Provider) AddNodePool(opts provider.NodePoolOptions) (*provider.NodePool, error) {
+	result, err := GetCluster(p.eks, opts.Cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to grab cluster info")
+	}
+	var subnets []*string
+	if result.Cluster.ResourcesVpcConfig != nil {
+		subnets = result.Cluster.ResourcesVpcConfig.SubnetIds
+	}
+	arn, _ := GetExistingRoleARN(p.iam)
+	if arn == "" {
+		return nil, errs.New(errs.NotFound, "Missing the EKSClusterRole node role -- create the cluster with Maker first")
+	}
+	input := EksNodegroupPoolInput(opts, arn, subnets)
+	if p.opts.DryRun {
+		return &provider.NodePool{Provider: "aws", Cluster: opts.Cluster, Name: opts.Name, Size: opts.NodeSize, Status: provider.StatusPlanned},
+			p.opts.PrintRequest("EKS.CreateNodegroup", input)
+	}
+	err = CreateEksNodeGroup(p.eks, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create EKS node group")
+	}
+	return p.waitNodePool(opts.Cluster, opts.Name, []string{eks.NodegroupStatusCreateFailed, waiter.StateGone})
+}
+
+// ListNodePools lists the node groups of an EKS cluster
+func (p *Provider) ListNodePools(cluster string) ([]provider.NodePool, error) {
+	nodegroups, err := ListEksNodegroups(p.eks, cluster)
+	if err != nil {
+		return nil, err
+	}
+	var pools []provider.NodePool
+	for _, nodegroup := range nodegroups {
+		pools = append(pools, EksNodegroupSummary(nodegroup))
+	}
+	return pools, nil
+}
+
+// ScaleNodePool resizes a node group of an EKS cluster
+func (p *Provider) ScaleNodePool(cluster, name string, scale provider.NodeScale) (*provider.NodePool, error) {
+	nodegroup, err := DescribeEksNodegroup(p.eks, cluster, name)
+	if err != nil {
+		return nil, err
+	}
+	input := EksNodegroupScaleInput(nodegroup, scale)
+	if p.opts.DryRun {
+		pool := EksNodegroupSummary(nodegroup)
+		pool.Status = provider.StatusPlanned
+		return &pool, p.opts.PrintRequest("EKS.UpdateNodegroupConfig", input)
+	}
+	err = UpdateEksNodegroup(p.eks, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to scale EKS node group")
+	}
+	return p.waitNodePool(cluster, name, []string{eks.NodegroupStatusDegraded, waiter.StateGone})
+}
+
+// waitNodePool waits for a node group to be active with --wait and summarizes it
+func (p *Provider) waitNodePool(cluster, name string, failed []string) (*provider.NodePool, error) {
+	if p.opts.Wait {
+		err := p.opts.Waiter().Until("node group "+name, EksNodeGroupState(p.eks, cluster, name), []string{eks.NodegroupStatusActive}, failed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	nodegroup, err := DescribeEksNodegroup(p.eks, cluster, name)
+	if err != nil {
+		return nil, err
+	}
+	pool := EksNodegroupSummary(nodegroup)
+	return &pool, nil
+}
+
+// DeleteNodePool deletes a node group of an EKS cluster
+func (p *Provider) DeleteNodePool(cluster, name string) error {
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("EKS.DeleteNodegroup", EksNodegroupDeleteInput(cluster, name))
+	}
+	err := DeleteEksNodeGroup(p.eks, cluster, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete the node group")
+	}
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("node group "+name, EksNodeGroupState(p.eks, cluster, name), []string{waiter.StateGone}, []string{eks.NodegroupStatusDeleteFailed})
+	}
+	return nil
+}
+
+// CreateBucket creates an S3 bucket
+func (p *Provider) CreateBucket(name string) (*provider.Resource, error) {
+	input := S3CreateInput(name)
+	if p.opts.DryRun {
+		return provider.Planned("aws", name, p.region, ""), p.opts.PrintRequest("S3.CreateBucket", input)
+	}
+	err := CreateS3Bucket(p.s3, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create S3 bucket")
+	}
+	return &provider.Resource{
+		Name:     name,
+		ID:       name,
+		Provider: "aws",
+		Region:   p.region,
+	}, nil
+}
+
+// GetBucket fetches info about an S3 bucket
+func (p *Provider) GetBucket(name string) (*provider.Resource, error) {
+	resource, err := GetS3BucketInfo(p.s3, name)
+	return resource, errors.Wrap(err, "Failed to fetch S3 bucket")
+}
+
+// ListBuckets lists all S3 buckets
+func (p *Provider) ListBuckets() ([]provider.Resource, error) {
+	buckets, err := ListS3Buckets(p.s3)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, bucket := range buckets {
+		region, _ := GetS3BucketRegion(p.s3, aws.StringValue(bucket.Name))
+		resources = append(resources, S3BucketResource(bucket, region))
+	}
+	return resources, nil
+}
+
+// DeleteBucket empties and deletes an S3 bucket, confirming first that its objects go too
+func (p *Provider) DeleteBucket(name string) error {
+	inputs, err := S3ObjectDeleteInputs(p.s3, name)
+	if err != nil {
+		return err
+	}
+	if p.opts.DryRun {
+		for _, input := range inputs {
+			if err := p.opts.PrintRequest("S3.DeleteObject", input); err != nil {
+				return err
+			}
+		}
+		return p.opts.PrintRequest("S3.DeleteBucket", S3DeleteInput(name))
+	}
+	if len(inputs) > 0 {
+		if err := p.opts.ConfirmEmptyBucket(name, len(inputs)); err != nil {
+			return err
+		}
+		if err := DeleteS3Objects(p.s3, name, inputs); err != nil {
+			return errors.Wrap(err, "Failed to delete bucket objects")
+		}
+	}
+	err = DeleteS3Bucket(p.s3, name)
+	return errors.Wrap(err, "Failed to delete S3 bucket")
+}
+
+// CreateDB creates a Postgres RDS instance
+func (p *Provider) CreateDB(opts provider.DBOptions) (*provider.Resource, error) {
+	input := RdsCreateInput(opts.Name, opts.Size)
+	if p.opts.DryRun {
+		return provider.Planned("aws", opts.Name, p.region, opts.Size), p.opts.PrintRequest("RDS.CreateDBInstance", input)
+	}
+	instance, err := CreateRdsInstance(p.rds, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create RDS instance")
+	}
+	if p.opts.Wait {
+		err = p.opts.Waiter().Until("database "+opts.Name, RdsState(p.rds, opts.Name),
+			[]string{"available"}, []string{"failed", "incompatible-parameters", "incompatible-network", "storage-full", waiter.StateGone})
+		if err != nil {
+			return nil, err
+		}
+		return GetRdsStatus(p.rds, opts.Name)
+	}
+	resource := RdsResource(instance)
+	// the AZ isn't picked until the instance is placed
+	if resource.Region == "" {
+		resource.Region = p.region
+	}
+	return &resource, nil
+}
+
+// GetDB fetches the status of an RDS instance
+func (p *Provider) GetDB(name string) (*provider.Resource, error) {
+	return GetRdsStatus(p.rds, name)
+}
+
+// ListDBs lists all RDS instances
+func (p *Provider) ListDBs() ([]provider.Resource, error) {
+	instances, err := ListRdsInstances(p.rds)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, instance := range instances {
+		resources = append(resources, RdsResource(instance))
+	}
+	return resources, nil
+}
+
+// DeleteDB deletes an RDS instance
+func (p *Provider) DeleteDB(name string) error {
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("RDS.DeleteDBInstance", RdsDeleteInput(name))
+	}
+	err := DeleteRdsInstance(p.rds, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete RDS instance")
+	}
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("database "+name, RdsState(p.rds, name), []string{waiter.StateGone}, nil)
+	}
+	return nil
+}
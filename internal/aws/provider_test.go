@@ -0,0 +1,556 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"maker/internal/state"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+)
+
+type fakes struct {
+	ec2 *fakeEC2
+	eks *fakeEKS
+	iam *fakeIAM
+	rds *fakeRDS
+	s3  *fakeS3
+}
+
+// newTestProvider returns a provider backed by in-memory fakes, with the
+// state file and kubeconfigs written to a temporary directory
+func newTestProvider(t *testing.T, opts provider.Options) (*Provider, *fakes) {
+	dir := t.TempDir()
+	oldState, oldFolder, oldKubeconfig, oldSSH := state.StatePath, utils.ConfigFolderPath, kubeconfig.Path, provider.SSHFolder
+	state.StatePath = filepath.Join(dir, "state.json")
+	utils.ConfigFolderPath = dir
+	kubeconfig.Path = filepath.Join(dir, "kubeconfig")
+	// no local SSH keys unless a test writes them
+	provider.SSHFolder = dir
+	t.Cleanup(func() {
+		state.StatePath, utils.ConfigFolderPath, kubeconfig.Path, provider.SSHFolder = oldState, oldFolder, oldKubeconfig, oldSSH
+	})
+
+	if opts.PollInterval == 0 {
+		opts.PollInterval = time.Millisecond
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	f := &fakes{
+		ec2: newFakeEC2("laptop"),
+		eks: newFakeEKS(),
+		iam: newFakeIAM(),
+		rds: newFakeRDS(),
+		s3:  newFakeS3(),
+	}
+	p := &Provider{
+		region: "us-east-1",
+		ec2:    f.ec2,
+		eks:    f.eks,
+		iam:    f.iam,
+		rds:    f.rds,
+		s3:     f.s3,
+		sts:    &fakeSTS{},
+		opts:   opts,
+	}
+	return p, f
+}
+
+// assertContext checks whether the test kubeconfig has a context called name
+func assertContext(t *testing.T, name string, want bool) {
+	t.Helper()
+	config, err := kubeconfig.Load(kubeconfig.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, context := range config.Contexts {
+		found = found || context.Name == name
+	}
+	if found != want {
+		t.Errorf("context %s in kubeconfig = %v, want %v", name, found, want)
+	}
+}
+
+func TestVMLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+
+	resource, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "t2.micro", Image: "ami-123", UserData: "#!/bin/sh\n"})
+	if err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	if data := aws.StringValue(f.ec2.launched[0].UserData); data != base64.StdEncoding.EncodeToString([]byte("#!/bin/sh\n")) {
+		t.Errorf("expected base64 user data, got %q", data)
+	}
+	if resource.Status != "running" {
+		t.Errorf("expected the create to wait until running, got %q", resource.Status)
+	}
+	if resource.Name != "web" {
+		t.Errorf("expected the Name tag to be set on launch, got %q", resource.Name)
+	}
+	if key := aws.StringValue(f.ec2.launched[0].KeyName); key != "laptop" {
+		t.Errorf("expected the only key pair to be used, got %q", key)
+	}
+
+	got, err := p.GetVM("web")
+	if err != nil {
+		t.Fatalf("GetVM: %v", err)
+	}
+	if got.ID != resource.ID {
+		t.Errorf("GetVM returned ID %s, want %s", got.ID, resource.ID)
+	}
+
+	if err := p.DeleteVM("web"); err != nil {
+		t.Fatalf("DeleteVM: %v", err)
+	}
+	if status := aws.StringValue(f.ec2.instances[resource.ID].State.Name); status != "terminated" {
+		t.Errorf("expected the delete to wait until terminated, got %q", status)
+	}
+	// terminated instances are skipped when looking up a name
+	if _, err := GetInstanceID(p.ec2, "web"); err == nil {
+		t.Errorf("expected a terminated instance not to be found by name")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	p, _ := newTestProvider(t, provider.Options{})
+	identity, err := p.Verify()
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	want := provider.Identity{Provider: "aws", User: "arn:aws:iam::123456789012:user/lab", Account: "123456789012", Region: "us-east-1"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestKubeToken(t *testing.T) {
+	p, _ := newTestProvider(t, provider.Options{})
+	// presigning is done locally, so a real client with made up keys never calls AWS
+	p.sts = sts.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("AKIDEXAMPLE", "secret", ""),
+	})))
+
+	token, err := p.KubeToken("k8s")
+	if err != nil {
+		t.Fatalf("KubeToken: %v", err)
+	}
+	if !strings.HasPrefix(token.Token, eksTokenPrefix) {
+		t.Fatalf("token %q lacks the %s prefix", token.Token, eksTokenPrefix)
+	}
+	url, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token.Token, eksTokenPrefix))
+	if err != nil {
+		t.Fatalf("token isn't base64: %v", err)
+	}
+	for _, want := range []string{"https://sts.amazonaws.com/", "Action=GetCallerIdentity", "X-Amz-Credential=AKIDEXAMPLE", "x-k8s-aws-id"} {
+		if !strings.Contains(string(url), want) {
+			t.Errorf("presigned URL %s doesn't contain %s", url, want)
+		}
+	}
+	if left := time.Until(token.Expiry); left <= 0 || left > 15*time.Minute {
+		t.Errorf("token expires in %v", left)
+	}
+}
+
+func TestCreateVMWithoutKeys(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	f.ec2.keys = nil
+	if _, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "t2.micro", Image: "ami-123"}); err == nil {
+		t.Fatal("expected an error when the account has no key pairs")
+	}
+	if len(f.ec2.launched) != 0 {
+		t.Errorf("no instance should be launched without a key pair")
+	}
+}
+
+func TestSSHKeys(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	path := filepath.Join(provider.SSHFolder, "id_ed25519.pub")
+	ioutil.WriteFile(path, []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIC9TtvMVg5+8hDazIXWO0tmh6sHaoEWEpTnnaLn2Fhr8 me@laptop\n"), 0600)
+
+	if _, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "t3.micro", Image: "ami-123", SSHKey: path}); err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	if key := aws.StringValue(f.ec2.launched[0].KeyName); key != "me@laptop" || len(f.ec2.keys) != 2 {
+		t.Errorf("expected the local key to be imported and used, got %s with %v", key, f.ec2.keys)
+	}
+	if _, err := p.CreateVM(provider.VMOptions{Name: "db", Size: "t3.micro", Image: "ami-123", SSHKey: "laptop"}); err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	if key := aws.StringValue(f.ec2.launched[1].KeyName); key != "laptop" {
+		t.Errorf("expected the named key pair, got %s", key)
+	}
+	if _, err := p.CreateVM(provider.VMOptions{Name: "app", Size: "t3.micro", Image: "ami-123"}); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error picking between several key pairs, got %v", err)
+	}
+
+	if err := p.RemoveSSHKey("me@laptop"); err != nil {
+		t.Fatalf("RemoveSSHKey: %v", err)
+	}
+	if keys, err := p.ListSSHKeys(); err != nil || len(keys) != 1 || keys[0].Name != "laptop" {
+		t.Errorf("expected only the laptop key to be left, got %+v, %v", keys, err)
+	}
+}
+
+func TestCreateVMResolvesImagePattern(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	_, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "t3.micro", Image: "099720109477/ubuntu/images/hvm-ssd/ubuntu-focal-20.04-amd64-server-*"})
+	if err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	for _, instance := range f.ec2.instances {
+		if aws.StringValue(instance.ImageId) != "ami-new" {
+			t.Errorf("expected the newest matching AMI, got %s", aws.StringValue(instance.ImageId))
+		}
+	}
+
+	_, err = p.CreateVM(provider.VMOptions{Name: "db", Size: "t3.micro", Image: "136693071363/debian-12-amd64-*"})
+	if errs.ClassOf(err) != errs.NotFound {
+		t.Errorf("expected a NotFound error when no AMI matches, got %v", err)
+	}
+}
+
+func TestClusterLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+
+	_, err := p.CreateCluster(provider.ClusterOptions{Name: "k8s", NodeSize: "t3.medium", NodeCount: 2, Subnets: []string{"subnet-a"}})
+	if err == nil {
+		t.Fatal("expected an error with fewer than two subnets")
+	}
+
+	resource, err := p.CreateCluster(provider.ClusterOptions{Name: "k8s", NodeSize: "t3.medium", NodeCount: 2, Subnets: []string{"subnet-a", "subnet-b"}})
+	if err != nil {
+		t.Fatalf("CreateCluster: %v", err)
+	}
+	if resource.Status != eks.ClusterStatusActive {
+		t.Errorf("expected an active cluster, got %q", resource.Status)
+	}
+	if resource.Details["nodegroup_status"] != eks.NodegroupStatusActive {
+		t.Errorf("expected the create to wait for the node group, got %q", resource.Details["nodegroup_status"])
+	}
+	if len(f.iam.roles["EKSClusterRole"]) != 4 {
+		t.Errorf("expected the cluster role to be created with its policies, got %v", f.iam.roles)
+	}
+	assertContext(t, "maker-aws-k8s", true)
+
+	// the fake refuses to delete a cluster that still has a node group
+	if err := p.DeleteCluster("k8s"); err != nil {
+		t.Fatalf("DeleteCluster: %v", err)
+	}
+	if len(f.eks.clusters) != 0 || len(f.eks.nodegroups) != 0 {
+		t.Errorf("cluster wasn't deleted")
+	}
+	assertContext(t, "maker-aws-k8s", false)
+
+	// a cluster of the same name can be made again once the old one is gone
+	if _, err := p.CreateCluster(provider.ClusterOptions{Name: "k8s", NodeSize: "t3.medium", NodeCount: 2, Subnets: []string{"subnet-a", "subnet-b"}}); err != nil {
+		t.Fatalf("CreateCluster: %v", err)
+	}
+	if len(f.eks.clusters) != 1 || len(f.eks.nodegroups) != 1 {
+		t.Errorf("cluster wasn't created again, got %d clusters and %d node groups", len(f.eks.clusters), len(f.eks.nodegroups))
+	}
+}
+
+func TestFetchKubeconfigBeforeEndpoint(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	f.eks.clusters["k8s"] = &eks.Cluster{Name: aws.String("k8s"), Status: aws.String(eks.ClusterStatusCreating)}
+
+	if err := p.FetchKubeconfig("k8s"); errors.Cause(err) != provider.ErrClusterNotReady {
+		t.Fatalf("expected a cluster not ready error, got %v", err)
+	}
+	assertContext(t, "maker-aws-k8s", false)
+}
+
+func TestNodePoolLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+	if _, err := p.CreateCluster(provider.ClusterOptions{Name: "k8s", NodeSize: "t3.medium", NodeCount: 2, Subnets: []string{"subnet-a", "subnet-b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := provider.NodePoolOptions{
+		Cluster:   "k8s",
+		Name:      "highmem",
+		NodeSize:  "r5.large",
+		NodeScale: provider.NodeScale{MinNodes: 1, MaxNodes: 3},
+		Labels:    map[string]string{"workload": "test"},
+		Taints:    []provider.Taint{{Key: "dedicated", Effect: provider.TaintNoSchedule}},
+	}
+	pool, err := p.AddNodePool(opts)
+	if err != nil {
+		t.Fatalf("AddNodePool: %v", err)
+	}
+	if pool.Status != eks.NodegroupStatusActive || pool.NodeCount != 1 || pool.MaxNodes != 3 || pool.Labels["workload"] != "test" {
+		t.Errorf("unexpected node pool %+v", *pool)
+	}
+	if len(pool.Taints) != 1 || pool.Taints[0].String() != "dedicated:NoSchedule" {
+		t.Errorf("expected the taint to be kept, got %v", pool.Taints)
+	}
+	if effect := aws.StringValue(f.eks.nodegroups["highmem"].Taints[0].Effect); effect != eks.TaintEffectNoSchedule {
+		t.Errorf("expected the effect sent as %s, got %s", eks.TaintEffectNoSchedule, effect)
+	}
+	if subnets := aws.StringValueSlice(f.eks.nodegroups["highmem"].Subnets); len(subnets) != 2 {
+		t.Errorf("expected the node group in the cluster subnets, got %v", subnets)
+	}
+
+	// an autoscaled group keeps its size within the new bounds
+	pool, err = p.ScaleNodePool("k8s", "highmem", provider.NodeScale{MinNodes: 2, MaxNodes: 5})
+	if err != nil {
+		t.Fatalf("ScaleNodePool: %v", err)
+	}
+	if pool.NodeCount != 2 || pool.MinNodes != 2 || pool.MaxNodes != 5 || pool.Status != eks.NodegroupStatusActive {
+		t.Errorf("unexpected scaled node pool %+v", *pool)
+	}
+	// going back to earlier bounds is a new request, not a replay of the first one
+	for _, scale := range []provider.NodeScale{{MinNodes: 1, MaxNodes: 3}, {MinNodes: 2, MaxNodes: 5}} {
+		if pool, err = p.ScaleNodePool("k8s", "highmem", scale); err != nil {
+			t.Fatalf("ScaleNodePool: %v", err)
+		}
+		if pool.MinNodes != scale.MinNodes || pool.MaxNodes != scale.MaxNodes {
+			t.Errorf("expected bounds %d-%d, got %+v", scale.MinNodes, scale.MaxNodes, *pool)
+		}
+	}
+
+	pools, err := p.ListNodePools("k8s")
+	if err != nil || len(pools) != 2 || pools[0].Name != "highmem" || pools[1].Name != "k8s-nodegroup" {
+		t.Fatalf("ListNodePools returned %v, %v", pools, err)
+	}
+	resource, err := p.GetCluster("k8s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resource.Details["node_count"] != "4" {
+		t.Errorf("expected the cluster to count the nodes of every group, got %v", resource.Details)
+	}
+
+	if err := p.DeleteNodePool("k8s", "highmem"); err != nil {
+		t.Fatalf("DeleteNodePool: %v", err)
+	}
+	if _, ok := f.eks.nodegroups["highmem"]; ok {
+		t.Errorf("node group wasn't deleted")
+	}
+
+	// a pool added again after it was deleted is created anew
+	if _, err := p.AddNodePool(opts); err != nil {
+		t.Fatalf("AddNodePool: %v", err)
+	}
+	if _, ok := f.eks.nodegroups["highmem"]; !ok {
+		t.Errorf("node group wasn't added again")
+	}
+
+	// deleting the cluster removes node groups added after it was created
+	if err := p.DeleteCluster("k8s"); err != nil {
+		t.Fatalf("DeleteCluster: %v", err)
+	}
+	if len(f.eks.nodegroups) != 0 {
+		t.Errorf("node groups left behind: %v", f.eks.nodegroups)
+	}
+}
+
+func TestUpgradeCluster(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	if _, err := p.CreateCluster(provider.ClusterOptions{Name: "k8s", NodeSize: "t3.medium", NodeCount: 2, Version: "1.19", Subnets: []string{"subnet-a", "subnet-b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := p.ClusterUpgrades("k8s")
+	if err != nil {
+		t.Fatalf("ClusterUpgrades: %v", err)
+	}
+	if versions.Current != "1.19" || len(versions.Available) != 1 || versions.Available[0] != "1.20" {
+		t.Errorf("expected only the next minor version, got %+v", *versions)
+	}
+	if _, err := p.UpgradeCluster("k8s", "1.21"); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error skipping a minor version, got %v", err)
+	}
+	f.eks.failUpdate = true
+	if _, err := p.UpgradeCluster("k8s", "1.20"); err == nil {
+		t.Fatal("expected the failed upgrade to be reported")
+	}
+
+	// retrying starts a new upgrade rather than getting the failed one back
+	resource, err := p.UpgradeCluster("k8s", "1.20")
+	if err != nil {
+		t.Fatalf("UpgradeCluster: %v", err)
+	}
+	if resource.Details["version"] != "1.20" {
+		t.Errorf("expected the control plane on 1.20, got %v", resource.Details)
+	}
+	if version := aws.StringValue(f.eks.nodegroups["k8s-nodegroup"].Version); version != "1.20" {
+		t.Errorf("expected the node group on 1.20, got %s", version)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	p, _ := newTestProvider(t, provider.Options{})
+
+	// only the newest image of each series is listed
+	images, err := p.Catalog(provider.CatalogImages)
+	if err != nil {
+		t.Fatalf("Catalog(images): %v", err)
+	}
+	if len(images) != 2 || images[0].Slug != "ami-amzn" || images[1].Slug != "ami-new" || images[1].Distro != "Ubuntu 20.04" {
+		t.Errorf("unexpected images %+v", images)
+	}
+
+	sizes, err := p.Catalog(provider.CatalogSizes)
+	if err != nil {
+		t.Fatalf("Catalog(sizes): %v", err)
+	}
+	if len(sizes) != 2 || sizes[0].Slug != "m5.large" || sizes[0].MemoryMB != 8192 {
+		t.Errorf("expected sizes from every page sorted by slug, got %+v", sizes)
+	}
+
+	versions, err := p.Catalog(provider.CatalogVersions)
+	if err != nil {
+		t.Fatalf("Catalog(versions): %v", err)
+	}
+	if len(versions) != 3 || versions[0].Slug != "1.19" || versions[2].Slug != "1.9" {
+		t.Errorf("expected the distinct versions newest first, got %+v", versions)
+	}
+}
+
+func TestDryRunSendsNothing(t *testing.T) {
+	var plan bytes.Buffer
+	p, f := newTestProvider(t, provider.Options{DryRun: true, Plan: &plan})
+
+	if _, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "t2.micro", Image: "ami-123"}); err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	resource, err := p.CreateCluster(provider.ClusterOptions{Name: "k8s", NodeSize: "t3.medium", NodeCount: 2, Subnets: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("CreateCluster: %v", err)
+	}
+	if resource.Status != provider.StatusPlanned {
+		t.Errorf("expected a planned resource, got %q", resource.Status)
+	}
+	if _, err := p.CreateBucket("assets"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if _, err := p.CreateDB(provider.DBOptions{Name: "pg", Size: "db.t3.micro"}); err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+
+	if len(f.ec2.launched) != 0 || len(f.eks.clusters) != 0 || len(f.iam.roles) != 0 || len(f.s3.buckets) != 0 || len(f.rds.instances) != 0 {
+		t.Errorf("dry run created objects")
+	}
+	for _, call := range []string{"# EC2.RunInstances", "# IAM.CreateRole", "# EKS.CreateCluster", "# EKS.CreateNodegroup", "# S3.CreateBucket", "# RDS.CreateDBInstance"} {
+		if !strings.Contains(plan.String(), call) {
+			t.Errorf("plan is missing %s:\n%s", call, plan.String())
+		}
+	}
+}
+
+func TestDryRunKeepsStdoutForThePlan(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	p, _ := newTestProvider(t, provider.Options{DryRun: true})
+	// resolving the AMI and picking the key pair report what they chose
+	_, err = p.CreateVM(provider.VMOptions{Name: "web", Size: "t3.micro", Image: "099720109477/ubuntu/images/hvm-ssd/ubuntu-focal-20.04-amd64-server-*", SSHKey: "laptop"})
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	out, _ := ioutil.ReadAll(r)
+	if !strings.HasPrefix(string(out), "# EC2.RunInstances") || strings.Contains(string(out), "Using") {
+		t.Errorf("stdout should only hold the plan, got:\n%s", out)
+	}
+}
+
+func TestBucketLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+
+	if _, err := p.CreateBucket("assets"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	f.s3.buckets["assets"] = []string{"a.txt"}
+
+	resource, err := p.GetBucket("assets")
+	if err != nil {
+		t.Fatalf("GetBucket: %v", err)
+	}
+	if resource.Region != "us-east-2" || resource.Details["object_count"] != "1" {
+		t.Errorf("unexpected bucket summary %+v", resource)
+	}
+	list, err := p.ListBuckets()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListBuckets returned %v, %v", list, err)
+	}
+
+	// a bucket holding objects is kept unless deleting them is confirmed
+	p.opts.In = strings.NewReader("n\n")
+	if err := p.DeleteBucket("assets"); errs.ClassOf(err) != errs.Usage || len(f.s3.buckets["assets"]) != 1 {
+		t.Errorf("expected a usage error keeping the bucket, got %v", err)
+	}
+	p.opts.Yes = true
+	if err := p.DeleteBucket("assets"); err != nil {
+		t.Fatalf("DeleteBucket: %v", err)
+	}
+	if len(f.s3.buckets) != 0 {
+		t.Errorf("bucket wasn't deleted")
+	}
+
+	// an empty bucket is deleted without asking about its objects
+	p.opts = provider.Options{In: strings.NewReader("")}
+	f.s3.buckets["assets"] = nil
+	if err := p.DeleteBucket("assets"); err != nil {
+		t.Fatalf("DeleteBucket: %v", err)
+	}
+	if len(f.s3.buckets) != 0 {
+		t.Errorf("bucket wasn't deleted")
+	}
+}
+
+func TestDBLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+
+	resource, err := p.CreateDB(provider.DBOptions{Name: "pg", Size: "db.t3.micro"})
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	if resource.Status != "available" {
+		t.Errorf("expected the create to wait until available, got %q", resource.Status)
+	}
+	if err := p.DeleteDB("pg"); err != nil {
+		t.Fatalf("DeleteDB: %v", err)
+	}
+	if len(f.rds.instances) != 0 {
+		t.Errorf("database wasn't deleted")
+	}
+}
+
+func TestResourcesFromSparseObjects(t *testing.T) {
+	// the API leaves out nested objects while they are being provisioned
+	Ec2Resource(&ec2.Instance{})
+	EksClusterResource(&eks.Cluster{}, "")
+	RdsResource(&rds.DBInstance{})
+	S3BucketResource(&s3.Bucket{}, "")
+	if name := InstanceName(&ec2.Instance{}); name != "" {
+		t.Errorf("expected no name for an untagged instance, got %q", name)
+	}
+}
@@ -2,17 +2,20 @@ package aws
 
 import (
 	"fmt"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
 	"github.com/pkg/errors"
 )
 
-// CreateRdsInstance creates a Postgres RDS instance in AWS
-func CreateRdsInstance(sess *session.Session, name, size string) error {
-	svc := rds.New(sess)
-	input := &rds.CreateDBInstanceInput{
+// RdsCreateInput builds the request to create a Postgres RDS instance
+func RdsCreateInput(name, size string) *rds.CreateDBInstanceInput {
+	return &rds.CreateDBInstanceInput{
 		AllocatedStorage:     aws.Int64(5),
 		DBInstanceClass:      aws.String(size),
 		DBInstanceIdentifier: aws.String(name),
@@ -20,66 +23,108 @@ func CreateRdsInstance(sess *session.Session, name, size string) error {
 		MasterUserPassword:   aws.String("rdsadmin"),
 		MasterUsername:       aws.String("rdsadmintemp"),
 	}
+}
 
-	_, err := svc.CreateDBInstance(input)
+// CreateRdsInstance creates a Postgres RDS instance in AWS
+func CreateRdsInstance(svc rdsiface.RDSAPI, input *rds.CreateDBInstanceInput) (*rds.DBInstance, error) {
+	name := aws.StringValue(input.DBInstanceIdentifier)
+	result, err := svc.CreateDBInstance(input)
 	if err != nil {
-		return errors.Errorf("Failed to create database %s:", name, err)
+		return nil, errors.Wrapf(err, "Failed to create database %s", name)
 	}
-	fmt.Println("Database", name, "creating")
-	return nil
+	fmt.Fprintln(os.Stderr, "Database", name, "creating")
+	return result.DBInstance, nil
 }
 
-// DeleteRdsInstance deletes a Postgres RDS instance in AWS
-func DeleteRdsInstance(sess *session.Session, name string) error {
-	svc := rds.New(sess)
-	input := &rds.DeleteDBInstanceInput{
+// RdsDeleteInput builds the request to delete an RDS instance without a final snapshot
+func RdsDeleteInput(name string) *rds.DeleteDBInstanceInput {
+	return &rds.DeleteDBInstanceInput{
 		DBInstanceIdentifier: aws.String(name),
 		SkipFinalSnapshot:    aws.Bool(true),
 	}
+}
 
-	_, err := svc.DeleteDBInstance(input)
+// DeleteRdsInstance deletes a Postgres RDS instance in AWS
+func DeleteRdsInstance(svc rdsiface.RDSAPI, name string) error {
+	_, err := svc.DeleteDBInstance(RdsDeleteInput(name))
 	if err != nil {
-		return errors.Errorf("Failed to create database %s:", name, err)
+		return errors.Wrapf(err, "Failed to delete database %s", name)
 	}
-	fmt.Println("Database", name, "is being deleted")
+	fmt.Fprintln(os.Stderr, "Database", name, "is being deleted")
 	return nil
 }
 
-// PrintRdsStatus prints the status of a RDS DB instance
-func PrintRdsStatus(sess *session.Session, name string) error {
-	svc := rds.New(sess)
+// ListRdsInstances fetches all RDS DB instances in the provider region
+func ListRdsInstances(svc rdsiface.RDSAPI) ([]*rds.DBInstance, error) {
+	var instances []*rds.DBInstance
+	err := svc.DescribeDBInstancesPages(&rds.DescribeDBInstancesInput{},
+		func(page *rds.DescribeDBInstancesOutput, lastPage bool) bool {
+			instances = append(instances, page.DBInstances...)
+			return true
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list databases")
+	}
+	return instances, nil
+}
+
+// GetRdsStatus fetches a RDS DB instance and summarizes it
+func GetRdsStatus(svc rdsiface.RDSAPI, name string) (*provider.Resource, error) {
 	input := &rds.DescribeDBInstancesInput{
 		DBInstanceIdentifier: aws.String(name),
 	}
 
 	result, err := svc.DescribeDBInstances(input)
 	if err != nil {
-		return errors.Errorf("Failed to create database %s:", name, err)
+		return nil, errors.Wrapf(err, "Failed to fetch database %s", name)
 	}
-	if *result.DBInstances[0].DBInstanceStatus == "creating" {
-		fmt.Printf("Name: %s\nARN: %s\nAZ: %s\nSize: %s\n\nDB Engine: %s\nDB Version: %s\n\nStatus: %s\n",
-			*result.DBInstances[0].DBInstanceIdentifier,
-			*result.DBInstances[0].DBInstanceArn,
-			*result.DBInstances[0].AvailabilityZone,
-			*result.DBInstances[0].DBInstanceClass,
-			*result.DBInstances[0].Engine,
-			*result.DBInstances[0].EngineVersion,
-			*result.DBInstances[0].DBInstanceStatus,
-		)
-		fmt.Println("Endpoint info and credentials available once created")
-	} else {
-		fmt.Printf("Name: %s\nARN: %s\nAZ: %s\nSize: %s\n\nDB Username: %s\n\nEndpoint: %s\nDB Engine: %s\nDB Version: %s\n\nCreated: %s\nStatus: %s\n",
-			*result.DBInstances[0].DBInstanceIdentifier,
-			*result.DBInstances[0].DBInstanceArn,
-			*result.DBInstances[0].AvailabilityZone,
-			*result.DBInstances[0].DBInstanceClass,
-			*result.DBInstances[0].MasterUsername,
-			*result.DBInstances[0].Endpoint.Address,
-			*result.DBInstances[0].Engine,
-			*result.DBInstances[0].EngineVersion,
-			*result.DBInstances[0].InstanceCreateTime,
-			*result.DBInstances[0].DBInstanceStatus,
-		)
+	if len(result.DBInstances) < 1 {
+		return nil, errs.Errorf(errs.NotFound, "Could not find database with name %s", name)
 	}
-	return nil
+	resource := RdsResource(result.DBInstances[0])
+	return &resource, nil
+}
+
+// RdsState reports the status of a DB instance for waiting on, or gone once it is deleted
+func RdsState(svc rdsiface.RDSAPI, name string) waiter.Condition {
+	return func() (string, error) {
+		result, err := svc.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+			DBInstanceIdentifier: aws.String(name),
+		})
+		if awsErrorCode(err) == rds.ErrCodeDBInstanceNotFoundFault {
+			return waiter.StateGone, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if len(result.DBInstances) < 1 {
+			return waiter.StateGone, nil
+		}
+		return aws.StringValue(result.DBInstances[0].DBInstanceStatus), nil
+	}
+}
+
+// RdsResource converts a RDS DB instance into the common resource summary
+func RdsResource(instance *rds.DBInstance) provider.Resource {
+	resource := provider.Resource{
+		Name:     aws.StringValue(instance.DBInstanceIdentifier),
+		ID:       aws.StringValue(instance.DbiResourceId),
+		Provider: "aws",
+		Region:   aws.StringValue(instance.AvailabilityZone),
+		Size:     aws.StringValue(instance.DBInstanceClass),
+		Status:   aws.StringValue(instance.DBInstanceStatus),
+		Created:  aws.TimeValue(instance.InstanceCreateTime),
+		Details: map[string]string{
+			"arn":      aws.StringValue(instance.DBInstanceArn),
+			"engine":   aws.StringValue(instance.Engine),
+			"version":  aws.StringValue(instance.EngineVersion),
+			"username": aws.StringValue(instance.MasterUsername),
+		},
+	}
+	// the endpoint isn't available until the instance finishes creating
+	if instance.Endpoint != nil {
+		resource.Endpoint = fmt.Sprintf("%s:%d",
+			aws.StringValue(instance.Endpoint.Address), aws.Int64Value(instance.Endpoint.Port))
+	}
+	return resource
 }
@@ -2,114 +2,150 @@ package aws
 
 import (
 	"fmt"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"os"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/pkg/errors"
 )
 
-// CreateS3Client creates a session to connect to AWS
-func CreateS3Client(credentialsFile, defaultRegion string) (*s3.S3, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(defaultRegion),
-		Credentials: credentials.NewSharedCredentials(credentialsFile, "default")},
-	)
-	if err != nil {
-		return nil, errors.Errorf("Failed to create session:", err)
+// S3CreateInput builds the request to create an S3 bucket
+func S3CreateInput(name string) *s3.CreateBucketInput {
+	return &s3.CreateBucketInput{
+		Bucket: aws.String(name),
 	}
-	s3Client := s3.New(sess)
-	return s3Client, nil
 }
 
 // CreateS3Bucket creats an S3 bucket on AWS
-func CreateS3Bucket(client *s3.S3, name string) error {
-	params := &s3.CreateBucketInput{
-		Bucket: aws.String(name),
-	}
-
+func CreateS3Bucket(client s3iface.S3API, params *s3.CreateBucketInput) error {
+	name := aws.StringValue(params.Bucket)
 	_, err := client.CreateBucket(params)
 	if err != nil {
-		return errors.Errorf("Failed to create Bucket:", err)
+		return errors.Wrap(err, "Failed to create Bucket")
 	}
-	fmt.Println("Bucket", name, "created")
+	fmt.Fprintln(os.Stderr, "Bucket", name, "created")
 	return nil
 }
 
-// GetS3BucketInfo may or may not get info about a space...
-func GetS3BucketInfo(client *s3.S3, name string) error {
-	spaces, err := client.ListBuckets(nil)
+// ListS3Buckets fetches all buckets owned by the account
+func ListS3Buckets(client s3iface.S3API) ([]*s3.Bucket, error) {
+	buckets, err := client.ListBuckets(nil)
 	if err != nil {
-		return errors.Errorf("Failed to list buckets:", err)
+		return nil, errors.Wrap(err, "Failed to list buckets")
+	}
+	return buckets.Buckets, nil
+}
+
+// GetS3BucketRegion returns the region a bucket lives in
+func GetS3BucketRegion(client s3iface.S3API, name string) (string, error) {
+	result, err := client.GetBucketLocation(&s3.GetBucketLocationInput{
+		Bucket: aws.String(name),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get location of bucket %s", name)
+	}
+	// buckets in us-east-1 have no location constraint
+	if result.LocationConstraint == nil {
+		return "us-east-1", nil
+	}
+	return aws.StringValue(result.LocationConstraint), nil
+}
+
+// GetS3BucketInfo fetches a bucket and summarizes it along with its contents
+func GetS3BucketInfo(client s3iface.S3API, name string) (*provider.Resource, error) {
+	buckets, err := ListS3Buckets(client)
+	if err != nil {
+		return nil, err
 	}
 
 	input := &s3.ListObjectsInput{Bucket: aws.String(name)}
 
 	objects, err := client.ListObjects(input)
 	if err != nil {
-		return errors.Errorf("Failed to fetch objects in bucket:", err)
+		return nil, errors.Wrap(err, "Failed to fetch objects in bucket")
 	}
 
-	for _, bucket := range spaces.Buckets {
+	for _, bucket := range buckets {
 		if aws.StringValue(bucket.Name) == name {
-			fmt.Printf("Name: %s\nCreation Date: %s\n\n", aws.StringValue(bucket.Name), bucket.CreationDate.Format(time.UnixDate))
+			region, _ := GetS3BucketRegion(client, name)
+			resource := S3BucketResource(bucket, region)
+			var keys []string
+			for _, obj := range objects.Contents {
+				keys = append(keys, aws.StringValue(obj.Key))
+			}
+			resource.Details = map[string]string{
+				"object_count": strconv.Itoa(len(keys)),
+				"objects":      strings.Join(keys, ","),
+			}
+			return &resource, nil
 		}
 	}
-	fmt.Println("Bucket contents:")
-	for _, obj := range objects.Contents {
-		fmt.Printf(" - %s\n", aws.StringValue(obj.Key))
-	}
-	return nil
+	return nil, errs.Errorf(errs.NotFound, "Could not find bucket with name %s", name)
 }
 
-// DeleteS3Objects removes all objects in a bucket to prep for deletion
-func DeleteS3Objects(client *s3.S3, name string) error {
-	// confirm that deleteing space will delete all files first
-	var confirmation string
-	fmt.Printf("\nWARNING: To delete an S3 bucket, all objects in that bucket must be deleted!\n")
-	fmt.Print("Do you wish to continue? (Y/n): ")
-	fmt.Scanln(&confirmation)
-	confirmation = strings.ToLower(string(confirmation))
-	println()
+// S3BucketResource converts an S3 bucket into the common resource summary
+func S3BucketResource(bucket *s3.Bucket, region string) provider.Resource {
+	name := aws.StringValue(bucket.Name)
+	resource := provider.Resource{
+		Name:     name,
+		ID:       name,
+		Provider: "aws",
+		Region:   region,
+		Created:  aws.TimeValue(bucket.CreationDate),
+	}
+	if region != "" {
+		resource.Endpoint = "https://" + name + ".s3." + region + ".amazonaws.com"
+	}
+	return resource
+}
 
-	if confirmation != "y" {
-		return errors.Errorf("Cannot proceed -- must delete files before deleting bucket")
+// DeleteS3Objects removes the objects of a bucket to prep for deletion
+func DeleteS3Objects(client s3iface.S3API, name string, inputs []*s3.DeleteObjectInput) error {
+	for _, input := range inputs {
+		_, err := client.DeleteObject(input)
+		if err != nil {
+			return errors.Wrap(err, "Failed to remove objects in bucket")
+		}
 	}
-	// loop through all objects in bucket and delete first
+	fmt.Fprintln(os.Stderr, "All objects from", name, "deleted")
+	return nil
+}
+
+// S3ObjectDeleteInputs builds the requests to delete every object in a bucket
+func S3ObjectDeleteInputs(client s3iface.S3API, name string) ([]*s3.DeleteObjectInput, error) {
 	listInput := &s3.ListObjectsInput{Bucket: aws.String(name)}
 	objects, err := client.ListObjects(listInput)
 	if err != nil {
-		return errors.Errorf("Failed to fetch objects in bucket:", err)
+		return nil, errors.Wrap(err, "Failed to fetch objects in bucket")
 	}
-
+	var inputs []*s3.DeleteObjectInput
 	for _, obj := range objects.Contents {
-		input := &s3.DeleteObjectInput{
+		inputs = append(inputs, &s3.DeleteObjectInput{
 			Bucket: aws.String(name),
 			Key:    aws.String(aws.StringValue(obj.Key)),
-		}
-
-		_, err := client.DeleteObject(input)
-		if err != nil {
-			return errors.Errorf("Failed to remove objects in bucket:", err)
-		}
+		})
 	}
-	fmt.Println("All objects from", name, "deleted")
-	return nil
+	return inputs, nil
 }
 
-// DeleteS3Bucket deletes an S3 bucket on AWS
-func DeleteS3Bucket(client *s3.S3, name string) error {
-	deleteInput := &s3.DeleteBucketInput{
+// S3DeleteInput builds the request to delete an S3 bucket
+func S3DeleteInput(name string) *s3.DeleteBucketInput {
+	return &s3.DeleteBucketInput{
 		Bucket: aws.String(name),
 	}
+}
 
-	_, err := client.DeleteBucket(deleteInput)
+// DeleteS3Bucket deletes an S3 bucket on AWS
+func DeleteS3Bucket(client s3iface.S3API, name string) error {
+	_, err := client.DeleteBucket(S3DeleteInput(name))
 	if err != nil {
-		return errors.Errorf("Failed to delete bucket:", err)
+		return errors.Wrap(err, "Failed to delete bucket")
 	}
-	fmt.Println("Bucket", name, "deleted")
+	fmt.Fprintln(os.Stderr, "Bucket", name, "deleted")
 	return nil
 }
@@ -0,0 +1,60 @@
+package aws
+
+import (
+	"fmt"
+	"maker/internal/provider"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+)
+
+// ListEc2KeyPairs fetches the key pairs of the region
+func ListEc2KeyPairs(svc ec2iface.EC2API) ([]*ec2.KeyPairInfo, error) {
+	result, err := svc.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to check keypairs")
+	}
+	return result.KeyPairs, nil
+}
+
+// Ec2ImportKeyPairInput builds the request to upload a public key as a key pair
+func Ec2ImportKeyPairInput(key provider.PublicKey) *ec2.ImportKeyPairInput {
+	return &ec2.ImportKeyPairInput{
+		KeyName:           aws.String(key.Name),
+		PublicKeyMaterial: []byte(key.Key),
+	}
+}
+
+// ImportEc2KeyPair uploads a public key as a key pair of the region
+func ImportEc2KeyPair(svc ec2iface.EC2API, input *ec2.ImportKeyPairInput) (*ec2.KeyPairInfo, error) {
+	result, err := svc.ImportKeyPair(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to import key pair")
+	}
+	fmt.Fprintln(os.Stderr, "Key pair", aws.StringValue(result.KeyName), "imported")
+	return &ec2.KeyPairInfo{KeyName: result.KeyName, KeyPairId: result.KeyPairId, KeyFingerprint: result.KeyFingerprint}, nil
+}
+
+// DeleteEc2KeyPair removes a key pair, instances launched with it keep it
+func DeleteEc2KeyPair(svc ec2iface.EC2API, name string) error {
+	_, err := svc.DeleteKeyPair(&ec2.DeleteKeyPairInput{KeyName: aws.String(name)})
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete key pair")
+	}
+	fmt.Fprintln(os.Stderr, "Key pair", name, "deleted")
+	return nil
+}
+
+// KeyPairSummary converts a key pair into the common SSH key summary.
+// EC2 doesn't return the public key, so local keys are matched by name.
+func KeyPairSummary(info *ec2.KeyPairInfo) provider.SSHKey {
+	return provider.SSHKey{
+		Provider:    "aws",
+		Name:        aws.StringValue(info.KeyName),
+		ID:          aws.StringValue(info.KeyPairId),
+		Fingerprint: aws.StringValue(info.KeyFingerprint),
+	}
+}
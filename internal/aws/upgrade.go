@@ -0,0 +1,81 @@
+package aws
+
+import (
+	"fmt"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/pkg/errors"
+)
+
+// EksClusterVersions finds the version an EKS cluster can be upgraded to, EKS only
+// moves a control plane forward one minor version at a time
+func EksClusterVersions(cluster *eks.Cluster) *provider.ClusterVersions {
+	versions := &provider.ClusterVersions{
+		Provider:  "aws",
+		Cluster:   aws.StringValue(cluster.Name),
+		Current:   aws.StringValue(cluster.Version),
+		Available: []string{},
+	}
+	if next := provider.NextMinorVersion(versions.Current); next != "" {
+		versions.Available = append(versions.Available, next)
+	}
+	return versions
+}
+
+// EksClusterVersionInput builds the request to upgrade the control plane of a cluster. The SDK
+// fills in a fresh request token, so retrying after a failed upgrade starts a new one.
+func EksClusterVersionInput(name, version string) *eks.UpdateClusterVersionInput {
+	return &eks.UpdateClusterVersionInput{
+		Name:    aws.String(name),
+		Version: aws.String(version),
+	}
+}
+
+// EksNodegroupVersionInput builds the request to upgrade a node group to the version of its control plane
+func EksNodegroupVersionInput(clusterName, nodeGroupName, version string) *eks.UpdateNodegroupVersionInput {
+	return &eks.UpdateNodegroupVersionInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodeGroupName),
+		Version:       aws.String(version),
+	}
+}
+
+// UpdateEksClusterVersion starts the upgrade of a control plane and returns the ID of the update
+func UpdateEksClusterVersion(svc eksiface.EKSAPI, input *eks.UpdateClusterVersionInput) (string, error) {
+	result, err := svc.UpdateClusterVersion(input)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to upgrade cluster")
+	}
+	fmt.Fprintln(os.Stderr, "Cluster", aws.StringValue(input.Name), "upgrading to", aws.StringValue(input.Version))
+	return aws.StringValue(result.Update.Id), nil
+}
+
+// UpdateEksNodegroupVersion starts the upgrade of a node group and returns the ID of the update
+func UpdateEksNodegroupVersion(svc eksiface.EKSAPI, input *eks.UpdateNodegroupVersionInput) (string, error) {
+	result, err := svc.UpdateNodegroupVersion(input)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to upgrade node group")
+	}
+	fmt.Fprintln(os.Stderr, "Node group", aws.StringValue(input.NodegroupName), "upgrading to", aws.StringValue(input.Version))
+	return aws.StringValue(result.Update.Id), nil
+}
+
+// EksUpdateState reports the status of an update to a cluster, or to one of its node groups when nodeGroupName is set
+func EksUpdateState(svc eksiface.EKSAPI, clusterName, nodeGroupName, id string) waiter.Condition {
+	return func() (string, error) {
+		input := &eks.DescribeUpdateInput{Name: aws.String(clusterName), UpdateId: aws.String(id)}
+		if nodeGroupName != "" {
+			input.NodegroupName = aws.String(nodeGroupName)
+		}
+		result, err := svc.DescribeUpdate(input)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to fetch update status")
+		}
+		return aws.StringValue(result.Update.Status), nil
+	}
+}
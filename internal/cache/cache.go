@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FolderName is the folder under .maker holding a file per cached response
+var FolderName = "cache"
+
+// entry is the layout of a cache file, the data is kept raw until it is known to be fresh
+type entry struct {
+	Written time.Time       `json:"written"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Path returns the file a key is cached in
+func Path(key string) string {
+	return filepath.Join(utils.ConfigFolderPath, FolderName, key+".json")
+}
+
+// Load reads the value cached under key into v, reporting whether one was written less
+// than ttl ago. Missing, stale and unreadable files all count as a miss.
+func Load(key string, ttl time.Duration, v interface{}) bool {
+	data, err := ioutil.ReadFile(Path(key))
+	if err != nil {
+		return false
+	}
+	var cached entry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return false
+	}
+	if time.Since(cached.Written) > ttl {
+		return false
+	}
+	return json.Unmarshal(cached.Data, v) == nil
+}
+
+// Save caches v under key
+func Save(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal cache entry")
+	}
+	data, err = json.Marshal(entry{Written: time.Now().UTC(), Data: data})
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal cache entry")
+	}
+	dir := filepath.Dir(Path(key))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "Failed to create cache directory %s", dir)
+	}
+	return errors.Wrapf(ioutil.WriteFile(Path(key), data, 0600), "Failed to write cache file %s", Path(key))
+}
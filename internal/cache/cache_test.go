@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"io/ioutil"
+	"maker/internal/utils"
+	"testing"
+	"time"
+)
+
+func useTempFolder(t *testing.T) {
+	old := utils.ConfigFolderPath
+	utils.ConfigFolderPath = t.TempDir()
+	t.Cleanup(func() {
+		utils.ConfigFolderPath = old
+	})
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	useTempFolder(t)
+	var got []string
+	if Load("sizes", time.Hour, &got) {
+		t.Fatal("expected a miss before anything is saved")
+	}
+	if err := Save("sizes", []string{"s-1vcpu-1gb", "s-2vcpu-2gb"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if !Load("sizes", time.Hour, &got) || len(got) != 2 || got[1] != "s-2vcpu-2gb" {
+		t.Errorf("Load = %v, want the saved sizes", got)
+	}
+	// a zero TTL treats every entry as stale
+	if Load("sizes", 0, &got) {
+		t.Error("expected a stale entry to miss")
+	}
+}
+
+func TestLoadCorruptFile(t *testing.T) {
+	useTempFolder(t)
+	if err := Save("images", []string{"debian"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(Path("images"), []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	if Load("images", time.Hour, &got) {
+		t.Errorf("expected a corrupt file to miss, got %v", got)
+	}
+}
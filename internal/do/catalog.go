@@ -0,0 +1,115 @@
+package do
+
+import (
+	"context"
+	"maker/internal/provider"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+)
+
+// catalogPageSize is the most items the API returns in one page
+const catalogPageSize = 200
+
+// listPages calls list with each page in turn until the API reports the last one
+func listPages(list func(opt *godo.ListOptions) (*godo.Response, error)) error {
+	opt := &godo.ListOptions{Page: 1, PerPage: catalogPageSize}
+	for {
+		resp, err := list(opt)
+		if err != nil {
+			return err
+		}
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			return nil
+		}
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return errors.Wrap(err, "Failed to read the page number")
+		}
+		opt.Page = page + 1
+	}
+}
+
+// ListDoSizes fetches the droplet sizes that can currently be created
+func ListDoSizes(sizesService godo.SizesService) ([]provider.CatalogEntry, error) {
+	var sizes []godo.Size
+	err := listPages(func(opt *godo.ListOptions) (*godo.Response, error) {
+		page, resp, err := sizesService.List(context.TODO(), opt)
+		sizes = append(sizes, page...)
+		return resp, err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not list sizes")
+	}
+	var entries []provider.CatalogEntry
+	for _, size := range sizes {
+		if !size.Available {
+			continue
+		}
+		entries = append(entries, provider.CatalogEntry{
+			Slug:        size.Slug,
+			Description: size.Description,
+			VCPUs:       size.Vcpus,
+			MemoryMB:    size.Memory,
+		})
+	}
+	return entries, nil
+}
+
+// ListDoImages fetches the public distribution images, the only ones with slugs
+func ListDoImages(imagesService godo.ImagesService) ([]provider.CatalogEntry, error) {
+	var images []godo.Image
+	err := listPages(func(opt *godo.ListOptions) (*godo.Response, error) {
+		page, resp, err := imagesService.ListDistribution(context.TODO(), opt)
+		images = append(images, page...)
+		return resp, err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not list images")
+	}
+	var entries []provider.CatalogEntry
+	for _, image := range images {
+		if image.Slug == "" {
+			continue
+		}
+		entries = append(entries, provider.CatalogEntry{
+			Slug:        image.Slug,
+			Description: image.Distribution + " " + image.Name,
+			Distro:      image.Distribution,
+		})
+	}
+	return entries, nil
+}
+
+// ListDoRegions fetches the regions new objects can be created in
+func ListDoRegions(regionsService godo.RegionsService) ([]provider.CatalogEntry, error) {
+	var regions []godo.Region
+	err := listPages(func(opt *godo.ListOptions) (*godo.Response, error) {
+		page, resp, err := regionsService.List(context.TODO(), opt)
+		regions = append(regions, page...)
+		return resp, err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not list regions")
+	}
+	var entries []provider.CatalogEntry
+	for _, region := range regions {
+		if region.Available {
+			entries = append(entries, provider.CatalogEntry{Slug: region.Slug, Description: region.Name})
+		}
+	}
+	return entries, nil
+}
+
+// ListDoKubernetesVersions fetches the versions new DOKS clusters can run
+func ListDoKubernetesVersions(kubernetesService godo.KubernetesService) ([]provider.CatalogEntry, error) {
+	options, _, err := kubernetesService.GetOptions(context.TODO())
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not fetch Kubernetes options")
+	}
+	var entries []provider.CatalogEntry
+	for _, version := range options.Versions {
+		entries = append(entries, provider.CatalogEntry{Slug: version.Slug, Description: "Kubernetes " + version.KubernetesVersion})
+	}
+	return entries, nil
+}
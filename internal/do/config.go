@@ -2,15 +2,12 @@ package do
 
 import (
 	"fmt"
-	"io/ioutil"
-	"maker/internal/utils"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"maker/internal/secrets"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/pkg/errors"
-	"github.com/spf13/viper"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 // ConfigFile makes up the required settings in a DO config file
@@ -25,83 +22,121 @@ type ConfigFile struct {
 // ConfigName is the name of the config file used by Maker
 var ConfigName = "do_config"
 
-// ConfigPath is the full path to the ConfigFile
-var ConfigPath = filepath.Join(utils.ConfigFolderPath, ConfigName)
+// ConfigPath returns the full path to the config file of the active profile
+func ConfigPath() string {
+	return profile.Path(ConfigName)
+}
+
+// configSchema lists the settings in the config file, the PAT token is only needed for
+// droplets, clusters and databases so a Spaces only config is valid
+var configSchema = provider.ConfigSchema{
+	Path: ConfigPath,
+	Type: "yaml",
+	Keys: []string{"pat_token", "default_region", "spaces_access_key", "spaces_secret_key", "spaces_endpoint_region"},
+}
+
+// TokenEnvs are the environment variables checked for a PAT token, the same ones doctl uses
+var TokenEnvs = []string{"DIGITALOCEAN_TOKEN", "DIGITALOCEAN_ACCESS_TOKEN"}
+
+// SpacesKeyEnvs and SpacesSecretEnvs are the environment variables checked for Spaces keys
+var (
+	SpacesKeyEnvs    = []string{"SPACES_ACCESS_KEY_ID"}
+	SpacesSecretEnvs = []string{"SPACES_SECRET_ACCESS_KEY"}
+)
 
 // SetupConfig setups config directory and file
-func SetupConfig() error {
-	// check that .maker exists
-	_, err := os.Stat(utils.ConfigFolderPath)
-	if os.IsNotExist(err) {
-		err := os.Mkdir(utils.ConfigFolderPath, 0755)
-		if err != nil {
-			return errors.Wrapf(err, "Failed to create config directory %s:", utils.ConfigFolder)
-		}
+func SetupConfig(opts provider.AuthOptions) error {
+	// check that the profile folder exists
+	if _, err := profile.EnsureDir(); err != nil {
+		return err
+	}
+
+	// everything needed came from flags and the environment, nothing to ask
+	if opts.NonInteractive || (opts.Token(TokenEnvs...) != "" && opts.Region != "") {
+		return ConfigureFromEnv(opts)
 	}
 
 	// check if config exists to create, or to verify
-	_, err = os.Stat(ConfigPath)
+	_, err := os.Stat(ConfigPath())
 	if os.IsNotExist(err) {
-		Configure()
+		if err := Configure(opts); err != nil {
+			return err
+		}
+	}
+	return ConfirmCurrentConfig(opts)
+}
+
+// ConfigureFromEnv writes the config file from flags and environment variables without prompting.
+// The Spaces keys are only updated when they are set in the environment.
+func ConfigureFromEnv(opts provider.AuthOptions) error {
+	config, err := currentConfig()
+	if err != nil {
+		return err
+	}
+	opts.NonInteractive = true
+	if err := askDropletConfig(config, opts); err != nil {
+		return err
 	}
-	ConfirmCurrentConfig()
+	if provider.Env(SpacesKeyEnvs...) != "" {
+		if err := askSpacesConfig(config, opts); err != nil {
+			return err
+		}
+	}
+	if err := SaveConfig(config); err != nil {
+		return errors.Wrapf(err, "Failed to create config file %s", ConfigName)
+	}
+	fmt.Println("Config file generated at", ConfigPath())
 	return nil
 }
 
 // Configure sets the PAT token and default Region for Digital Ocean
-func Configure() error {
-	config := &ConfigFile{}
-	task := GetConfigTasks()
-
-	_, err := os.Stat(ConfigPath)
-	fmt.Println(err)
-	// file exists, err is nil -- load it to update first
-	if err == nil {
-		config, err = LoadConfig()
-		if err != nil {
-			return errors.Wrapf(err, "Failed to load current config")
-		}
+func Configure(opts provider.AuthOptions) error {
+	config, err := currentConfig()
+	if err != nil {
+		return err
 	}
 
-	switch task {
+	switch GetConfigTasks(opts) {
 	case "1":
-		err := CreateDropletConfigFile(config)
-		if err != nil {
-			return errors.Wrapf(err, "Failed to create config file %s:", ConfigName)
-		}
+		err = askDropletConfig(config, opts)
 	case "2":
-		err := CreateSpacesConfigFile(config)
-		if err != nil {
-			return errors.Wrapf(err, "Failed to create config file %s:", ConfigName)
-		}
+		err = askSpacesConfig(config, opts)
 	default:
-		err := CreateDropletConfigFile(config)
-		if err != nil {
-			return errors.Wrapf(err, "Failed to create config file %s:", ConfigName)
-		}
-		err = CreateSpacesConfigFile(config)
-		if err != nil {
-			return errors.Wrapf(err, "Failed to create config file %s:", ConfigName)
+		err = askDropletConfig(config, opts)
+		if err == nil {
+			err = askSpacesConfig(config, opts)
 		}
 	}
-	fmt.Println("Config file generated at", ConfigPath)
+	if err != nil {
+		return err
+	}
+	if err := SaveConfig(config); err != nil {
+		return errors.Wrapf(err, "Failed to create config file %s", ConfigName)
+	}
+	fmt.Println("Config file generated at", ConfigPath())
 	PrintCurrentConfig()
 	return nil
 }
 
+// currentConfig loads the config file so one half of it can be updated, or returns an empty one
+func currentConfig() (*ConfigFile, error) {
+	if _, err := os.Stat(ConfigPath()); err != nil {
+		return &ConfigFile{}, nil
+	}
+	config, err := readConfig()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to load current config")
+	}
+	return config, nil
+}
+
 // ConfirmCurrentConfig prints out the current config file
-func ConfirmCurrentConfig() error {
+func ConfirmCurrentConfig(opts provider.AuthOptions) error {
 	PrintCurrentConfig()
 
 	// show config and confirm
-	var confirmation string
-	fmt.Printf("\nIs this info accurate? (Y/n): ")
-	fmt.Scanln(&confirmation)
-	confirmation = strings.ToLower(string(confirmation))
-	println()
-
-	if confirmation != "y" {
-		err := Configure()
+	if !opts.Confirm("\nIs this info accurate? (Y/n): ") {
+		err := Configure(opts)
 		if err != nil {
 			return errors.Wrap(err, "Failed to configure")
 		}
@@ -110,112 +145,129 @@ func ConfirmCurrentConfig() error {
 }
 
 // GetConfigTasks determines what configs to set
-func GetConfigTasks() string {
-	var selection string
+func GetConfigTasks(opts provider.AuthOptions) string {
 	fmt.Println("Select Configuration Option:")
 	fmt.Printf("1. Set Droplet Config\n2. Set Spaces Config\n3. Set All\n")
-	fmt.Printf("Selection?: ")
-	fmt.Scanln(&selection)
-	return string(selection)
+	selection, _ := opts.Ask("selection", "", "Selection?: ", "", false)
+	return selection
 }
 
-// PrintCurrentConfig outputs the current config file
+// PrintCurrentConfig outputs the current config file with its secrets redacted
 func PrintCurrentConfig() error {
-	data, err := ioutil.ReadFile(ConfigPath)
+	config, err := readConfig()
 	if err != nil {
-		return errors.Wrapf(err, "Failed to read file %s:", ConfigPath)
+		return err
 	}
-	fmt.Printf("\nCurrent Config:\n\n%s", string(data))
+	fmt.Printf("\nCurrent Config:\n\n")
+	fmt.Printf("pat_token: %s\n", secrets.Redact(config.PatToken))
+	fmt.Printf("default_region: %s\n", config.DefaultRegion)
+	fmt.Printf("spaces_access_key: %s\n", config.SpacesAccessKey)
+	fmt.Printf("spaces_secret_key: %s\n", secrets.Redact(config.SpacesSecretKey))
+	fmt.Printf("spaces_endpoint_region: %s\n", config.SpacesDefaultEndpoint)
 	return nil
 }
 
-// CreateDropletConfigFile creates the config file to use in all DO commands
-func CreateDropletConfigFile(config *ConfigFile) error {
-	// preserve existing configs for spaces
-	tmpSpacesAccessKey := config.SpacesAccessKey
-	tmpSpacesSecretKey := config.SpacesSecretKey
-	tmpSpacesDefaultEndpoint := config.SpacesDefaultEndpoint
+// askDropletConfig sets the PAT token and default region, prompting for whatever
+// --token-env, DIGITALOCEAN_TOKEN and --region don't give
+func askDropletConfig(config *ConfigFile, opts provider.AuthOptions) error {
+	token := opts.Token(TokenEnvs...)
+	if token == "" && !opts.NonInteractive {
+		fmt.Println("Please authenticate using your Digital Ocean account...")
+		fmt.Println("Tokens can be generated at https://cloud.digitalocean.com/account/api/tokens")
+	}
+	token, err := opts.Ask("PAT token", "--token-env or DIGITALOCEAN_TOKEN", "Enter PAT Token: ", token, true)
+	if err != nil {
+		return err
+	}
+	region, err := opts.Ask("default region", "--region", "Default Region: ", opts.Region, false)
+	if err != nil {
+		return err
+	}
+	config.PatToken = token
+	config.DefaultRegion = region
+	return nil
+}
 
-	// ask for PAT token
-	fmt.Println("Please authenticate using your Digital Ocean account...")
-	fmt.Println("Tokens can be generated at https://cloud.digitalocean.com/account/api/tokens")
-	fmt.Print("Enter PAT Token: ")
-	pass, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+// askSpacesConfig sets the Spaces keys and endpoint region, prompting for whatever
+// SPACES_ACCESS_KEY_ID, SPACES_SECRET_ACCESS_KEY and --region don't give
+func askSpacesConfig(config *ConfigFile, opts provider.AuthOptions) error {
+	accessKey := provider.Env(SpacesKeyEnvs...)
+	if accessKey == "" && !opts.NonInteractive {
+		fmt.Println("Please authenticate using your Digital Ocean account...")
+		fmt.Println("Tokens can be generated at https://cloud.digitalocean.com/account/api/tokens")
+	}
+	accessKey, err := opts.Ask("Spaces access key", "SPACES_ACCESS_KEY_ID", "Enter Spaces Access Key: ", accessKey, false)
 	if err != nil {
-		return errors.Errorf("Failed to capture password:", err)
-	}
-	config.PatToken = string(pass)
-	println()
-
-	// ask for default region
-	var region string
-	fmt.Print("Default Region: ")
-	fmt.Scanln(&region)
-	config.DefaultRegion = string(region)
-	println()
-
-	// create the config
-	viper.SetConfigType("yaml")
-	viper.Set("pat_token", config.PatToken)
-	viper.Set("default_region", config.DefaultRegion)
-	viper.Set("spaces_access_key", tmpSpacesAccessKey)
-	viper.Set("spaces_secret_key", tmpSpacesSecretKey)
-	viper.Set("spaces_endpoint_region", tmpSpacesDefaultEndpoint)
-	return viper.WriteConfigAs(ConfigPath)
-}
-
-// CreateSpacesConfigFile creates the config file to use in spaces creation
-func CreateSpacesConfigFile(config *ConfigFile) error {
-	// preserve existing config for droplets
-	tmpPatToken := config.PatToken
-	tmpRegion := config.DefaultRegion
-
-	// ask for access key
-	var accessKey string
-	fmt.Println("Please authenticate using your Digital Ocean account...")
-	fmt.Println("Tokens can be generated at https://cloud.digitalocean.com/account/api/tokens")
-	fmt.Print("Enter Spaces Access Key: ")
-	fmt.Scanln(&accessKey)
-	config.SpacesAccessKey = string(accessKey)
-
-	// ask for secret key
-	fmt.Print("Enter Spaces Secret Key: ")
-	pass, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		return err
+	}
+	secretKey, err := opts.Ask("Spaces secret key", "SPACES_SECRET_ACCESS_KEY", "Enter Spaces Secret Key: ", provider.Env(SpacesSecretEnvs...), true)
+	if err != nil {
+		return err
+	}
+	region, err := opts.Ask("Spaces endpoint region", "--region", "Default Spaces Endpoint Region (ie, nyc3): ", opts.Region, false)
 	if err != nil {
-		return errors.Errorf("Failed to secret key:", err)
+		return err
+	}
+	config.SpacesAccessKey = accessKey
+	config.SpacesSecretKey = secretKey
+	config.SpacesDefaultEndpoint = region
+	return nil
+}
+
+// SaveConfig writes the config file used in all DO commands. The PAT token and
+// Spaces secret go to the secret store and only references to them are written.
+func SaveConfig(config *ConfigFile) error {
+	token, err := saveSecret("pat_token", config.PatToken)
+	if err != nil {
+		return err
+	}
+	spacesSecret, err := saveSecret("spaces_secret_key", config.SpacesSecretKey)
+	if err != nil {
+		return err
 	}
-	config.SpacesSecretKey = string(pass)
-	println()
 
-	// ask for default region
-	var region string
-	fmt.Print("Default Spaces Endpoint Region (ie, nyc3): ")
-	fmt.Scanln(&region)
-	config.SpacesDefaultEndpoint = string(region)
-	println()
+	return configSchema.Write(map[string]string{
+		"pat_token":              token,
+		"default_region":         config.DefaultRegion,
+		"spaces_access_key":      config.SpacesAccessKey,
+		"spaces_secret_key":      spacesSecret,
+		"spaces_endpoint_region": config.SpacesDefaultEndpoint,
+	})
+}
 
-	// create the config
-	viper.SetConfigType("yaml")
-	viper.Set("pat_token", tmpPatToken)
-	viper.Set("default_region", tmpRegion)
-	viper.Set("spaces_access_key", config.SpacesAccessKey)
-	viper.Set("spaces_secret_key", config.SpacesSecretKey)
-	viper.Set("spaces_endpoint_region", config.SpacesDefaultEndpoint)
-	return viper.WriteConfigAs(ConfigPath)
+// saveSecret moves a secret to the secret store under the active profile, values that
+// are already references are kept
+func saveSecret(field, value string) (string, error) {
+	if secrets.IsRef(value) {
+		return value, nil
+	}
+	return secrets.Save(profile.Current()+"/do/"+field, value)
 }
 
-// LoadConfig parses the viper config file and loads into a struct
+// LoadConfig parses the config file and loads into a struct, fetching secrets from the secret store
 func LoadConfig() (*ConfigFile, error) {
-	viper.SetConfigFile(ConfigPath)
-	viper.SetConfigType("yml")
-	err := viper.ReadInConfig()
+	conf, err := readConfig()
 	if err != nil {
-		return nil, errors.Errorf("Error reading config file %s:", ConfigPath, err)
+		return nil, err
 	}
-	conf := &ConfigFile{}
-	err = viper.Unmarshal(conf)
+	if conf.PatToken, err = secrets.Resolve(conf.PatToken); err != nil {
+		return nil, err
+	}
+	if conf.SpacesSecretKey, err = secrets.Resolve(conf.SpacesSecretKey); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// readConfig parses the config file, leaving references to stored secrets as they are
+func readConfig() (*ConfigFile, error) {
+	v, err := configSchema.Read()
 	if err != nil {
-		return nil, errors.Errorf("Error reading config file %s:", ConfigPath, err)
+		return nil, err
+	}
+	conf := &ConfigFile{}
+	if err := v.Unmarshal(conf); err != nil {
+		return nil, errors.Wrapf(err, "Error reading config file %s", ConfigPath())
 	}
 	return conf, nil
 }
@@ -0,0 +1,98 @@
+package do
+
+import (
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"maker/internal/secrets"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTempConfig points the config file at a temporary directory
+func useTempConfig(t *testing.T) {
+	dir := t.TempDir()
+	oldFolder, oldProfile := utils.ConfigFolderPath, profile.Name
+	utils.ConfigFolderPath, profile.Name = dir, ""
+	t.Cleanup(func() {
+		utils.ConfigFolderPath, profile.Name = oldFolder, oldProfile
+	})
+	setenv(t, profile.EnvVar, "")
+	setenv(t, secrets.StoreEnv, secrets.File)
+	setenv(t, secrets.PassphraseEnv, "test")
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestSetupConfigFromEnv(t *testing.T) {
+	useTempConfig(t)
+	setenv(t, "LAB_DO_TOKEN", "secret")
+	setenv(t, "SPACES_ACCESS_KEY_ID", "key")
+	setenv(t, "SPACES_SECRET_ACCESS_KEY", "spaces-secret")
+
+	err := SetupConfig(provider.AuthOptions{TokenEnv: "LAB_DO_TOKEN", Region: "nyc3"})
+	if err != nil {
+		t.Fatalf("SetupConfig: %v", err)
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	want := ConfigFile{PatToken: "secret", DefaultRegion: "nyc3", SpacesAccessKey: "key", SpacesSecretKey: "spaces-secret", SpacesDefaultEndpoint: "nyc3"}
+	if *config != want {
+		t.Errorf("config = %+v, want %+v", *config, want)
+	}
+
+	data, _ := ioutil.ReadFile(ConfigPath())
+	if strings.Contains(string(data), "spaces-secret") || strings.Contains(string(data), ": secret") {
+		t.Errorf("secrets written to the config file:\n%s", data)
+	}
+}
+
+func TestSetupConfigProfile(t *testing.T) {
+	useTempConfig(t)
+	setenv(t, "DIGITALOCEAN_TOKEN", "team")
+	setenv(t, profile.EnvVar, "staging")
+
+	if err := SetupConfig(provider.AuthOptions{NonInteractive: true, Region: "ams3"}); err != nil {
+		t.Fatalf("SetupConfig: %v", err)
+	}
+	want := filepath.Join(utils.ConfigFolderPath, "profiles", "staging", ConfigName)
+	if ConfigPath() != want {
+		t.Errorf("ConfigPath = %s, want %s", ConfigPath(), want)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("profile config wasn't written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(utils.ConfigFolderPath, ConfigName)); !os.IsNotExist(err) {
+		t.Errorf("the default profile shouldn't be touched")
+	}
+}
+
+func TestSetupConfigNonInteractiveMissingToken(t *testing.T) {
+	useTempConfig(t)
+	setenv(t, "DIGITALOCEAN_TOKEN", "")
+	setenv(t, "DIGITALOCEAN_ACCESS_TOKEN", "")
+
+	err := SetupConfig(provider.AuthOptions{NonInteractive: true, Region: "nyc3"})
+	if errs.ClassOf(err) != errs.Usage {
+		t.Fatalf("expected a usage error, got %v", err)
+	}
+	if _, err := os.Stat(ConfigPath()); !os.IsNotExist(err) {
+		t.Errorf("no config file should be written")
+	}
+}
@@ -3,15 +3,19 @@ package do
 import (
 	"context"
 	"fmt"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+	"strconv"
 
 	"github.com/digitalocean/godo"
 	"github.com/pkg/errors"
 )
 
-// CreateDoDatabase creates a Postgres DB cluster on Digital Ocean
-func CreateDoDatabase(client *godo.Client, name, size, region string) error {
-	ctx := context.TODO()
-	createRequest := &godo.DatabaseCreateRequest{
+// DatabaseCreateRequest builds the request to create a single node Postgres DB cluster
+func DatabaseCreateRequest(name, size, region string) *godo.DatabaseCreateRequest {
+	return &godo.DatabaseCreateRequest{
 		Name:       name,
 		EngineSlug: "pg",
 		Version:    "10",
@@ -19,25 +23,52 @@ func CreateDoDatabase(client *godo.Client, name, size, region string) error {
 		SizeSlug:   size,
 		NumNodes:   1,
 	}
+}
 
-	cluster, _, err := client.Databases.Create(ctx, createRequest)
+// CreateDoDatabase creates a Postgres DB cluster on Digital Ocean
+func CreateDoDatabase(databasesService godo.DatabasesService, createRequest *godo.DatabaseCreateRequest) (*godo.Database, error) {
+	ctx := context.TODO()
+	cluster, _, err := databasesService.Create(ctx, createRequest)
 	if err != nil {
-		return errors.Errorf("Failed to create database:", err)
+		return nil, errors.Wrap(err, "Failed to create database")
 	}
-	fmt.Println(cluster.Name, "created")
-	return nil
+	fmt.Fprintln(os.Stderr, cluster.Name, "created")
+	return cluster, nil
 }
 
-// GetDoDatabase grabs the database ID with the provided name
-func GetDoDatabase(client *godo.Client, name string) (string, error) {
-	var databaseID string
+// DatabaseState reports the status of a database cluster for waiting on, or gone once it is deleted
+func DatabaseState(databasesService godo.DatabasesService, id string) waiter.Condition {
+	return func() (string, error) {
+		database, _, err := databasesService.Get(context.TODO(), id)
+		if isNotFound(err) {
+			return waiter.StateGone, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return database.Status, nil
+	}
+}
+
+// ListDoDatabases fetches all database clusters on the account
+func ListDoDatabases(databasesService godo.DatabasesService) ([]godo.Database, error) {
 	ctx := context.TODO()
 	opt := &godo.ListOptions{
 		Page:    1,
 		PerPage: 200,
 	}
 
-	databases, _, err := client.Databases.List(ctx, opt)
+	databases, _, err := databasesService.List(ctx, opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not list databases")
+	}
+	return databases, nil
+}
+
+// GetDoDatabase grabs the database ID with the provided name
+func GetDoDatabase(databasesService godo.DatabasesService, name string) (string, error) {
+	var databaseID string
+	databases, err := ListDoDatabases(databasesService)
 	if err != nil {
 		return "", errors.Wrapf(err, "Could not list databases to search for %s:", name)
 	}
@@ -49,42 +80,53 @@ func GetDoDatabase(client *godo.Client, name string) (string, error) {
 	if databaseID != "" {
 		return databaseID, nil
 	}
-	return "", errors.Wrapf(err, "Could not find database with name %s:", name)
+	return "", errs.Errorf(errs.NotFound, "Could not find database with name %s", name)
 
 }
 
-// PrintDatabaseStatus outputs some database info
-func PrintDatabaseStatus(client *godo.Client, id string) {
+// GetDatabaseStatus fetches a database cluster and summarizes it
+func GetDatabaseStatus(databasesService godo.DatabasesService, id string) (*provider.Resource, error) {
 	ctx := context.TODO()
-	database, _, err := client.Databases.Get(ctx, id)
+	database, _, err := databasesService.Get(ctx, id)
 	if err != nil {
-		fmt.Println("Could not fetch database status:", err)
+		return nil, errors.Wrap(err, "Could not fetch database status")
+	}
+	resource := DatabaseResource(database)
+	return &resource, nil
+}
+
+// DatabaseResource converts a database cluster into the common resource summary
+func DatabaseResource(database *godo.Database) provider.Resource {
+	resource := provider.Resource{
+		Name:     database.Name,
+		ID:       database.ID,
+		Provider: "do",
+		Region:   database.RegionSlug,
+		Size:     database.SizeSlug,
+		Status:   database.Status,
+		Created:  database.CreatedAt,
+		Details: map[string]string{
+			"engine":    database.EngineSlug,
+			"version":   database.VersionSlug,
+			"num_nodes": strconv.Itoa(database.NumNodes),
+		},
+	}
+	if database.Connection != nil {
+		resource.Endpoint = fmt.Sprintf("%s:%d", database.Connection.Host, database.Connection.Port)
+		resource.Details["uri"] = database.Connection.URI
+		resource.Details["username"] = database.Connection.User
+		resource.Details["password"] = database.Connection.Password
 	}
-	fmt.Printf(
-		"Name: %s\nUID: %s\nEngine: %s\n\nConnection URI: %s\nHost: %s\nPort: %d\n\nUsername: %s\nPassword: %s\n\nNumber of Nodes: %d\nNode Size: %s\n\nRegion: %s\nCreated: %v\nStatus: %s\n",
-		database.Name,
-		database.ID,
-		database.EngineSlug,
-		database.Connection.URI,
-		database.Connection.Host,
-		database.Connection.Port,
-		database.Connection.User,
-		database.Connection.Password,
-		database.NumNodes,
-		database.SizeSlug,
-		database.RegionSlug,
-		database.CreatedAt,
-		database.Status,
-	)
+	return resource
 }
 
 // DeleteDoDatabase delets a database with the provided ID
-func DeleteDoDatabase(client *godo.Client, id string, name string) error {
+func DeleteDoDatabase(databasesService godo.DatabasesService, id string, name string) error {
 	ctx := context.TODO()
-	_, err := client.Databases.Delete(ctx, id)
+	_, err := databasesService.Delete(ctx, id)
 	if err != nil {
-		return errors.Errorf("Deleting database failed:", err)
+		return errors.Wrap(err, "Deleting database failed")
 	}
-	fmt.Println("Database", name, "deleted")
+	fmt.Fprintln(os.Stderr, "Database", name, "deleted")
 	return nil
 }
@@ -3,6 +3,12 @@ package do
 import (
 	"context"
 	"fmt"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/digitalocean/godo"
 	"github.com/pkg/errors"
@@ -14,67 +20,63 @@ func CreateDoClient(patToken, defaultRegion string) *godo.Client {
 	return client
 }
 
-// CreateDoDroplet creates a droplet with provided specs
-func CreateDoDroplet(client *godo.Client, name string, region string, sizeSlug string, imageSlug string) error {
-	ctx := context.TODO()
-	dropletKey := &godo.DropletCreateSSHKey{}
-	opt := &godo.ListOptions{
-		Page:    1,
-		PerPage: 200,
-	}
-	keys, _, err := client.Keys.List(ctx, opt)
-	if err != nil {
-		return errors.Errorf("Failed to create droplet:", err)
-	}
-	if len(keys) < 1 {
-		fmt.Println("To access a DO Droplet an SSH Key is required")
-		fmt.Println("Create an SSH Key and Upload and try again")
-		fmt.Println("https://docs.digitalocean.com/products/droplets/how-to/add-ssh-keys/to-account/")
-		return errors.Errorf("failed to create droplet: SSH Key required and none are avaiable")
-	}
-	if len(keys) > 1 {
-		var sshkeyID int
-		fmt.Println("Multiple SSH Keys found -- Which would you like to use?")
-		for _, sshkeys := range keys {
-			fmt.Printf("Name: %s  ID: %d\n", sshkeys.Name, sshkeys.ID)
-		}
-		fmt.Printf("Enter a Key ID (not name): ")
-		fmt.Scanln(&sshkeyID)
-		*dropletKey = godo.DropletCreateSSHKey{ID: sshkeyID}
-	} else {
-		fmt.Printf("Using SSH Key %s\n", keys[0].Name)
-		sshkeyID := keys[0].ID
-		*dropletKey = godo.DropletCreateSSHKey{ID: sshkeyID}
-	}
-
-	createRequest := &godo.DropletCreateRequest{
+// DropletCreateRequest builds the request to create a droplet that the SSH key is added to
+func DropletCreateRequest(name string, region string, sizeSlug string, imageSlug string, key godo.DropletCreateSSHKey) *godo.DropletCreateRequest {
+	return &godo.DropletCreateRequest{
 		Name:   name,
 		Region: region,
 		Size:   sizeSlug,
 		Image: godo.DropletCreateImage{
 			Slug: imageSlug,
 		},
-		SSHKeys: []godo.DropletCreateSSHKey{*dropletKey},
+		SSHKeys: []godo.DropletCreateSSHKey{key},
 	}
+}
 
-	droplet, _, err := client.Droplets.Create(ctx, createRequest)
+// CreateDoDroplet creates a droplet from the provided request
+func CreateDoDroplet(dropletsService godo.DropletsService, createRequest *godo.DropletCreateRequest) (*godo.Droplet, error) {
+	ctx := context.TODO()
+	droplet, _, err := dropletsService.Create(ctx, createRequest)
 	if err != nil {
-		return errors.Errorf("Failed to create droplet:", err)
+		return nil, errors.Wrap(err, "Failed to create droplet")
 	}
-	fmt.Println(droplet.Name, "created")
-	return nil
+	fmt.Fprintln(os.Stderr, droplet.Name, "created")
+	return droplet, nil
 }
 
-// GetDoDroplet grabs the droplet ID with the provided name
-func GetDoDroplet(client *godo.Client, name string) (int, error) {
-	var dropletID int
+// DropletState reports the status of a droplet for waiting on, or gone once it is deleted
+func DropletState(dropletsService godo.DropletsService, id int) waiter.Condition {
+	return func() (string, error) {
+		droplet, _, err := dropletsService.Get(context.TODO(), id)
+		if isNotFound(err) {
+			return waiter.StateGone, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return droplet.Status, nil
+	}
+}
+
+// ListDoDroplets fetches all droplets on the account
+func ListDoDroplets(dropletsService godo.DropletsService) ([]godo.Droplet, error) {
 	ctx := context.TODO()
 	opt := &godo.ListOptions{
 		Page:    1,
 		PerPage: 200,
 	}
 
-	droplets, _, err := client.Droplets.List(ctx, opt)
+	droplets, _, err := dropletsService.List(ctx, opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not list droplets")
+	}
+	return droplets, nil
+}
+
+// GetDoDroplet grabs the droplet ID with the provided name
+func GetDoDroplet(dropletsService godo.DropletsService, name string) (int, error) {
+	var dropletID int
+	droplets, err := ListDoDroplets(dropletsService)
 	if err != nil {
 		return 1, errors.Wrapf(err, "Could not list droplets to search for %s:", name)
 	}
@@ -86,38 +88,61 @@ func GetDoDroplet(client *godo.Client, name string) (int, error) {
 	if dropletID != 0 {
 		return dropletID, nil
 	}
-	return 1, errors.Wrapf(err, "Could not find droplet with name %s:", name)
+	return 1, errs.Errorf(errs.NotFound, "Could not find droplet with name %s", name)
 
 }
 
-// PrintDropletStatus outputs some droplet info
-func PrintDropletStatus(client *godo.Client, id int) {
+// GetDropletStatus fetches a droplet and summarizes it
+func GetDropletStatus(dropletsService godo.DropletsService, id int) (*provider.Resource, error) {
 	ctx := context.TODO()
-	droplet, _, err := client.Droplets.Get(ctx, id)
+	droplet, _, err := dropletsService.Get(ctx, id)
 	if err != nil {
-		fmt.Println("Could not fetch droplet status:", err)
+		return nil, errors.Wrap(err, "Could not fetch droplet status")
+	}
+	resource := DropletResource(droplet)
+	return &resource, nil
+}
+
+// DropletResource converts a droplet into the common resource summary
+func DropletResource(droplet *godo.Droplet) provider.Resource {
+	created, _ := time.Parse(time.RFC3339, droplet.Created)
+	resource := provider.Resource{
+		Name:     droplet.Name,
+		ID:       strconv.Itoa(droplet.ID),
+		Provider: "do",
+		Size:     droplet.SizeSlug,
+		Status:   droplet.Status,
+		Created:  created,
+		Details: map[string]string{
+			"memory": strconv.Itoa(droplet.Memory),
+			"disk":   strconv.Itoa(droplet.Disk),
+		},
+	}
+	if droplet.Region != nil {
+		resource.Region = droplet.Region.Slug
+	}
+	if droplet.Image != nil {
+		resource.Details["distribution"] = droplet.Image.Distribution
+		resource.Details["image"] = droplet.Image.Name
+	}
+	if droplet.Networks != nil {
+		for _, network := range droplet.Networks.V4 {
+			resource.Addresses = append(resource.Addresses, network.IPAddress)
+		}
+		for _, network := range droplet.Networks.V6 {
+			resource.Addresses = append(resource.Addresses, network.IPAddress)
+		}
 	}
-	fmt.Printf(
-		"Name: %s\nUID: %d\nMemory: %d\nDisk: %d\n\nDistribution: %s\nVersion: %s\n\nPublic IP: %s\nRegion: %s\nStatus: %s\n",
-		droplet.Name,
-		droplet.ID,
-		droplet.Memory,
-		droplet.Disk,
-		droplet.Image.Distribution,
-		droplet.Image.Name,
-		droplet.Networks.V4[1].IPAddress,
-		droplet.Region.Slug,
-		droplet.Status,
-	)
+	return resource
 }
 
 // DeleteDoDroplet delets a droplet with the provided ID
-func DeleteDoDroplet(client *godo.Client, id int, name string) error {
+func DeleteDoDroplet(dropletsService godo.DropletsService, id int, name string) error {
 	ctx := context.TODO()
-	_, err := client.Droplets.Delete(ctx, id)
+	_, err := dropletsService.Delete(ctx, id)
 	if err != nil {
-		return errors.Errorf("Deleting droplet failed:", err)
+		return errors.Wrap(err, "Deleting droplet failed")
 	}
-	fmt.Println("Droplet", name, "deleted")
+	fmt.Fprintln(os.Stderr, "Droplet", name, "deleted")
 	return nil
 }
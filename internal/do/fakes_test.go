@@ -0,0 +1,454 @@
+package do
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/digitalocean/godo"
+)
+
+// notFound is the error the API returns for objects that don't exist
+func notFound() error {
+	return &godo.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}, Message: "not found"}
+}
+
+// unauthorized is the error the API returns for a bad token
+func unauthorized() error {
+	request, _ := http.NewRequest(http.MethodGet, "https://api.digitalocean.com/v2/account", nil)
+	return &godo.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized, Request: request}, Message: "Unable to authenticate you"}
+}
+
+// fakeDroplets keeps droplets in memory. New droplets become active on the second Get.
+type fakeDroplets struct {
+	godo.DropletsService
+	droplets map[int]*godo.Droplet
+	nextID   int
+	created  []*godo.DropletCreateRequest
+}
+
+func newFakeDroplets() *fakeDroplets {
+	return &fakeDroplets{droplets: map[int]*godo.Droplet{}, nextID: 100}
+}
+
+func (f *fakeDroplets) Create(ctx context.Context, req *godo.DropletCreateRequest) (*godo.Droplet, *godo.Response, error) {
+	f.nextID++
+	f.created = append(f.created, req)
+	droplet := &godo.Droplet{
+		ID:       f.nextID,
+		Name:     req.Name,
+		Status:   "new",
+		Region:   &godo.Region{Slug: req.Region},
+		SizeSlug: req.Size,
+	}
+	f.droplets[droplet.ID] = droplet
+	copied := *droplet
+	return &copied, nil, nil
+}
+
+func (f *fakeDroplets) Get(ctx context.Context, id int) (*godo.Droplet, *godo.Response, error) {
+	droplet, ok := f.droplets[id]
+	if !ok {
+		return nil, nil, notFound()
+	}
+	copied := *droplet
+	droplet.Status = "active"
+	return &copied, nil, nil
+}
+
+func (f *fakeDroplets) List(ctx context.Context, opt *godo.ListOptions) ([]godo.Droplet, *godo.Response, error) {
+	var droplets []godo.Droplet
+	for _, droplet := range f.droplets {
+		droplets = append(droplets, *droplet)
+	}
+	return droplets, nil, nil
+}
+
+func (f *fakeDroplets) Delete(ctx context.Context, id int) (*godo.Response, error) {
+	if _, ok := f.droplets[id]; !ok {
+		return nil, notFound()
+	}
+	delete(f.droplets, id)
+	return nil, nil
+}
+
+// fakeKubernetes keeps clusters in memory. New clusters are running on the second Get.
+type fakeKubernetes struct {
+	godo.KubernetesService
+	clusters map[string]*godo.KubernetesCluster
+}
+
+func newFakeKubernetes() *fakeKubernetes {
+	return &fakeKubernetes{clusters: map[string]*godo.KubernetesCluster{}}
+}
+
+func (f *fakeKubernetes) Create(ctx context.Context, req *godo.KubernetesClusterCreateRequest) (*godo.KubernetesCluster, *godo.Response, error) {
+	cluster := &godo.KubernetesCluster{
+		ID:          "cluster-" + req.Name,
+		Name:        req.Name,
+		RegionSlug:  req.RegionSlug,
+		VersionSlug: req.VersionSlug,
+		Status:      &godo.KubernetesClusterStatus{State: godo.KubernetesClusterStatusProvisioning},
+	}
+	for _, pool := range req.NodePools {
+		cluster.NodePools = append(cluster.NodePools, &godo.KubernetesNodePool{ID: "pool-" + pool.Name, Name: pool.Name, Size: pool.Size, Count: pool.Count})
+	}
+	f.clusters[cluster.ID] = cluster
+	return cluster, nil, nil
+}
+
+func (f *fakeKubernetes) Get(ctx context.Context, id string) (*godo.KubernetesCluster, *godo.Response, error) {
+	cluster, ok := f.clusters[id]
+	if !ok {
+		return nil, nil, notFound()
+	}
+	copied := *cluster
+	cluster.Status = &godo.KubernetesClusterStatus{State: godo.KubernetesClusterStatusRunning}
+	return &copied, nil, nil
+}
+
+func (f *fakeKubernetes) List(ctx context.Context, opt *godo.ListOptions) ([]*godo.KubernetesCluster, *godo.Response, error) {
+	var clusters []*godo.KubernetesCluster
+	for _, cluster := range f.clusters {
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil, nil
+}
+
+func (f *fakeKubernetes) Delete(ctx context.Context, id string) (*godo.Response, error) {
+	if _, ok := f.clusters[id]; !ok {
+		return nil, notFound()
+	}
+	delete(f.clusters, id)
+	return nil, nil
+}
+
+func (f *fakeKubernetes) GetKubeConfig(ctx context.Context, id string) (*godo.KubernetesClusterConfig, *godo.Response, error) {
+	if _, ok := f.clusters[id]; !ok {
+		return nil, nil, notFound()
+	}
+	return &godo.KubernetesClusterConfig{KubeconfigYAML: []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: do-nyc1-` + f.clusters[id].Name + `
+  cluster:
+    server: https://` + id + `.k8s.ondigitalocean.com
+    certificate-authority-data: Y2E=
+users:
+- name: do-nyc1-` + f.clusters[id].Name + `-admin
+  user:
+    token: secret
+`)}, nil, nil
+}
+
+// fakeNodes makes the nodes of a pool, new nodes are provisioning until the pool is fetched
+// fakeUpgrade is the only version clusters can be upgraded to
+const fakeUpgrade = "1.20.2-do.0"
+
+func (f *fakeKubernetes) GetUpgrades(ctx context.Context, id string) ([]*godo.KubernetesVersion, *godo.Response, error) {
+	cluster, ok := f.clusters[id]
+	if !ok {
+		return nil, nil, notFound()
+	}
+	if cluster.VersionSlug == fakeUpgrade {
+		return nil, nil, nil
+	}
+	return []*godo.KubernetesVersion{{Slug: fakeUpgrade, KubernetesVersion: "1.20.2"}}, nil, nil
+}
+
+func (f *fakeKubernetes) Upgrade(ctx context.Context, id string, req *godo.KubernetesClusterUpgradeRequest) (*godo.Response, error) {
+	cluster, ok := f.clusters[id]
+	if !ok {
+		return nil, notFound()
+	}
+	cluster.VersionSlug = req.VersionSlug
+	cluster.Status = &godo.KubernetesClusterStatus{State: godo.KubernetesClusterStatusUpgrading}
+	return nil, nil
+}
+
+func fakeNodes(pool *godo.KubernetesNodePool) {
+	pool.Nodes = nil
+	for i := 0; i < pool.Count; i++ {
+		pool.Nodes = append(pool.Nodes, &godo.KubernetesNode{
+			Name:   fmt.Sprintf("%s-%d", pool.Name, i),
+			Status: &godo.KubernetesNodeStatus{State: "provisioning"},
+		})
+	}
+}
+
+func (f *fakeKubernetes) findPool(clusterID, poolID string) (*godo.KubernetesCluster, int, error) {
+	cluster, ok := f.clusters[clusterID]
+	if !ok {
+		return nil, 0, notFound()
+	}
+	for i, pool := range cluster.NodePools {
+		if pool.ID == poolID {
+			return cluster, i, nil
+		}
+	}
+	return nil, 0, notFound()
+}
+
+func (f *fakeKubernetes) CreateNodePool(ctx context.Context, clusterID string, req *godo.KubernetesNodePoolCreateRequest) (*godo.KubernetesNodePool, *godo.Response, error) {
+	cluster, ok := f.clusters[clusterID]
+	if !ok {
+		return nil, nil, notFound()
+	}
+	pool := &godo.KubernetesNodePool{
+		ID:        "pool-" + req.Name,
+		Name:      req.Name,
+		Size:      req.Size,
+		Count:     req.Count,
+		Labels:    req.Labels,
+		Taints:    req.Taints,
+		AutoScale: req.AutoScale,
+		MinNodes:  req.MinNodes,
+		MaxNodes:  req.MaxNodes,
+	}
+	fakeNodes(pool)
+	cluster.NodePools = append(cluster.NodePools, pool)
+	return pool, nil, nil
+}
+
+func (f *fakeKubernetes) GetNodePool(ctx context.Context, clusterID, poolID string) (*godo.KubernetesNodePool, *godo.Response, error) {
+	cluster, i, err := f.findPool(clusterID, poolID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := cluster.NodePools[i]
+	copied := *pool
+	for _, node := range pool.Nodes {
+		node.Status = &godo.KubernetesNodeStatus{State: "running"}
+	}
+	return &copied, nil, nil
+}
+
+func (f *fakeKubernetes) ListNodePools(ctx context.Context, clusterID string, opts *godo.ListOptions) ([]*godo.KubernetesNodePool, *godo.Response, error) {
+	cluster, ok := f.clusters[clusterID]
+	if !ok {
+		return nil, nil, notFound()
+	}
+	return cluster.NodePools, nil, nil
+}
+
+func (f *fakeKubernetes) UpdateNodePool(ctx context.Context, clusterID, poolID string, req *godo.KubernetesNodePoolUpdateRequest) (*godo.KubernetesNodePool, *godo.Response, error) {
+	cluster, i, err := f.findPool(clusterID, poolID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := cluster.NodePools[i]
+	if req.Count != nil {
+		pool.Count = *req.Count
+		fakeNodes(pool)
+	}
+	if req.AutoScale != nil {
+		pool.AutoScale = *req.AutoScale
+	}
+	if req.MinNodes != nil {
+		pool.MinNodes = *req.MinNodes
+	}
+	return pool, nil, nil
+}
+
+func (f *fakeKubernetes) DeleteNodePool(ctx context.Context, clusterID, poolID string) (*godo.Response, error) {
+	cluster, i, err := f.findPool(clusterID, poolID)
+	if err != nil {
+		return nil, err
+	}
+	cluster.NodePools = append(cluster.NodePools[:i], cluster.NodePools[i+1:]...)
+	return nil, nil
+}
+
+// fakeDatabases keeps database clusters in memory. New databases are online on the second Get.
+type fakeDatabases struct {
+	godo.DatabasesService
+	databases map[string]*godo.Database
+}
+
+func newFakeDatabases() *fakeDatabases {
+	return &fakeDatabases{databases: map[string]*godo.Database{}}
+}
+
+func (f *fakeDatabases) Create(ctx context.Context, req *godo.DatabaseCreateRequest) (*godo.Database, *godo.Response, error) {
+	database := &godo.Database{
+		ID:         "db-" + req.Name,
+		Name:       req.Name,
+		EngineSlug: req.EngineSlug,
+		RegionSlug: req.Region,
+		SizeSlug:   req.SizeSlug,
+		NumNodes:   req.NumNodes,
+		Status:     "creating",
+	}
+	f.databases[database.ID] = database
+	copied := *database
+	return &copied, nil, nil
+}
+
+func (f *fakeDatabases) Get(ctx context.Context, id string) (*godo.Database, *godo.Response, error) {
+	database, ok := f.databases[id]
+	if !ok {
+		return nil, nil, notFound()
+	}
+	copied := *database
+	database.Status = "online"
+	return &copied, nil, nil
+}
+
+func (f *fakeDatabases) List(ctx context.Context, opt *godo.ListOptions) ([]godo.Database, *godo.Response, error) {
+	var databases []godo.Database
+	for _, database := range f.databases {
+		databases = append(databases, *database)
+	}
+	return databases, nil, nil
+}
+
+func (f *fakeDatabases) Delete(ctx context.Context, id string) (*godo.Response, error) {
+	if _, ok := f.databases[id]; !ok {
+		return nil, notFound()
+	}
+	delete(f.databases, id)
+	return nil, nil
+}
+
+// fakeSizes, fakeImages and fakeRegions return fixed catalogs, including entries that can't be used
+type fakeSizes struct {
+	godo.SizesService
+}
+
+// List serves the sizes over two pages, like the API does once there are more than PerPage
+func (f *fakeSizes) List(ctx context.Context, opt *godo.ListOptions) ([]godo.Size, *godo.Response, error) {
+	if opt.Page == 2 {
+		links := &godo.Links{Pages: &godo.Pages{Prev: "https://api.digitalocean.com/v2/sizes?page=1"}}
+		return []godo.Size{
+			{Slug: "s-8vcpu-16gb", Vcpus: 8, Memory: 16384, Available: true, Description: "Basic"},
+		}, &godo.Response{Links: links}, nil
+	}
+	links := &godo.Links{Pages: &godo.Pages{Next: "https://api.digitalocean.com/v2/sizes?page=2"}}
+	return []godo.Size{
+		{Slug: "s-1vcpu-1gb", Vcpus: 1, Memory: 1024, Available: true, Description: "Basic"},
+		{Slug: "s-4vcpu-8gb", Vcpus: 4, Memory: 8192, Available: true, Description: "Basic"},
+		{Slug: "s-retired", Vcpus: 1, Memory: 512},
+	}, &godo.Response{Links: links}, nil
+}
+
+type fakeImages struct {
+	godo.ImagesService
+}
+
+func (f *fakeImages) ListDistribution(ctx context.Context, opt *godo.ListOptions) ([]godo.Image, *godo.Response, error) {
+	return []godo.Image{
+		{Slug: "ubuntu-20-04-x64", Name: "20.04 (LTS) x64", Distribution: "Ubuntu"},
+		{Slug: "debian-10-x64", Name: "10 x64", Distribution: "Debian"},
+		{Name: "snapshot without a slug", Distribution: "Ubuntu"},
+	}, nil, nil
+}
+
+type fakeRegions struct {
+	godo.RegionsService
+}
+
+func (f *fakeRegions) List(ctx context.Context, opt *godo.ListOptions) ([]godo.Region, *godo.Response, error) {
+	return []godo.Region{
+		{Slug: "nyc1", Name: "New York 1", Available: true},
+		{Slug: "nyc2", Name: "New York 2"},
+	}, nil, nil
+}
+
+func (f *fakeKubernetes) GetOptions(ctx context.Context) (*godo.KubernetesOptions, *godo.Response, error) {
+	return &godo.KubernetesOptions{Versions: []*godo.KubernetesVersion{{Slug: fakeUpgrade, KubernetesVersion: "1.20.2"}}}, nil, nil
+}
+
+// fakeKeys keeps SSH keys in memory
+type fakeKeys struct {
+	godo.KeysService
+	keys []godo.Key
+}
+
+func (f *fakeKeys) List(ctx context.Context, opt *godo.ListOptions) ([]godo.Key, *godo.Response, error) {
+	return f.keys, nil, nil
+}
+
+func (f *fakeKeys) Create(ctx context.Context, req *godo.KeyCreateRequest) (*godo.Key, *godo.Response, error) {
+	key := godo.Key{ID: 100 + len(f.keys), Name: req.Name, PublicKey: req.PublicKey, Fingerprint: "fp-" + req.Name}
+	f.keys = append(f.keys, key)
+	return &key, nil, nil
+}
+
+func (f *fakeKeys) DeleteByID(ctx context.Context, id int) (*godo.Response, error) {
+	for i, key := range f.keys {
+		if key.ID == id {
+			f.keys = append(f.keys[:i], f.keys[i+1:]...)
+			return nil, nil
+		}
+	}
+	return nil, notFound()
+}
+
+// fakeAccount returns a fixed account, or err when the token is bad
+type fakeAccount struct {
+	godo.AccountService
+	err error
+}
+
+func (f *fakeAccount) Get(ctx context.Context) (*godo.Account, *godo.Response, error) {
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	return &godo.Account{Email: "lab@example.com", UUID: "b6fr89dbf6d9156cace5f3c78dc9851d957381ef", Status: "active"}, nil, nil
+}
+
+// fakeSpaces keeps Spaces and the keys of their objects in memory
+type fakeSpaces struct {
+	s3iface.S3API
+	buckets map[string][]string
+}
+
+func newFakeSpaces() *fakeSpaces {
+	return &fakeSpaces{buckets: map[string][]string{}}
+}
+
+func (f *fakeSpaces) CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	f.buckets[aws.StringValue(input.Bucket)] = nil
+	return &s3.CreateBucketOutput{}, nil
+}
+
+func (f *fakeSpaces) ListBuckets(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	output := &s3.ListBucketsOutput{}
+	for name := range f.buckets {
+		output.Buckets = append(output.Buckets, &s3.Bucket{Name: aws.String(name)})
+	}
+	return output, nil
+}
+
+func (f *fakeSpaces) ListObjects(input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	output := &s3.ListObjectsOutput{}
+	for _, key := range f.buckets[aws.StringValue(input.Bucket)] {
+		output.Contents = append(output.Contents, &s3.Object{Key: aws.String(key)})
+	}
+	return output, nil
+}
+
+func (f *fakeSpaces) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	name := aws.StringValue(input.Bucket)
+	var kept []string
+	for _, key := range f.buckets[name] {
+		if key != aws.StringValue(input.Key) {
+			kept = append(kept, key)
+		}
+	}
+	f.buckets[name] = kept
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeSpaces) DeleteBucket(input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	delete(f.buckets, aws.StringValue(input.Bucket))
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+// fakeKey returns a single SSH key so creating a droplet never prompts
+func fakeKey() []godo.Key {
+	return []godo.Key{{ID: 7, Name: "laptop", Fingerprint: "aa:bb"}}
+}
@@ -3,18 +3,21 @@ package do
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
-	"maker/internal/utils"
-	"time"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/digitalocean/godo"
 	"github.com/pkg/errors"
 )
 
-// CreateDoCluster creates a Kubernetes cluster on DigitalOcean
-func CreateDoCluster(client *godo.Client, name, defaultRegion, nodeSize, version string, nodeCount int) (string, error) {
-	ctx := context.TODO()
-	req := &godo.KubernetesClusterCreateRequest{
+// ClusterCreateRequest builds the request to create a cluster with a single node pool
+func ClusterCreateRequest(name, defaultRegion, nodeSize, version string, nodeCount int) *godo.KubernetesClusterCreateRequest {
+	return &godo.KubernetesClusterCreateRequest{
 		Name:        name,
 		RegionSlug:  defaultRegion,
 		VersionSlug: version,
@@ -26,42 +29,55 @@ func CreateDoCluster(client *godo.Client, name, defaultRegion, nodeSize, version
 			},
 		},
 	}
-	cluster, _, err := client.Kubernetes.Create(ctx, req)
+}
+
+// CreateDoCluster creates a Kubernetes cluster on DigitalOcean
+func CreateDoCluster(kubernetesService godo.KubernetesService, req *godo.KubernetesClusterCreateRequest) (string, error) {
+	ctx := context.TODO()
+	cluster, _, err := kubernetesService.Create(ctx, req)
 	if err != nil {
-		return "", errors.Errorf("Creating cluster failed:", err)
+		return "", errors.Wrap(err, "Creating cluster failed")
 	}
-	fmt.Println("Cluster", name, "creating...")
+	fmt.Fprintln(os.Stderr, "Cluster", req.Name, "creating...")
+	return cluster.ID, nil
+}
 
-	// check status before fetching kubeconfig
-	status := ""
-	for {
-		cluster, _, err := client.Kubernetes.Get(ctx, cluster.ID)
+// ClusterState reports the state of a cluster for waiting on, or gone once it is deleted
+func ClusterState(kubernetesService godo.KubernetesService, id string) waiter.Condition {
+	return func() (string, error) {
+		cluster, _, err := kubernetesService.Get(context.TODO(), id)
+		if isNotFound(err) {
+			return waiter.StateGone, nil
+		}
 		if err != nil {
-			fmt.Println("Could not fetch cluster status:", err)
+			return "", err
 		}
-		status = string(cluster.Status.State)
-		if status == "error" || status == "degraded" || status == "invalid" {
-			return "", errors.Errorf("Creating cluster failed:", err)
-		} else if status == "running" {
-			fmt.Println("Cluster creation completed!")
-			return cluster.ID, nil
+		if cluster.Status == nil {
+			return "", nil
 		}
-		fmt.Println("Waiting for completion...")
-		time.Sleep(30 * time.Second)
-		continue
+		return string(cluster.Status.State), nil
 	}
 }
 
-// GetDoCluster grabs the droplet ID with the provided name
-func GetDoCluster(client *godo.Client, name string) (string, error) {
-	var clusterID string
+// ListDoClusters fetches all Kubernetes clusters on the account
+func ListDoClusters(kubernetesService godo.KubernetesService) ([]*godo.KubernetesCluster, error) {
 	ctx := context.TODO()
 	opt := &godo.ListOptions{
 		Page:    1,
 		PerPage: 200,
 	}
 
-	clusters, _, err := client.Kubernetes.List(ctx, opt)
+	clusters, _, err := kubernetesService.List(ctx, opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not list clusters")
+	}
+	return clusters, nil
+}
+
+// GetDoCluster grabs the cluster ID with the provided name
+func GetDoCluster(kubernetesService godo.KubernetesService, name string) (string, error) {
+	var clusterID string
+	clusters, err := ListDoClusters(kubernetesService)
 	if err != nil {
 		return "", errors.Wrapf(err, "Could not list clusters to search for %s:", name)
 	}
@@ -73,65 +89,93 @@ func GetDoCluster(client *godo.Client, name string) (string, error) {
 	if clusterID != "" {
 		return clusterID, nil
 	}
-	return "", errors.Wrapf(err, "Could not find cluster with name %s:", name)
+	return "", errs.Errorf(errs.NotFound, "Could not find cluster with name %s", name)
 }
 
-// PrintClusterStatus outputs some droplet info
-func PrintClusterStatus(client *godo.Client, id string) {
+// GetClusterStatus fetches a cluster and summarizes it
+func GetClusterStatus(kubernetesService godo.KubernetesService, id string) (*provider.Resource, error) {
 	ctx := context.TODO()
-	cluster, _, err := client.Kubernetes.Get(ctx, id)
+	cluster, _, err := kubernetesService.Get(ctx, id)
 	if err != nil {
-		fmt.Println("Could not fetch cluster status:", err)
-	}
-	fmt.Printf("Cluster Info\n------------\n")
-	fmt.Printf(
-		"Name: %s\nUID: %s\nRegion: %s\nVersion: %s\n\nCluster Subnet: %s\nService Subnet: %s\nPublic IP: %s\nEndpoint: %s\n\nNode Size: %s\nNode Count: %d\nCreated: %s\n\n",
-		cluster.Name,
-		cluster.ID,
-		cluster.RegionSlug,
-		cluster.VersionSlug,
-		cluster.ClusterSubnet,
-		cluster.ServiceSubnet,
-		cluster.IPv4,
-		cluster.Endpoint,
-		cluster.NodePools[0].Size,
-		cluster.NodePools[0].Count,
-		cluster.CreatedAt,
-	)
-	fmt.Printf("Node Info\n---------\n")
-	for i := 0; i < cluster.NodePools[0].Count; i++ {
-		fmt.Printf(
-			"Node Name: %s\nNode Status %s\n\n",
-			cluster.NodePools[0].Nodes[i].Name,
-			cluster.NodePools[0].Nodes[i].Status.State,
-		)
+		return nil, errors.Wrap(err, "Could not fetch cluster status")
+	}
+	resource := ClusterResource(cluster)
+	return &resource, nil
+}
+
+// ClusterResource converts a cluster into the common resource summary
+func ClusterResource(cluster *godo.KubernetesCluster) provider.Resource {
+	resource := provider.Resource{
+		Name:     cluster.Name,
+		ID:       cluster.ID,
+		Provider: "do",
+		Region:   cluster.RegionSlug,
+		Endpoint: cluster.Endpoint,
+		Created:  cluster.CreatedAt,
+		Details: map[string]string{
+			"version":        cluster.VersionSlug,
+			"cluster_subnet": cluster.ClusterSubnet,
+			"service_subnet": cluster.ServiceSubnet,
+		},
+	}
+	if cluster.IPv4 != "" {
+		resource.Addresses = []string{cluster.IPv4}
 	}
+	if cluster.Status != nil {
+		resource.Status = string(cluster.Status.State)
+	}
+	if len(cluster.NodePools) > 0 {
+		resource.Size = cluster.NodePools[0].Size
+
+		var pools, nodes []string
+		count := 0
+		for _, pool := range cluster.NodePools {
+			pools = append(pools, pool.Name)
+			count += pool.Count
+			for _, node := range pool.Nodes {
+				state := ""
+				if node.Status != nil {
+					state = node.Status.State
+				}
+				nodes = append(nodes, node.Name+"="+state)
+			}
+		}
+		resource.Details["node_pools"] = strings.Join(pools, ",")
+		resource.Details["node_count"] = strconv.Itoa(count)
+		resource.Details["nodes"] = strings.Join(nodes, ",")
+	}
+	return resource
 }
 
 // DeleteDoCluster delets a droplet with the provided ID
-func DeleteDoCluster(client *godo.Client, id string, name string) error {
+func DeleteDoCluster(kubernetesService godo.KubernetesService, id string, name string) error {
 	ctx := context.TODO()
-	_, err := client.Kubernetes.Delete(ctx, id)
+	_, err := kubernetesService.Delete(ctx, id)
 	if err != nil {
-		return errors.Errorf("Deleting cluster failed:", err)
+		return errors.Wrap(err, "Deleting cluster failed")
 	}
-	fmt.Println("Cluster", name, "deleted")
+	fmt.Fprintln(os.Stderr, "Cluster", name, "deleted")
 	return nil
 }
 
-// FetchDoKubeConfig fetches a kubeconfig file for the cluster and writes it to .makers config directory
-func FetchDoKubeConfig(client *godo.Client, id string) error {
+// FetchDoKubeConfig fetches the kubeconfig of a cluster and merges it into the kubeconfig file
+func FetchDoKubeConfig(kubernetesService godo.KubernetesService, id, name string) error {
 	ctx := context.TODO()
-	config, _, err := client.Kubernetes.GetKubeConfig(ctx, id)
+	config, _, err := kubernetesService.GetKubeConfig(ctx, id)
 	if err != nil {
-		return errors.Errorf("Fetching kubeconfig failed:", err)
+		return errors.Wrap(err, "Fetching kubeconfig failed")
 	}
-	kubeConfigFile := string(config.KubeconfigYAML)
-	err = ioutil.WriteFile(utils.ConfigFolderPath+"/do_kubeconfig", []byte(kubeConfigFile), 0755)
+	doConfig, err := kubeconfig.Parse(config.KubeconfigYAML)
 	if err != nil {
-		return errors.Errorf("Failed to write kubeconfig:", err)
+		return err
 	}
-	fmt.Println("Kubeconfig file written to", utils.ConfigFolderPath)
-	fmt.Printf("To use the kubeconfig, be sure to run 'export KUBECONFIG=%s/do_kubeconfig'\n", utils.ConfigFolderPath)
-	return nil
+	if len(doConfig.Clusters) == 0 || len(doConfig.Users) == 0 {
+		return errors.Errorf("Kubeconfig of cluster %s has no cluster or user", name)
+	}
+	return kubeconfig.Merge(kubeconfig.Entry{
+		Provider: "do",
+		Name:     name,
+		Cluster:  doConfig.Clusters[0].Cluster,
+		User:     doConfig.Users[0].User,
+	})
 }
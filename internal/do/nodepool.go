@@ -0,0 +1,156 @@
+package do
+
+import (
+	"context"
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+)
+
+// NodePoolCreateRequest builds the request to add a node pool to a cluster
+func NodePoolCreateRequest(opts provider.NodePoolOptions) *godo.KubernetesNodePoolCreateRequest {
+	req := &godo.KubernetesNodePoolCreateRequest{
+		Name:      opts.Name,
+		Size:      opts.NodeSize,
+		Count:     opts.InitialCount(),
+		Labels:    opts.Labels,
+		AutoScale: opts.Autoscaled(),
+		MinNodes:  opts.MinNodes,
+		MaxNodes:  opts.MaxNodes,
+	}
+	for _, taint := range opts.Taints {
+		req.Taints = append(req.Taints, godo.Taint{Key: taint.Key, Value: taint.Value, Effect: taint.Effect})
+	}
+	return req
+}
+
+// NodePoolUpdateRequest builds the request to resize a node pool. The update API can't
+// change the maximum of an autoscaled pool, so only the one it was added with is accepted.
+func NodePoolUpdateRequest(pool *godo.KubernetesNodePool, scale provider.NodeScale) (*godo.KubernetesNodePoolUpdateRequest, error) {
+	req := &godo.KubernetesNodePoolUpdateRequest{
+		Name:      pool.Name,
+		AutoScale: godo.Bool(scale.Autoscaled()),
+	}
+	if !scale.Autoscaled() {
+		req.Count = godo.Int(scale.NodeCount)
+		return req, nil
+	}
+	if scale.MaxNodes != pool.MaxNodes {
+		return nil, errs.Errorf(errs.Usage, "The maximum of node pool %s can't be changed on DigitalOcean, it is %d", pool.Name, pool.MaxNodes)
+	}
+	req.MinNodes = godo.Int(scale.MinNodes)
+	if scale.NodeCount > 0 {
+		req.Count = godo.Int(scale.NodeCount)
+	}
+	return req, nil
+}
+
+// CreateDoNodePool adds a node pool to a cluster
+func CreateDoNodePool(kubernetesService godo.KubernetesService, clusterID string, req *godo.KubernetesNodePoolCreateRequest) (*godo.KubernetesNodePool, error) {
+	pool, _, err := kubernetesService.CreateNodePool(context.TODO(), clusterID, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Creating node pool failed")
+	}
+	fmt.Fprintln(os.Stderr, "Node pool", req.Name, "creating...")
+	return pool, nil
+}
+
+// GetDoNodePool finds the node pool of a cluster with the provided name
+func GetDoNodePool(kubernetesService godo.KubernetesService, clusterID, name string) (*godo.KubernetesNodePool, error) {
+	pools, err := ListDoNodePools(kubernetesService, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range pools {
+		if pool.Name == name {
+			return pool, nil
+		}
+	}
+	return nil, errs.Errorf(errs.NotFound, "Could not find node pool with name %s", name)
+}
+
+// ListDoNodePools fetches the node pools of a cluster
+func ListDoNodePools(kubernetesService godo.KubernetesService, clusterID string) ([]*godo.KubernetesNodePool, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	pools, _, err := kubernetesService.ListNodePools(context.TODO(), clusterID, opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not list node pools")
+	}
+	return pools, nil
+}
+
+// UpdateDoNodePool resizes a node pool
+func UpdateDoNodePool(kubernetesService godo.KubernetesService, clusterID, poolID string, req *godo.KubernetesNodePoolUpdateRequest) (*godo.KubernetesNodePool, error) {
+	pool, _, err := kubernetesService.UpdateNodePool(context.TODO(), clusterID, poolID, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Updating node pool failed")
+	}
+	fmt.Fprintln(os.Stderr, "Node pool", req.Name, "scaling...")
+	return pool, nil
+}
+
+// DeleteDoNodePool deletes a node pool and its nodes
+func DeleteDoNodePool(kubernetesService godo.KubernetesService, clusterID, poolID, name string) error {
+	_, err := kubernetesService.DeleteNodePool(context.TODO(), clusterID, poolID)
+	if err != nil {
+		return errors.Wrap(err, "Deleting node pool failed")
+	}
+	fmt.Fprintln(os.Stderr, "Node pool", name, "deleted")
+	return nil
+}
+
+// NodePoolState reports running once every node of a pool is, or gone once the pool is deleted
+func NodePoolState(kubernetesService godo.KubernetesService, clusterID, poolID string) waiter.Condition {
+	return func() (string, error) {
+		pool, _, err := kubernetesService.GetNodePool(context.TODO(), clusterID, poolID)
+		if isNotFound(err) {
+			return waiter.StateGone, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if len(pool.Nodes) < pool.Count {
+			return "provisioning", nil
+		}
+		for _, node := range pool.Nodes {
+			if node.Status == nil || node.Status.State != "running" {
+				return "provisioning", nil
+			}
+		}
+		return "running", nil
+	}
+}
+
+// NodePoolSummary converts a node pool into the common node pool summary
+func NodePoolSummary(pool *godo.KubernetesNodePool, cluster string) provider.NodePool {
+	summary := provider.NodePool{
+		Provider:  "do",
+		Cluster:   cluster,
+		Name:      pool.Name,
+		ID:        pool.ID,
+		Size:      pool.Size,
+		NodeCount: pool.Count,
+		Status:    "running",
+		Labels:    pool.Labels,
+	}
+	if pool.AutoScale {
+		summary.MinNodes, summary.MaxNodes = pool.MinNodes, pool.MaxNodes
+	}
+	for _, node := range pool.Nodes {
+		if node.Status == nil || node.Status.State != "running" {
+			summary.Status = "provisioning"
+		}
+	}
+	for _, taint := range pool.Taints {
+		summary.Taints = append(summary.Taints, provider.Taint{Key: taint.Key, Value: taint.Value, Effect: taint.Effect})
+	}
+	return summary
+}
@@ -0,0 +1,606 @@
+package do
+
+import (
+	"context"
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"maker/internal/state"
+	"maker/internal/waiter"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+)
+
+// Provider implements provider.Provider for DigitalOcean
+type Provider struct {
+	config     *ConfigFile
+	droplets   godo.DropletsService
+	kubernetes godo.KubernetesService
+	databases  godo.DatabasesService
+	keys       godo.KeysService
+	account    godo.AccountService
+	sizes      godo.SizesService
+	images     godo.ImagesService
+	regions    godo.RegionsService
+	// spaces uses separate keys from the PAT token
+	spaces s3iface.S3API
+	opts   provider.Options
+}
+
+func init() {
+	provider.Register("do", provider.Registration{
+		Configure:  SetupConfig,
+		New:        New,
+		ConfigFile: ConfigName,
+	})
+}
+
+// New loads the DO config file and creates a client to talk to DigitalOcean
+func New(opts provider.Options) (provider.Provider, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		// without a config there are no credentials to use
+		return nil, errs.WrapAs(errs.AuthFailed, err, "Failed to load config")
+	}
+	client := CreateDoClient(config.PatToken, config.DefaultRegion)
+	return &Provider{
+		config:     config,
+		droplets:   client.Droplets,
+		kubernetes: client.Kubernetes,
+		databases:  client.Databases,
+		keys:       client.Keys,
+		account:    client.Account,
+		sizes:      client.Sizes,
+		images:     client.Images,
+		regions:    client.Regions,
+		spaces:     CreateDoSpacesClient(config.SpacesAccessKey, config.SpacesSecretKey, config.SpacesDefaultEndpoint),
+		opts:       opts,
+	}, nil
+}
+
+// Region returns the default region droplets and clusters are created in
+func (p *Provider) Region() string {
+	return p.config.DefaultRegion
+}
+
+// Verify checks the PAT token by fetching the account it belongs to
+func (p *Provider) Verify() (*provider.Identity, error) {
+	account, _, err := p.account.Get(context.TODO())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get account")
+	}
+	return &provider.Identity{
+		Provider: "do",
+		User:     account.Email,
+		Account:  account.UUID,
+		Region:   p.config.DefaultRegion,
+	}, nil
+}
+
+// Catalog lists the sizes, images, regions or Kubernetes versions DigitalOcean offers
+func (p *Provider) Catalog(kind string) ([]provider.CatalogEntry, error) {
+	switch kind {
+	case provider.CatalogSizes:
+		return ListDoSizes(p.sizes)
+	case provider.CatalogImages:
+		return ListDoImages(p.images)
+	case provider.CatalogRegions:
+		return ListDoRegions(p.regions)
+	case provider.CatalogVersions:
+		return ListDoKubernetesVersions(p.kubernetes)
+	}
+	return nil, provider.UnknownCatalog(kind)
+}
+
+// ListSSHKeys fetches the SSH keys of the account
+func (p *Provider) ListSSHKeys() ([]provider.SSHKey, error) {
+	keys, err := ListDoSSHKeys(p.keys)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]provider.SSHKey, 0, len(keys))
+	for _, key := range keys {
+		summaries = append(summaries, SSHKeySummary(key))
+	}
+	return summaries, nil
+}
+
+// AddSSHKey uploads a public key to the account
+func (p *Provider) AddSSHKey(key provider.PublicKey) (*provider.SSHKey, error) {
+	req := SSHKeyCreateRequest(key)
+	if p.opts.DryRun {
+		return &provider.SSHKey{Provider: "do", Name: key.Name, Fingerprint: key.FingerprintMD5(), PublicKey: key.Key}, p.opts.PrintRequest("Keys.Create", req)
+	}
+	created, err := CreateDoSSHKey(p.keys, req)
+	if err != nil {
+		return nil, err
+	}
+	summary := SSHKeySummary(*created)
+	return &summary, nil
+}
+
+// RemoveSSHKey deletes a key of the account by name, ID or fingerprint
+func (p *Provider) RemoveSSHKey(name string) error {
+	keys, err := p.ListSSHKeys()
+	if err != nil {
+		return err
+	}
+	key, err := provider.MatchSSHKey(keys, name)
+	if err != nil {
+		return err
+	}
+	id, _ := strconv.Atoi(key.ID)
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("Keys.DeleteByID", map[string]interface{}{"id": id, "name": key.Name})
+	}
+	return DeleteDoSSHKey(p.keys, id, key.Name)
+}
+
+// dropletKey finds the key a droplet is created with, uploading a local key the account doesn't have yet
+func (p *Provider) dropletKey(ref string) (*provider.SSHKey, error) {
+	keys, err := p.ListSSHKeys()
+	if err != nil {
+		return nil, err
+	}
+	key, local, err := provider.ChooseSSHKey(keys, ref)
+	if err != nil {
+		return nil, err
+	}
+	if local != nil {
+		return p.AddSSHKey(*local)
+	}
+	fmt.Fprintln(os.Stderr, "Using SSH Key", key.Name)
+	return key, nil
+}
+
+// dropletID prefers the ID recorded when Maker created the droplet, since names aren't unique on DO
+func (p *Provider) dropletID(name string) (int, error) {
+	if id, err := strconv.Atoi(state.LookupID("do", provider.KindVM, name)); err == nil {
+		return id, nil
+	}
+	return GetDoDroplet(p.droplets, name)
+}
+
+// clusterID prefers the ID recorded when Maker created the cluster
+func (p *Provider) clusterID(name string) (string, error) {
+	if id := state.LookupID("do", provider.KindCluster, name); id != "" {
+		return id, nil
+	}
+	return GetDoCluster(p.kubernetes, name)
+}
+
+// databaseID prefers the ID recorded when Maker created the database
+func (p *Provider) databaseID(name string) (string, error) {
+	if id := state.LookupID("do", provider.KindDB, name); id != "" {
+		return id, nil
+	}
+	return GetDoDatabase(p.databases, name)
+}
+
+// isNotFound checks for the 404 the API returns once an object is deleted
+func isNotFound(err error) bool {
+	if errResp, ok := errors.Cause(err).(*godo.ErrorResponse); ok && errResp.Response != nil {
+		return errResp.Response.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// CreateVM creates a droplet
+func (p *Provider) CreateVM(opts provider.VMOptions) (*provider.Resource, error) {
+	key, err := p.dropletKey(opts.SSHKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create droplet")
+	}
+	req := DropletCreateRequest(opts.Name, p.config.DefaultRegion, opts.Size, opts.Image, DropletSSHKey(*key))
+	req.UserData = opts.UserData
+	if p.opts.DryRun {
+		return provider.Planned("do", opts.Name, req.Region, req.Size), p.opts.PrintRequest("Droplets.Create", req)
+	}
+	droplet, err := CreateDoDroplet(p.droplets, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create droplet")
+	}
+	if p.opts.Wait {
+		err = p.opts.Waiter().Until("droplet "+opts.Name, DropletState(p.droplets, droplet.ID), []string{"active"}, []string{waiter.StateGone})
+		if err != nil {
+			return nil, err
+		}
+		return GetDropletStatus(p.droplets, droplet.ID)
+	}
+	resource := DropletResource(droplet)
+	return &resource, nil
+}
+
+// GetVM fetches the status of a droplet
+func (p *Provider) GetVM(name string) (*provider.Resource, error) {
+	dropletID, err := p.dropletID(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch droplet ID")
+	}
+	return GetDropletStatus(p.droplets, dropletID)
+}
+
+// ListVMs lists all droplets
+func (p *Provider) ListVMs() ([]provider.Resource, error) {
+	droplets, err := ListDoDroplets(p.droplets)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for i := range droplets {
+		resources = append(resources, DropletResource(&droplets[i]))
+	}
+	return resources, nil
+}
+
+// DeleteVM deletes a droplet
+func (p *Provider) DeleteVM(name string) error {
+	dropletID, err := p.dropletID(name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch droplet ID")
+	}
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("Droplets.Delete", map[string]interface{}{"id": dropletID, "name": name})
+	}
+	err = DeleteDoDroplet(p.droplets, dropletID, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete droplet")
+	}
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("droplet "+name, DropletState(p.droplets, dropletID), []string{waiter.StateGone}, nil)
+	}
+	return nil
+}
+
+// CreateCluster creates a DOKS cluster and fetches its kubeconfig
+func (p *Provider) CreateCluster(opts provider.ClusterOptions) (*provider.Resource, error) {
+	req := ClusterCreateRequest(opts.Name, p.config.DefaultRegion, opts.NodeSize, opts.Version, opts.NodeCount)
+	if p.opts.DryRun {
+		return provider.Planned("do", opts.Name, req.RegionSlug, opts.NodeSize), p.opts.PrintRequest("Kubernetes.Create", req)
+	}
+	clusterID, err := CreateDoCluster(p.kubernetes, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create cluster")
+	}
+	// the kubeconfig can't be fetched until the cluster is running
+	err = p.opts.Waiter().Until("cluster "+opts.Name, ClusterState(p.kubernetes, clusterID),
+		[]string{"running"}, []string{"error", "degraded", "invalid", waiter.StateGone})
+	if err != nil {
+		return nil, err
+	}
+	err = FetchDoKubeConfig(p.kubernetes, clusterID, opts.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch kubeconfig")
+	}
+	return GetClusterStatus(p.kubernetes, clusterID)
+}
+
+// GetCluster fetches the status of a DOKS cluster
+func (p *Provider) GetCluster(name string) (*provider.Resource, error) {
+	clusterID, err := p.clusterID(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch cluster ID")
+	}
+	return GetClusterStatus(p.kubernetes, clusterID)
+}
+
+// ListClusters lists all DOKS clusters
+func (p *Provider) ListClusters() ([]provider.Resource, error) {
+	clusters, err := ListDoClusters(p.kubernetes)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, cluster := range clusters {
+		resources = append(resources, ClusterResource(cluster))
+	}
+	return resources, nil
+}
+
+// FetchKubeconfig writes the kubeconfig of a DOKS cluster
+func (p *Provider) FetchKubeconfig(name string) error {
+	clusterID, err := p.clusterID(name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch cluster ID")
+	}
+	return FetchDoKubeConfig(p.kubernetes, clusterID, name)
+}
+
+// ClusterUpgrades lists the versions a DOKS cluster can be upgraded to
+func (p *Provider) ClusterUpgrades(name string) (*provider.ClusterVersions, error) {
+	clusterID, err := p.clusterID(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch cluster ID")
+	}
+	return GetDoClusterVersions(p.kubernetes, clusterID, name)
+}
+
+// UpgradeCluster upgrades a DOKS cluster and its node pools and waits for it to run the new version
+func (p *Provider) UpgradeCluster(name, version string) (*provider.Resource, error) {
+	clusterID, err := p.clusterID(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch cluster ID")
+	}
+	versions, err := GetDoClusterVersions(p.kubernetes, clusterID, name)
+	if err != nil {
+		return nil, err
+	}
+	version, err = versions.Resolve(version)
+	if err != nil {
+		return nil, err
+	}
+	if version == versions.Current {
+		// DigitalOcean upgrades the node pools with the control plane, so none are left behind
+		return GetClusterStatus(p.kubernetes, clusterID)
+	}
+	req := ClusterUpgradeRequest(version)
+	if p.opts.DryRun {
+		resource, err := GetClusterStatus(p.kubernetes, clusterID)
+		if err != nil {
+			return nil, err
+		}
+		resource.Status = provider.StatusPlanned
+		return resource, p.opts.PrintRequest("Kubernetes.Upgrade", req)
+	}
+	err = UpgradeDoCluster(p.kubernetes, clusterID, name, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to upgrade cluster")
+	}
+	err = p.opts.Waiter().Until("cluster "+name, ClusterUpgradeState(p.kubernetes, clusterID, version),
+		[]string{"running"}, []string{"error", "degraded", "invalid", waiter.StateGone})
+	if err != nil {
+		return nil, err
+	}
+	return GetClusterStatus(p.kubernetes, clusterID)
+}
+
+// DeleteCluster deletes a DOKS cluster
+func (p *Provider) DeleteCluster(name string) error {
+	clusterID, err := p.clusterID(name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch cluster ID")
+	}
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("Kubernetes.Delete", map[string]interface{}{"id": clusterID, "name": name})
+	}
+	err = DeleteDoCluster(p.kubernetes, clusterID, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete cluster")
+	}
+	kubeconfig.Forget("do", name)
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("cluster "+name, ClusterState(p.kubernetes, clusterID), []string{waiter.StateGone}, nil)
+	}
+	return nil
+}
+
+// AddNodePool adds a node pool to a DOKS cluster
+func (p *Provider) AddNodePool(opts provider.NodePoolOptions) (*provider.NodePool, error) {
+	clusterID, err := p.clusterID(opts.Cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch cluster ID")
+	}
+	req := NodePoolCreateRequest(opts)
+	if p.opts.DryRun {
+		return &provider.NodePool{Provider: "do", Cluster: opts.Cluster, Name: opts.Name, Size: opts.NodeSize, Status: provider.StatusPlanned},
+			p.opts.PrintRequest("Kubernetes.CreateNodePool", req)
+	}
+	pool, err := CreateDoNodePool(p.kubernetes, clusterID, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create node pool")
+	}
+	return p.waitNodePool(clusterID, opts.Cluster, pool)
+}
+
+// ListNodePools lists the node pools of a DOKS cluster
+func (p *Provider) ListNodePools(cluster string) ([]provider.NodePool, error) {
+	clusterID, err := p.clusterID(cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch cluster ID")
+	}
+	pools, err := ListDoNodePools(p.kubernetes, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	var summaries []provider.NodePool
+	for _, pool := range pools {
+		summaries = append(summaries, NodePoolSummary(pool, cluster))
+	}
+	return summaries, nil
+}
+
+// ScaleNodePool resizes a node pool of a DOKS cluster
+func (p *Provider) ScaleNodePool(cluster, name string, scale provider.NodeScale) (*provider.NodePool, error) {
+	clusterID, err := p.clusterID(cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch cluster ID")
+	}
+	pool, err := GetDoNodePool(p.kubernetes, clusterID, name)
+	if err != nil {
+		return nil, err
+	}
+	req, err := NodePoolUpdateRequest(pool, scale)
+	if err != nil {
+		return nil, err
+	}
+	if p.opts.DryRun {
+		summary := NodePoolSummary(pool, cluster)
+		summary.Status = provider.StatusPlanned
+		return &summary, p.opts.PrintRequest("Kubernetes.UpdateNodePool", req)
+	}
+	pool, err = UpdateDoNodePool(p.kubernetes, clusterID, pool.ID, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to scale node pool")
+	}
+	return p.waitNodePool(clusterID, cluster, pool)
+}
+
+// waitNodePool waits for the nodes of a pool with --wait and summarizes it
+func (p *Provider) waitNodePool(clusterID, cluster string, pool *godo.KubernetesNodePool) (*provider.NodePool, error) {
+	if p.opts.Wait {
+		err := p.opts.Waiter().Until("node pool "+pool.Name, NodePoolState(p.kubernetes, clusterID, pool.ID), []string{"running"}, []string{waiter.StateGone})
+		if err != nil {
+			return nil, err
+		}
+		pool, err = GetDoNodePool(p.kubernetes, clusterID, pool.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	summary := NodePoolSummary(pool, cluster)
+	return &summary, nil
+}
+
+// DeleteNodePool deletes a node pool of a DOKS cluster
+func (p *Provider) DeleteNodePool(cluster, name string) error {
+	clusterID, err := p.clusterID(cluster)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch cluster ID")
+	}
+	pool, err := GetDoNodePool(p.kubernetes, clusterID, name)
+	if err != nil {
+		return err
+	}
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("Kubernetes.DeleteNodePool", map[string]interface{}{"cluster_id": clusterID, "id": pool.ID, "name": name})
+	}
+	err = DeleteDoNodePool(p.kubernetes, clusterID, pool.ID, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete node pool")
+	}
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("node pool "+name, NodePoolState(p.kubernetes, clusterID, pool.ID), []string{waiter.StateGone}, nil)
+	}
+	return nil
+}
+
+// CreateBucket creates a Space
+func (p *Provider) CreateBucket(name string) (*provider.Resource, error) {
+	input := SpaceCreateInput(name)
+	if p.opts.DryRun {
+		return provider.Planned("do", name, p.config.SpacesDefaultEndpoint, ""), p.opts.PrintRequest("Spaces.CreateBucket", input)
+	}
+	err := CreateDoSpace(p.spaces, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create Space")
+	}
+	return &provider.Resource{
+		Name:     name,
+		ID:       name,
+		Provider: "do",
+		Region:   p.config.SpacesDefaultEndpoint,
+	}, nil
+}
+
+// GetBucket fetches info about a Space
+func (p *Provider) GetBucket(name string) (*provider.Resource, error) {
+	resource, err := GetDoSpaceInfo(p.spaces, name, p.config.SpacesDefaultEndpoint)
+	return resource, errors.Wrap(err, "Failed to fetch Space")
+}
+
+// ListBuckets lists all Spaces
+func (p *Provider) ListBuckets() ([]provider.Resource, error) {
+	spaces, err := ListDoSpaces(p.spaces)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, space := range spaces {
+		resources = append(resources, SpaceResource(space, p.config.SpacesDefaultEndpoint))
+	}
+	return resources, nil
+}
+
+// DeleteBucket empties and deletes a Space, confirming first that its objects go too
+func (p *Provider) DeleteBucket(name string) error {
+	inputs, err := SpaceObjectDeleteInputs(p.spaces, name)
+	if err != nil {
+		return err
+	}
+	if p.opts.DryRun {
+		for _, input := range inputs {
+			if err := p.opts.PrintRequest("Spaces.DeleteObject", input); err != nil {
+				return err
+			}
+		}
+		return p.opts.PrintRequest("Spaces.DeleteBucket", SpaceDeleteInput(name))
+	}
+	if len(inputs) > 0 {
+		if err := p.opts.ConfirmEmptyBucket(name, len(inputs)); err != nil {
+			return err
+		}
+		if err := DeleteSpaceObjects(p.spaces, name, inputs); err != nil {
+			return errors.Wrap(err, "Failed to delete Space")
+		}
+	}
+	err = DeleteDoSpace(p.spaces, SpaceDeleteInput(name))
+	return errors.Wrap(err, "Failed to delete Space")
+}
+
+// CreateDB creates a Postgres database cluster
+func (p *Provider) CreateDB(opts provider.DBOptions) (*provider.Resource, error) {
+	req := DatabaseCreateRequest(opts.Name, opts.Size, p.config.DefaultRegion)
+	if p.opts.DryRun {
+		return provider.Planned("do", opts.Name, req.Region, req.SizeSlug), p.opts.PrintRequest("Databases.Create", req)
+	}
+	database, err := CreateDoDatabase(p.databases, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create database")
+	}
+	if p.opts.Wait {
+		err = p.opts.Waiter().Until("database "+opts.Name, DatabaseState(p.databases, database.ID), []string{"online"}, []string{waiter.StateGone})
+		if err != nil {
+			return nil, err
+		}
+		return GetDatabaseStatus(p.databases, database.ID)
+	}
+	resource := DatabaseResource(database)
+	return &resource, nil
+}
+
+// GetDB fetches the status of a database cluster
+func (p *Provider) GetDB(name string) (*provider.Resource, error) {
+	databaseID, err := p.databaseID(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch database ID")
+	}
+	return GetDatabaseStatus(p.databases, databaseID)
+}
+
+// ListDBs lists all database clusters
+func (p *Provider) ListDBs() ([]provider.Resource, error) {
+	databases, err := ListDoDatabases(p.databases)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for i := range databases {
+		resources = append(resources, DatabaseResource(&databases[i]))
+	}
+	return resources, nil
+}
+
+// DeleteDB deletes a database cluster
+func (p *Provider) DeleteDB(name string) error {
+	databaseID, err := p.databaseID(name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch database ID")
+	}
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("Databases.Delete", map[string]interface{}{"id": databaseID, "name": name})
+	}
+	err = DeleteDoDatabase(p.databases, databaseID, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete database")
+	}
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("database "+name, DatabaseState(p.databases, databaseID), []string{waiter.StateGone}, nil)
+	}
+	return nil
+}
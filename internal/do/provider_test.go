@@ -0,0 +1,439 @@
+package do
+
+import (
+	"bytes"
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"maker/internal/state"
+	"maker/internal/utils"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/digitalocean/godo"
+)
+
+type fakes struct {
+	droplets   *fakeDroplets
+	kubernetes *fakeKubernetes
+	databases  *fakeDatabases
+	spaces     *fakeSpaces
+}
+
+// newTestProvider returns a provider backed by in-memory fakes, with the
+// state file and kubeconfigs written to a temporary directory
+func newTestProvider(t *testing.T, opts provider.Options) (*Provider, *fakes) {
+	dir := t.TempDir()
+	oldState, oldFolder, oldKubeconfig, oldSSH := state.StatePath, utils.ConfigFolderPath, kubeconfig.Path, provider.SSHFolder
+	state.StatePath = filepath.Join(dir, "state.json")
+	utils.ConfigFolderPath = dir
+	kubeconfig.Path = filepath.Join(dir, "kubeconfig")
+	// no local SSH keys unless a test writes them
+	provider.SSHFolder = dir
+	t.Cleanup(func() {
+		state.StatePath, utils.ConfigFolderPath, kubeconfig.Path, provider.SSHFolder = oldState, oldFolder, oldKubeconfig, oldSSH
+	})
+
+	if opts.PollInterval == 0 {
+		opts.PollInterval = time.Millisecond
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	f := &fakes{
+		droplets:   newFakeDroplets(),
+		kubernetes: newFakeKubernetes(),
+		databases:  newFakeDatabases(),
+		spaces:     newFakeSpaces(),
+	}
+	p := &Provider{
+		config:     &ConfigFile{DefaultRegion: "nyc1", SpacesDefaultEndpoint: "nyc3"},
+		droplets:   f.droplets,
+		kubernetes: f.kubernetes,
+		databases:  f.databases,
+		keys:       &fakeKeys{keys: fakeKey()},
+		account:    &fakeAccount{},
+		sizes:      &fakeSizes{},
+		images:     &fakeImages{},
+		regions:    &fakeRegions{},
+		spaces:     f.spaces,
+		opts:       opts,
+	}
+	return p, f
+}
+
+// assertContext checks whether the test kubeconfig has a context called name
+func assertContext(t *testing.T, name string, want bool) {
+	t.Helper()
+	config, err := kubeconfig.Load(kubeconfig.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, context := range config.Contexts {
+		found = found || context.Name == name
+	}
+	if found != want {
+		t.Errorf("context %s in kubeconfig = %v, want %v", name, found, want)
+	}
+}
+
+func TestVMLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+
+	resource, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "s-1vcpu-1gb", Image: "ubuntu-20-04-x64", UserData: "#cloud-config\n"})
+	if err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	if resource.Status != "active" {
+		t.Errorf("expected the create to wait until active, got %q", resource.Status)
+	}
+	if resource.Region != "nyc1" || resource.Size != "s-1vcpu-1gb" {
+		t.Errorf("unexpected region/size %q/%q", resource.Region, resource.Size)
+	}
+	if keys := f.droplets.created[0].SSHKeys; len(keys) != 1 || keys[0].ID != 7 {
+		t.Errorf("expected the only SSH key to be used, got %+v", keys)
+	}
+	if data := f.droplets.created[0].UserData; data != "#cloud-config\n" {
+		t.Errorf("expected the user data to be sent, got %q", data)
+	}
+
+	got, err := p.GetVM("web")
+	if err != nil {
+		t.Fatalf("GetVM: %v", err)
+	}
+	if got.ID != resource.ID {
+		t.Errorf("GetVM returned ID %s, want %s", got.ID, resource.ID)
+	}
+	list, err := p.ListVMs()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListVMs returned %v, %v", list, err)
+	}
+
+	if err := p.DeleteVM("web"); err != nil {
+		t.Fatalf("DeleteVM: %v", err)
+	}
+	if len(f.droplets.droplets) != 0 {
+		t.Errorf("droplet wasn't deleted")
+	}
+	if _, err := p.GetVM("web"); errs.ClassOf(err) != errs.NotFound {
+		t.Errorf("expected a not found error fetching a deleted droplet, got %v", err)
+	}
+}
+
+func TestDeleteVMPrefersStateID(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	// two droplets may share a name, the recorded ID decides which one is deleted
+	first, _ := p.CreateVM(provider.VMOptions{Name: "web", Size: "s", Image: "i"})
+	second, _ := p.CreateVM(provider.VMOptions{Name: "web", Size: "s", Image: "i"})
+
+	st, _ := state.Load()
+	st.Add(state.Entry{Provider: "do", Kind: provider.KindVM, Name: "web", ID: second.ID})
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.DeleteVM("web"); err != nil {
+		t.Fatalf("DeleteVM: %v", err)
+	}
+	id, _ := strconv.Atoi(first.ID)
+	if _, ok := f.droplets.droplets[id]; !ok || len(f.droplets.droplets) != 1 {
+		t.Errorf("expected only droplet %s to be deleted", second.ID)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	p, _ := newTestProvider(t, provider.Options{})
+	identity, err := p.Verify()
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	want := provider.Identity{Provider: "do", User: "lab@example.com", Account: "b6fr89dbf6d9156cace5f3c78dc9851d957381ef", Region: "nyc1"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+
+	p.account = &fakeAccount{err: unauthorized()}
+	if _, err := p.Verify(); errs.ClassOf(err) != errs.AuthFailed {
+		t.Errorf("expected an AuthFailed error for a bad token, got %v", err)
+	}
+}
+
+func TestCreateVMWithoutKeys(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	p.keys = &fakeKeys{}
+	if _, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "s", Image: "i"}); err == nil {
+		t.Fatal("expected an error when the account has no SSH keys")
+	}
+	if len(f.droplets.created) != 0 {
+		t.Errorf("no droplet should be created without a key")
+	}
+}
+
+func TestSSHKeys(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	path := filepath.Join(provider.SSHFolder, "id_ed25519.pub")
+	ioutil.WriteFile(path, []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIC9TtvMVg5+8hDazIXWO0tmh6sHaoEWEpTnnaLn2Fhr8 me@laptop\n"), 0600)
+
+	if _, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "s", Image: "i", SSHKey: path}); err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	keys, err := p.ListSSHKeys()
+	if err != nil || len(keys) != 2 || keys[1].Name != "me@laptop" {
+		t.Fatalf("expected the local key to be uploaded, got %+v, %v", keys, err)
+	}
+	if used := f.droplets.created[0].SSHKeys[0]; strconv.Itoa(used.ID) != keys[1].ID {
+		t.Errorf("expected the uploaded key to be used, got %+v", used)
+	}
+	// a second VM reuses the uploaded key
+	if _, err := p.CreateVM(provider.VMOptions{Name: "db", Size: "s", Image: "i", SSHKey: path}); err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	if keys, _ := p.ListSSHKeys(); len(keys) != 2 {
+		t.Errorf("expected the key to be uploaded once, got %+v", keys)
+	}
+
+	// with several keys and no --ssh-key nothing is prompted for
+	if _, err := p.CreateVM(provider.VMOptions{Name: "app", Size: "s", Image: "i"}); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error picking between several keys, got %v", err)
+	}
+	if err := p.RemoveSSHKey("me@laptop"); err != nil {
+		t.Fatalf("RemoveSSHKey: %v", err)
+	}
+	if err := p.RemoveSSHKey("me@laptop"); errs.ClassOf(err) != errs.NotFound {
+		t.Errorf("expected a not found error removing a removed key, got %v", err)
+	}
+}
+
+func TestDryRunSendsNothing(t *testing.T) {
+	var plan bytes.Buffer
+	p, f := newTestProvider(t, provider.Options{DryRun: true, Plan: &plan})
+
+	resource, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "s-1vcpu-1gb", Image: "ubuntu"})
+	if err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	if resource.Status != provider.StatusPlanned {
+		t.Errorf("expected a planned resource, got %q", resource.Status)
+	}
+	if _, err := p.CreateCluster(provider.ClusterOptions{Name: "k8s", NodeSize: "s-2vcpu-2gb", NodeCount: 2}); err != nil {
+		t.Fatalf("CreateCluster: %v", err)
+	}
+	if _, err := p.CreateBucket("assets"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if _, err := p.CreateDB(provider.DBOptions{Name: "pg", Size: "db-s-1vcpu-1gb"}); err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+
+	if len(f.droplets.created) != 0 || len(f.kubernetes.clusters) != 0 || len(f.spaces.buckets) != 0 || len(f.databases.databases) != 0 {
+		t.Errorf("dry run created objects")
+	}
+	for _, call := range []string{"# Droplets.Create", "# Kubernetes.Create", "# Spaces.CreateBucket", "# Databases.Create"} {
+		if !strings.Contains(plan.String(), call) {
+			t.Errorf("plan is missing %s:\n%s", call, plan.String())
+		}
+	}
+}
+
+func TestCreateClusterWritesKubeconfig(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+
+	resource, err := p.CreateCluster(provider.ClusterOptions{Name: "k8s", NodeSize: "s-2vcpu-2gb", NodeCount: 3, Version: "1.20"})
+	if err != nil {
+		t.Fatalf("CreateCluster: %v", err)
+	}
+	if resource.Status != string(godo.KubernetesClusterStatusRunning) {
+		t.Errorf("expected a running cluster, got %q", resource.Status)
+	}
+	if resource.Details["node_count"] != "3" {
+		t.Errorf("expected 3 nodes, got %q", resource.Details["node_count"])
+	}
+	assertContext(t, "maker-do-k8s", true)
+
+	if err := p.DeleteCluster("k8s"); err != nil {
+		t.Fatalf("DeleteCluster: %v", err)
+	}
+	if len(f.kubernetes.clusters) != 0 {
+		t.Errorf("cluster wasn't deleted")
+	}
+	assertContext(t, "maker-do-k8s", false)
+}
+
+func TestNodePoolLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+	if _, err := CreateDoCluster(f.kubernetes, ClusterCreateRequest("k8s", "nyc1", "s-2vcpu-2gb", "1.20", 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := p.AddNodePool(provider.NodePoolOptions{
+		Cluster:   "k8s",
+		Name:      "highmem",
+		NodeSize:  "m-2vcpu-16gb",
+		NodeScale: provider.NodeScale{MinNodes: 1, MaxNodes: 4},
+		Labels:    map[string]string{"workload": "test"},
+		Taints:    []provider.Taint{{Key: "dedicated", Value: "test", Effect: provider.TaintNoSchedule}},
+	})
+	if err != nil {
+		t.Fatalf("AddNodePool: %v", err)
+	}
+	if pool.Status != "running" || pool.NodeCount != 1 || !pool.Autoscaled() || pool.Taints[0].String() != "dedicated=test:NoSchedule" {
+		t.Errorf("unexpected node pool %+v", *pool)
+	}
+
+	pools, err := p.ListNodePools("k8s")
+	if err != nil || len(pools) != 2 {
+		t.Fatalf("ListNodePools returned %v, %v", pools, err)
+	}
+	resource, err := p.GetCluster("k8s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resource.Details["node_pools"] != "k8s-pool,highmem" || resource.Details["node_count"] != "3" {
+		t.Errorf("cluster summary doesn't cover every pool: %v", resource.Details)
+	}
+
+	// the update API can't change the maximum
+	if _, err := p.ScaleNodePool("k8s", "highmem", provider.NodeScale{MinNodes: 1, MaxNodes: 6}); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error changing the maximum, got %v", err)
+	}
+	pool, err = p.ScaleNodePool("k8s", "highmem", provider.NodeScale{NodeCount: 3})
+	if err != nil {
+		t.Fatalf("ScaleNodePool: %v", err)
+	}
+	if pool.NodeCount != 3 || pool.Autoscaled() {
+		t.Errorf("expected 3 fixed nodes, got %+v", *pool)
+	}
+
+	if err := p.DeleteNodePool("k8s", "highmem"); err != nil {
+		t.Fatalf("DeleteNodePool: %v", err)
+	}
+	if _, err := p.ScaleNodePool("k8s", "highmem", provider.NodeScale{NodeCount: 1}); errs.ClassOf(err) != errs.NotFound {
+		t.Errorf("expected a deleted pool to be NotFound, got %v", err)
+	}
+}
+
+func TestUpgradeCluster(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	if _, err := CreateDoCluster(f.kubernetes, ClusterCreateRequest("k8s", "nyc1", "s-2vcpu-2gb", "1.19.6-do.0", 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := p.ClusterUpgrades("k8s")
+	if err != nil {
+		t.Fatalf("ClusterUpgrades: %v", err)
+	}
+	if versions.Current != "1.19.6-do.0" || len(versions.Available) != 1 || versions.Available[0] != fakeUpgrade {
+		t.Errorf("unexpected versions %+v", *versions)
+	}
+	if _, err := p.UpgradeCluster("k8s", "1.21"); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error for an unavailable version, got %v", err)
+	}
+
+	resource, err := p.UpgradeCluster("k8s", "1.20")
+	if err != nil {
+		t.Fatalf("UpgradeCluster: %v", err)
+	}
+	if resource.Status != "running" || resource.Details["version"] != fakeUpgrade {
+		t.Errorf("expected a running cluster on %s, got %s %v", fakeUpgrade, resource.Status, resource.Details)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	p, _ := newTestProvider(t, provider.Options{})
+
+	// sizes and regions that can't be used and images without a slug are left out, every page of sizes is read
+	tests := []struct {
+		kind  string
+		slugs []string
+	}{
+		{provider.CatalogSizes, []string{"s-1vcpu-1gb", "s-4vcpu-8gb", "s-8vcpu-16gb"}},
+		{provider.CatalogImages, []string{"ubuntu-20-04-x64", "debian-10-x64"}},
+		{provider.CatalogRegions, []string{"nyc1"}},
+		{provider.CatalogVersions, []string{fakeUpgrade}},
+	}
+	for _, tt := range tests {
+		entries, err := p.Catalog(tt.kind)
+		if err != nil {
+			t.Fatalf("Catalog(%s): %v", tt.kind, err)
+		}
+		var slugs []string
+		for _, entry := range entries {
+			slugs = append(slugs, entry.Slug)
+		}
+		if strings.Join(slugs, ",") != strings.Join(tt.slugs, ",") {
+			t.Errorf("Catalog(%s) = %v, want %v", tt.kind, slugs, tt.slugs)
+		}
+	}
+
+	if _, err := p.Catalog("flavors"); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error for an unknown catalog, got %v", err)
+	}
+}
+
+func TestBucketLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+
+	if _, err := p.CreateBucket("assets"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	f.spaces.buckets["assets"] = []string{"a.txt", "b.txt"}
+
+	resource, err := p.GetBucket("assets")
+	if err != nil {
+		t.Fatalf("GetBucket: %v", err)
+	}
+	if resource.Details["object_count"] != "2" {
+		t.Errorf("expected 2 objects, got %q", resource.Details["object_count"])
+	}
+
+	var plan bytes.Buffer
+	p.opts.DryRun, p.opts.Plan = true, &plan
+	if err := p.DeleteBucket("assets"); err != nil {
+		t.Fatalf("DeleteBucket dry run: %v", err)
+	}
+	if strings.Count(plan.String(), "# Spaces.DeleteObject") != 2 {
+		t.Errorf("expected a delete per object in the plan:\n%s", plan.String())
+	}
+
+	// an empty Space is deleted without asking about its objects
+	f.spaces.buckets["assets"] = nil
+	p.opts.DryRun = false
+	if err := p.DeleteBucket("assets"); err != nil {
+		t.Fatalf("DeleteBucket: %v", err)
+	}
+	if _, ok := f.spaces.buckets["assets"]; ok {
+		t.Errorf("Space wasn't deleted")
+	}
+}
+
+func TestDBLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+
+	resource, err := p.CreateDB(provider.DBOptions{Name: "pg", Size: "db-s-1vcpu-1gb"})
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	if resource.Status != "online" {
+		t.Errorf("expected the create to wait until online, got %q", resource.Status)
+	}
+	if err := p.DeleteDB("pg"); err != nil {
+		t.Fatalf("DeleteDB: %v", err)
+	}
+	if len(f.databases.databases) != 0 {
+		t.Errorf("database wasn't deleted")
+	}
+}
+
+func TestResourcesFromSparseObjects(t *testing.T) {
+	// the API leaves out nested objects while they are being provisioned
+	DropletResource(&godo.Droplet{})
+	ClusterResource(&godo.KubernetesCluster{})
+	ClusterResource(&godo.KubernetesCluster{NodePools: []*godo.KubernetesNodePool{{Nodes: []*godo.KubernetesNode{{}}}}})
+	DatabaseResource(&godo.Database{})
+	SpaceResource(&s3.Bucket{}, "nyc3")
+}
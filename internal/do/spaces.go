@@ -2,13 +2,17 @@ package do
 
 import (
 	"fmt"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"os"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/pkg/errors"
 )
 
@@ -25,91 +29,118 @@ func CreateDoSpacesClient(spacesKey, spacesSecret, defaultRegion string) *s3.S3
 	return s3Client
 }
 
-// CreateDoSpace creats a Spaces bucket on DigitalOcean
-func CreateDoSpace(client *s3.S3, name string) error {
-	params := &s3.CreateBucketInput{
+// SpaceCreateInput builds the request to create a Space
+func SpaceCreateInput(name string) *s3.CreateBucketInput {
+	return &s3.CreateBucketInput{
 		Bucket: aws.String(name),
 	}
+}
 
+// CreateDoSpace creats a Spaces bucket on DigitalOcean
+func CreateDoSpace(client s3iface.S3API, params *s3.CreateBucketInput) error {
 	_, err := client.CreateBucket(params)
 	if err != nil {
-		return errors.Errorf("Failed to create Space:", err)
+		return errors.Wrap(err, "Failed to create Space")
 	}
-	fmt.Println("Space", name, "created")
+	fmt.Fprintln(os.Stderr, "Space", aws.StringValue(params.Bucket), "created")
 	return nil
 }
 
-// GetDoSpaceInfo may or may not get info about a space...
-func GetDoSpaceInfo(client *s3.S3, name string) error {
+// ListDoSpaces fetches all Spaces the access key can see
+func ListDoSpaces(client s3iface.S3API) ([]*s3.Bucket, error) {
 	spaces, err := client.ListBuckets(nil)
 	if err != nil {
-		return errors.Errorf("Failed to list spaces:", err)
+		return nil, errors.Wrap(err, "Failed to list spaces")
+	}
+	return spaces.Buckets, nil
+}
+
+// GetDoSpaceInfo fetches a Space and summarizes it along with its contents
+func GetDoSpaceInfo(client s3iface.S3API, name, region string) (*provider.Resource, error) {
+	spaces, err := ListDoSpaces(client)
+	if err != nil {
+		return nil, err
 	}
 
 	input := &s3.ListObjectsInput{Bucket: aws.String(name)}
 
 	objects, err := client.ListObjects(input)
 	if err != nil {
-		return errors.Errorf("Failed to fetch objects in space:", err)
+		return nil, errors.Wrap(err, "Failed to fetch objects in space")
 	}
 
-	for _, bucket := range spaces.Buckets {
-		if aws.StringValue(bucket.Name) == name {
-			fmt.Printf("Name: %s\nCreation Date: %s\n\n", aws.StringValue(bucket.Name), bucket.CreationDate.Format(time.UnixDate))
+	for _, space := range spaces {
+		if aws.StringValue(space.Name) == name {
+			resource := SpaceResource(space, region)
+			var keys []string
+			for _, obj := range objects.Contents {
+				keys = append(keys, aws.StringValue(obj.Key))
+			}
+			resource.Details = map[string]string{
+				"object_count": strconv.Itoa(len(keys)),
+				"objects":      strings.Join(keys, ","),
+			}
+			return &resource, nil
 		}
 	}
-	fmt.Println("Space contents:")
-	for _, obj := range objects.Contents {
-		fmt.Printf(" - %s\n", aws.StringValue(obj.Key))
+	return nil, errs.Errorf(errs.NotFound, "Could not find space with name %s", name)
+}
+
+// SpaceResource converts a Space into the common resource summary
+func SpaceResource(space *s3.Bucket, region string) provider.Resource {
+	name := aws.StringValue(space.Name)
+	return provider.Resource{
+		Name:     name,
+		ID:       name,
+		Provider: "do",
+		Region:   region,
+		Endpoint: "https://" + name + "." + region + ".digitaloceanspaces.com",
+		Created:  aws.TimeValue(space.CreationDate),
 	}
-	return nil
 }
 
-// DeleteSpaceObjects removes all objects in a space to prep for deletion
-func DeleteSpaceObjects(client *s3.S3, name string) error {
-	// confirm that deleteing space will delete all files first
-	var confirmation string
-	fmt.Printf("\nWARNING: To delete a Space, all objects in that space must be deleted!\n")
-	fmt.Print("Do you wish to continue? (Y/n): ")
-	fmt.Scanln(&confirmation)
-	confirmation = strings.ToLower(string(confirmation))
-	println()
-
-	if confirmation != "y" {
-		return errors.Errorf("Cannot proceed -- must delete files before deleting Space")
+// DeleteSpaceObjects removes the objects of a space to prep for deletion
+func DeleteSpaceObjects(client s3iface.S3API, name string, inputs []*s3.DeleteObjectInput) error {
+	for _, input := range inputs {
+		_, err := client.DeleteObject(input)
+		if err != nil {
+			return errors.Wrap(err, "Failed to remove objects in space")
+		}
 	}
-	// loop through all objects in bucket and delete first
+	fmt.Fprintln(os.Stderr, "All objects from", name, "deleted")
+	return nil
+}
+
+// SpaceObjectDeleteInputs builds the requests to delete every object in a Space
+func SpaceObjectDeleteInputs(client s3iface.S3API, name string) ([]*s3.DeleteObjectInput, error) {
 	listInput := &s3.ListObjectsInput{Bucket: aws.String(name)}
 	objects, err := client.ListObjects(listInput)
 	if err != nil {
-		return errors.Errorf("Failed to fetch objects in space:", err)
+		return nil, errors.Wrap(err, "Failed to fetch objects in space")
 	}
-
+	var inputs []*s3.DeleteObjectInput
 	for _, obj := range objects.Contents {
-		input := &s3.DeleteObjectInput{
+		inputs = append(inputs, &s3.DeleteObjectInput{
 			Bucket: aws.String(name),
 			Key:    aws.String(aws.StringValue(obj.Key)),
-		}
-
-		_, err := client.DeleteObject(input)
-		if err != nil {
-			return errors.Errorf("Failed to remove objects in space:", err)
-		}
+		})
 	}
-	fmt.Println("All objects from", name, "deleted")
-	return nil
+	return inputs, nil
 }
 
-// DeleteDoSpace deletes a Space bucket on DigitalOcean
-func DeleteDoSpace(client *s3.S3, name string) error {
-	deleteInput := &s3.DeleteBucketInput{
+// SpaceDeleteInput builds the request to delete a Space
+func SpaceDeleteInput(name string) *s3.DeleteBucketInput {
+	return &s3.DeleteBucketInput{
 		Bucket: aws.String(name),
 	}
+}
 
+// DeleteDoSpace deletes a Space bucket on DigitalOcean
+func DeleteDoSpace(client s3iface.S3API, deleteInput *s3.DeleteBucketInput) error {
 	_, err := client.DeleteBucket(deleteInput)
 	if err != nil {
-		return errors.Errorf("Failed to delete Space:", err)
+		return errors.Wrap(err, "Failed to delete Space")
 	}
-	fmt.Println("Space", name, "deleted")
+	fmt.Fprintln(os.Stderr, "Space", aws.StringValue(deleteInput.Bucket), "deleted")
 	return nil
 }
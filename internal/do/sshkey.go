@@ -0,0 +1,70 @@
+package do
+
+import (
+	"context"
+	"fmt"
+	"maker/internal/provider"
+	"os"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+)
+
+// ListDoSSHKeys fetches the SSH keys of the account
+func ListDoSSHKeys(keysService godo.KeysService) ([]godo.Key, error) {
+	opt := &godo.ListOptions{
+		Page:    1,
+		PerPage: 200,
+	}
+	keys, _, err := keysService.List(context.TODO(), opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list SSH keys")
+	}
+	return keys, nil
+}
+
+// SSHKeyCreateRequest builds the request to upload a public key to the account
+func SSHKeyCreateRequest(key provider.PublicKey) *godo.KeyCreateRequest {
+	return &godo.KeyCreateRequest{Name: key.Name, PublicKey: key.Key}
+}
+
+// CreateDoSSHKey uploads a public key to the account
+func CreateDoSSHKey(keysService godo.KeysService, req *godo.KeyCreateRequest) (*godo.Key, error) {
+	key, _, err := keysService.Create(context.TODO(), req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to upload SSH key")
+	}
+	fmt.Fprintln(os.Stderr, "SSH Key", req.Name, "uploaded")
+	return key, nil
+}
+
+// DeleteDoSSHKey removes a key from the account, droplets created with it keep it
+func DeleteDoSSHKey(keysService godo.KeysService, id int, name string) error {
+	_, err := keysService.DeleteByID(context.TODO(), id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete SSH key")
+	}
+	fmt.Fprintln(os.Stderr, "SSH Key", name, "deleted")
+	return nil
+}
+
+// SSHKeySummary converts a key into the common SSH key summary
+func SSHKeySummary(key godo.Key) provider.SSHKey {
+	return provider.SSHKey{
+		Provider:    "do",
+		Name:        key.Name,
+		ID:          strconv.Itoa(key.ID),
+		Fingerprint: key.Fingerprint,
+		PublicKey:   key.PublicKey,
+	}
+}
+
+// DropletSSHKey refers to a key in a droplet create request, by ID once it is uploaded and
+// by fingerprint in dry run mode where it isn't
+func DropletSSHKey(key provider.SSHKey) godo.DropletCreateSSHKey {
+	if id, err := strconv.Atoi(key.ID); err == nil {
+		return godo.DropletCreateSSHKey{ID: id}
+	}
+	return godo.DropletCreateSSHKey{Fingerprint: key.Fingerprint}
+}
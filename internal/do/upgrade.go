@@ -0,0 +1,65 @@
+package do
+
+import (
+	"context"
+	"fmt"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+)
+
+// GetDoClusterVersions fetches the version of a cluster and the versions it can be upgraded to
+func GetDoClusterVersions(kubernetesService godo.KubernetesService, id, name string) (*provider.ClusterVersions, error) {
+	cluster, _, err := kubernetesService.Get(context.TODO(), id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not fetch cluster")
+	}
+	upgrades, _, err := kubernetesService.GetUpgrades(context.TODO(), id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not fetch cluster upgrades")
+	}
+	versions := &provider.ClusterVersions{Provider: "do", Cluster: name, Current: cluster.VersionSlug, Available: []string{}}
+	for _, upgrade := range upgrades {
+		versions.Available = append(versions.Available, upgrade.Slug)
+	}
+	return versions, nil
+}
+
+// ClusterUpgradeRequest builds the request to upgrade a cluster, DigitalOcean upgrades
+// the control plane and then recycles the nodes of every pool on its own
+func ClusterUpgradeRequest(version string) *godo.KubernetesClusterUpgradeRequest {
+	return &godo.KubernetesClusterUpgradeRequest{VersionSlug: version}
+}
+
+// UpgradeDoCluster starts the upgrade of a cluster
+func UpgradeDoCluster(kubernetesService godo.KubernetesService, id, name string, req *godo.KubernetesClusterUpgradeRequest) error {
+	_, err := kubernetesService.Upgrade(context.TODO(), id, req)
+	if err != nil {
+		return errors.Wrap(err, "Upgrading cluster failed")
+	}
+	fmt.Fprintln(os.Stderr, "Cluster", name, "upgrading to", req.VersionSlug+"...")
+	return nil
+}
+
+// ClusterUpgradeState reports the state of a cluster being upgraded, it stays upgrading
+// until the cluster runs the new version so a poll made before the upgrade starts doesn't count
+func ClusterUpgradeState(kubernetesService godo.KubernetesService, id, version string) waiter.Condition {
+	state := ClusterState(kubernetesService, id)
+	return func() (string, error) {
+		current, err := state()
+		if err != nil || current != string(godo.KubernetesClusterStatusRunning) {
+			return current, err
+		}
+		cluster, _, err := kubernetesService.Get(context.TODO(), id)
+		if err != nil {
+			return "", err
+		}
+		if cluster.VersionSlug != version {
+			return string(godo.KubernetesClusterStatusUpgrading), nil
+		}
+		return current, nil
+	}
+}
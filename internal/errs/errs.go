@@ -0,0 +1,243 @@
+package errs
+
+import (
+	"fmt"
+	"maker/internal/waiter"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Class groups errors by what a caller can do about them
+type Class int
+
+// Classes of errors, each exits with its own code
+const (
+	Unknown Class = iota
+	Usage
+	NotFound
+	AlreadyExists
+	AuthFailed
+	QuotaExceeded
+	Timeout
+)
+
+var classNames = map[Class]string{
+	Unknown:       "unknown",
+	Usage:         "usage",
+	NotFound:      "not found",
+	AlreadyExists: "already exists",
+	AuthFailed:    "auth failed",
+	QuotaExceeded: "quota exceeded",
+	Timeout:       "timeout",
+}
+
+// String names the class in messages
+func (c Class) String() string {
+	return classNames[c]
+}
+
+// ExitCode is the process exit code for errors of this class
+func (c Class) ExitCode() int {
+	if c == Unknown {
+		return 1
+	}
+	return int(c) + 1
+}
+
+// Error is an error tagged with its class
+type Error struct {
+	Class Class
+	Err   error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Cause lets errors.Cause see through the class
+func (e *Error) Cause() error { return e.Err }
+
+// Unwrap lets errors.As see through the class
+func (e *Error) Unwrap() error { return e.Err }
+
+// New returns an error of the given class
+func New(class Class, message string) error {
+	return &Error{Class: class, Err: errors.New(message)}
+}
+
+// Errorf returns a formatted error of the given class
+func Errorf(class Class, format string, args ...interface{}) error {
+	return &Error{Class: class, Err: errors.Errorf(format, args...)}
+}
+
+// Wrap adds a message to err and tags it with the class found in its chain.
+// It returns nil if err is nil.
+func Wrap(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: ClassOf(err), Err: errors.Wrap(err, message)}
+}
+
+// WrapAs adds a message to err and tags it with class, whatever its chain says.
+// It returns nil if err is nil.
+func WrapAs(class Class, err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: class, Err: errors.Wrap(err, message)}
+}
+
+// Wrapf is Wrap with a formatted message
+func Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return Wrap(err, fmt.Sprintf(format, args...))
+}
+
+// ExitCode returns the process exit code for err, 0 when err is nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return ClassOf(err).ExitCode()
+}
+
+// ClassOf walks the chain of err and returns the first class it recognises,
+// either from an Error or from the error types of the provider SDKs
+func ClassOf(err error) Class {
+	for err != nil {
+		if class := classify(err); class != Unknown {
+			return class
+		}
+		err = errors.Unwrap(err)
+	}
+	return Unknown
+}
+
+// classify looks at a single error without unwrapping it
+func classify(err error) Class {
+	switch e := err.(type) {
+	case *Error:
+		return e.Class
+	case awserr.Error:
+		return awsClass(e)
+	case *godo.ErrorResponse:
+		if e.Response != nil {
+			return httpClass(e.Response.StatusCode, e.Message)
+		}
+	case *googleapi.Error:
+		for _, item := range e.Errors {
+			if strings.Contains(item.Reason, "quotaExceeded") || strings.Contains(item.Reason, "rateLimitExceeded") {
+				return QuotaExceeded
+			}
+		}
+		return httpClass(e.Code, e.Message)
+	}
+	if err == waiter.ErrTimeout {
+		return Timeout
+	}
+	if err == storage.ErrBucketNotExist || err == storage.ErrObjectNotExist {
+		return NotFound
+	}
+	if s, ok := status.FromError(err); ok {
+		return grpcClass(s.Code())
+	}
+	return Unknown
+}
+
+// awsCodes maps AWS error codes to classes, codes ending in NotFound, LimitExceeded
+// or AlreadyExists are matched by suffix in awsClass
+var awsCodes = map[string]Class{
+	"NoSuchBucket":                 NotFound,
+	"NoSuchEntity":                 NotFound,
+	"NoSuchKey":                    NotFound,
+	"ResourceNotFoundException":    NotFound,
+	"DBInstanceNotFound":           NotFound,
+	"BucketAlreadyExists":          AlreadyExists,
+	"BucketAlreadyOwnedByYou":      AlreadyExists,
+	"DBInstanceAlreadyExists":      AlreadyExists,
+	"EntityAlreadyExists":          AlreadyExists,
+	"ResourceInUseException":       AlreadyExists,
+	"AuthFailure":                  AuthFailed,
+	"AccessDenied":                 AuthFailed,
+	"AccessDeniedException":        AuthFailed,
+	"UnauthorizedOperation":        AuthFailed,
+	"UnrecognizedClientException":  AuthFailed,
+	"InvalidClientTokenId":         AuthFailed,
+	"InvalidAccessKeyId":           AuthFailed,
+	"SignatureDoesNotMatch":        AuthFailed,
+	"ExpiredToken":                 AuthFailed,
+	"ExpiredTokenException":        AuthFailed,
+	"NoCredentialProviders":        AuthFailed,
+	"Throttling":                   QuotaExceeded,
+	"ThrottlingException":          QuotaExceeded,
+	"RequestLimitExceeded":         QuotaExceeded,
+	"InsufficientInstanceCapacity": QuotaExceeded,
+}
+
+// awsClass maps an AWS error code to a class, falling back to its HTTP status
+func awsClass(err awserr.Error) Class {
+	code := err.Code()
+	if class, ok := awsCodes[code]; ok {
+		return class
+	}
+	switch {
+	case strings.HasSuffix(code, "NotFound"), strings.HasSuffix(code, "NotFoundFault"):
+		return NotFound
+	case strings.HasSuffix(code, "AlreadyExists"), strings.HasSuffix(code, "AlreadyExistsFault"), strings.HasSuffix(code, ".Duplicate"):
+		return AlreadyExists
+	case strings.HasSuffix(code, "LimitExceeded"), strings.HasSuffix(code, "QuotaExceeded"), strings.HasSuffix(code, "QuotaExceededFault"):
+		return QuotaExceeded
+	}
+	if failure, ok := err.(awserr.RequestFailure); ok {
+		return httpClass(failure.StatusCode(), failure.Message())
+	}
+	return Unknown
+}
+
+// httpClass maps the HTTP status of a failed API call to a class
+func httpClass(code int, message string) Class {
+	switch code {
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusConflict:
+		return AlreadyExists
+	case http.StatusUnprocessableEntity:
+		// DigitalOcean reports duplicate names as unprocessable
+		if strings.Contains(strings.ToLower(message), "already") {
+			return AlreadyExists
+		}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return AuthFailed
+	case http.StatusTooManyRequests:
+		return QuotaExceeded
+	}
+	return Unknown
+}
+
+// grpcClass maps the status code of a failed GKE call to a class
+func grpcClass(code codes.Code) Class {
+	switch code {
+	case codes.NotFound:
+		return NotFound
+	case codes.AlreadyExists:
+		return AlreadyExists
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return AuthFailed
+	case codes.ResourceExhausted:
+		return QuotaExceeded
+	case codes.DeadlineExceeded:
+		return Timeout
+	case codes.InvalidArgument:
+		return Usage
+	}
+	return Unknown
+}
@@ -0,0 +1,86 @@
+package errs
+
+import (
+	"maker/internal/waiter"
+	"net/http"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func godoError(code int, message string) error {
+	return &godo.ErrorResponse{Response: &http.Response{StatusCode: code}, Message: message}
+}
+
+func TestClassOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Class
+	}{
+		{"plain", errors.New("boom"), Unknown},
+		{"tagged", New(Usage, "bad flag"), Usage},
+		{"aws instance", awserr.New("InvalidInstanceID.NotFound", "no instance", nil), NotFound},
+		{"aws bucket", awserr.New("BucketAlreadyOwnedByYou", "yours", nil), AlreadyExists},
+		{"aws rds", awserr.New("DBInstanceAlreadyExists", "exists", nil), AlreadyExists},
+		{"aws auth", awserr.New("AuthFailure", "bad key", nil), AuthFailed},
+		{"aws quota", awserr.New("VcpuLimitExceeded", "too many", nil), QuotaExceeded},
+		{"aws status", awserr.NewRequestFailure(awserr.New("Odd", "denied", nil), http.StatusForbidden, "req"), AuthFailed},
+		{"do missing", godoError(http.StatusNotFound, "not found"), NotFound},
+		{"do duplicate", godoError(http.StatusUnprocessableEntity, "Name has already been taken"), AlreadyExists},
+		{"do invalid", godoError(http.StatusUnprocessableEntity, "size is invalid"), Unknown},
+		{"do token", godoError(http.StatusUnauthorized, "Unable to authenticate you"), AuthFailed},
+		{"do rate", godoError(http.StatusTooManyRequests, "slow down"), QuotaExceeded},
+		{"gcp conflict", &googleapi.Error{Code: http.StatusConflict}, AlreadyExists},
+		{"gcp quota", &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}, QuotaExceeded},
+		{"gcp denied", &googleapi.Error{Code: http.StatusForbidden}, AuthFailed},
+		{"gcs bucket", storage.ErrBucketNotExist, NotFound},
+		{"gke", status.Error(codes.AlreadyExists, "exists"), AlreadyExists},
+		{"timeout", errors.Wrap(waiter.ErrTimeout, "Gave up"), Timeout},
+	}
+	for _, test := range tests {
+		if got := ClassOf(test.err); got != test.want {
+			t.Errorf("%s: ClassOf(%v) = %s, want %s", test.name, test.err, got, test.want)
+		}
+	}
+}
+
+func TestWrapKeepsClass(t *testing.T) {
+	cause := awserr.New("BucketAlreadyExists", "taken", nil)
+	err := Wrap(errors.Wrap(cause, "Failed to create bucket assets"), "Failed to create bucket")
+	if ClassOf(err) != AlreadyExists {
+		t.Errorf("class lost through wrapping: %s", ClassOf(err))
+	}
+	if err.Error() != "Failed to create bucket: Failed to create bucket assets: BucketAlreadyExists: taken" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if errors.Cause(err) != cause {
+		t.Errorf("errors.Cause should reach the SDK error")
+	}
+	if Wrap(nil, "Failed") != nil || WrapAs(AuthFailed, nil, "Failed") != nil {
+		t.Errorf("wrapping nil should return nil")
+	}
+	if ClassOf(WrapAs(AuthFailed, errors.New("no such file"), "Failed to load config")) != AuthFailed {
+		t.Errorf("WrapAs should set the class")
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	if ExitCode(nil) != 0 || ExitCode(errors.New("boom")) != 1 {
+		t.Errorf("unexpected exit codes for nil and unclassed errors")
+	}
+	seen := map[int]Class{}
+	for _, class := range []Class{Unknown, Usage, NotFound, AlreadyExists, AuthFailed, QuotaExceeded, Timeout} {
+		code := class.ExitCode()
+		if other, ok := seen[code]; ok {
+			t.Errorf("%s and %s share exit code %d", class, other, code)
+		}
+		seen[code] = class
+	}
+}
@@ -0,0 +1,98 @@
+package gcp
+
+import (
+	"context"
+	"maker/internal/provider"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// imageProjects are the public image projects listed as images, named after their distribution
+var imageProjects = []struct {
+	project, distro string
+}{
+	{"debian-cloud", "Debian"},
+	{"ubuntu-os-cloud", "Ubuntu"},
+	{"centos-cloud", "CentOS"},
+	{"cos-cloud", "Container-Optimized OS"},
+}
+
+// ListGceMachineTypes fetches the machine types of a zone
+func ListGceMachineTypes(service ComputeAPI, project, zone string) ([]provider.CatalogEntry, error) {
+	machineTypes, err := service.ListMachineTypes(context.Background(), project, zone)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list machine types")
+	}
+	var entries []provider.CatalogEntry
+	for _, machineType := range machineTypes {
+		if machineType.Deprecated != nil {
+			continue
+		}
+		entries = append(entries, provider.CatalogEntry{
+			Slug:        machineType.Name,
+			Description: machineType.Description,
+			VCPUs:       int(machineType.GuestCpus),
+			MemoryMB:    int(machineType.MemoryMb),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Slug < entries[j].Slug })
+	return entries, nil
+}
+
+// ListGceImages fetches the current images of the public image projects, in the
+// 'project/name' format create vm expects
+func ListGceImages(service ComputeAPI) ([]provider.CatalogEntry, error) {
+	var entries []provider.CatalogEntry
+	for _, source := range imageProjects {
+		images, err := service.ListImages(context.Background(), source.project)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to list images of %s", source.project)
+		}
+		for _, image := range images {
+			// older builds of a family are deprecated once a new one is published
+			if image.Deprecated != nil {
+				continue
+			}
+			entries = append(entries, provider.CatalogEntry{
+				Slug:        source.project + "/" + image.Name,
+				Description: image.Description,
+				Distro:      source.distro,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// ListGceZones fetches the zones of the project that are up, GCP objects are placed in a zone rather than a region
+func ListGceZones(service ComputeAPI, project string) ([]provider.CatalogEntry, error) {
+	zones, err := service.ListZones(context.Background(), project)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list zones")
+	}
+	var entries []provider.CatalogEntry
+	for _, zone := range zones {
+		if zone.Status == "UP" {
+			entries = append(entries, provider.CatalogEntry{Slug: zone.Name, Description: LastPathSegment(zone.Region)})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Slug < entries[j].Slug })
+	return entries, nil
+}
+
+// ListGkeVersions fetches the versions new GKE clusters in a zone can run
+func ListGkeVersions(client ClusterManagerAPI, project, zone string) ([]provider.CatalogEntry, error) {
+	config, err := GetGkeServerConfig(client, project, zone)
+	if err != nil {
+		return nil, err
+	}
+	var entries []provider.CatalogEntry
+	for _, version := range config.ValidMasterVersions {
+		entry := provider.CatalogEntry{Slug: version}
+		if version == config.DefaultClusterVersion {
+			entry.Description = "default"
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,216 @@
+package gcp
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+	gax "github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/iterator"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+	containerpb "google.golang.org/genproto/googleapis/container/v1"
+)
+
+// ComputeAPI is the part of the Compute Engine API Maker uses
+type ComputeAPI interface {
+	InsertInstance(ctx context.Context, project, zone string, instance *compute.Instance) (*compute.Operation, error)
+	GetInstance(ctx context.Context, project, zone, name string) (*compute.Instance, error)
+	ListInstances(ctx context.Context, project string) ([]*compute.Instance, error)
+	DeleteInstance(ctx context.Context, project, zone, name string) (*compute.Operation, error)
+	GetZoneOperation(ctx context.Context, project, zone, operation string) (*compute.Operation, error)
+	GetProject(ctx context.Context, project string) (*compute.Project, error)
+	SetCommonInstanceMetadata(ctx context.Context, project string, md *compute.Metadata) (*compute.Operation, error)
+	ListMachineTypes(ctx context.Context, project, zone string) ([]*compute.MachineType, error)
+	ListImages(ctx context.Context, project string) ([]*compute.Image, error)
+	ListZones(ctx context.Context, project string) ([]*compute.Zone, error)
+}
+
+// ClusterManagerAPI is the part of the GKE cluster manager Maker uses, *container.ClusterManagerClient satisfies it
+type ClusterManagerAPI interface {
+	CreateCluster(ctx context.Context, req *containerpb.CreateClusterRequest, opts ...gax.CallOption) (*containerpb.Operation, error)
+	GetCluster(ctx context.Context, req *containerpb.GetClusterRequest, opts ...gax.CallOption) (*containerpb.Cluster, error)
+	ListClusters(ctx context.Context, req *containerpb.ListClustersRequest, opts ...gax.CallOption) (*containerpb.ListClustersResponse, error)
+	DeleteCluster(ctx context.Context, req *containerpb.DeleteClusterRequest, opts ...gax.CallOption) (*containerpb.Operation, error)
+	GetServerConfig(ctx context.Context, req *containerpb.GetServerConfigRequest, opts ...gax.CallOption) (*containerpb.ServerConfig, error)
+	UpdateMaster(ctx context.Context, req *containerpb.UpdateMasterRequest, opts ...gax.CallOption) (*containerpb.Operation, error)
+	UpdateNodePool(ctx context.Context, req *containerpb.UpdateNodePoolRequest, opts ...gax.CallOption) (*containerpb.Operation, error)
+	CreateNodePool(ctx context.Context, req *containerpb.CreateNodePoolRequest, opts ...gax.CallOption) (*containerpb.Operation, error)
+	GetNodePool(ctx context.Context, req *containerpb.GetNodePoolRequest, opts ...gax.CallOption) (*containerpb.NodePool, error)
+	ListNodePools(ctx context.Context, req *containerpb.ListNodePoolsRequest, opts ...gax.CallOption) (*containerpb.ListNodePoolsResponse, error)
+	SetNodePoolAutoscaling(ctx context.Context, req *containerpb.SetNodePoolAutoscalingRequest, opts ...gax.CallOption) (*containerpb.Operation, error)
+	SetNodePoolSize(ctx context.Context, req *containerpb.SetNodePoolSizeRequest, opts ...gax.CallOption) (*containerpb.Operation, error)
+	DeleteNodePool(ctx context.Context, req *containerpb.DeleteNodePoolRequest, opts ...gax.CallOption) (*containerpb.Operation, error)
+	GetOperation(ctx context.Context, req *containerpb.GetOperationRequest, opts ...gax.CallOption) (*containerpb.Operation, error)
+}
+
+// SQLAPI is the part of the Cloud SQL admin API Maker uses
+type SQLAPI interface {
+	InsertInstance(ctx context.Context, project string, instance *sqladmin.DatabaseInstance) (*sqladmin.Operation, error)
+	GetInstance(ctx context.Context, project, name string) (*sqladmin.DatabaseInstance, error)
+	ListInstances(ctx context.Context, project string) ([]*sqladmin.DatabaseInstance, error)
+	DeleteInstance(ctx context.Context, project, name string) (*sqladmin.Operation, error)
+	GetOperation(ctx context.Context, project, operation string) (*sqladmin.Operation, error)
+}
+
+// StorageAPI is the part of Cloud Storage Maker uses
+type StorageAPI interface {
+	CreateBucket(ctx context.Context, project, name string) error
+	BucketAttrs(ctx context.Context, name string) (*storage.BucketAttrs, error)
+	ListBuckets(ctx context.Context, project string) ([]*storage.BucketAttrs, error)
+	DeleteBucket(ctx context.Context, name string) error
+	ListObjects(ctx context.Context, bucket string) ([]string, error)
+	DeleteObject(ctx context.Context, bucket, object string) error
+}
+
+// computeService implements ComputeAPI with the Compute Engine REST client
+type computeService struct {
+	svc *compute.Service
+}
+
+func (c computeService) InsertInstance(ctx context.Context, project, zone string, instance *compute.Instance) (*compute.Operation, error) {
+	return c.svc.Instances.Insert(project, zone, instance).Context(ctx).Do()
+}
+
+func (c computeService) GetInstance(ctx context.Context, project, zone, name string) (*compute.Instance, error) {
+	return c.svc.Instances.Get(project, zone, name).Context(ctx).Do()
+}
+
+func (c computeService) ListInstances(ctx context.Context, project string) ([]*compute.Instance, error) {
+	var instances []*compute.Instance
+	err := c.svc.Instances.AggregatedList(project).Pages(ctx,
+		func(page *compute.InstanceAggregatedList) error {
+			for _, scope := range page.Items {
+				instances = append(instances, scope.Instances...)
+			}
+			return nil
+		})
+	return instances, err
+}
+
+func (c computeService) DeleteInstance(ctx context.Context, project, zone, name string) (*compute.Operation, error) {
+	return c.svc.Instances.Delete(project, zone, name).Context(ctx).Do()
+}
+
+func (c computeService) GetZoneOperation(ctx context.Context, project, zone, operation string) (*compute.Operation, error) {
+	return c.svc.ZoneOperations.Get(project, zone, operation).Context(ctx).Do()
+}
+
+func (c computeService) GetProject(ctx context.Context, project string) (*compute.Project, error) {
+	return c.svc.Projects.Get(project).Context(ctx).Do()
+}
+
+func (c computeService) SetCommonInstanceMetadata(ctx context.Context, project string, md *compute.Metadata) (*compute.Operation, error) {
+	return c.svc.Projects.SetCommonInstanceMetadata(project, md).Context(ctx).Do()
+}
+
+func (c computeService) ListMachineTypes(ctx context.Context, project, zone string) ([]*compute.MachineType, error) {
+	var machineTypes []*compute.MachineType
+	err := c.svc.MachineTypes.List(project, zone).Pages(ctx,
+		func(page *compute.MachineTypeList) error {
+			machineTypes = append(machineTypes, page.Items...)
+			return nil
+		})
+	return machineTypes, err
+}
+
+func (c computeService) ListImages(ctx context.Context, project string) ([]*compute.Image, error) {
+	var images []*compute.Image
+	err := c.svc.Images.List(project).Pages(ctx,
+		func(page *compute.ImageList) error {
+			images = append(images, page.Items...)
+			return nil
+		})
+	return images, err
+}
+
+func (c computeService) ListZones(ctx context.Context, project string) ([]*compute.Zone, error) {
+	var zones []*compute.Zone
+	err := c.svc.Zones.List(project).Pages(ctx,
+		func(page *compute.ZoneList) error {
+			zones = append(zones, page.Items...)
+			return nil
+		})
+	return zones, err
+}
+
+// sqlService implements SQLAPI with the Cloud SQL admin REST client
+type sqlService struct {
+	svc *sqladmin.Service
+}
+
+func (s sqlService) InsertInstance(ctx context.Context, project string, instance *sqladmin.DatabaseInstance) (*sqladmin.Operation, error) {
+	return s.svc.Instances.Insert(project, instance).Context(ctx).Do()
+}
+
+func (s sqlService) GetInstance(ctx context.Context, project, name string) (*sqladmin.DatabaseInstance, error) {
+	return s.svc.Instances.Get(project, name).Context(ctx).Do()
+}
+
+func (s sqlService) ListInstances(ctx context.Context, project string) ([]*sqladmin.DatabaseInstance, error) {
+	var instances []*sqladmin.DatabaseInstance
+	err := s.svc.Instances.List(project).Pages(ctx,
+		func(page *sqladmin.InstancesListResponse) error {
+			instances = append(instances, page.Items...)
+			return nil
+		})
+	return instances, err
+}
+
+func (s sqlService) DeleteInstance(ctx context.Context, project, name string) (*sqladmin.Operation, error) {
+	return s.svc.Instances.Delete(project, name).Context(ctx).Do()
+}
+
+func (s sqlService) GetOperation(ctx context.Context, project, operation string) (*sqladmin.Operation, error) {
+	return s.svc.Operations.Get(project, operation).Context(ctx).Do()
+}
+
+// storageClient implements StorageAPI with the Cloud Storage client
+type storageClient struct {
+	client *storage.Client
+}
+
+func (s storageClient) CreateBucket(ctx context.Context, project, name string) error {
+	return s.client.Bucket(name).Create(ctx, project, nil)
+}
+
+func (s storageClient) BucketAttrs(ctx context.Context, name string) (*storage.BucketAttrs, error) {
+	return s.client.Bucket(name).Attrs(ctx)
+}
+
+func (s storageClient) ListBuckets(ctx context.Context, project string) ([]*storage.BucketAttrs, error) {
+	var buckets []*storage.BucketAttrs
+	it := s.client.Buckets(ctx, project)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return buckets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, attrs)
+	}
+}
+
+func (s storageClient) DeleteBucket(ctx context.Context, name string) error {
+	return s.client.Bucket(name).Delete(ctx)
+}
+
+func (s storageClient) ListObjects(ctx context.Context, bucket string) ([]string, error) {
+	var objects []string
+	it := s.client.Bucket(bucket).Objects(ctx, nil)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, attrs.Name)
+	}
+}
+
+func (s storageClient) DeleteObject(ctx context.Context, bucket, object string) error {
+	return s.client.Bucket(bucket).Object(object).Delete(ctx)
+}
@@ -2,29 +2,31 @@ package gcp
 
 import (
 	"fmt"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
 
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 	sqladmin "google.golang.org/api/sqladmin/v1beta4"
 )
 
 // CreateSQLService creates a new client to interact with GCP
-func CreateSQLService(keyfile string) (*sqladmin.Service, error) {
+func CreateSQLService(tokens oauth2.TokenSource) (SQLAPI, error) {
 	ctx := context.Background()
-	sqlService, err := sqladmin.NewService(
-		ctx, option.WithCredentialsFile(keyfile))
+	svc, err := sqladmin.NewService(
+		ctx, option.WithTokenSource(tokens))
 	if err != nil {
-		return nil, errors.Errorf("Failed to create client: ", err)
+		return nil, errors.Wrap(err, "Failed to create client")
 	}
-	return sqlService, nil
+	return sqlService{svc: svc}, nil
 }
 
-// CreateSQLInstance creates a compute instance with provided specs
-func CreateSQLInstance(sqlService *sqladmin.Service, name, project, zone, machineType string) error {
-	ctx := context.Background()
-
-	db := &sqladmin.DatabaseInstance{
+// SQLInstanceRequest builds the Postgres instance to insert
+func SQLInstanceRequest(name, project, zone, machineType string) *sqladmin.DatabaseInstance {
+	return &sqladmin.DatabaseInstance{
 		ConnectionName:  name,
 		DatabaseVersion: "POSTGRES_12",
 		GceZone:         zone,
@@ -34,48 +36,89 @@ func CreateSQLInstance(sqlService *sqladmin.Service, name, project, zone, machin
 		RootPassword:    "cloudsqltemp",
 		Settings:        &sqladmin.Settings{Tier: machineType},
 	}
+}
 
-	_, err := sqlService.Instances.Insert(project, db).Context(ctx).Do()
+// CreateSQLInstance creates a compute instance with provided specs
+func CreateSQLInstance(sqlService SQLAPI, project string, db *sqladmin.DatabaseInstance) (*sqladmin.Operation, error) {
+	ctx := context.Background()
+	op, err := sqlService.InsertInstance(ctx, project, db)
 	if err != nil {
-		return errors.Errorf("Failed to create SQL Instance: ", err)
+		return nil, errors.Wrap(err, "Failed to create SQL Instance")
 	}
-	fmt.Printf("SQL Instance %s is being created\n", name)
-	return nil
+	fmt.Fprintf(os.Stderr, "SQL Instance %s is being created\n", db.Name)
+	return op, nil
 }
 
-// PrintSQLDbStatus outputs instance info
-func PrintSQLDbStatus(sqlService *sqladmin.Service, name, project, zone string) error {
+// SQLOperationState reports the status of an operation for waiting on.
+// Operations that finish with an error fail the wait.
+func SQLOperationState(sqlService SQLAPI, project, operation string) waiter.Condition {
+	return func() (string, error) {
+		ctx := context.Background()
+		op, err := sqlService.GetOperation(ctx, project, operation)
+		if err != nil {
+			return "", err
+		}
+		if op.Status == "DONE" && op.Error != nil && len(op.Error.Errors) > 0 {
+			return op.Status, waiter.Fail(errors.New(op.Error.Errors[0].Message))
+		}
+		return op.Status, nil
+	}
+}
+
+// ListSQLInstances fetches every Cloud SQL instance in the project
+func ListSQLInstances(sqlService SQLAPI, project string) ([]*sqladmin.DatabaseInstance, error) {
 	ctx := context.Background()
+	instances, err := sqlService.ListInstances(ctx, project)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list SQL Instances")
+	}
+	return instances, nil
+}
 
-	resp, err := sqlService.Instances.Get(project, name).Context(ctx).Do()
+// GetSQLDbStatus fetches a SQL instance and summarizes it
+func GetSQLDbStatus(sqlService SQLAPI, name, project string) (*provider.Resource, error) {
+	ctx := context.Background()
+
+	resp, err := sqlService.GetInstance(ctx, project, name)
 	if err != nil {
-		return errors.Errorf("Failed to retreive GCE Instance %s: ", name, err)
+		return nil, errors.Wrapf(err, "Failed to retreive SQL Instance %s", name)
+	}
+	resource := SQLResource(resp)
+	return &resource, nil
+}
+
+// SQLResource converts a SQL instance into the common resource summary
+func SQLResource(instance *sqladmin.DatabaseInstance) provider.Resource {
+	resource := provider.Resource{
+		Name:     instance.Name,
+		ID:       instance.ConnectionName,
+		Provider: "gcp",
+		Region:   instance.Region,
+		Status:   instance.State,
+		Endpoint: instance.ConnectionName,
+		Details: map[string]string{
+			"version":       instance.DatabaseVersion,
+			"instance_type": instance.InstanceType,
+			"zone":          instance.GceZone,
+		},
+	}
+	if instance.Settings != nil {
+		resource.Size = instance.Settings.Tier
+	}
+	for _, ip := range instance.IpAddresses {
+		resource.Addresses = append(resource.Addresses, ip.IpAddress)
 	}
-	fmt.Printf(
-		"Name: %s\nConnection Name: %s\nDB Version: %s\n\nMaster Name: %s\nInstance Type: %s\nTier: %s\n\nIP Address: %s\nProject: %s\nRegion: %s\nZone: %s\nState: %s\n",
-		resp.Name,
-		resp.ConnectionName,
-		resp.DatabaseVersion,
-		resp.MasterInstanceName,
-		resp.InstanceType,
-		resp.Settings.Tier,
-		resp.IpAddresses[0].IpAddress,
-		resp.Project,
-		resp.Region,
-		resp.GceZone,
-		resp.State,
-	)
-	return nil
+	return resource
 }
 
 // DeleteSQLInstance delets a droplet with the provided ID
-func DeleteSQLInstance(sqlService *sqladmin.Service, name, project, zone string) error {
+func DeleteSQLInstance(sqlService SQLAPI, name, project, zone string) (*sqladmin.Operation, error) {
 	ctx := context.Background()
 
-	_, err := sqlService.Instances.Delete(project, name).Context(ctx).Do()
+	op, err := sqlService.DeleteInstance(ctx, project, name)
 	if err != nil {
-		return errors.Errorf("Failed to delete SQL Instance %s: ", name, err)
+		return nil, errors.Wrapf(err, "Failed to delete SQL Instance %s", name)
 	}
-	fmt.Printf("SQL Instance %s has been deleted\n", name)
-	return nil
+	fmt.Fprintf(os.Stderr, "SQL Instance %s is being deleted\n", name)
+	return op, nil
 }
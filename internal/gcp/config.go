@@ -1,120 +1,202 @@
 package gcp
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"maker/internal/utils"
+	"maker/internal/errs"
+	"maker/internal/profile"
+	"maker/internal/provider"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/pkg/errors"
-	"github.com/spf13/viper"
+	"golang.org/x/oauth2/google"
 )
 
 // ConfigFile makes up the required settings in a GCP config file
 type ConfigFile struct {
 	DefaultRegion string
 	GcpProject    string
-	Keyfile       string
+	// Keyfile is a service account key file, empty to use Application Default Credentials
+	Keyfile string
+	// Impersonate is a service account to act as instead of the key file or ADC identity
+	Impersonate string
 }
 
 // ConfigName is the name of the config file used by Maker
 var ConfigName = "gcp_config"
 
-// ConfigPath is the full path to the ConfigFile
-var ConfigPath = filepath.Join(utils.ConfigFolderPath, ConfigName)
+// ConfigPath returns the full path to the config file of the active profile
+func ConfigPath() string {
+	return profile.Path(ConfigName)
+}
+
+// configSchema lists the settings in the config file
+var configSchema = provider.ConfigSchema{
+	Path:     ConfigPath,
+	Type:     "yaml",
+	Keys:     []string{"keyfile", "impersonate_service_account", "default_region", "gcp_project"},
+	Required: []string{"default_region", "gcp_project"},
+}
+
+// Environment variables checked for settings, the same ones the Google SDKs and gcloud use
+var (
+	KeyfileEnvs = []string{"GOOGLE_APPLICATION_CREDENTIALS"}
+	ProjectEnvs = []string{"GOOGLE_CLOUD_PROJECT", "CLOUDSDK_CORE_PROJECT"}
+	ZoneEnvs    = []string{"CLOUDSDK_COMPUTE_ZONE"}
+)
 
 // Configure sets path to the key file requried to auth with a service accoutn
-func Configure() error {
-	// check that .maker exists
-	_, err := os.Stat(utils.ConfigFolderPath)
-	if os.IsNotExist(err) {
-		err := os.Mkdir(utils.ConfigFolderPath, 0755)
-		if err != nil {
-			return errors.Wrapf(err, "Failed to create config directory %s:", utils.ConfigFolder)
+func Configure(opts provider.AuthOptions) error {
+	// check that the profile folder exists
+	if _, err := profile.EnsureDir(); err != nil {
+		return err
+	}
+
+	// everything needed came from flags and the environment, nothing to ask
+	if opts.NonInteractive || opts.CredentialChain || (opts.Token(KeyfileEnvs...) != "" && authZone(opts) != "") {
+		opts.NonInteractive = true
+		if err := CreateConfigFile(&ConfigFile{}, opts); err != nil {
+			return errors.Wrapf(err, "Failed to create config file %s", ConfigName)
 		}
+		fmt.Println("Config file generated at", ConfigPath())
+		return nil
 	}
 
 	// check if config exists to create, or to verify
-	_, err = os.Stat(ConfigPath)
+	_, err := os.Stat(ConfigPath())
 	if os.IsNotExist(err) {
 		config := &ConfigFile{}
-		err = CreateConfigFile(config)
+		err = CreateConfigFile(config, opts)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to create config file %s:", ConfigName)
+			return errors.Wrapf(err, "Failed to create config file %s", ConfigName)
 		}
-		fmt.Println("Config file generated at", ConfigPath)
+		fmt.Println("Config file generated at", ConfigPath())
 	}
-	ShowCurrentConfig()
-	return nil
+	return ShowCurrentConfig(opts)
+}
+
+// authZone returns the zone set with --region or in the environment
+func authZone(opts provider.AuthOptions) string {
+	if opts.Region != "" {
+		return opts.Region
+	}
+	return provider.Env(ZoneEnvs...)
 }
 
 // ShowCurrentConfig prints out the current config file
-func ShowCurrentConfig() error {
-	data, err := ioutil.ReadFile(ConfigPath)
+func ShowCurrentConfig(opts provider.AuthOptions) error {
+	data, err := ioutil.ReadFile(ConfigPath())
 	if err != nil {
-		return errors.Wrapf(err, "Failed to read file %s:", ConfigPath)
+		return errors.Wrapf(err, "Failed to read file %s", ConfigPath())
 	}
 	fmt.Printf("\nCurrent Config:\n\n%s", string(data))
 
 	// show config and confirm
-	var confirmation string
-	fmt.Printf("\nIs this info accurate? (Y/n): ")
-	fmt.Scanln(&confirmation)
-	confirmation = strings.ToLower(string(confirmation))
-	println()
-
-	if confirmation != "y" {
+	if !opts.Confirm("\nIs this info accurate? (Y/n): ") {
 		config := &ConfigFile{}
-		err = CreateConfigFile(config)
+		err = CreateConfigFile(config, opts)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to create config file %s:", ConfigName)
+			return errors.Wrapf(err, "Failed to create config file %s", ConfigName)
 		}
-		fmt.Println("Config file generated at", ConfigPath)
+		fmt.Println("Config file generated at", ConfigPath())
 	}
 	return nil
 }
 
-// CreateConfigFile makes the config file to use in all GCP commands
-func CreateConfigFile(config *ConfigFile) error {
-	// ask for default region
-	var region string
-	fmt.Print("Enter Default Compute Zone (ie: us-east1-b): ")
-	fmt.Scanln(&region)
-	config.DefaultRegion = string(region)
-	println()
-
-	// ask for default GCP Project
-	var project string
-	fmt.Print("Enter Target GCP Project: ")
-	fmt.Scanln(&project)
-	config.GcpProject = string(project)
-	println()
-
-	// ask for path to key json
-	var keyfile string
-	fmt.Println("GCP requires a Service Account Key file to make requests")
-	fmt.Println("See 'https://cloud.google.com/iam/docs/creating-managing-service-account-keys#iam-service-account-keys-create-console' for help")
-	fmt.Printf("\nEnter path to your key file (full path): ")
-	fmt.Scanln(&keyfile)
-	config.Keyfile = string(keyfile)
-	println()
-
-	viper.SetConfigType("yaml")
-	viper.Set("keyfile", config.Keyfile)
-	viper.Set("default_region", config.DefaultRegion)
-	viper.Set("gcp_project", config.GcpProject)
-	return viper.WriteConfigAs(ConfigPath)
+// CreateConfigFile makes the config file to use in all GCP commands, prompting for whatever
+// GOOGLE_APPLICATION_CREDENTIALS, --project and --region don't give. The project
+// defaults to the one the key file belongs to. With the credential chain no key file
+// is stored and Application Default Credentials are used instead.
+func CreateConfigFile(config *ConfigFile, opts provider.AuthOptions) error {
+	keyfile, project := "", ""
+	if opts.CredentialChain {
+		project = adcProject()
+	} else {
+		keyfile = opts.Token(KeyfileEnvs...)
+		if keyfile == "" && !opts.NonInteractive {
+			fmt.Println("GCP requires a Service Account Key file to make requests")
+			fmt.Println("See 'https://cloud.google.com/iam/docs/creating-managing-service-account-keys#iam-service-account-keys-create-console' for help")
+			fmt.Println("Or run 'gcloud auth application-default login' and 'maker auth --credential-chain' to skip the key file")
+		}
+		var err error
+		keyfile, err = opts.Ask("key file", "--token-env, GOOGLE_APPLICATION_CREDENTIALS or --credential-chain", "\nEnter path to your key file (full path): ", keyfile, false)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(keyfile); err != nil {
+			return errs.WrapAs(errs.Usage, err, "Invalid key file")
+		}
+		project = keyfileProject(keyfile)
+	}
+
+	if opts.Project != "" {
+		project = opts.Project
+	} else if env := provider.Env(ProjectEnvs...); env != "" {
+		project = env
+	}
+	project, err := opts.Ask("project", "--project or GOOGLE_CLOUD_PROJECT", "Enter Target GCP Project: ", project, false)
+	if err != nil {
+		return err
+	}
+	zone, err := opts.Ask("default zone", "--region or CLOUDSDK_COMPUTE_ZONE", "Enter Default Compute Zone (ie: us-east1-b): ", authZone(opts), false)
+	if err != nil {
+		return err
+	}
+	config.Keyfile = keyfile
+	config.Impersonate = opts.Impersonate
+	config.GcpProject = project
+	config.DefaultRegion = zone
+
+	return configSchema.Write(map[string]string{
+		"keyfile":                     config.Keyfile,
+		"impersonate_service_account": config.Impersonate,
+		"default_region":              config.DefaultRegion,
+		"gcp_project":                 config.GcpProject,
+	})
+}
+
+// adcProject returns the project of the Application Default Credentials, empty if there is none
+func adcProject() string {
+	creds, err := google.FindDefaultCredentials(context.Background(), cloudPlatformScope)
+	if err != nil {
+		return ""
+	}
+	return creds.ProjectID
+}
+
+// serviceAccountKey holds the fields Maker reads from a service account key file
+type serviceAccountKey struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+}
+
+// readKeyfile parses a service account key file, fields are empty if it can't be read
+func readKeyfile(keyfile string) serviceAccountKey {
+	var key serviceAccountKey
+	data, err := ioutil.ReadFile(keyfile)
+	if err == nil {
+		json.Unmarshal(data, &key)
+	}
+	return key
+}
+
+// keyfileProject returns the project a service account key file belongs to, empty if it can't be read
+func keyfileProject(keyfile string) string {
+	return readKeyfile(keyfile).ProjectID
 }
 
-// LoadConfig parses the viper config file and loads into a struct
-func LoadConfig() (string, string, string, error) {
-	viper.SetConfigFile(ConfigPath)
-	viper.SetConfigType("yaml")
-	err := viper.ReadInConfig()
+// LoadConfig parses the config file and loads into a struct
+func LoadConfig() (*ConfigFile, error) {
+	v, err := configSchema.Read()
 	if err != nil {
-		return "", "", "", errors.Errorf("Error reading config file %s:", ConfigPath, err)
+		return nil, err
 	}
-	return viper.GetString("keyfile"), viper.GetString("default_region"), viper.GetString("gcp_project"), nil
+	return &ConfigFile{
+		DefaultRegion: v.GetString("default_region"),
+		GcpProject:    v.GetString("gcp_project"),
+		Keyfile:       v.GetString("keyfile"),
+		Impersonate:   v.GetString("impersonate_service_account"),
+	}, nil
 }
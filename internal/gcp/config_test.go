@@ -0,0 +1,97 @@
+package gcp
+
+import (
+	"context"
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/profile"
+	"maker/internal/provider"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useTempConfig points the config file at a temporary directory
+func useTempConfig(t *testing.T) string {
+	dir := t.TempDir()
+	oldFolder, oldProfile := utils.ConfigFolderPath, profile.Name
+	utils.ConfigFolderPath, profile.Name = dir, ""
+	t.Cleanup(func() {
+		utils.ConfigFolderPath, profile.Name = oldFolder, oldProfile
+	})
+	setenv(t, profile.EnvVar, "")
+	for _, name := range append(append(KeyfileEnvs, ProjectEnvs...), ZoneEnvs...) {
+		setenv(t, name, "")
+	}
+	return dir
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestConfigFromKeyfile(t *testing.T) {
+	dir := useTempConfig(t)
+	keyfile := filepath.Join(dir, "key.json")
+	ioutil.WriteFile(keyfile, []byte(`{"type": "service_account", "project_id": "lab-123", "client_email": "builder@lab-123.iam.gserviceaccount.com"}`), 0600)
+	setenv(t, "GOOGLE_APPLICATION_CREDENTIALS", keyfile)
+
+	if err := Configure(provider.AuthOptions{NonInteractive: true, Region: "us-east1-b"}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	want := ConfigFile{DefaultRegion: "us-east1-b", GcpProject: "lab-123", Keyfile: keyfile}
+	if *config != want {
+		t.Errorf("config = %+v, want %+v", *config, want)
+	}
+
+	creds := Credentials{Keyfile: config.Keyfile}
+	if creds.ServiceAccount() != "builder@lab-123.iam.gserviceaccount.com" {
+		t.Errorf("service account should come from the key file, got %q", creds.ServiceAccount())
+	}
+	creds.Impersonate = "deployer@lab-123.iam.gserviceaccount.com"
+	if creds.ServiceAccount() != creds.Impersonate {
+		t.Errorf("impersonated account should win, got %q", creds.ServiceAccount())
+	}
+}
+
+func TestConfigCredentialChain(t *testing.T) {
+	useTempConfig(t)
+	opts := provider.AuthOptions{
+		CredentialChain: true,
+		Project:         "lab",
+		Region:          "us-east1-b",
+		Impersonate:     "deployer@lab.iam.gserviceaccount.com",
+	}
+	if err := Configure(opts); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	want := ConfigFile{DefaultRegion: "us-east1-b", GcpProject: "lab", Impersonate: opts.Impersonate}
+	if *config != want {
+		t.Errorf("config = %+v, want %+v", *config, want)
+	}
+}
+
+func TestTokenSourceMissingKeyfile(t *testing.T) {
+	dir := useTempConfig(t)
+	creds := Credentials{Keyfile: filepath.Join(dir, "missing.json")}
+	if _, err := creds.TokenSource(context.Background()); errs.ClassOf(err) != errs.AuthFailed {
+		t.Errorf("expected an AuthFailed error, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"maker/internal/errs"
+
+	credentials "cloud.google.com/go/iam/credentials/apiv1"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	credentialspb "google.golang.org/genproto/googleapis/iam/credentials/v1"
+)
+
+// cloudPlatformScope is the OAuth scope requested for every GCP API
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Credentials picks how Maker authenticates to GCP
+type Credentials struct {
+	// Keyfile is a service account key file, Application Default Credentials are used when empty
+	Keyfile string
+	// Impersonate is a service account to act as, its tokens are minted with the key file or ADC
+	Impersonate string
+}
+
+// TokenSource returns the access tokens every client is created with
+func (c Credentials) TokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	base, err := c.base(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.Impersonate == "" {
+		return base.TokenSource, nil
+	}
+	client, err := credentials.NewIamCredentialsClient(ctx, option.WithTokenSource(base.TokenSource))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create IAM credentials client")
+	}
+	return oauth2.ReuseTokenSource(nil, impersonatedTokens{ctx: ctx, client: client, target: c.Impersonate}), nil
+}
+
+// base returns the credentials of the key file, or the Application Default Credentials
+func (c Credentials) base(ctx context.Context) (*google.Credentials, error) {
+	if c.Keyfile != "" {
+		data, err := ioutil.ReadFile(c.Keyfile)
+		if err != nil {
+			return nil, errs.WrapAs(errs.AuthFailed, err, "Failed to read key file")
+		}
+		creds, err := google.CredentialsFromJSON(ctx, data, cloudPlatformScope)
+		if err != nil {
+			return nil, errs.WrapAs(errs.AuthFailed, err, "Invalid key file "+c.Keyfile)
+		}
+		return creds, nil
+	}
+	creds, err := google.FindDefaultCredentials(ctx, cloudPlatformScope)
+	if err != nil {
+		return nil, errs.WrapAs(errs.AuthFailed, err, "Failed to find Application Default Credentials -- run 'gcloud auth application-default login'")
+	}
+	return creds, nil
+}
+
+// ServiceAccount returns the email of the service account the credentials act as,
+// empty for the user credentials of 'gcloud auth application-default login'
+func (c Credentials) ServiceAccount() string {
+	if c.Impersonate != "" {
+		return c.Impersonate
+	}
+	if c.Keyfile != "" {
+		return readKeyfile(c.Keyfile).ClientEmail
+	}
+	creds, err := google.FindDefaultCredentials(context.Background(), cloudPlatformScope)
+	if err != nil {
+		return ""
+	}
+	var key serviceAccountKey
+	json.Unmarshal(creds.JSON, &key)
+	return key.ClientEmail
+}
+
+// impersonatedTokens mints access tokens for a service account through the IAM credentials API
+type impersonatedTokens struct {
+	ctx    context.Context
+	client *credentials.IamCredentialsClient
+	target string
+}
+
+func (t impersonatedTokens) Token() (*oauth2.Token, error) {
+	resp, err := t.client.GenerateAccessToken(t.ctx, &credentialspb.GenerateAccessTokenRequest{
+		Name:  "projects/-/serviceAccounts/" + t.target,
+		Scope: []string{cloudPlatformScope},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to impersonate %s", t.target)
+	}
+	return &oauth2.Token{AccessToken: resp.AccessToken, Expiry: resp.ExpireTime.AsTime()}, nil
+}
@@ -0,0 +1,453 @@
+package gcp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	gax "github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+	containerpb "google.golang.org/genproto/googleapis/container/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// notFound is the error the REST APIs return for objects that don't exist
+func notFound() error {
+	return &googleapi.Error{Code: http.StatusNotFound, Message: "not found"}
+}
+
+// fakeCompute keeps instances in memory. Operations are done on the second Get.
+type fakeCompute struct {
+	instances  map[string]*compute.Instance
+	operations map[string]*compute.Operation
+	inserted   []*compute.Instance
+	metadata   *compute.Metadata
+}
+
+func newFakeCompute() *fakeCompute {
+	return &fakeCompute{instances: map[string]*compute.Instance{}, operations: map[string]*compute.Operation{}}
+}
+
+func (f *fakeCompute) operation() *compute.Operation {
+	op := &compute.Operation{Name: "operation-" + strconv.Itoa(len(f.operations)+1), Status: "RUNNING"}
+	f.operations[op.Name] = op
+	return op
+}
+
+func (f *fakeCompute) InsertInstance(ctx context.Context, project, zone string, instance *compute.Instance) (*compute.Operation, error) {
+	f.inserted = append(f.inserted, instance)
+	created := *instance
+	created.Id = uint64(len(f.inserted))
+	created.Zone = "projects/" + project + "/zones/" + zone
+	created.Status = "RUNNING"
+	f.instances[instance.Name] = &created
+	op := f.operation()
+	op.TargetId = created.Id
+	return op, nil
+}
+
+func (f *fakeCompute) GetInstance(ctx context.Context, project, zone, name string) (*compute.Instance, error) {
+	instance, ok := f.instances[name]
+	if !ok {
+		return nil, notFound()
+	}
+	return instance, nil
+}
+
+func (f *fakeCompute) ListInstances(ctx context.Context, project string) ([]*compute.Instance, error) {
+	var instances []*compute.Instance
+	for _, instance := range f.instances {
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func (f *fakeCompute) DeleteInstance(ctx context.Context, project, zone, name string) (*compute.Operation, error) {
+	if _, ok := f.instances[name]; !ok {
+		return nil, notFound()
+	}
+	delete(f.instances, name)
+	return f.operation(), nil
+}
+
+func (f *fakeCompute) GetZoneOperation(ctx context.Context, project, zone, operation string) (*compute.Operation, error) {
+	op, ok := f.operations[operation]
+	if !ok {
+		return nil, notFound()
+	}
+	copied := *op
+	op.Status = "DONE"
+	return &copied, nil
+}
+
+func (f *fakeCompute) GetProject(ctx context.Context, project string) (*compute.Project, error) {
+	if project != "lab" {
+		return nil, notFound()
+	}
+	return &compute.Project{Name: project, Id: 1234, CommonInstanceMetadata: f.metadata}, nil
+}
+
+func (f *fakeCompute) SetCommonInstanceMetadata(ctx context.Context, project string, md *compute.Metadata) (*compute.Operation, error) {
+	if f.metadata != nil && md.Fingerprint != f.metadata.Fingerprint {
+		return nil, &googleapi.Error{Code: http.StatusPreconditionFailed, Message: "fingerprint mismatch"}
+	}
+	updated := *md
+	updated.Fingerprint = "fingerprint-" + strconv.Itoa(len(f.operations)+1)
+	f.metadata = &updated
+	return f.operation(), nil
+}
+
+func (f *fakeCompute) ListMachineTypes(ctx context.Context, project, zone string) ([]*compute.MachineType, error) {
+	return []*compute.MachineType{
+		{Name: "e2-standard-2", GuestCpus: 2, MemoryMb: 8192},
+		{Name: "e2-micro", GuestCpus: 2, MemoryMb: 1024},
+		{Name: "n1-retired", GuestCpus: 1, MemoryMb: 3840, Deprecated: &compute.DeprecationStatus{State: "OBSOLETE"}},
+	}, nil
+}
+
+func (f *fakeCompute) ListImages(ctx context.Context, project string) ([]*compute.Image, error) {
+	if project != "debian-cloud" {
+		return nil, nil
+	}
+	return []*compute.Image{
+		{Name: "debian-10-buster-v20210122", Deprecated: &compute.DeprecationStatus{State: "DEPRECATED"}},
+		{Name: "debian-10-buster-v20210217"},
+	}, nil
+}
+
+func (f *fakeCompute) ListZones(ctx context.Context, project string) ([]*compute.Zone, error) {
+	return []*compute.Zone{
+		{Name: "us-east1-c", Region: "https://www.googleapis.com/compute/v1/projects/lab/regions/us-east1", Status: "UP"},
+		{Name: "us-east1-b", Region: "https://www.googleapis.com/compute/v1/projects/lab/regions/us-east1", Status: "UP"},
+		{Name: "us-east1-a", Status: "DOWN"},
+	}, nil
+}
+
+// fakeVersions are the versions GKE offers, new clusters run the oldest
+var fakeVersions = []string{"1.19.8-gke.1600", "1.20.8-gke.900", "1.21.1-gke.100"}
+
+// fakeClusterManager keeps clusters in memory. New clusters are running with an endpoint on the second Get.
+// Upgrades leave the cluster and pool RUNNING and only apply once their operation is polled, like GKE.
+type fakeClusterManager struct {
+	clusters   map[string]*containerpb.Cluster
+	operations map[string]*containerpb.Operation
+	done       map[string]func()
+}
+
+func newFakeClusterManager() *fakeClusterManager {
+	return &fakeClusterManager{clusters: map[string]*containerpb.Cluster{}, operations: map[string]*containerpb.Operation{}, done: map[string]func(){}}
+}
+
+// operation starts an operation on a cluster, done is applied when it is polled the first time
+func (f *fakeClusterManager) operation(kind containerpb.Operation_Type, cluster string, done func()) *containerpb.Operation {
+	op := &containerpb.Operation{Name: "operation-" + strconv.Itoa(len(f.operations)+1), OperationType: kind, TargetLink: cluster, Status: containerpb.Operation_RUNNING}
+	f.operations[op.Name] = op
+	f.done[op.Name] = done
+	return op
+}
+
+// busy refuses a new operation while another upgrade of the cluster is running
+func (f *fakeClusterManager) busy(cluster string) error {
+	for _, op := range f.operations {
+		if op.TargetLink == cluster && op.Status != containerpb.Operation_DONE {
+			return status.Error(codes.FailedPrecondition, "operation already in progress")
+		}
+	}
+	return nil
+}
+
+func (f *fakeClusterManager) GetOperation(ctx context.Context, req *containerpb.GetOperationRequest, opts ...gax.CallOption) (*containerpb.Operation, error) {
+	op, ok := f.operations[LastPathSegment(req.Name)]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "operation not found")
+	}
+	copied := proto.Clone(op).(*containerpb.Operation)
+	if op.Status != containerpb.Operation_DONE {
+		op.Status = containerpb.Operation_DONE
+		f.done[op.Name]()
+	}
+	return copied, nil
+}
+
+func (f *fakeClusterManager) CreateCluster(ctx context.Context, req *containerpb.CreateClusterRequest, opts ...gax.CallOption) (*containerpb.Operation, error) {
+	cluster := proto.Clone(req.Cluster).(*containerpb.Cluster)
+	cluster.Location = LastPathSegment(req.Parent)
+	cluster.Status = containerpb.Cluster_PROVISIONING
+	cluster.CurrentMasterVersion = fakeVersions[0]
+	for _, pool := range cluster.NodePools {
+		pool.Version = fakeVersions[0]
+	}
+	cluster.CreateTime = time.Now().Format(time.RFC3339)
+	f.clusters[cluster.Name] = cluster
+	return &containerpb.Operation{Name: "create-" + cluster.Name}, nil
+}
+
+func (f *fakeClusterManager) GetCluster(ctx context.Context, req *containerpb.GetClusterRequest, opts ...gax.CallOption) (*containerpb.Cluster, error) {
+	cluster, ok := f.clusters[LastPathSegment(req.Name)]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "cluster not found")
+	}
+	copied := proto.Clone(cluster).(*containerpb.Cluster)
+	cluster.Status = containerpb.Cluster_RUNNING
+	cluster.Endpoint = "10.0.0.1"
+	cluster.MasterAuth = &containerpb.MasterAuth{ClusterCaCertificate: "Y2VydA=="}
+	for _, pool := range cluster.NodePools {
+		if pool.Status == containerpb.NodePool_STATUS_UNSPECIFIED {
+			pool.Status = containerpb.NodePool_RUNNING
+		}
+	}
+	return copied, nil
+}
+
+func (f *fakeClusterManager) ListClusters(ctx context.Context, req *containerpb.ListClustersRequest, opts ...gax.CallOption) (*containerpb.ListClustersResponse, error) {
+	resp := &containerpb.ListClustersResponse{}
+	for _, cluster := range f.clusters {
+		resp.Clusters = append(resp.Clusters, cluster)
+	}
+	return resp, nil
+}
+
+func (f *fakeClusterManager) DeleteCluster(ctx context.Context, req *containerpb.DeleteClusterRequest, opts ...gax.CallOption) (*containerpb.Operation, error) {
+	name := LastPathSegment(req.Name)
+	if _, ok := f.clusters[name]; !ok {
+		return nil, status.Error(codes.NotFound, "cluster not found")
+	}
+	delete(f.clusters, name)
+	return &containerpb.Operation{Name: "delete-" + name}, nil
+}
+
+func (f *fakeClusterManager) GetServerConfig(ctx context.Context, req *containerpb.GetServerConfigRequest, opts ...gax.CallOption) (*containerpb.ServerConfig, error) {
+	return &containerpb.ServerConfig{ValidMasterVersions: fakeVersions, ValidNodeVersions: fakeVersions}, nil
+}
+
+func (f *fakeClusterManager) UpdateMaster(ctx context.Context, req *containerpb.UpdateMasterRequest, opts ...gax.CallOption) (*containerpb.Operation, error) {
+	cluster, ok := f.clusters[LastPathSegment(req.Name)]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "cluster not found")
+	}
+	if err := f.busy(cluster.Name); err != nil {
+		return nil, err
+	}
+	return f.operation(containerpb.Operation_UPGRADE_MASTER, cluster.Name, func() {
+		cluster.CurrentMasterVersion = req.MasterVersion
+	}), nil
+}
+
+// pool finds a node pool by its resource name
+func (f *fakeClusterManager) pool(path string) (*containerpb.Cluster, int, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) != 8 {
+		return nil, 0, status.Error(codes.InvalidArgument, "bad node pool name "+path)
+	}
+	cluster, ok := f.clusters[parts[5]]
+	if !ok {
+		return nil, 0, status.Error(codes.NotFound, "cluster not found")
+	}
+	for i, pool := range cluster.NodePools {
+		if pool.Name == parts[7] {
+			return cluster, i, nil
+		}
+	}
+	return nil, 0, status.Error(codes.NotFound, "node pool not found")
+}
+
+// update starts an operation on a node pool, refusing it like GKE while another is running
+func (f *fakeClusterManager) update(path string, change func(*containerpb.NodePool)) (*containerpb.Operation, error) {
+	cluster, i, err := f.pool(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := cluster.NodePools[i]
+	if pool.Status != containerpb.NodePool_RUNNING {
+		return nil, status.Error(codes.FailedPrecondition, "operation already in progress")
+	}
+	if err := f.busy(cluster.Name); err != nil {
+		return nil, err
+	}
+	change(pool)
+	pool.Status = containerpb.NodePool_RECONCILING
+	return &containerpb.Operation{Name: "update-" + pool.Name}, nil
+}
+
+func (f *fakeClusterManager) CreateNodePool(ctx context.Context, req *containerpb.CreateNodePoolRequest, opts ...gax.CallOption) (*containerpb.Operation, error) {
+	cluster, ok := f.clusters[LastPathSegment(req.Parent)]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "cluster not found")
+	}
+	pool := proto.Clone(req.NodePool).(*containerpb.NodePool)
+	pool.Status = containerpb.NodePool_PROVISIONING
+	cluster.NodePools = append(cluster.NodePools, pool)
+	return &containerpb.Operation{Name: "create-" + pool.Name}, nil
+}
+
+func (f *fakeClusterManager) GetNodePool(ctx context.Context, req *containerpb.GetNodePoolRequest, opts ...gax.CallOption) (*containerpb.NodePool, error) {
+	cluster, i, err := f.pool(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	pool := cluster.NodePools[i]
+	copied := proto.Clone(pool).(*containerpb.NodePool)
+	pool.Status = containerpb.NodePool_RUNNING
+	return copied, nil
+}
+
+func (f *fakeClusterManager) ListNodePools(ctx context.Context, req *containerpb.ListNodePoolsRequest, opts ...gax.CallOption) (*containerpb.ListNodePoolsResponse, error) {
+	cluster, ok := f.clusters[LastPathSegment(req.Parent)]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "cluster not found")
+	}
+	return &containerpb.ListNodePoolsResponse{NodePools: cluster.NodePools}, nil
+}
+
+func (f *fakeClusterManager) SetNodePoolAutoscaling(ctx context.Context, req *containerpb.SetNodePoolAutoscalingRequest, opts ...gax.CallOption) (*containerpb.Operation, error) {
+	return f.update(req.Name, func(pool *containerpb.NodePool) {
+		pool.Autoscaling = req.Autoscaling
+	})
+}
+
+func (f *fakeClusterManager) SetNodePoolSize(ctx context.Context, req *containerpb.SetNodePoolSizeRequest, opts ...gax.CallOption) (*containerpb.Operation, error) {
+	return f.update(req.Name, func(pool *containerpb.NodePool) {
+		pool.InitialNodeCount = req.NodeCount
+	})
+}
+
+func (f *fakeClusterManager) UpdateNodePool(ctx context.Context, req *containerpb.UpdateNodePoolRequest, opts ...gax.CallOption) (*containerpb.Operation, error) {
+	cluster, i, err := f.pool(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.busy(cluster.Name); err != nil {
+		return nil, err
+	}
+	pool := cluster.NodePools[i]
+	return f.operation(containerpb.Operation_UPGRADE_NODES, cluster.Name, func() {
+		pool.Version = req.NodeVersion
+	}), nil
+}
+
+func (f *fakeClusterManager) DeleteNodePool(ctx context.Context, req *containerpb.DeleteNodePoolRequest, opts ...gax.CallOption) (*containerpb.Operation, error) {
+	cluster, i, err := f.pool(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	cluster.NodePools = append(cluster.NodePools[:i], cluster.NodePools[i+1:]...)
+	return &containerpb.Operation{Name: "delete-" + req.Name}, nil
+}
+
+// fakeSQL keeps Cloud SQL instances in memory. Operations are done on the second Get.
+type fakeSQL struct {
+	instances  map[string]*sqladmin.DatabaseInstance
+	operations map[string]*sqladmin.Operation
+}
+
+func newFakeSQL() *fakeSQL {
+	return &fakeSQL{instances: map[string]*sqladmin.DatabaseInstance{}, operations: map[string]*sqladmin.Operation{}}
+}
+
+func (f *fakeSQL) operation() *sqladmin.Operation {
+	op := &sqladmin.Operation{Name: "operation-" + strconv.Itoa(len(f.operations)+1), Status: "RUNNING"}
+	f.operations[op.Name] = op
+	return op
+}
+
+func (f *fakeSQL) InsertInstance(ctx context.Context, project string, instance *sqladmin.DatabaseInstance) (*sqladmin.Operation, error) {
+	created := *instance
+	created.State = "RUNNABLE"
+	created.Region = ZoneRegion(instance.GceZone)
+	f.instances[instance.Name] = &created
+	return f.operation(), nil
+}
+
+func (f *fakeSQL) GetInstance(ctx context.Context, project, name string) (*sqladmin.DatabaseInstance, error) {
+	instance, ok := f.instances[name]
+	if !ok {
+		return nil, notFound()
+	}
+	return instance, nil
+}
+
+func (f *fakeSQL) ListInstances(ctx context.Context, project string) ([]*sqladmin.DatabaseInstance, error) {
+	var instances []*sqladmin.DatabaseInstance
+	for _, instance := range f.instances {
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func (f *fakeSQL) DeleteInstance(ctx context.Context, project, name string) (*sqladmin.Operation, error) {
+	if _, ok := f.instances[name]; !ok {
+		return nil, notFound()
+	}
+	delete(f.instances, name)
+	return f.operation(), nil
+}
+
+func (f *fakeSQL) GetOperation(ctx context.Context, project, operation string) (*sqladmin.Operation, error) {
+	op, ok := f.operations[operation]
+	if !ok {
+		return nil, notFound()
+	}
+	copied := *op
+	op.Status = "DONE"
+	return &copied, nil
+}
+
+// fakeStorage keeps buckets and the names of their objects in memory
+type fakeStorage struct {
+	buckets map[string][]string
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{buckets: map[string][]string{}}
+}
+
+func (f *fakeStorage) CreateBucket(ctx context.Context, project, name string) error {
+	f.buckets[name] = nil
+	return nil
+}
+
+func (f *fakeStorage) BucketAttrs(ctx context.Context, name string) (*storage.BucketAttrs, error) {
+	if _, ok := f.buckets[name]; !ok {
+		return nil, storage.ErrBucketNotExist
+	}
+	return &storage.BucketAttrs{Name: name, Location: "US", StorageClass: "STANDARD"}, nil
+}
+
+func (f *fakeStorage) ListBuckets(ctx context.Context, project string) ([]*storage.BucketAttrs, error) {
+	var buckets []*storage.BucketAttrs
+	for name := range f.buckets {
+		attrs, _ := f.BucketAttrs(ctx, name)
+		buckets = append(buckets, attrs)
+	}
+	return buckets, nil
+}
+
+func (f *fakeStorage) DeleteBucket(ctx context.Context, name string) error {
+	if _, ok := f.buckets[name]; !ok {
+		return storage.ErrBucketNotExist
+	}
+	delete(f.buckets, name)
+	return nil
+}
+
+func (f *fakeStorage) ListObjects(ctx context.Context, bucket string) ([]string, error) {
+	return f.buckets[bucket], nil
+}
+
+func (f *fakeStorage) DeleteObject(ctx context.Context, bucket, object string) error {
+	var kept []string
+	for _, name := range f.buckets[bucket] {
+		if name != object {
+			kept = append(kept, name)
+		}
+	}
+	f.buckets[bucket] = kept
+	return nil
+}
@@ -2,39 +2,45 @@ package gcp
 
 import (
 	"fmt"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/option"
 )
 
 // CreateGceService creates a new client to interact with GCP
-func CreateGceService(keyfile string) (*compute.Service, error) {
+func CreateGceService(tokens oauth2.TokenSource) (ComputeAPI, error) {
 	ctx := context.Background()
-	computeService, err := compute.NewService(
-		ctx, option.WithCredentialsFile(keyfile))
+	svc, err := compute.NewService(
+		ctx, option.WithTokenSource(tokens))
 	if err != nil {
-		return nil, errors.Errorf("Failed to create client: ", err)
+		return nil, errors.Wrap(err, "Failed to create client")
 	}
-	return computeService, nil
+	return computeService{svc: svc}, nil
 }
 
-// CreateGceInstance creates a compute instance with provided specs
-func CreateGceInstance(computeService *compute.Service, name, project, zone, machineType, diskImage string) error {
+// GceInstanceRequest builds the instance to insert with provided specs
+func GceInstanceRequest(name, project, zone, machineType, diskImage string) (*compute.Instance, error) {
 	// make sure image is provided in proper format for GCP
 	imageCheck := strings.Contains(diskImage, "/")
 	if !imageCheck {
 		err := errors.New("\nExample: 'ubuntu-os-cloud/ubuntu-1604-xenial-v20210119'")
 		err = errors.Wrapf(err, "\nImage name must be provided in 'project/name' format")
-		return err
+		return nil, err
 	}
-	s := strings.Split(diskImage, "/")
+	// 'project/family/name' picks the newest image of a family, as the image aliases do
+	s := strings.SplitN(diskImage, "/", 2)
 	imageProject, imageName := s[0], s[1]
 	sourceImage := fmt.Sprintf("projects/%s/global/images/%s", imageProject, imageName)
 
-	ctx := context.Background()
 	image := compute.AttachedDiskInitializeParams{SourceImage: sourceImage}
 	machineTypePath := fmt.Sprintf("projects/%s/zones/%s/machineTypes/%s", project, zone, machineType)
 	publicNic := &compute.AccessConfig{
@@ -56,50 +62,131 @@ func CreateGceInstance(computeService *compute.Service, name, project, zone, mac
 	}
 	disks := []*compute.AttachedDisk{disk}
 
-	rb := &compute.Instance{
+	return &compute.Instance{
 		MachineType:       machineTypePath,
 		Disks:             disks,
 		Name:              name,
 		NetworkInterfaces: nics,
+	}, nil
+}
+
+// GceInstanceMetadata passes the user data and SSH keys of a VM in instance metadata. Images with
+// cloud-init read a cloud-config from user-data, scripts go to startup-script which every image runs.
+func GceInstanceMetadata(opts provider.VMOptions, sshKeys []string) *compute.Metadata {
+	md := WithSSHKeys(nil, sshKeys)
+	if opts.UserData != "" {
+		key := "startup-script"
+		if opts.CloudConfig() {
+			key = "user-data"
+		}
+		md.Items = append(md.Items, &compute.MetadataItems{Key: key, Value: &opts.UserData})
+	}
+	if len(md.Items) == 0 {
+		return nil
 	}
+	return md
+}
 
-	_, err := computeService.Instances.Insert(project, zone, rb).Context(ctx).Do()
+// CreateGceInstance creates a compute instance from the provided request
+func CreateGceInstance(computeService ComputeAPI, project, zone string, rb *compute.Instance) (*compute.Operation, error) {
+	ctx := context.Background()
+	op, err := computeService.InsertInstance(ctx, project, zone, rb)
 	if err != nil {
-		return errors.Errorf("Failed to create GCE Instance: ", err)
+		return nil, errors.Wrap(err, "Failed to create GCE Instance")
 	}
-	fmt.Printf("Compute Instance %s is being created\n", name)
-	return nil
+	fmt.Fprintf(os.Stderr, "Compute Instance %s is being created\n", rb.Name)
+	return op, nil
 }
 
-// PrintInstanceStatus outputs instance info
-func PrintInstanceStatus(computeService *compute.Service, name, project, zone string) error {
+// ListGceInstances fetches the compute instances in every zone of the project
+func ListGceInstances(computeService ComputeAPI, project string) ([]*compute.Instance, error) {
 	ctx := context.Background()
+	instances, err := computeService.ListInstances(ctx, project)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list GCE Instances")
+	}
+	return instances, nil
+}
+
+// ZoneRegion returns the region a zone belongs to, ie us-east1-b is in us-east1
+func ZoneRegion(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
 
-	resp, err := computeService.Instances.Get(project, zone, name).Context(ctx).Do()
+// LastPathSegment trims a GCP resource URL down to the resource name
+func LastPathSegment(url string) string {
+	s := strings.Split(url, "/")
+	return s[len(s)-1]
+}
+
+// GetInstanceStatus fetches an instance and summarizes it
+func GetInstanceStatus(computeService ComputeAPI, name, project, zone string) (*provider.Resource, error) {
+	ctx := context.Background()
+
+	resp, err := computeService.GetInstance(ctx, project, zone, name)
 	if err != nil {
-		return errors.Errorf("Failed to retreive GCE Instance %s: ", name, err)
-	}
-	os := strings.Split(resp.Disks[0].Licenses[0], "/")
-	currentZone := strings.Split(resp.Zone, "/")
-	fmt.Printf(
-		"Name: %s\nDistribution: %s\n\nPublic IP: %s\nZone: %s\nStatus: %s\n",
-		resp.Name,
-		os[len(os)-1],
-		resp.NetworkInterfaces[0].AccessConfigs[0].NatIP,
-		currentZone[len(currentZone)-1],
-		resp.Status,
-	)
-	return nil
+		return nil, errors.Wrapf(err, "Failed to retreive GCE Instance %s", name)
+	}
+	resource := GceResource(resp)
+	return &resource, nil
+}
+
+// GceResource converts a compute instance into the common resource summary
+func GceResource(instance *compute.Instance) provider.Resource {
+	created, _ := time.Parse(time.RFC3339, instance.CreationTimestamp)
+	resource := provider.Resource{
+		Name:     instance.Name,
+		ID:       strconv.FormatUint(instance.Id, 10),
+		Provider: "gcp",
+		Region:   LastPathSegment(instance.Zone),
+		Size:     LastPathSegment(instance.MachineType),
+		Status:   instance.Status,
+		Created:  created,
+		Details:  map[string]string{},
+	}
+	if len(instance.Disks) > 0 && len(instance.Disks[0].Licenses) > 0 {
+		resource.Details["distribution"] = LastPathSegment(instance.Disks[0].Licenses[0])
+	}
+	for _, nic := range instance.NetworkInterfaces {
+		for _, accessConfig := range nic.AccessConfigs {
+			if accessConfig.NatIP != "" {
+				resource.Addresses = append(resource.Addresses, accessConfig.NatIP)
+			}
+		}
+		if nic.NetworkIP != "" {
+			resource.Addresses = append(resource.Addresses, nic.NetworkIP)
+		}
+	}
+	return resource
 }
 
 // DeleteGceInstance delets a droplet with the provided ID
-func DeleteGceInstance(computeService *compute.Service, name, project, zone string) error {
+func DeleteGceInstance(computeService ComputeAPI, name, project, zone string) (*compute.Operation, error) {
 	ctx := context.Background()
 
-	_, err := computeService.Instances.Delete(project, zone, name).Context(ctx).Do()
+	op, err := computeService.DeleteInstance(ctx, project, zone, name)
 	if err != nil {
-		return errors.Errorf("Failed to delete GCE Instance %s: ", name, err)
+		return nil, errors.Wrapf(err, "Failed to delete GCE Instance %s", name)
+	}
+	fmt.Fprintf(os.Stderr, "Instance %s is being deleted\n", name)
+	return op, nil
+}
+
+// GceOperationState reports the status of a zone operation for waiting on.
+// Operations that finish with an error fail the wait.
+func GceOperationState(computeService ComputeAPI, project, zone, operation string) waiter.Condition {
+	return func() (string, error) {
+		ctx := context.Background()
+		op, err := computeService.GetZoneOperation(ctx, project, zone, operation)
+		if err != nil {
+			return "", err
+		}
+		if op.Status == "DONE" && op.Error != nil && len(op.Error.Errors) > 0 {
+			return op.Status, waiter.Fail(errors.New(op.Error.Errors[0].Message))
+		}
+		return op.Status, nil
 	}
-	fmt.Printf("Instance %s has been deleted\n", name)
-	return nil
 }
@@ -3,35 +3,39 @@ package gcp
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
-	"maker/internal/utils"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	container "cloud.google.com/go/container/apiv1"
-	credentials "cloud.google.com/go/iam/credentials/apiv1"
 	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 	containerpb "google.golang.org/genproto/googleapis/container/v1"
-	credentialspb "google.golang.org/genproto/googleapis/iam/credentials/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // CreateGkeClient returns a client needed to interact with GKE
-func CreateGkeClient(keyfile string) (*container.ClusterManagerClient, error) {
+func CreateGkeClient(tokens oauth2.TokenSource) (ClusterManagerAPI, error) {
 	ctx := context.Background()
 	client, err := container.NewClusterManagerClient(
-		ctx, option.WithCredentialsFile(keyfile))
+		ctx, option.WithTokenSource(tokens))
 
 	if err != nil {
-		return nil, errors.Errorf("Failed to create cluster manager client:", err)
+		return nil, errors.Wrap(err, "Failed to create cluster manager client")
 	}
 	return client, nil
 }
 
-// CreateGkeCluster creates an GKE cluster with provided specs
-func CreateGkeCluster(client *container.ClusterManagerClient, name, project, zone, nodeSize string, nodeCount int) error {
-	ctx := context.Background()
+// GkeClusterRequest builds the request to create a GKE cluster with provided specs
+func GkeClusterRequest(name, project, zone, nodeSize string, nodeCount int) *containerpb.CreateClusterRequest {
 	parent := "projects/" + project + "/locations/" + zone
-	req := &containerpb.CreateClusterRequest{
+	return &containerpb.CreateClusterRequest{
 		Cluster: &containerpb.Cluster{
 			Name:        name,
 			Description: "cluster created by Maker",
@@ -45,76 +49,60 @@ func CreateGkeCluster(client *container.ClusterManagerClient, name, project, zon
 		},
 		Parent: parent,
 	}
+}
+
+// CreateGkeCluster creates an GKE cluster from the provided request
+func CreateGkeCluster(client ClusterManagerAPI, req *containerpb.CreateClusterRequest) error {
+	ctx := context.Background()
 	_, err := client.CreateCluster(ctx, req)
 	if err != nil {
-		return errors.Errorf("Failed to create cluster:", err)
+		return errors.Wrap(err, "Failed to create cluster")
 	}
-	fmt.Println("GKE Cluster", name, "creating")
+	fmt.Fprintln(os.Stderr, "GKE Cluster", req.Cluster.Name, "creating")
 	return nil
 }
 
-// CreateKubeconfig creates a kubeconfig needed to access the cluster
-func CreateKubeconfig(client *container.ClusterManagerClient, name, project, zone, accessToken string) error {
-	// Cluster has to be finished before an Endpoint IP is available
-	for {
-		if cluster, err := GetCluster(client, name, project, zone); cluster.Status.String() != "ERROR" {
-			if err != nil {
-				return errors.Errorf("Failed to get cluster status:", err)
-			}
-			if cluster.Status.String() == "RUNNING" {
-				if cluster.Endpoint == "" {
-					fmt.Println("Cluster completed!...waiting for Endpoint to populate")
-					fmt.Println("Current status:", cluster.Status.String())
-				}
-				fmt.Println("Cluster completed!")
-				fmt.Println("Current status:", cluster.Status.String())
-				break
-			}
-			fmt.Println("Waiting for cluster completion -- Current status:", cluster.Status.String())
-			time.Sleep(1 * time.Minute)
-		}
-	}
+// CreateKubeconfig merges the cluster into the kubeconfig file, kubectl gets tokens from 'maker kube-token'
+func CreateKubeconfig(client ClusterManagerAPI, name, project, zone string) error {
 	cluster, err := GetCluster(client, name, project, zone)
-	contextString := "gke_" + project + "_" + zone + "_" + name
-	configString := `apiVersion: v1
-clusters:
-- cluster:
-    certificate-authority-data: ` + cluster.MasterAuth.ClusterCaCertificate + `
-    server: https://` + cluster.Endpoint + `
-  name: ` + contextString + `
-contexts:
-- context:
-    cluster: ` + contextString + `
-    user: ` + contextString + `
-  name: ` + contextString + `
-current-context: ` + contextString + `
-kind: Config
-preferences: {}
-users:
-- name: ` + contextString + `
-  user:
-    auth-provider:
-      config:
-        access-token: ` + accessToken + `
-        cmd-args: config config-helper --format=json
-        cmd-path: /usr/lib64/google-cloud-sdk/bin/gcloud
-        expiry: "2021-02-28T15:31:34Z"
-        expiry-key: '{.credential.token_expiry}'
-        token-key: '{.credential.access_token}'
-      name: gcp
-`
-	linesToWrite := configString
-	err = ioutil.WriteFile(utils.ConfigFolderPath+"/gke_kubeconfig", []byte(linesToWrite), 0755)
 	if err != nil {
-		return errors.Errorf("Failed to write kubeconfig:", err)
+		return err
+	}
+	// Cluster has to be finished before an Endpoint IP is available
+	if cluster.Endpoint == "" || cluster.MasterAuth == nil {
+		return errors.Wrapf(provider.ErrClusterNotReady, "Cluster %s has no endpoint yet -- current status: %s", name, cluster.Status.String())
+	}
+	return kubeconfig.Merge(kubeconfig.Entry{
+		Provider: "gcp",
+		Name:     name,
+		Cluster: map[string]interface{}{
+			"server":                     "https://" + cluster.Endpoint,
+			"certificate-authority-data": cluster.MasterAuth.ClusterCaCertificate,
+		},
+		User: kubeconfig.ExecUser("gcp", name),
+	})
+}
+
+// GkeClusterState reports the status of a cluster for waiting on, or gone once it is deleted.
+// A running cluster only counts once its endpoint is available.
+func GkeClusterState(client ClusterManagerAPI, name, project, zone string) waiter.Condition {
+	return func() (string, error) {
+		cluster, err := GetCluster(client, name, project, zone)
+		if status.Code(errors.Cause(err)) == codes.NotFound {
+			return waiter.StateGone, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if cluster.Status == containerpb.Cluster_RUNNING && cluster.Endpoint == "" {
+			return "WAITING_FOR_ENDPOINT", nil
+		}
+		return cluster.Status.String(), nil
 	}
-	fmt.Println("Kubeconfig created at", utils.ConfigFolderPath+"/gke_kubeconfig")
-	fmt.Printf("To use the kubeconfig, be sure to run 'export KUBECONFIG=%s/gke_kubeconfig'\n", utils.ConfigFolderPath)
-	return nil
 }
 
 // GetCluster describes the cluster and returns cluster details needed for kubeconfig and status
-func GetCluster(client *container.ClusterManagerClient, name, project, zone string) (*containerpb.Cluster, error) {
+func GetCluster(client ClusterManagerAPI, name, project, zone string) (*containerpb.Cluster, error) {
 	ctx := context.Background()
 
 	req := &containerpb.GetClusterRequest{
@@ -122,63 +110,89 @@ func GetCluster(client *container.ClusterManagerClient, name, project, zone stri
 	}
 	resp, err := client.GetCluster(ctx, req)
 	if err != nil {
-		return nil, errors.Errorf("Failed to fetch cluster data:", err)
+		return nil, errors.Wrap(err, "Failed to fetch cluster data")
 	}
 	return resp, nil
 }
 
-// PrintGkeClusterStatus outputs EKS cluster info
-func PrintGkeClusterStatus(client *container.ClusterManagerClient, name, project, zone string) {
-	cluster, _ := GetCluster(client, name, project, zone)
-	fmt.Printf(
-		"Name: %s\nVersion: %s\nEndpoint: %s\nNetwork: %s\nServices CIDR: %s\nZone: %s\n\nNode Pool Name: %s\nNode Count: %d\nMachine Type: %s\nImage: %s\nStatus: %s\nCreation Date: %s\n\n",
-		cluster.Name,
-		cluster.CurrentNodeVersion,
-		cluster.Endpoint,
-		cluster.Network,
-		cluster.ServicesIpv4Cidr,
-		cluster.Zone,
-		cluster.NodePools[0].Name,
-		cluster.CurrentNodeCount,
-		cluster.NodeConfig.MachineType,
-		cluster.NodeConfig.ImageType,
-		cluster.Status,
-		cluster.CreateTime,
-	)
-}
-
-// DeleteGkeCluster destroys an EKS cluster
-func DeleteGkeCluster(client *container.ClusterManagerClient, name, project, zone string) error {
+// ListGkeClusters fetches the GKE clusters in every location of the project
+func ListGkeClusters(client ClusterManagerAPI, project string) ([]*containerpb.Cluster, error) {
 	ctx := context.Background()
 
-	req := &containerpb.DeleteClusterRequest{
-		Name: "projects/" + project + "/locations/" + zone + "/clusters/" + name,
+	req := &containerpb.ListClustersRequest{
+		Parent: "projects/" + project + "/locations/-",
+	}
+	resp, err := client.ListClusters(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list clusters")
 	}
+	return resp.Clusters, nil
+}
 
-	_, err := client.DeleteCluster(ctx, req)
+// GetGkeClusterStatus fetches a GKE cluster and summarizes it
+func GetGkeClusterStatus(client ClusterManagerAPI, name, project, zone string) (*provider.Resource, error) {
+	cluster, err := GetCluster(client, name, project, zone)
 	if err != nil {
-		return errors.Errorf("Failed to delete cluster:", err)
+		return nil, err
+	}
+	resource := GkeClusterResource(cluster, project)
+	return &resource, nil
+}
+
+// GkeClusterResource converts a GKE cluster into the common resource summary
+func GkeClusterResource(cluster *containerpb.Cluster, project string) provider.Resource {
+	created, _ := time.Parse(time.RFC3339, cluster.CreateTime)
+	resource := provider.Resource{
+		Name:     cluster.Name,
+		ID:       "projects/" + project + "/locations/" + cluster.Location + "/clusters/" + cluster.Name,
+		Provider: "gcp",
+		Region:   cluster.Location,
+		Status:   cluster.Status.String(),
+		Endpoint: cluster.Endpoint,
+		Created:  created,
+		Details: map[string]string{
+			"version":        cluster.CurrentNodeVersion,
+			"master_version": cluster.CurrentMasterVersion,
+			"network":        cluster.Network,
+			"services_cidr":  cluster.ServicesIpv4Cidr,
+			"node_count":     strconv.Itoa(int(cluster.CurrentNodeCount)),
+		},
+	}
+	if cluster.NodeConfig != nil {
+		resource.Size = cluster.NodeConfig.MachineType
+		resource.Details["image"] = cluster.NodeConfig.ImageType
+	}
+	var pools []string
+	for _, pool := range cluster.NodePools {
+		pools = append(pools, pool.Name)
+	}
+	resource.Details["node_pools"] = strings.Join(pools, ",")
+	return resource
+}
+
+// GkeDeleteRequest builds the request to delete a GKE cluster
+func GkeDeleteRequest(name, project, zone string) *containerpb.DeleteClusterRequest {
+	return &containerpb.DeleteClusterRequest{
+		Name: "projects/" + project + "/locations/" + zone + "/clusters/" + name,
 	}
-	fmt.Println("Cluster deleted")
-	return nil
 }
 
-// FetchAccessToken prints an access token
-func FetchAccessToken(keyfile string) (string, error) {
+// DeleteGkeCluster destroys an EKS cluster
+func DeleteGkeCluster(client ClusterManagerAPI, name, project, zone string) error {
 	ctx := context.Background()
-	c, err := credentials.NewIamCredentialsClient(
-		ctx, option.WithCredentialsFile(keyfile))
+	_, err := client.DeleteCluster(ctx, GkeDeleteRequest(name, project, zone))
 	if err != nil {
-		return "", errors.Errorf("Failed to generate token:", err)
+		return errors.Wrap(err, "Failed to delete cluster")
 	}
+	fmt.Fprintln(os.Stderr, "GKE Cluster", name, "deleting")
+	return nil
+}
 
-	req := &credentialspb.GenerateAccessTokenRequest{
-		Name:  "projects/-/serviceAccounts/maker-sa@review-287714.iam.gserviceaccount.com",
-		Scope: []string{"https://www.googleapis.com/auth/cloud-platform"},
-	}
-	resp, err := c.GenerateAccessToken(ctx, req)
+// FetchAccessToken returns an access token for GKE clusters, belonging to whoever Maker authenticates as
+func FetchAccessToken(tokens oauth2.TokenSource) (*oauth2.Token, error) {
+	token, err := tokens.Token()
 	if err != nil {
-		return "", errors.Errorf("Failed to generate token:", err)
+		return nil, errors.Wrap(err, "Failed to generate token")
 	}
-	return resp.AccessToken, nil
+	return token, nil
 }
@@ -0,0 +1,182 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+
+	"github.com/pkg/errors"
+	containerpb "google.golang.org/genproto/googleapis/container/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gkeTaintEffects maps Kubernetes taint effects to the GKE enum
+var gkeTaintEffects = map[string]containerpb.NodeTaint_Effect{
+	provider.TaintNoSchedule:       containerpb.NodeTaint_NO_SCHEDULE,
+	provider.TaintPreferNoSchedule: containerpb.NodeTaint_PREFER_NO_SCHEDULE,
+	provider.TaintNoExecute:        containerpb.NodeTaint_NO_EXECUTE,
+}
+
+// clusterPath is the resource name of a cluster
+func clusterPath(project, zone, cluster string) string {
+	return "projects/" + project + "/locations/" + zone + "/clusters/" + cluster
+}
+
+// nodePoolPath is the resource name of a node pool
+func nodePoolPath(project, zone, cluster, name string) string {
+	return clusterPath(project, zone, cluster) + "/nodePools/" + name
+}
+
+// GkeNodePoolRequest builds the request to add a node pool to a cluster
+func GkeNodePoolRequest(opts provider.NodePoolOptions, project, zone string) *containerpb.CreateNodePoolRequest {
+	config := &containerpb.NodeConfig{
+		MachineType: opts.NodeSize,
+		Labels:      opts.Labels,
+	}
+	for _, taint := range opts.Taints {
+		config.Taints = append(config.Taints, &containerpb.NodeTaint{Key: taint.Key, Value: taint.Value, Effect: gkeTaintEffects[taint.Effect]})
+	}
+	return &containerpb.CreateNodePoolRequest{
+		Parent: clusterPath(project, zone, opts.Cluster),
+		NodePool: &containerpb.NodePool{
+			Name:             opts.Name,
+			Config:           config,
+			InitialNodeCount: int32(opts.InitialCount()),
+			Autoscaling:      gkeAutoscaling(opts.NodeScale),
+		},
+	}
+}
+
+// gkeAutoscaling converts a node scale into the autoscaling settings of a pool
+func gkeAutoscaling(scale provider.NodeScale) *containerpb.NodePoolAutoscaling {
+	if !scale.Autoscaled() {
+		return &containerpb.NodePoolAutoscaling{}
+	}
+	return &containerpb.NodePoolAutoscaling{
+		Enabled:      true,
+		MinNodeCount: int32(scale.MinNodes),
+		MaxNodeCount: int32(scale.MaxNodes),
+	}
+}
+
+// GkeNodePoolScaleRequests builds the requests to resize a node pool, the autoscaling settings
+// first and then the size. Either is left out when it wouldn't change anything.
+func GkeNodePoolScaleRequests(pool *containerpb.NodePool, scale provider.NodeScale, path string) (*containerpb.SetNodePoolAutoscalingRequest, *containerpb.SetNodePoolSizeRequest) {
+	var autoscaling *containerpb.SetNodePoolAutoscalingRequest
+	if scale.Autoscaled() || (pool.Autoscaling != nil && pool.Autoscaling.Enabled) {
+		autoscaling = &containerpb.SetNodePoolAutoscalingRequest{Name: path, Autoscaling: gkeAutoscaling(scale)}
+	}
+	var size *containerpb.SetNodePoolSizeRequest
+	if scale.NodeCount > 0 {
+		size = &containerpb.SetNodePoolSizeRequest{Name: path, NodeCount: int32(scale.NodeCount)}
+	}
+	return autoscaling, size
+}
+
+// CreateGkeNodePool adds a node pool to a cluster
+func CreateGkeNodePool(client ClusterManagerAPI, req *containerpb.CreateNodePoolRequest) error {
+	_, err := client.CreateNodePool(context.Background(), req)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create node pool")
+	}
+	fmt.Fprintln(os.Stderr, "Node pool", req.NodePool.Name, "creating")
+	return nil
+}
+
+// GetGkeNodePool fetches a node pool of a cluster
+func GetGkeNodePool(client ClusterManagerAPI, path string) (*containerpb.NodePool, error) {
+	pool, err := client.GetNodePool(context.Background(), &containerpb.GetNodePoolRequest{Name: path})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch node pool")
+	}
+	return pool, nil
+}
+
+// ListGkeNodePools fetches the node pools of a cluster
+func ListGkeNodePools(client ClusterManagerAPI, project, zone, cluster string) ([]*containerpb.NodePool, error) {
+	resp, err := client.ListNodePools(context.Background(), &containerpb.ListNodePoolsRequest{Parent: clusterPath(project, zone, cluster)})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list node pools")
+	}
+	return resp.NodePools, nil
+}
+
+// SetGkeNodePoolAutoscaling changes the autoscaling bounds of a node pool
+func SetGkeNodePoolAutoscaling(client ClusterManagerAPI, req *containerpb.SetNodePoolAutoscalingRequest) error {
+	_, err := client.SetNodePoolAutoscaling(context.Background(), req)
+	if err != nil {
+		return errors.Wrap(err, "Failed to set node pool autoscaling")
+	}
+	fmt.Fprintln(os.Stderr, "Node pool", LastPathSegment(req.Name), "autoscaling updating")
+	return nil
+}
+
+// SetGkeNodePoolSize resizes a node pool
+func SetGkeNodePoolSize(client ClusterManagerAPI, req *containerpb.SetNodePoolSizeRequest) error {
+	_, err := client.SetNodePoolSize(context.Background(), req)
+	if err != nil {
+		return errors.Wrap(err, "Failed to set node pool size")
+	}
+	fmt.Fprintln(os.Stderr, "Node pool", LastPathSegment(req.Name), "scaling")
+	return nil
+}
+
+// GkeNodePoolDeleteRequest builds the request to delete a node pool
+func GkeNodePoolDeleteRequest(path string) *containerpb.DeleteNodePoolRequest {
+	return &containerpb.DeleteNodePoolRequest{Name: path}
+}
+
+// DeleteGkeNodePool deletes a node pool and its nodes
+func DeleteGkeNodePool(client ClusterManagerAPI, path string) error {
+	_, err := client.DeleteNodePool(context.Background(), GkeNodePoolDeleteRequest(path))
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete node pool")
+	}
+	fmt.Fprintln(os.Stderr, "Node pool", LastPathSegment(path), "deleted")
+	return nil
+}
+
+// GkeNodePoolState reports the status of a node pool for waiting on, or gone once it is deleted
+func GkeNodePoolState(client ClusterManagerAPI, path string) waiter.Condition {
+	return func() (string, error) {
+		pool, err := GetGkeNodePool(client, path)
+		if status.Code(errors.Cause(err)) == codes.NotFound {
+			return waiter.StateGone, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return pool.Status.String(), nil
+	}
+}
+
+// GkeNodePoolSummary converts a node pool into the common node pool summary.
+// GKE pools don't report their current size, the count is the one they were created with.
+func GkeNodePoolSummary(pool *containerpb.NodePool, cluster string) provider.NodePool {
+	summary := provider.NodePool{
+		Provider:  "gcp",
+		Cluster:   cluster,
+		Name:      pool.Name,
+		ID:        pool.SelfLink,
+		NodeCount: int(pool.InitialNodeCount),
+		Status:    pool.Status.String(),
+	}
+	if pool.Autoscaling != nil && pool.Autoscaling.Enabled {
+		summary.MinNodes, summary.MaxNodes = int(pool.Autoscaling.MinNodeCount), int(pool.Autoscaling.MaxNodeCount)
+	}
+	if config := pool.Config; config != nil {
+		summary.Size = config.MachineType
+		summary.Labels = config.Labels
+		for _, taint := range config.Taints {
+			for effect, value := range gkeTaintEffects {
+				if value == taint.Effect {
+					summary.Taints = append(summary.Taints, provider.Taint{Key: taint.Key, Value: taint.Value, Effect: effect})
+				}
+			}
+		}
+	}
+	return summary
+}
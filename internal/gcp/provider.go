@@ -0,0 +1,828 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	containerpb "google.golang.org/genproto/googleapis/container/v1"
+)
+
+// Provider implements provider.Provider for GCP
+type Provider struct {
+	creds   Credentials
+	zone    string
+	project string
+	opts    provider.Options
+	// tokens authenticate every client, created on first use
+	tokens oauth2.TokenSource
+
+	// clients are created on first use so a command only connects to the APIs it needs
+	compute  ComputeAPI
+	clusters ClusterManagerAPI
+	sql      SQLAPI
+	storage  StorageAPI
+}
+
+func init() {
+	provider.Register("gcp", provider.Registration{
+		Configure:  Configure,
+		New:        New,
+		ConfigFile: ConfigName,
+	})
+}
+
+// New loads the GCP config file needed to create the various service clients
+func New(opts provider.Options) (provider.Provider, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		// without a config there are no credentials to use
+		return nil, errs.WrapAs(errs.AuthFailed, err, "Failed to load config")
+	}
+	return &Provider{
+		creds:   Credentials{Keyfile: config.Keyfile, Impersonate: config.Impersonate},
+		zone:    config.DefaultRegion,
+		project: config.GcpProject,
+		opts:    opts,
+	}, nil
+}
+
+// tokenSource returns the access tokens the clients use, finding the credentials on first use
+func (p *Provider) tokenSource() (oauth2.TokenSource, error) {
+	if p.tokens == nil {
+		tokens, err := p.creds.TokenSource(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		p.tokens = tokens
+	}
+	return p.tokens, nil
+}
+
+// Region returns the zone objects are created in
+func (p *Provider) Region() string {
+	return p.zone
+}
+
+// Verify checks the credentials by fetching the project they are configured for
+func (p *Provider) Verify() (*provider.Identity, error) {
+	compute, err := p.computeAPI()
+	if err != nil {
+		return nil, err
+	}
+	project, err := compute.GetProject(context.Background(), p.project)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get project %s", p.project)
+	}
+	return &provider.Identity{
+		Provider: "gcp",
+		User:     p.creds.ServiceAccount(),
+		Account:  project.Name,
+		Region:   p.zone,
+	}, nil
+}
+
+// ListSSHKeys fetches the project wide SSH keys
+func (p *Provider) ListSSHKeys() ([]provider.SSHKey, error) {
+	service, err := p.computeAPI()
+	if err != nil {
+		return nil, err
+	}
+	md, err := GetGceProjectMetadata(service, p.project)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGceSSHKeys(md), nil
+}
+
+// AddSSHKey adds a public key to the project wide SSH keys, so every instance gets it
+func (p *Provider) AddSSHKey(key provider.PublicKey) (*provider.SSHKey, error) {
+	added := provider.SSHKey{Provider: "gcp", Name: key.Name, ID: key.User, Fingerprint: key.FingerprintSHA256(), PublicKey: key.Key}
+	err := p.updateSSHKeys(func(lines []string) ([]string, error) {
+		for _, line := range lines {
+			if existing, ok := parseGceSSHKeyLine(line); ok && key.Same(existing) {
+				return nil, errs.Errorf(errs.AlreadyExists, "SSH key %s is already a project key", key.Name)
+			}
+		}
+		return append(lines, GceSSHKeyLine(key)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &added, nil
+}
+
+// RemoveSSHKey removes a key from the project wide SSH keys by name, user or fingerprint.
+// Instances created with the key keep it.
+func (p *Provider) RemoveSSHKey(name string) error {
+	return p.updateSSHKeys(func(lines []string) ([]string, error) {
+		var keys []provider.SSHKey
+		for _, line := range lines {
+			if key, ok := parseGceSSHKeyLine(line); ok {
+				keys = append(keys, key)
+			}
+		}
+		removed, err := provider.MatchSSHKey(keys, name)
+		if err != nil {
+			return nil, err
+		}
+		var kept []string
+		for _, line := range lines {
+			if key, ok := parseGceSSHKeyLine(line); !ok || key.Fingerprint != removed.Fingerprint {
+				kept = append(kept, line)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// updateSSHKeys rewrites the lines of the project wide SSH keys with change. Lines are
+// passed through as they are, so entries Maker can't parse are kept.
+func (p *Provider) updateSSHKeys(change func([]string) ([]string, error)) error {
+	service, err := p.computeAPI()
+	if err != nil {
+		return err
+	}
+	md, err := GetGceProjectMetadata(service, p.project)
+	if err != nil {
+		return err
+	}
+	lines, err := change(sshKeyLines(md))
+	if err != nil {
+		return err
+	}
+	updated := WithSSHKeys(md, lines)
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("Compute.Projects.SetCommonInstanceMetadata", updated)
+	}
+	return SetGceProjectMetadata(service, p.project, updated)
+}
+
+// instanceKeys returns the ssh-keys metadata of a new instance. Keys of the project reach every
+// instance already, so only a local key that isn't one is added: the --ssh-key file, or the
+// default key when none is set. Without either, project keys and OS Login are left to grant access.
+func (p *Provider) instanceKeys(ref string) ([]string, error) {
+	keys, err := p.ListSSHKeys()
+	if err != nil {
+		return nil, err
+	}
+	var local *provider.PublicKey
+	switch {
+	case ref != "" && !provider.IsPublicKeyPath(ref):
+		key, err := provider.MatchSSHKey(keys, ref)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(os.Stderr, "Using project SSH Key", key.Name)
+		return nil, nil
+	case ref != "":
+		if local, err = provider.ReadPublicKey(ref); err != nil {
+			return nil, err
+		}
+	default:
+		if local, err = provider.DefaultPublicKey(); errs.ClassOf(err) == errs.NotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range keys {
+		if local.Same(key) {
+			fmt.Fprintln(os.Stderr, "Using project SSH Key", key.Name)
+			return nil, nil
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Using SSH Key", local.Name, "for user", local.User)
+	return []string{GceSSHKeyLine(*local)}, nil
+}
+
+// Catalog lists the machine types, images, zones or Kubernetes versions GCP offers
+func (p *Provider) Catalog(kind string) ([]provider.CatalogEntry, error) {
+	if kind == provider.CatalogVersions {
+		client, err := p.clusterAPI()
+		if err != nil {
+			return nil, err
+		}
+		return ListGkeVersions(client, p.project, p.zone)
+	}
+	service, err := p.computeAPI()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case provider.CatalogSizes:
+		return ListGceMachineTypes(service, p.project, p.zone)
+	case provider.CatalogImages:
+		return ListGceImages(service)
+	case provider.CatalogRegions:
+		return ListGceZones(service, p.project)
+	}
+	return nil, provider.UnknownCatalog(kind)
+}
+
+// computeAPI returns the Compute Engine client, creating it on first use
+func (p *Provider) computeAPI() (ComputeAPI, error) {
+	if p.compute == nil {
+		tokens, err := p.tokenSource()
+		if err != nil {
+			return nil, err
+		}
+		service, err := CreateGceService(tokens)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create a Compute Service")
+		}
+		p.compute = service
+	}
+	return p.compute, nil
+}
+
+// clusterAPI returns the GKE client, creating it on first use
+func (p *Provider) clusterAPI() (ClusterManagerAPI, error) {
+	if p.clusters == nil {
+		tokens, err := p.tokenSource()
+		if err != nil {
+			return nil, err
+		}
+		client, err := CreateGkeClient(tokens)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create a Cluster Manager client")
+		}
+		p.clusters = client
+	}
+	return p.clusters, nil
+}
+
+// sqlAPI returns the Cloud SQL client, creating it on first use
+func (p *Provider) sqlAPI() (SQLAPI, error) {
+	if p.sql == nil {
+		tokens, err := p.tokenSource()
+		if err != nil {
+			return nil, err
+		}
+		service, err := CreateSQLService(tokens)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create a SQL Service")
+		}
+		p.sql = service
+	}
+	return p.sql, nil
+}
+
+// storageAPI returns the Cloud Storage client, creating it on first use
+func (p *Provider) storageAPI() (StorageAPI, error) {
+	if p.storage == nil {
+		tokens, err := p.tokenSource()
+		if err != nil {
+			return nil, err
+		}
+		client, err := CreateStorageClient(tokens)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create a Storage client")
+		}
+		p.storage = client
+	}
+	return p.storage, nil
+}
+
+// CreateVM creates a GCE instance
+func (p *Provider) CreateVM(opts provider.VMOptions) (*provider.Resource, error) {
+	instance, err := GceInstanceRequest(opts.Name, p.project, p.zone, opts.Size, opts.Image)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create GCE instance")
+	}
+	sshKeys, err := p.instanceKeys(opts.SSHKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create GCE instance")
+	}
+	instance.Metadata = GceInstanceMetadata(opts, sshKeys)
+	if p.opts.DryRun {
+		return provider.Planned("gcp", opts.Name, p.zone, opts.Size), p.opts.PrintRequest("Compute.Instances.Insert", instance)
+	}
+	service, err := p.computeAPI()
+	if err != nil {
+		return nil, err
+	}
+	op, err := CreateGceInstance(service, p.project, p.zone, instance)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create GCE instance")
+	}
+	if p.opts.Wait {
+		err = p.opts.Waiter().Until("instance "+opts.Name, GceOperationState(service, p.project, p.zone, op.Name), []string{"DONE"}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return GetInstanceStatus(service, opts.Name, p.project, p.zone)
+	}
+	return &provider.Resource{
+		Name:     opts.Name,
+		ID:       strconv.FormatUint(op.TargetId, 10),
+		Provider: "gcp",
+		Region:   p.zone,
+		Size:     opts.Size,
+		Status:   op.Status,
+	}, nil
+}
+
+// GetVM fetches the status of a GCE instance
+func (p *Provider) GetVM(name string) (*provider.Resource, error) {
+	service, err := p.computeAPI()
+	if err != nil {
+		return nil, err
+	}
+	resource, err := GetInstanceStatus(service, name, p.project, p.zone)
+	return resource, errors.Wrap(err, "Failed to fetch GCE instance")
+}
+
+// ListVMs lists all GCE instances
+func (p *Provider) ListVMs() ([]provider.Resource, error) {
+	service, err := p.computeAPI()
+	if err != nil {
+		return nil, err
+	}
+	instances, err := ListGceInstances(service, p.project)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, instance := range instances {
+		resources = append(resources, GceResource(instance))
+	}
+	return resources, nil
+}
+
+// DeleteVM deletes a GCE instance
+func (p *Provider) DeleteVM(name string) error {
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("Compute.Instances.Delete", map[string]string{"project": p.project, "zone": p.zone, "instance": name})
+	}
+	service, err := p.computeAPI()
+	if err != nil {
+		return err
+	}
+	op, err := DeleteGceInstance(service, name, p.project, p.zone)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete GCE instance")
+	}
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("instance "+name, GceOperationState(service, p.project, p.zone, op.Name), []string{"DONE"}, nil)
+	}
+	return nil
+}
+
+// CreateCluster creates a GKE cluster and writes its kubeconfig once it is running
+func (p *Provider) CreateCluster(opts provider.ClusterOptions) (*provider.Resource, error) {
+	req := GkeClusterRequest(opts.Name, p.project, p.zone, opts.NodeSize, opts.NodeCount)
+	if p.opts.DryRun {
+		return provider.Planned("gcp", opts.Name, p.zone, opts.NodeSize), p.opts.PrintRequest("ClusterManager.CreateCluster", req)
+	}
+	client, err := p.clusterAPI()
+	if err != nil {
+		return nil, err
+	}
+	err = CreateGkeCluster(client, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create GKE Cluster")
+	}
+	// the kubeconfig needs the endpoint of a running cluster
+	err = p.opts.Waiter().Until("cluster "+opts.Name, GkeClusterState(client, opts.Name, p.project, p.zone),
+		[]string{"RUNNING"}, []string{"ERROR", "DEGRADED", waiter.StateGone})
+	if err != nil {
+		return nil, err
+	}
+	err = p.FetchKubeconfig(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	return GetGkeClusterStatus(client, opts.Name, p.project, p.zone)
+}
+
+// GetCluster fetches the status of a GKE cluster
+func (p *Provider) GetCluster(name string) (*provider.Resource, error) {
+	client, err := p.clusterAPI()
+	if err != nil {
+		return nil, err
+	}
+	resource, err := GetGkeClusterStatus(client, name, p.project, p.zone)
+	return resource, errors.Wrap(err, "Failed to fetch GKE cluster")
+}
+
+// ListClusters lists all GKE clusters
+func (p *Provider) ListClusters() ([]provider.Resource, error) {
+	client, err := p.clusterAPI()
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := ListGkeClusters(client, p.project)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, cluster := range clusters {
+		resources = append(resources, GkeClusterResource(cluster, p.project))
+	}
+	return resources, nil
+}
+
+// FetchKubeconfig writes the kubeconfig of a GKE cluster
+func (p *Provider) FetchKubeconfig(name string) error {
+	client, err := p.clusterAPI()
+	if err != nil {
+		return err
+	}
+	err = CreateKubeconfig(client, name, p.project, p.zone)
+	return errors.Wrap(err, "Failed to create kubeconfig")
+}
+
+// KubeToken returns an access token for a GKE cluster, GKE accepts the tokens of any
+// identity with access to the project so the cluster isn't looked up
+func (p *Provider) KubeToken(cluster string) (*provider.KubeToken, error) {
+	tokens, err := p.tokenSource()
+	if err != nil {
+		return nil, err
+	}
+	token, err := FetchAccessToken(tokens)
+	if err != nil {
+		return nil, errs.WrapAs(errs.AuthFailed, err, "Failed to fetch token")
+	}
+	return &provider.KubeToken{Token: token.AccessToken, Expiry: token.Expiry}, nil
+}
+
+// ClusterUpgrades lists the versions the control plane of a GKE cluster can be upgraded to
+func (p *Provider) ClusterUpgrades(name string) (*provider.ClusterVersions, error) {
+	client, err := p.clusterAPI()
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := GetCluster(client, name, p.project, p.zone)
+	if err != nil {
+		return nil, err
+	}
+	config, err := GetGkeServerConfig(client, p.project, p.zone)
+	if err != nil {
+		return nil, err
+	}
+	return GkeClusterVersions(cluster, config), nil
+}
+
+// UpgradeCluster upgrades the control plane of a GKE cluster and then its node pools,
+// waiting for each as GKE runs one operation on a cluster at a time
+func (p *Provider) UpgradeCluster(name, version string) (*provider.Resource, error) {
+	client, err := p.clusterAPI()
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := GetCluster(client, name, p.project, p.zone)
+	if err != nil {
+		return nil, err
+	}
+	config, err := GetGkeServerConfig(client, p.project, p.zone)
+	if err != nil {
+		return nil, err
+	}
+	versions := GkeClusterVersions(cluster, config)
+	version, err = versions.Resolve(version)
+	if err != nil {
+		return nil, err
+	}
+	master := GkeMasterUpgradeRequest(p.project, p.zone, name, version)
+	var pools []*containerpb.UpdateNodePoolRequest
+	for _, pool := range cluster.NodePools {
+		if pool.Version != version {
+			pools = append(pools, GkeNodePoolUpgradeRequest(pool, nodePoolPath(p.project, p.zone, name, pool.Name), version))
+		}
+	}
+
+	if p.opts.DryRun {
+		if version != versions.Current {
+			if err := p.opts.PrintRequest("ClusterManager.UpdateMaster", master); err != nil {
+				return nil, err
+			}
+		}
+		for _, req := range pools {
+			if err := p.opts.PrintRequest("ClusterManager.UpdateNodePool", req); err != nil {
+				return nil, err
+			}
+		}
+		resource := GkeClusterResource(cluster, p.project)
+		resource.Status = provider.StatusPlanned
+		return &resource, nil
+	}
+
+	// GKE runs one operation on a cluster at a time, so each upgrade is waited on until its operation is done
+	if version != versions.Current {
+		op, err := UpgradeGkeMaster(client, master)
+		if err != nil {
+			return nil, err
+		}
+		err = p.opts.Waiter().Until("cluster "+name+" upgrade", GkeOperationState(client, p.project, p.zone, op.Name), []string{"DONE"}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, req := range pools {
+		op, err := UpgradeGkeNodePool(client, req)
+		if err != nil {
+			return nil, err
+		}
+		err = p.opts.Waiter().Until("node pool "+LastPathSegment(req.Name)+" upgrade", GkeOperationState(client, p.project, p.zone, op.Name), []string{"DONE"}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return GetGkeClusterStatus(client, name, p.project, p.zone)
+}
+
+// DeleteCluster deletes a GKE cluster
+func (p *Provider) DeleteCluster(name string) error {
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("ClusterManager.DeleteCluster", GkeDeleteRequest(name, p.project, p.zone))
+	}
+	client, err := p.clusterAPI()
+	if err != nil {
+		return err
+	}
+	err = DeleteGkeCluster(client, name, p.project, p.zone)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete GKE cluster")
+	}
+	kubeconfig.Forget("gcp", name)
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("cluster "+name, GkeClusterState(client, name, p.project, p.zone), []string{waiter.StateGone}, []string{"ERROR"})
+	}
+	return nil
+}
+
+// AddNodePool adds a node pool to a GKE cluster
+func (p *Provider) AddNodePool(opts provider.NodePoolOptions) (*provider.NodePool, error) {
+	req := GkeNodePoolRequest(opts, p.project, p.zone)
+	if p.opts.DryRun {
+		return &provider.NodePool{Provider: "gcp", Cluster: opts.Cluster, Name: opts.Name, Size: opts.NodeSize, Status: provider.StatusPlanned},
+			p.opts.PrintRequest("ClusterManager.CreateNodePool", req)
+	}
+	client, err := p.clusterAPI()
+	if err != nil {
+		return nil, err
+	}
+	err = CreateGkeNodePool(client, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create GKE node pool")
+	}
+	return p.waitNodePool(client, opts.Cluster, opts.Name, p.opts.Wait)
+}
+
+// ListNodePools lists the node pools of a GKE cluster
+func (p *Provider) ListNodePools(cluster string) ([]provider.NodePool, error) {
+	client, err := p.clusterAPI()
+	if err != nil {
+		return nil, err
+	}
+	pools, err := ListGkeNodePools(client, p.project, p.zone, cluster)
+	if err != nil {
+		return nil, err
+	}
+	var summaries []provider.NodePool
+	for _, pool := range pools {
+		summaries = append(summaries, GkeNodePoolSummary(pool, cluster))
+	}
+	return summaries, nil
+}
+
+// ScaleNodePool resizes a node pool of a GKE cluster. GKE runs one operation on a cluster
+// at a time, so the size is only set once the autoscaling change is done.
+func (p *Provider) ScaleNodePool(cluster, name string, scale provider.NodeScale) (*provider.NodePool, error) {
+	client, err := p.clusterAPI()
+	if err != nil {
+		return nil, err
+	}
+	path := nodePoolPath(p.project, p.zone, cluster, name)
+	pool, err := GetGkeNodePool(client, path)
+	if err != nil {
+		return nil, err
+	}
+	autoscaling, size := GkeNodePoolScaleRequests(pool, scale, path)
+	if p.opts.DryRun {
+		summary := GkeNodePoolSummary(pool, cluster)
+		summary.Status = provider.StatusPlanned
+		if autoscaling != nil {
+			if err := p.opts.PrintRequest("ClusterManager.SetNodePoolAutoscaling", autoscaling); err != nil {
+				return nil, err
+			}
+		}
+		if size != nil {
+			err = p.opts.PrintRequest("ClusterManager.SetNodePoolSize", size)
+		}
+		return &summary, err
+	}
+	if autoscaling != nil {
+		if err := SetGkeNodePoolAutoscaling(client, autoscaling); err != nil {
+			return nil, err
+		}
+		if size != nil {
+			if _, err := p.waitNodePool(client, cluster, name, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if size != nil {
+		if err := SetGkeNodePoolSize(client, size); err != nil {
+			return nil, err
+		}
+	}
+	return p.waitNodePool(client, cluster, name, p.opts.Wait)
+}
+
+// waitNodePool waits for a node pool to be running when wait is set and summarizes it
+func (p *Provider) waitNodePool(client ClusterManagerAPI, cluster, name string, wait bool) (*provider.NodePool, error) {
+	path := nodePoolPath(p.project, p.zone, cluster, name)
+	if wait {
+		err := p.opts.Waiter().Until("node pool "+name, GkeNodePoolState(client, path),
+			[]string{"RUNNING"}, []string{"ERROR", waiter.StateGone})
+		if err != nil {
+			return nil, err
+		}
+	}
+	pool, err := GetGkeNodePool(client, path)
+	if err != nil {
+		return nil, err
+	}
+	summary := GkeNodePoolSummary(pool, cluster)
+	return &summary, nil
+}
+
+// DeleteNodePool deletes a node pool of a GKE cluster
+func (p *Provider) DeleteNodePool(cluster, name string) error {
+	path := nodePoolPath(p.project, p.zone, cluster, name)
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("ClusterManager.DeleteNodePool", GkeNodePoolDeleteRequest(path))
+	}
+	client, err := p.clusterAPI()
+	if err != nil {
+		return err
+	}
+	err = DeleteGkeNodePool(client, path)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete GKE node pool")
+	}
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("node pool "+name, GkeNodePoolState(client, path), []string{waiter.StateGone}, []string{"ERROR"})
+	}
+	return nil
+}
+
+// CreateBucket creates a Storage bucket
+func (p *Provider) CreateBucket(name string) (*provider.Resource, error) {
+	if p.opts.DryRun {
+		return provider.Planned("gcp", name, "", ""), p.opts.PrintRequest("Storage.Buckets.Insert", map[string]string{"project": p.project, "bucket": name})
+	}
+	client, err := p.storageAPI()
+	if err != nil {
+		return nil, err
+	}
+	err = CreateStorageBucket(client, name, p.project)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create Storage bucket")
+	}
+	return GetStorageBucketInfo(client, name)
+}
+
+// GetBucket fetches info about a Storage bucket
+func (p *Provider) GetBucket(name string) (*provider.Resource, error) {
+	client, err := p.storageAPI()
+	if err != nil {
+		return nil, err
+	}
+	resource, err := GetStorageBucketInfo(client, name)
+	return resource, errors.Wrap(err, "Failed to fetch Storage bucket")
+}
+
+// ListBuckets lists all Storage buckets
+func (p *Provider) ListBuckets() ([]provider.Resource, error) {
+	client, err := p.storageAPI()
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := ListStorageBuckets(client, p.project)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, bucket := range buckets {
+		resources = append(resources, StorageBucketResource(bucket))
+	}
+	return resources, nil
+}
+
+// DeleteBucket empties and deletes a Storage bucket, confirming first that its objects go too
+func (p *Provider) DeleteBucket(name string) error {
+	client, err := p.storageAPI()
+	if err != nil {
+		return err
+	}
+	objects, err := ListStorageObjects(client, name)
+	if err != nil {
+		return err
+	}
+	if p.opts.DryRun {
+		for _, object := range objects {
+			err := p.opts.PrintRequest("Storage.Objects.Delete", map[string]string{"bucket": name, "object": object})
+			if err != nil {
+				return err
+			}
+		}
+		return p.opts.PrintRequest("Storage.Buckets.Delete", map[string]string{"bucket": name})
+	}
+	if len(objects) > 0 {
+		if err := p.opts.ConfirmEmptyBucket(name, len(objects)); err != nil {
+			return err
+		}
+		if err := DeleteStorageObjects(client, name, objects); err != nil {
+			return errors.Wrap(err, "Failed to delete objects in bucket")
+		}
+	}
+	err = DeleteStorageBucket(client, name, p.project)
+	return errors.Wrap(err, "Failed to delete Storage bucket")
+}
+
+// CreateDB creates a Postgres Cloud SQL instance
+func (p *Provider) CreateDB(opts provider.DBOptions) (*provider.Resource, error) {
+	region := ZoneRegion(p.zone)
+	instance := SQLInstanceRequest(opts.Name, p.project, p.zone, opts.Size)
+	if p.opts.DryRun {
+		return provider.Planned("gcp", opts.Name, region, opts.Size), p.opts.PrintRequest("SQLAdmin.Instances.Insert", instance)
+	}
+	service, err := p.sqlAPI()
+	if err != nil {
+		return nil, err
+	}
+	op, err := CreateSQLInstance(service, p.project, instance)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create SQL instance")
+	}
+	if p.opts.Wait {
+		err = p.opts.Waiter().Until("database "+opts.Name, SQLOperationState(service, p.project, op.Name), []string{"DONE"}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return GetSQLDbStatus(service, opts.Name, p.project)
+	}
+	return &provider.Resource{
+		Name:     opts.Name,
+		ID:       p.project + ":" + region + ":" + opts.Name,
+		Provider: "gcp",
+		Region:   region,
+		Size:     opts.Size,
+	}, nil
+}
+
+// GetDB fetches the status of a Cloud SQL instance
+func (p *Provider) GetDB(name string) (*provider.Resource, error) {
+	service, err := p.sqlAPI()
+	if err != nil {
+		return nil, err
+	}
+	resource, err := GetSQLDbStatus(service, name, p.project)
+	return resource, errors.Wrap(err, "Failed to fetch SQL instance")
+}
+
+// ListDBs lists all Cloud SQL instances
+func (p *Provider) ListDBs() ([]provider.Resource, error) {
+	service, err := p.sqlAPI()
+	if err != nil {
+		return nil, err
+	}
+	instances, err := ListSQLInstances(service, p.project)
+	if err != nil {
+		return nil, err
+	}
+	var resources []provider.Resource
+	for _, instance := range instances {
+		resources = append(resources, SQLResource(instance))
+	}
+	return resources, nil
+}
+
+// DeleteDB deletes a Cloud SQL instance
+func (p *Provider) DeleteDB(name string) error {
+	if p.opts.DryRun {
+		return p.opts.PrintRequest("SQLAdmin.Instances.Delete", map[string]string{"project": p.project, "instance": name})
+	}
+	service, err := p.sqlAPI()
+	if err != nil {
+		return err
+	}
+	op, err := DeleteSQLInstance(service, name, p.project, p.zone)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete SQL instance")
+	}
+	if p.opts.Wait {
+		return p.opts.Waiter().Until("database "+name, SQLOperationState(service, p.project, op.Name), []string{"DONE"}, nil)
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+package gcp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/kubeconfig"
+	"maker/internal/provider"
+	"maker/internal/state"
+	"maker/internal/utils"
+	"maker/internal/waiter"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/compute/v1"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+	containerpb "google.golang.org/genproto/googleapis/container/v1"
+)
+
+type fakes struct {
+	compute  *fakeCompute
+	clusters *fakeClusterManager
+	sql      *fakeSQL
+	storage  *fakeStorage
+}
+
+// newTestProvider returns a provider backed by in-memory fakes, with the
+// state file and kubeconfigs written to a temporary directory
+func newTestProvider(t *testing.T, opts provider.Options) (*Provider, *fakes) {
+	dir := t.TempDir()
+	oldState, oldFolder, oldKubeconfig, oldSSH := state.StatePath, utils.ConfigFolderPath, kubeconfig.Path, provider.SSHFolder
+	state.StatePath = filepath.Join(dir, "state.json")
+	utils.ConfigFolderPath = dir
+	kubeconfig.Path = filepath.Join(dir, "kubeconfig")
+	// no local SSH keys unless a test writes them
+	provider.SSHFolder = dir
+	t.Cleanup(func() {
+		state.StatePath, utils.ConfigFolderPath, kubeconfig.Path, provider.SSHFolder = oldState, oldFolder, oldKubeconfig, oldSSH
+	})
+
+	if opts.PollInterval == 0 {
+		opts.PollInterval = time.Millisecond
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	f := &fakes{
+		compute:  newFakeCompute(),
+		clusters: newFakeClusterManager(),
+		sql:      newFakeSQL(),
+		storage:  newFakeStorage(),
+	}
+	p := &Provider{
+		zone:     "us-east1-b",
+		project:  "lab",
+		opts:     opts,
+		compute:  f.compute,
+		clusters: f.clusters,
+		sql:      f.sql,
+		storage:  f.storage,
+	}
+	return p, f
+}
+
+func TestVMLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+
+	resource, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "e2-micro", Image: "ubuntu-os-cloud/ubuntu-2004-focal-v20210223", UserData: "#!/bin/sh\n"})
+	if err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	if resource.Status != "RUNNING" || resource.Region != "us-east1-b" {
+		t.Errorf("unexpected instance summary %+v", resource)
+	}
+	if image := f.compute.inserted[0].Disks[0].InitializeParams.SourceImage; image != "projects/ubuntu-os-cloud/global/images/ubuntu-2004-focal-v20210223" {
+		t.Errorf("unexpected source image %q", image)
+	}
+	if items := f.compute.inserted[0].Metadata.Items; len(items) != 1 || items[0].Key != "startup-script" {
+		t.Errorf("expected a script to be passed as startup-script, got %+v", items)
+	}
+
+	list, err := p.ListVMs()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListVMs returned %v, %v", list, err)
+	}
+	if err := p.DeleteVM("web"); err != nil {
+		t.Fatalf("DeleteVM: %v", err)
+	}
+	if _, err := p.GetVM("web"); errs.ClassOf(err) != errs.NotFound {
+		t.Errorf("expected a not found error fetching a deleted instance, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	p, _ := newTestProvider(t, provider.Options{})
+	identity, err := p.Verify()
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if identity.Account != "lab" || identity.Region != "us-east1-b" {
+		t.Errorf("unexpected identity %+v", *identity)
+	}
+
+	p.project = "missing"
+	if _, err := p.Verify(); errs.ClassOf(err) != errs.NotFound {
+		t.Errorf("expected a NotFound error for an unknown project, got %v", err)
+	}
+}
+
+func TestKubeToken(t *testing.T) {
+	p, _ := newTestProvider(t, provider.Options{})
+	expiry := time.Now().Add(time.Hour)
+	p.tokens = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ya29.token", Expiry: expiry})
+
+	token, err := p.KubeToken("k8s")
+	if err != nil {
+		t.Fatalf("KubeToken: %v", err)
+	}
+	if token.Token != "ya29.token" || !token.Expiry.Equal(expiry) {
+		t.Errorf("unexpected token %+v", *token)
+	}
+}
+
+func TestNodePoolLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+	if err := CreateGkeCluster(f.clusters, GkeClusterRequest("k8s", "lab", "us-east1-b", "e2-medium", 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := p.AddNodePool(provider.NodePoolOptions{
+		Cluster:   "k8s",
+		Name:      "highmem",
+		NodeSize:  "e2-highmem-4",
+		NodeScale: provider.NodeScale{NodeCount: 2},
+		Labels:    map[string]string{"workload": "test"},
+		Taints:    []provider.Taint{{Key: "dedicated", Value: "test", Effect: provider.TaintNoExecute}},
+	})
+	if err != nil {
+		t.Fatalf("AddNodePool: %v", err)
+	}
+	if pool.Status != "RUNNING" || pool.NodeCount != 2 || pool.Autoscaled() || len(pool.Taints) != 1 || pool.Taints[0].Effect != provider.TaintNoExecute {
+		t.Errorf("unexpected node pool %+v", *pool)
+	}
+
+	// the size is only set once the autoscaling operation is done, the fake refuses overlapping operations
+	pool, err = p.ScaleNodePool("k8s", "highmem", provider.NodeScale{NodeCount: 3, MinNodes: 1, MaxNodes: 5})
+	if err != nil {
+		t.Fatalf("ScaleNodePool: %v", err)
+	}
+	if pool.NodeCount != 3 || pool.MinNodes != 1 || pool.MaxNodes != 5 {
+		t.Errorf("unexpected scaled node pool %+v", *pool)
+	}
+
+	pools, err := p.ListNodePools("k8s")
+	if err != nil || len(pools) != 2 {
+		t.Fatalf("ListNodePools returned %v, %v", pools, err)
+	}
+	resource, err := p.GetCluster("k8s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resource.Details["node_pools"] != "k8s-nodepool,highmem" {
+		t.Errorf("cluster summary doesn't cover every pool: %v", resource.Details)
+	}
+
+	if err := p.DeleteNodePool("k8s", "highmem"); err != nil {
+		t.Fatalf("DeleteNodePool: %v", err)
+	}
+	if pools, _ := p.ListNodePools("k8s"); len(pools) != 1 {
+		t.Errorf("node pool wasn't deleted: %v", pools)
+	}
+}
+
+func TestUpgradeCluster(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	if err := CreateGkeCluster(f.clusters, GkeClusterRequest("k8s", "lab", "us-east1-b", "e2-medium", 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1.21 is two minor versions ahead of the control plane
+	versions, err := p.ClusterUpgrades("k8s")
+	if err != nil {
+		t.Fatalf("ClusterUpgrades: %v", err)
+	}
+	if versions.Current != fakeVersions[0] || len(versions.Available) != 1 || versions.Available[0] != fakeVersions[1] {
+		t.Errorf("unexpected versions %+v", *versions)
+	}
+	if _, err := p.UpgradeCluster("k8s", "1.21"); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error skipping a minor version, got %v", err)
+	}
+
+	// the cluster stays RUNNING while the control plane upgrades, so the node pool is only upgraded
+	// once the operation is done, the fake refuses overlapping operations
+	resource, err := p.UpgradeCluster("k8s", "1.20")
+	if err != nil {
+		t.Fatalf("UpgradeCluster: %v", err)
+	}
+	if resource.Details["master_version"] != fakeVersions[1] {
+		t.Errorf("expected the control plane on %s, got %v", fakeVersions[1], resource.Details)
+	}
+	if version := f.clusters.clusters["k8s"].NodePools[0].Version; version != fakeVersions[1] {
+		t.Errorf("expected the node pool on %s, got %s", fakeVersions[1], version)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	p, _ := newTestProvider(t, provider.Options{})
+
+	// deprecated machine types and images and zones that are down are left out
+	tests := []struct {
+		kind  string
+		slugs []string
+	}{
+		{provider.CatalogSizes, []string{"e2-micro", "e2-standard-2"}},
+		{provider.CatalogImages, []string{"debian-cloud/debian-10-buster-v20210217"}},
+		{provider.CatalogRegions, []string{"us-east1-b", "us-east1-c"}},
+		{provider.CatalogVersions, fakeVersions},
+	}
+	for _, tt := range tests {
+		entries, err := p.Catalog(tt.kind)
+		if err != nil {
+			t.Fatalf("Catalog(%s): %v", tt.kind, err)
+		}
+		var slugs []string
+		for _, entry := range entries {
+			slugs = append(slugs, entry.Slug)
+		}
+		if strings.Join(slugs, ",") != strings.Join(tt.slugs, ",") {
+			t.Errorf("Catalog(%s) = %v, want %v", tt.kind, slugs, tt.slugs)
+		}
+	}
+}
+
+func TestCreateVMRejectsBareImage(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	if _, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "e2-micro", Image: "ubuntu"}); err == nil {
+		t.Fatal("expected an error for an image without its project")
+	}
+	if len(f.compute.inserted) != 0 {
+		t.Errorf("no instance should be created")
+	}
+}
+
+func TestSSHKeys(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	line := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIC9TtvMVg5+8hDazIXWO0tmh6sHaoEWEpTnnaLn2Fhr8 me@laptop"
+	ioutil.WriteFile(filepath.Join(provider.SSHFolder, "id_ed25519.pub"), []byte(line+"\n"), 0600)
+
+	// the default local key is added to instances while it isn't a project key
+	if _, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "e2-micro", Image: "debian-cloud/debian-10"}); err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	if value := metadataValue(f.compute.inserted[0].Metadata, SSHKeysMetadata); value != "me:"+line {
+		t.Errorf("unexpected instance ssh-keys %q", value)
+	}
+
+	key, err := provider.DefaultPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.AddSSHKey(*key); err != nil {
+		t.Fatalf("AddSSHKey: %v", err)
+	}
+	if _, err := p.AddSSHKey(*key); errs.ClassOf(err) != errs.AlreadyExists {
+		t.Errorf("expected an already exists error adding a key twice, got %v", err)
+	}
+	keys, err := p.ListSSHKeys()
+	if err != nil || len(keys) != 1 || keys[0].Name != "me@laptop" || keys[0].ID != "me" {
+		t.Fatalf("unexpected project keys %+v, %v", keys, err)
+	}
+	if _, err := p.CreateVM(provider.VMOptions{Name: "db", Size: "e2-micro", Image: "debian-cloud/debian-10"}); err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	if md := f.compute.inserted[1].Metadata; md != nil {
+		t.Errorf("expected no instance keys once the key is a project key, got %+v", md.Items)
+	}
+
+	if err := p.RemoveSSHKey(keys[0].Fingerprint); err != nil {
+		t.Fatalf("RemoveSSHKey: %v", err)
+	}
+	if keys, _ := p.ListSSHKeys(); len(keys) != 0 {
+		t.Errorf("expected no project keys left, got %+v", keys)
+	}
+}
+
+func TestSSHKeysKeepOtherLines(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+	// lines added by other tools, which Maker can't parse, must survive every change
+	other := []string{
+		`admin:ecdsa-sha2-nistp256 not-base64 google-ssh {"userName":"admin@example.com","expireOn":"2030-01-01T00:00:00+0000"}`,
+		"legacy entry without a key",
+	}
+	value := strings.Join(other, "\n")
+	f.compute.metadata = &compute.Metadata{Fingerprint: "1", Items: []*compute.MetadataItems{{Key: SSHKeysMetadata, Value: &value}}}
+
+	key, err := provider.ParsePublicKey("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIC9TtvMVg5+8hDazIXWO0tmh6sHaoEWEpTnnaLn2Fhr8 me@laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.User = "me"
+	added, err := p.AddSSHKey(*key)
+	if err != nil {
+		t.Fatalf("AddSSHKey: %v", err)
+	}
+	want := append(other, GceSSHKeyLine(*key))
+	if lines := sshKeyLines(f.compute.metadata); strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ssh-keys after add = %q, want %q", lines, want)
+	}
+
+	if err := p.RemoveSSHKey(added.Fingerprint); err != nil {
+		t.Fatalf("RemoveSSHKey: %v", err)
+	}
+	if lines := sshKeyLines(f.compute.metadata); strings.Join(lines, "\n") != value {
+		t.Errorf("ssh-keys after remove = %q, want %q", lines, other)
+	}
+}
+
+func TestInstanceRequestImageFamily(t *testing.T) {
+	instance, err := GceInstanceRequest("web", "lab", "us-east1-b", "e2-micro", "debian-cloud/family/debian-12")
+	if err != nil {
+		t.Fatalf("GceInstanceRequest: %v", err)
+	}
+	if image := instance.Disks[0].InitializeParams.SourceImage; image != "projects/debian-cloud/global/images/family/debian-12" {
+		t.Errorf("unexpected source image %q", image)
+	}
+}
+
+func TestClusterStatusAndDelete(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+	if err := CreateGkeCluster(f.clusters, GkeClusterRequest("k8s", "lab", "us-east1-b", "e2-medium", 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a running cluster only counts once it has an endpoint
+	cond := GkeClusterState(f.clusters, "k8s", "lab", "us-east1-b")
+	for _, want := range []string{"PROVISIONING", "RUNNING"} {
+		if got, err := cond(); err != nil || got != want {
+			t.Errorf("state = %q, %v, want %q", got, err, want)
+		}
+	}
+
+	resource, err := p.GetCluster("k8s")
+	if err != nil {
+		t.Fatalf("GetCluster: %v", err)
+	}
+	if resource.Details["node_pools"] != "k8s-nodepool" || resource.Region != "us-east1-b" {
+		t.Errorf("unexpected cluster summary %+v", resource)
+	}
+	list, err := p.ListClusters()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListClusters returned %v, %v", list, err)
+	}
+
+	if err := p.DeleteCluster("k8s"); err != nil {
+		t.Fatalf("DeleteCluster: %v", err)
+	}
+	if got, err := cond(); err != nil || got != waiter.StateGone {
+		t.Errorf("expected a deleted cluster to be gone, got %q, %v", got, err)
+	}
+}
+
+func TestDryRunSendsNothing(t *testing.T) {
+	var plan bytes.Buffer
+	p, f := newTestProvider(t, provider.Options{DryRun: true, Plan: &plan})
+
+	if _, err := p.CreateVM(provider.VMOptions{Name: "web", Size: "e2-micro", Image: "debian-cloud/debian-10"}); err != nil {
+		t.Fatalf("CreateVM: %v", err)
+	}
+	resource, err := p.CreateCluster(provider.ClusterOptions{Name: "k8s", NodeSize: "e2-medium", NodeCount: 2})
+	if err != nil {
+		t.Fatalf("CreateCluster: %v", err)
+	}
+	if resource.Status != provider.StatusPlanned {
+		t.Errorf("expected a planned resource, got %q", resource.Status)
+	}
+	if _, err := p.CreateBucket("assets"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if _, err := p.CreateDB(provider.DBOptions{Name: "pg", Size: "db-f1-micro"}); err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+
+	if len(f.compute.inserted) != 0 || len(f.clusters.clusters) != 0 || len(f.storage.buckets) != 0 || len(f.sql.instances) != 0 {
+		t.Errorf("dry run created objects")
+	}
+	for _, call := range []string{"# Compute.Instances.Insert", "# ClusterManager.CreateCluster", "# Storage.Buckets.Insert", "# SQLAdmin.Instances.Insert"} {
+		if !strings.Contains(plan.String(), call) {
+			t.Errorf("plan is missing %s:\n%s", call, plan.String())
+		}
+	}
+}
+
+func TestBucketLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{})
+
+	if _, err := p.CreateBucket("assets"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	f.storage.buckets["assets"] = []string{"a.txt", "b.txt"}
+
+	resource, err := p.GetBucket("assets")
+	if err != nil {
+		t.Fatalf("GetBucket: %v", err)
+	}
+	if resource.Endpoint != "gs://assets" || resource.Details["object_count"] != "2" {
+		t.Errorf("unexpected bucket summary %+v", resource)
+	}
+
+	var plan bytes.Buffer
+	p.opts.DryRun, p.opts.Plan = true, &plan
+	if err := p.DeleteBucket("assets"); err != nil {
+		t.Fatalf("DeleteBucket dry run: %v", err)
+	}
+	if strings.Count(plan.String(), "# Storage.Objects.Delete") != 2 {
+		t.Errorf("expected a delete per object in the plan:\n%s", plan.String())
+	}
+
+	// an empty bucket is deleted without asking about its objects
+	f.storage.buckets["assets"] = nil
+	p.opts.DryRun = false
+	if err := p.DeleteBucket("assets"); err != nil {
+		t.Fatalf("DeleteBucket: %v", err)
+	}
+	if len(f.storage.buckets) != 0 {
+		t.Errorf("bucket wasn't deleted")
+	}
+}
+
+func TestDBLifecycle(t *testing.T) {
+	p, f := newTestProvider(t, provider.Options{Wait: true})
+
+	resource, err := p.CreateDB(provider.DBOptions{Name: "pg", Size: "db-f1-micro"})
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	if resource.Status != "RUNNABLE" || resource.Region != "us-east1" {
+		t.Errorf("unexpected database summary %+v", resource)
+	}
+	if err := p.DeleteDB("pg"); err != nil {
+		t.Fatalf("DeleteDB: %v", err)
+	}
+	if len(f.sql.instances) != 0 {
+		t.Errorf("database wasn't deleted")
+	}
+}
+
+func TestResourcesFromSparseObjects(t *testing.T) {
+	// the API leaves out nested objects while they are being provisioned
+	GceResource(&compute.Instance{})
+	GkeClusterResource(&containerpb.Cluster{}, "lab")
+	SQLResource(&sqladmin.DatabaseInstance{})
+	StorageBucketResource(&storage.BucketAttrs{})
+}
+
+func TestZoneRegion(t *testing.T) {
+	for zone, want := range map[string]string{"us-east1-b": "us-east1", "europe-west4-a": "europe-west4", "global": "global"} {
+		if got := ZoneRegion(zone); got != want {
+			t.Errorf("ZoneRegion(%q) = %q, want %q", zone, got, want)
+		}
+	}
+}
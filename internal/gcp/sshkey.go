@@ -0,0 +1,118 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"maker/internal/provider"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/compute/v1"
+)
+
+// SSHKeysMetadata is the metadata key GCE reads 'user:public-key' lines from,
+// project wide keys are added to every instance that doesn't block them
+const SSHKeysMetadata = "ssh-keys"
+
+// GceSSHKeyLine writes a public key the way ssh-keys metadata holds it
+func GceSSHKeyLine(key provider.PublicKey) string {
+	return key.User + ":" + key.Key
+}
+
+// ParseGceSSHKeys reads the keys of ssh-keys metadata, named after their comment or user.
+// Lines that aren't valid keys are skipped.
+func ParseGceSSHKeys(md *compute.Metadata) []provider.SSHKey {
+	var keys []provider.SSHKey
+	for _, line := range sshKeyLines(md) {
+		if key, ok := parseGceSSHKeyLine(line); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// sshKeyLines returns the raw lines of ssh-keys metadata, including the ones Maker can't parse
+func sshKeyLines(md *compute.Metadata) []string {
+	var lines []string
+	for _, line := range strings.Split(metadataValue(md, SSHKeysMetadata), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseGceSSHKeyLine reads a 'user:public-key' line, reporting whether it holds a valid key
+func parseGceSSHKeyLine(line string) (provider.SSHKey, bool) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return provider.SSHKey{}, false
+	}
+	key, err := provider.ParsePublicKey(line[i+1:])
+	if err != nil {
+		return provider.SSHKey{}, false
+	}
+	user := line[:i]
+	if key.Name == "" {
+		key.Name = user
+	}
+	return provider.SSHKey{
+		Provider:    "gcp",
+		Name:        key.Name,
+		ID:          user,
+		Fingerprint: key.FingerprintSHA256(),
+		PublicKey:   key.Key,
+	}, true
+}
+
+// metadataValue returns the value of a metadata item, empty when it isn't set
+func metadataValue(md *compute.Metadata, key string) string {
+	if md == nil {
+		return ""
+	}
+	for _, item := range md.Items {
+		if item.Key == key && item.Value != nil {
+			return *item.Value
+		}
+	}
+	return ""
+}
+
+// WithSSHKeys returns a copy of metadata with its ssh-keys replaced, keeping the fingerprint
+// so concurrent changes are refused. Empty keys remove the item.
+func WithSSHKeys(md *compute.Metadata, lines []string) *compute.Metadata {
+	updated := &compute.Metadata{}
+	if md != nil {
+		updated.Fingerprint = md.Fingerprint
+		for _, item := range md.Items {
+			if item.Key != SSHKeysMetadata {
+				updated.Items = append(updated.Items, item)
+			}
+		}
+	}
+	if len(lines) > 0 {
+		value := strings.Join(lines, "\n")
+		updated.Items = append(updated.Items, &compute.MetadataItems{Key: SSHKeysMetadata, Value: &value})
+	}
+	return updated
+}
+
+// GetGceProjectMetadata fetches the metadata every instance of the project gets
+func GetGceProjectMetadata(computeService ComputeAPI, project string) (*compute.Metadata, error) {
+	result, err := computeService.GetProject(context.Background(), project)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get project %s", project)
+	}
+	return result.CommonInstanceMetadata, nil
+}
+
+// SetGceProjectMetadata replaces the metadata every instance of the project gets
+func SetGceProjectMetadata(computeService ComputeAPI, project string, md *compute.Metadata) error {
+	_, err := computeService.SetCommonInstanceMetadata(context.Background(), project, md)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update project SSH keys")
+	}
+	fmt.Fprintln(os.Stderr, "Project", project, "SSH keys updating")
+	return nil
+}
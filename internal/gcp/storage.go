@@ -2,113 +2,113 @@ package gcp
 
 import (
 	"fmt"
-	"log"
+	"maker/internal/provider"
+	"os"
+	"strconv"
 	"strings"
 
 	"cloud.google.com/go/storage"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
-	"google.golang.org/api/iterator"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 )
 
 // CreateStorageClient creates a new client to interact with GCP
-func CreateStorageClient(keyfile string) (*storage.Client, error) {
+func CreateStorageClient(tokens oauth2.TokenSource) (StorageAPI, error) {
 	ctx := context.Background()
 	client, err := storage.NewClient(
-		ctx, option.WithCredentialsFile(keyfile))
+		ctx, option.WithTokenSource(tokens))
 	if err != nil {
-		return nil, errors.Errorf("Failed to create client: ", err)
+		return nil, errors.Wrap(err, "Failed to create client")
 	}
-	return client, nil
+	return storageClient{client: client}, nil
 }
 
 // CreateStorageBucket creates a storage bucket on GCP
-func CreateStorageBucket(client *storage.Client, name, project string) error {
+func CreateStorageBucket(client StorageAPI, name, project string) error {
 	ctx := context.Background()
-	bkt := client.Bucket(name)
-
-	err := bkt.Create(ctx, project, nil)
+	err := client.CreateBucket(ctx, project, name)
 	if err != nil {
-		return errors.Errorf("Failed to create bucket: ", err)
+		return errors.Wrap(err, "Failed to create bucket")
 	}
-	fmt.Println("Bucket", name, "created")
+	fmt.Fprintln(os.Stderr, "Bucket", name, "created")
 	return nil
 }
 
-// GetStorageBucketInfo outputs instance info
-func GetStorageBucketInfo(client *storage.Client, name string) error {
+// ListStorageBuckets fetches the attributes of every bucket in the project
+func ListStorageBuckets(client StorageAPI, project string) ([]*storage.BucketAttrs, error) {
 	ctx := context.Background()
-	bkt := client.Bucket(name)
-	attrs, err := bkt.Attrs(ctx)
+	buckets, err := client.ListBuckets(ctx, project)
 	if err != nil {
-		return errors.Errorf("Failed to fetch bucket: ", err)
+		return nil, errors.Wrap(err, "Failed to list buckets")
+	}
+	return buckets, nil
+}
+
+// GetStorageBucketInfo fetches a bucket and summarizes it along with its contents
+func GetStorageBucketInfo(client StorageAPI, name string) (*provider.Resource, error) {
+	ctx := context.Background()
+	attrs, err := client.BucketAttrs(ctx, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch bucket")
 	}
 
 	// get objects
-	query := &storage.Query{Prefix: ""}
-	var names []string
-	obj := bkt.Objects(ctx, query)
-	for {
-		attrs, err := obj.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
-		names = append(names, attrs.Name)
+	names, err := client.ListObjects(ctx, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch objects in bucket")
 	}
 
-	fmt.Printf(
-		"Name: %s\nCreated: %s\nLocation: %s\nStorage Class: %s\n\n",
-		attrs.Name, attrs.Created, attrs.Location, attrs.StorageClass)
-	fmt.Println("Bucket contents:")
-	for _, file := range names {
-		fmt.Printf(" - %s\n", file)
+	resource := StorageBucketResource(attrs)
+	resource.Details = map[string]string{
+		"object_count": strconv.Itoa(len(names)),
+		"objects":      strings.Join(names, ","),
 	}
-	return nil
+	return &resource, nil
 }
 
-// DeleteStorageObjects empties a bucket for deletion
-func DeleteStorageBucket(client *storage.Client, name, project string) error {
+// StorageBucketResource converts a Storage bucket into the common resource summary
+func StorageBucketResource(attrs *storage.BucketAttrs) provider.Resource {
+	return provider.Resource{
+		Name:     attrs.Name,
+		ID:       attrs.Name,
+		Provider: "gcp",
+		Region:   attrs.Location,
+		Size:     attrs.StorageClass,
+		Endpoint: "gs://" + attrs.Name,
+		Created:  attrs.Created,
+	}
+}
+
+// DeleteStorageBucket delets a Storage bucket from GCP
+func DeleteStorageBucket(client StorageAPI, name, project string) error {
 	ctx := context.Background()
-	err := client.Bucket(name).Delete(ctx)
+	err := client.DeleteBucket(ctx, name)
 	if err != nil {
-		return errors.Errorf("Failed to delete bucket:", err)
+		return errors.Wrap(err, "Failed to delete bucket")
 	}
-	fmt.Println("Bucket", name, "deleted")
+	fmt.Fprintln(os.Stderr, "Bucket", name, "deleted")
 	return nil
 }
 
-// DeleteStorageBucket delets a Storage bucket from GCP
-func DeleteStorageObjects(client *storage.Client, name, project string) error {
-	// confirm that deleteing space will delete all files first
-	var confirmation string
-	fmt.Printf("\nWARNING: To delete a Storage bucket, all objects in that bucket must be deleted!\n")
-	fmt.Print("Do you wish to continue? (Y/n): ")
-	fmt.Scanln(&confirmation)
-	confirmation = strings.ToLower(string(confirmation))
-	println()
-
-	if confirmation != "y" {
-		return errors.Errorf("Cannot proceed -- must delete files before deleting bucket")
-	}
+// DeleteStorageObjects empties a bucket for deletion
+func DeleteStorageObjects(client StorageAPI, name string, objects []string) error {
 	ctx := context.Background()
-	bucket := client.Bucket(name)
-	item := bucket.Objects(ctx, nil)
-	for {
-		objAttrs, err := item.Next()
-		if err != nil && err != iterator.Done {
-			return errors.Errorf("Failed to fetch files from bucket")
-		}
-		if err == iterator.Done {
-			break
-		}
-		if err := bucket.Object(objAttrs.Name).Delete(ctx); err != nil {
-			return errors.Errorf("Failed to delete files from bucket")
+	for _, object := range objects {
+		if err := client.DeleteObject(ctx, name, object); err != nil {
+			return errors.Wrap(err, "Failed to delete files from bucket")
 		}
 	}
-	fmt.Println("Deleted all object items in the bucket specified.")
+	fmt.Fprintln(os.Stderr, "All objects from", name, "deleted")
 	return nil
 }
+
+// ListStorageObjects fetches the names of every object in a bucket
+func ListStorageObjects(client StorageAPI, name string) ([]string, error) {
+	objects, err := client.ListObjects(context.Background(), name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch files from bucket")
+	}
+	return objects, nil
+}
@@ -0,0 +1,99 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"maker/internal/provider"
+	"maker/internal/waiter"
+	"os"
+
+	"github.com/pkg/errors"
+	containerpb "google.golang.org/genproto/googleapis/container/v1"
+)
+
+// GetGkeServerConfig fetches the Kubernetes versions GKE offers in a zone
+func GetGkeServerConfig(client ClusterManagerAPI, project, zone string) (*containerpb.ServerConfig, error) {
+	config, err := client.GetServerConfig(context.Background(), &containerpb.GetServerConfigRequest{
+		Name: "projects/" + project + "/locations/" + zone,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch GKE versions")
+	}
+	return config, nil
+}
+
+// GkeClusterVersions finds the versions the control plane of a cluster can be upgraded to,
+// the valid master versions newer than its own and at most one minor version ahead
+func GkeClusterVersions(cluster *containerpb.Cluster, config *containerpb.ServerConfig) *provider.ClusterVersions {
+	versions := &provider.ClusterVersions{
+		Provider:  "gcp",
+		Cluster:   cluster.Name,
+		Current:   cluster.CurrentMasterVersion,
+		Available: []string{},
+	}
+	minor, next := provider.MinorVersion(versions.Current), provider.NextMinorVersion(versions.Current)
+	for _, version := range config.ValidMasterVersions {
+		if provider.CompareVersions(version, versions.Current) <= 0 {
+			continue
+		}
+		if m := provider.MinorVersion(version); m == minor || m == next {
+			versions.Available = append(versions.Available, version)
+		}
+	}
+	return versions
+}
+
+// GkeMasterUpgradeRequest builds the request to upgrade the control plane of a cluster
+func GkeMasterUpgradeRequest(project, zone, name, version string) *containerpb.UpdateMasterRequest {
+	return &containerpb.UpdateMasterRequest{
+		Name:          clusterPath(project, zone, name),
+		MasterVersion: version,
+	}
+}
+
+// GkeNodePoolUpgradeRequest builds the request to upgrade a node pool, keeping its image type
+func GkeNodePoolUpgradeRequest(pool *containerpb.NodePool, path, version string) *containerpb.UpdateNodePoolRequest {
+	req := &containerpb.UpdateNodePoolRequest{Name: path, NodeVersion: version}
+	if pool.Config != nil {
+		req.ImageType = pool.Config.ImageType
+	}
+	return req
+}
+
+// UpgradeGkeMaster starts the upgrade of the control plane of a cluster, returning the operation to wait on
+func UpgradeGkeMaster(client ClusterManagerAPI, req *containerpb.UpdateMasterRequest) (*containerpb.Operation, error) {
+	op, err := client.UpdateMaster(context.Background(), req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to upgrade cluster")
+	}
+	fmt.Fprintln(os.Stderr, "Cluster", LastPathSegment(req.Name), "upgrading to", req.MasterVersion)
+	return op, nil
+}
+
+// UpgradeGkeNodePool starts the upgrade of a node pool, returning the operation to wait on
+func UpgradeGkeNodePool(client ClusterManagerAPI, req *containerpb.UpdateNodePoolRequest) (*containerpb.Operation, error) {
+	op, err := client.UpdateNodePool(context.Background(), req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to upgrade node pool")
+	}
+	fmt.Fprintln(os.Stderr, "Node pool", LastPathSegment(req.Name), "upgrading to", req.NodeVersion)
+	return op, nil
+}
+
+// GkeOperationState reports the status of a cluster operation for waiting on. The cluster
+// and its pools stay RUNNING while an upgrade starts, so only the operation tells when it is
+// over. Operations that finish with an error fail the wait.
+func GkeOperationState(client ClusterManagerAPI, project, zone, operation string) waiter.Condition {
+	return func() (string, error) {
+		op, err := client.GetOperation(context.Background(), &containerpb.GetOperationRequest{
+			Name: "projects/" + project + "/locations/" + zone + "/operations/" + operation,
+		})
+		if err != nil {
+			return "", err
+		}
+		if op.Status == containerpb.Operation_DONE && op.StatusMessage != "" {
+			return op.Status.String(), waiter.Fail(errors.New(op.StatusMessage))
+		}
+		return op.Status.String(), nil
+	}
+}
@@ -0,0 +1,233 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"maker/internal/profile"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Path is the kubeconfig set with the --kubeconfig flag, it wins over $KUBECONFIG and ~/.kube/config
+var Path string
+
+// SwitchContext is set by the --switch-context flag and makes merged clusters the current context
+var SwitchContext bool
+
+// Messages is where merges and removals are reported, stderr keeps stdout free for command output
+var Messages io.Writer = os.Stderr
+
+// Config is a kubeconfig file. Fields Maker doesn't use are kept as they are so
+// merging into an existing file doesn't lose anything.
+type Config struct {
+	APIVersion     string                 `yaml:"apiVersion"`
+	Kind           string                 `yaml:"kind"`
+	Preferences    map[string]interface{} `yaml:"preferences"`
+	Clusters       []NamedEntry           `yaml:"clusters"`
+	Contexts       []NamedEntry           `yaml:"contexts"`
+	CurrentContext string                 `yaml:"current-context"`
+	Users          []NamedEntry           `yaml:"users"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+// NamedEntry is a named cluster, context or user of a kubeconfig
+type NamedEntry struct {
+	Name    string                 `yaml:"name"`
+	Cluster map[string]interface{} `yaml:"cluster,omitempty"`
+	Context map[string]interface{} `yaml:"context,omitempty"`
+	User    map[string]interface{} `yaml:"user,omitempty"`
+}
+
+// Entry is what a provider adds to the kubeconfig for one of its clusters
+type Entry struct {
+	Provider string
+	Name     string
+	// Cluster holds the server and certificate-authority-data of the cluster
+	Cluster map[string]interface{}
+	// User holds how kubectl authenticates, e.g. a token or an exec plugin
+	User map[string]interface{}
+}
+
+// ExecAPIVersion is the client authentication API of the exec credentials 'maker kube-token' prints
+const ExecAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// ExecUser returns a kubeconfig user that gets its tokens from 'maker kube-token'
+// with the credentials of the active profile
+func ExecUser(providerName, cluster string) map[string]interface{} {
+	return map[string]interface{}{
+		"exec": map[string]interface{}{
+			"apiVersion":  ExecAPIVersion,
+			"command":     "maker",
+			"args":        []string{"kube-token", "--provider", providerName, "--cluster", cluster, "--profile", profile.Current()},
+			"installHint": "maker is required to authenticate to clusters it created, install it and run 'maker auth --provider " + providerName + "'",
+		},
+	}
+}
+
+// ExecCredential is the token kubectl reads from the output of an exec plugin
+type ExecCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Spec       struct{}             `json:"spec"`
+	Status     ExecCredentialStatus `json:"status"`
+}
+
+// ExecCredentialStatus holds the token of an ExecCredential
+type ExecCredentialStatus struct {
+	ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+	Token               string    `json:"token"`
+}
+
+// NewExecCredential returns the ExecCredential for a token, kubectl runs the plugin again once it expires
+func NewExecCredential(token string, expiry time.Time) ExecCredential {
+	return ExecCredential{
+		APIVersion: ExecAPIVersion,
+		Kind:       "ExecCredential",
+		Status:     ExecCredentialStatus{ExpirationTimestamp: expiry.UTC(), Token: token},
+	}
+}
+
+// ContextName returns the context, cluster and user name Maker uses for a cluster
+func ContextName(providerName, cluster string) string {
+	return "maker-" + providerName + "-" + cluster
+}
+
+// File returns the kubeconfig to merge into: --kubeconfig, then the first file in
+// $KUBECONFIG, then ~/.kube/config
+func File() string {
+	if Path != "" {
+		return Path
+	}
+	for _, path := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if path != "" {
+			return path
+		}
+	}
+	return filepath.Join(utils.HomeDir, ".kube", "config")
+}
+
+// Parse decodes a kubeconfig
+func Parse(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse kubeconfig")
+	}
+	return config, nil
+}
+
+// Load reads a kubeconfig, a missing file is an empty config
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{APIVersion: "v1", Kind: "Config"}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read kubeconfig %s", path)
+	}
+	config, err := Parse(data)
+	return config, errors.Wrapf(err, "Invalid kubeconfig %s", path)
+}
+
+// Save writes the kubeconfig readable by the owner only, since it holds credentials
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal kubeconfig")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrapf(err, "Failed to create directory for %s", path)
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return errors.Wrapf(err, "Failed to write kubeconfig %s", path)
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
+
+// Add puts the cluster, user and context of an entry in the config, replacing any with the same name
+func (c *Config) Add(entry Entry) string {
+	name := ContextName(entry.Provider, entry.Name)
+	c.Remove(name)
+	c.Clusters = append(c.Clusters, NamedEntry{Name: name, Cluster: entry.Cluster})
+	c.Users = append(c.Users, NamedEntry{Name: name, User: entry.User})
+	c.Contexts = append(c.Contexts, NamedEntry{Name: name, Context: map[string]interface{}{"cluster": name, "user": name}})
+	return name
+}
+
+// Remove drops the cluster, user and context called name, reporting whether there was one.
+// The current context is cleared if it pointed at them.
+func (c *Config) Remove(name string) bool {
+	found := false
+	drop := func(entries []NamedEntry) []NamedEntry {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry.Name == name {
+				found = true
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		return kept
+	}
+	c.Clusters, c.Contexts, c.Users = drop(c.Clusters), drop(c.Contexts), drop(c.Users)
+	if c.CurrentContext == name {
+		c.CurrentContext = ""
+	}
+	return found
+}
+
+// Merge adds a cluster to the kubeconfig File, making it the current context with
+// --switch-context or when there is none yet
+func Merge(entry Entry) error {
+	path := File()
+	config, err := Load(path)
+	if err != nil {
+		return err
+	}
+	name := config.Add(entry)
+	if SwitchContext || config.CurrentContext == "" {
+		config.CurrentContext = name
+	}
+	if err := config.Save(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(Messages, "Kubeconfig context %s added to %s\n", name, path)
+	if config.CurrentContext != name {
+		fmt.Fprintf(Messages, "To use it, run 'kubectl config use-context %s'\n", name)
+	}
+	return nil
+}
+
+// Remove deletes the context of a cluster from the kubeconfig File
+func Remove(providerName, cluster string) error {
+	path := File()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	config, err := Load(path)
+	if err != nil {
+		return err
+	}
+	name := ContextName(providerName, cluster)
+	if !config.Remove(name) {
+		return nil
+	}
+	if err := config.Save(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(Messages, "Kubeconfig context %s removed from %s\n", name, path)
+	return nil
+}
+
+// Forget removes the context of a deleted cluster, warning rather than failing since the cluster is already gone
+func Forget(providerName, cluster string) {
+	if err := Remove(providerName, cluster); err != nil {
+		fmt.Fprintln(Messages, "Warning: cluster deleted but kubeconfig not updated:", err)
+	}
+}
@@ -0,0 +1,202 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useTempKubeconfig points the package at a kubeconfig in a temporary directory
+func useTempKubeconfig(t *testing.T) string {
+	oldPath, oldSwitch, oldMessages := Path, SwitchContext, Messages
+	Path = filepath.Join(t.TempDir(), "config")
+	Messages = ioutil.Discard
+	t.Cleanup(func() {
+		Path, SwitchContext, Messages = oldPath, oldSwitch, oldMessages
+	})
+	return Path
+}
+
+// setenv sets an environment variable for the duration of a test
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func testEntry(providerName, name, server string) Entry {
+	return Entry{
+		Provider: providerName,
+		Name:     name,
+		Cluster:  map[string]interface{}{"server": server},
+		User:     map[string]interface{}{"token": "secret"},
+	}
+}
+
+func load(t *testing.T, path string) *Config {
+	t.Helper()
+	config, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func names(entries []NamedEntry) []string {
+	var result []string
+	for _, entry := range entries {
+		result = append(result, entry.Name)
+	}
+	return result
+}
+
+func TestMerge(t *testing.T) {
+	path := useTempKubeconfig(t)
+	existing := `apiVersion: v1
+kind: Config
+clusters:
+- name: minikube
+  cluster:
+    server: https://192.168.49.2:8443
+contexts:
+- name: minikube
+  context:
+    cluster: minikube
+    user: minikube
+    namespace: dev
+current-context: minikube
+users:
+- name: minikube
+  user:
+    client-key: /home/me/.minikube/client.key
+extensions:
+- name: tool
+`
+	if err := ioutil.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Merge(testEntry("do", "k8s", "https://old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Merge(testEntry("aws", "k8s", "https://eks")); err != nil {
+		t.Fatal(err)
+	}
+	// merging a cluster again replaces it
+	if err := Merge(testEntry("do", "k8s", "https://new")); err != nil {
+		t.Fatal(err)
+	}
+
+	config := load(t, path)
+	want := []string{"minikube", "maker-aws-k8s", "maker-do-k8s"}
+	for _, got := range [][]string{names(config.Clusters), names(config.Contexts), names(config.Users)} {
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+			t.Errorf("entries = %v, want %v", got, want)
+		}
+	}
+	if server := config.Clusters[2].Cluster["server"]; server != "https://new" {
+		t.Errorf("expected the cluster to be replaced, server is %v", server)
+	}
+	if config.CurrentContext != "minikube" {
+		t.Errorf("current context changed to %q without --switch-context", config.CurrentContext)
+	}
+	if config.Contexts[0].Context["namespace"] != "dev" || config.Extra["extensions"] == nil {
+		t.Errorf("unrelated settings were lost: %+v", config)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("kubeconfig mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestMergeCurrentContext(t *testing.T) {
+	path := useTempKubeconfig(t)
+
+	// the first cluster of an empty kubeconfig becomes the current context
+	if err := Merge(testEntry("do", "a", "https://a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Merge(testEntry("do", "b", "https://b")); err != nil {
+		t.Fatal(err)
+	}
+	if got := load(t, path).CurrentContext; got != "maker-do-a" {
+		t.Errorf("current context = %q, want maker-do-a", got)
+	}
+
+	SwitchContext = true
+	if err := Merge(testEntry("do", "b", "https://b")); err != nil {
+		t.Fatal(err)
+	}
+	if got := load(t, path).CurrentContext; got != "maker-do-b" {
+		t.Errorf("current context = %q, want maker-do-b", got)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	path := useTempKubeconfig(t)
+
+	// nothing to remove from a missing kubeconfig
+	if err := Remove("do", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Remove created the kubeconfig")
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if err := Merge(testEntry("gcp", name, "https://"+name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Remove("gcp", "a"); err != nil {
+		t.Fatal(err)
+	}
+	config := load(t, path)
+	if got := names(config.Contexts); len(got) != 1 || got[0] != "maker-gcp-b" {
+		t.Errorf("contexts = %v, want [maker-gcp-b]", got)
+	}
+	if len(config.Clusters) != 1 || len(config.Users) != 1 {
+		t.Errorf("cluster or user left behind: %v %v", names(config.Clusters), names(config.Users))
+	}
+	if config.CurrentContext != "" {
+		t.Errorf("current context still points at the removed cluster: %q", config.CurrentContext)
+	}
+}
+
+func TestFile(t *testing.T) {
+	useTempKubeconfig(t)
+	Path = ""
+	setenv(t, "KUBECONFIG", string(filepath.ListSeparator)+"/tmp/one"+string(filepath.ListSeparator)+"/tmp/two")
+	if got := File(); got != "/tmp/one" {
+		t.Errorf("File() = %q, want the first file of $KUBECONFIG", got)
+	}
+	Path = "/tmp/flag"
+	if got := File(); got != "/tmp/flag" {
+		t.Errorf("File() = %q, want the --kubeconfig flag", got)
+	}
+}
+
+func TestExecCredential(t *testing.T) {
+	expiry := time.Date(2021, 3, 1, 12, 0, 0, 0, time.FixedZone("EST", -5*3600))
+	data, err := json.Marshal(NewExecCredential("k8s-aws-v1.abc", expiry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","spec":{},"status":{"expirationTimestamp":"2021-03-01T17:00:00Z","token":"k8s-aws-v1.abc"}}`
+	if string(data) != want {
+		t.Errorf("ExecCredential = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,132 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Supported output formats
+const (
+	Table = "table"
+	JSON  = "json"
+	YAML  = "yaml"
+)
+
+// Formats lists every supported output format
+var Formats = []string{Table, JSON, YAML}
+
+// Validate checks that format is one Maker knows how to render
+func Validate(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return errs.Errorf(errs.Usage, "Unknown output format %s -- must be one of %s", format, strings.Join(Formats, "|"))
+}
+
+// PrintResource renders a single resource in the given format
+func PrintResource(out io.Writer, format string, resource *provider.Resource) error {
+	switch format {
+	case JSON:
+		return printJSON(out, resource)
+	case YAML:
+		return printYAML(out, resource)
+	default:
+		return printTable(out, []provider.Resource{*resource})
+	}
+}
+
+// PrintResources renders a list of resources in the given format
+func PrintResources(out io.Writer, format string, resources []provider.Resource) error {
+	// always emit a list so scripts don't have to special case no results
+	if resources == nil {
+		resources = []provider.Resource{}
+	}
+	switch format {
+	case JSON:
+		return printJSON(out, resources)
+	case YAML:
+		return printYAML(out, resources)
+	default:
+		return printTable(out, resources)
+	}
+}
+
+// PrintRows renders v in JSON or YAML, or rows as a table, for output that isn't a list of resources
+func PrintRows(out io.Writer, format string, v interface{}, header []string, rows [][]string) error {
+	switch format {
+	case JSON:
+		return printJSON(out, v)
+	case YAML:
+		return printYAML(out, v)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = orDash(cell)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+func printJSON(out io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal JSON")
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+func printYAML(out io.Writer, v interface{}) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal YAML")
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// printTable writes resources as an aligned table
+func printTable(out io.Writer, resources []provider.Resource) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tNAME\tID\tREGION\tSIZE\tSTATUS\tADDRESSES\tENDPOINT\tCREATED")
+	for _, r := range resources {
+		created := "-"
+		if !r.Created.IsZero() {
+			created = r.Created.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			orDash(r.Provider),
+			orDash(r.Name),
+			orDash(r.ID),
+			orDash(r.Region),
+			orDash(r.Size),
+			orDash(r.Status),
+			orDash(strings.Join(r.Addresses, ",")),
+			orDash(r.Endpoint),
+			created,
+		)
+	}
+	return w.Flush()
+}
+
+// orDash keeps empty table cells from collapsing the column alignment
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"maker/internal/provider"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, format := range Formats {
+		if err := Validate(format); err != nil {
+			t.Errorf("Validate(%q): %v", format, err)
+		}
+	}
+	if err := Validate("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestPrintResources(t *testing.T) {
+	resources := []provider.Resource{
+		{Provider: "do", Name: "web", ID: "1", Addresses: []string{"10.0.0.1", "10.0.0.2"}},
+	}
+
+	var table bytes.Buffer
+	if err := PrintResources(&table, Table, resources); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "PROVIDER") {
+		t.Fatalf("unexpected table:\n%s", table.String())
+	}
+	if !strings.Contains(lines[1], "10.0.0.1,10.0.0.2") || !strings.Contains(lines[1], " - ") {
+		t.Errorf("expected joined addresses and dashes for empty cells: %q", lines[1])
+	}
+
+	var out bytes.Buffer
+	if err := PrintResources(&out, JSON, resources); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []provider.Resource
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded[0].Name != "web" {
+		t.Errorf("JSON didn't round trip: %v %+v", err, decoded)
+	}
+
+	// scripts get an empty list rather than null
+	out.Reset()
+	if err := PrintResources(&out, JSON, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("expected an empty JSON list, got %q", out.String())
+	}
+	out.Reset()
+	if err := PrintResource(&out, YAML, &resources[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "name: web") {
+		t.Errorf("unexpected YAML:\n%s", out.String())
+	}
+}
+
+func TestPrintRows(t *testing.T) {
+	var table bytes.Buffer
+	rows := [][]string{{"default", "*", ""}, {"staging", "", "aws"}}
+	if err := PrintRows(&table, Table, nil, []string{"NAME", "CURRENT", "PROVIDERS"}, rows); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[1], "-") {
+		t.Errorf("unexpected table:\n%s", table.String())
+	}
+
+	var out bytes.Buffer
+	if err := PrintRows(&out, JSON, map[string]string{"name": "staging"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"name": "staging"`) {
+		t.Errorf("unexpected JSON: %s", out.String())
+	}
+}
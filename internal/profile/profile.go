@@ -0,0 +1,166 @@
+package profile
+
+import (
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Default is the profile whose config files live directly in the .maker folder
+const Default = "default"
+
+// EnvVar selects the profile when --profile isn't set
+const EnvVar = "MAKER_PROFILE"
+
+// Name is the profile set with the --profile flag, it wins over the environment and the current profile
+var Name string
+
+// FolderName is the folder under .maker holding a folder per named profile
+var FolderName = "profiles"
+
+// CurrentName is the file under .maker recording the profile chosen with 'maker profile use'
+var CurrentName = "current_profile"
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Validate checks that name can be used as a folder name
+func Validate(name string) error {
+	if !validName.MatchString(name) {
+		return errs.Errorf(errs.Usage, "Invalid profile name %q -- use letters, numbers, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// Current returns the active profile, from --profile, then MAKER_PROFILE, then 'maker profile use'
+func Current() string {
+	if Name != "" {
+		return Name
+	}
+	if name := os.Getenv(EnvVar); name != "" {
+		return name
+	}
+	data, err := ioutil.ReadFile(filepath.Join(utils.ConfigFolderPath, CurrentName))
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data))
+	}
+	return Default
+}
+
+// invalidFolder stands in for names that fail Validate, no valid profile can be named like it
+const invalidFolder = "_invalid"
+
+// Dir returns the folder holding the config files of a profile. Names that fail
+// Validate get a folder no profile can have, so they never point outside .maker
+func Dir(name string) string {
+	if name == Default {
+		return utils.ConfigFolderPath
+	}
+	if Validate(name) != nil {
+		name = invalidFolder
+	}
+	return filepath.Join(utils.ConfigFolderPath, FolderName, name)
+}
+
+// Path returns the path of a config file in the active profile
+func Path(file string) string {
+	return filepath.Join(Dir(Current()), file)
+}
+
+// EnsureDir creates the folder of the active profile if needed and returns it
+func EnsureDir() (string, error) {
+	name := Current()
+	if err := Validate(name); err != nil {
+		return "", err
+	}
+	dir := Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrapf(err, "Failed to create config directory %s", dir)
+	}
+	return dir, nil
+}
+
+// List returns the default profile and every named profile in sorted order
+func List() ([]string, error) {
+	names := []string{Default}
+	entries, err := ioutil.ReadDir(filepath.Join(utils.ConfigFolderPath, FolderName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "Failed to read profiles")
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+// Exists reports whether a profile has been created
+func Exists(name string) bool {
+	if name == Default {
+		return true
+	}
+	info, err := os.Stat(Dir(name))
+	return err == nil && info.IsDir()
+}
+
+// Files returns which of the given config files exist in a profile
+func Files(name string, files ...string) []string {
+	var found []string
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(Dir(name), file)); err == nil {
+			found = append(found, file)
+		}
+	}
+	return found
+}
+
+// Use makes name the current profile for later commands
+func Use(name string) error {
+	if err := Validate(name); err != nil {
+		return err
+	}
+	if !Exists(name) {
+		return errs.Errorf(errs.NotFound, "Profile %s doesn't exist -- create it with 'maker auth --profile %s'", name, name)
+	}
+	if err := os.MkdirAll(utils.ConfigFolderPath, 0755); err != nil {
+		return errors.Wrapf(err, "Failed to create config directory %s", utils.ConfigFolder)
+	}
+	path := filepath.Join(utils.ConfigFolderPath, CurrentName)
+	if name == Default {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "Failed to reset current profile")
+		}
+		return nil
+	}
+	return errors.Wrap(ioutil.WriteFile(path, []byte(name+"\n"), 0600), "Failed to save current profile")
+}
+
+// Delete removes a named profile and its config files, switching back to
+// the default profile if it was the current one
+func Delete(name string) error {
+	if name == Default {
+		return errs.New(errs.Usage, "The default profile can't be deleted")
+	}
+	if err := Validate(name); err != nil {
+		return err
+	}
+	if !Exists(name) {
+		return errs.Errorf(errs.NotFound, "Profile %s doesn't exist", name)
+	}
+	if err := os.RemoveAll(Dir(name)); err != nil {
+		return errors.Wrapf(err, "Failed to delete profile %s", name)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(utils.ConfigFolderPath, CurrentName))
+	if strings.TrimSpace(string(data)) == name {
+		return Use(Default)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package profile
+
+import (
+	"maker/internal/errs"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useTempFolder points the .maker folder at a temporary directory with no profile selected
+func useTempFolder(t *testing.T) string {
+	dir := t.TempDir()
+	oldFolder, oldName := utils.ConfigFolderPath, Name
+	oldEnv, hadEnv := os.LookupEnv(EnvVar)
+	utils.ConfigFolderPath, Name = dir, ""
+	os.Unsetenv(EnvVar)
+	t.Cleanup(func() {
+		utils.ConfigFolderPath, Name = oldFolder, oldName
+		if hadEnv {
+			os.Setenv(EnvVar, oldEnv)
+		}
+	})
+	return dir
+}
+
+func TestCurrentPrecedence(t *testing.T) {
+	dir := useTempFolder(t)
+	if Current() != Default {
+		t.Errorf("expected the default profile, got %s", Current())
+	}
+	if Path("do_config") != filepath.Join(dir, "do_config") {
+		t.Errorf("the default profile should use the .maker folder, got %s", Path("do_config"))
+	}
+
+	os.MkdirAll(Dir("sandbox"), 0755)
+	if err := Use("sandbox"); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if Current() != "sandbox" {
+		t.Errorf("expected the used profile, got %s", Current())
+	}
+	os.Setenv(EnvVar, "staging")
+	if Current() != "staging" {
+		t.Errorf("expected %s to win over the used profile, got %s", EnvVar, Current())
+	}
+	Name = "prod"
+	if Current() != "prod" {
+		t.Errorf("expected --profile to win, got %s", Current())
+	}
+	if Path("do_config") != filepath.Join(dir, "profiles", "prod", "do_config") {
+		t.Errorf("unexpected profile path %s", Path("do_config"))
+	}
+}
+
+func TestListUseDelete(t *testing.T) {
+	useTempFolder(t)
+	if err := Use("staging"); errs.ClassOf(err) != errs.NotFound {
+		t.Errorf("expected using a missing profile to fail, got %v", err)
+	}
+
+	Name = "staging"
+	if _, err := EnsureDir(); err != nil {
+		t.Fatal(err)
+	}
+	Name = ""
+	names, err := List()
+	if err != nil || !reflect.DeepEqual(names, []string{Default, "staging"}) {
+		t.Fatalf("List = %v, %v", names, err)
+	}
+
+	if err := Use("staging"); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if err := Delete("staging"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if Exists("staging") || Current() != Default {
+		t.Errorf("deleting the current profile should switch back to the default one")
+	}
+	if err := Delete(Default); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected the default profile to be kept, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{"staging", "team-2", "a.b_c"} {
+		if err := Validate(name); err != nil {
+			t.Errorf("Validate(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../etc", "a/b", ".hidden"} {
+		if err := Validate(name); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestInvalidCurrentStaysInFolder(t *testing.T) {
+	dir := useTempFolder(t)
+	for _, name := range []string{"../../etc", "a/b", ".."} {
+		os.Setenv(EnvVar, name)
+		if err := Validate(Current()); errs.ClassOf(err) != errs.Usage {
+			t.Errorf("expected a usage error for %q, got %v", name, err)
+		}
+		if path := Path("do_config"); path != filepath.Join(dir, "profiles", invalidFolder, "do_config") {
+			t.Errorf("expected %q to stay in the profiles folder, got %s", name, path)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"maker/internal/errs"
+	"sort"
+	"strings"
+)
+
+// Aliases maps provider neutral names for sizes and images to the values each provider takes,
+// keyed by catalog kind, alias and provider name
+type Aliases map[string]map[string]map[string]string
+
+// DefaultAliases are the built-in aliases, the settings file can override and add to them.
+// AWS images are 'OWNER/NAME-PATTERN' and resolve to the newest matching AMI of the region,
+// GCP images use the image family so they don't go stale either.
+var DefaultAliases = Aliases{
+	CatalogSizes: {
+		"micro":  {"do": "s-1vcpu-1gb", "aws": "t3.micro", "gcp": "e2-micro"},
+		"small":  {"do": "s-1vcpu-2gb", "aws": "t3.small", "gcp": "e2-small"},
+		"medium": {"do": "s-2vcpu-4gb", "aws": "t3.medium", "gcp": "e2-medium"},
+		"large":  {"do": "s-4vcpu-8gb", "aws": "t3.large", "gcp": "e2-standard-2"},
+		"xlarge": {"do": "s-8vcpu-16gb", "aws": "t3.xlarge", "gcp": "e2-standard-4"},
+	},
+	CatalogImages: {
+		"ubuntu-22.04": {
+			"do":  "ubuntu-22-04-x64",
+			"aws": "099720109477/ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-*",
+			"gcp": "ubuntu-os-cloud/family/ubuntu-2204-lts",
+		},
+		"ubuntu-20.04": {
+			"do":  "ubuntu-20-04-x64",
+			"aws": "099720109477/ubuntu/images/hvm-ssd/ubuntu-focal-20.04-amd64-server-*",
+			"gcp": "ubuntu-os-cloud/family/ubuntu-2004-lts",
+		},
+		"debian-12": {
+			"do":  "debian-12-x64",
+			"aws": "136693071363/debian-12-amd64-*",
+			"gcp": "debian-cloud/family/debian-12",
+		},
+		"debian-11": {
+			"do":  "debian-11-x64",
+			"aws": "136693071363/debian-11-amd64-*",
+			"gcp": "debian-cloud/family/debian-11",
+		},
+	},
+}
+
+// Merge returns the aliases with overrides laid over them, per provider, neither is changed
+func (a Aliases) Merge(overrides Aliases) Aliases {
+	merged := Aliases{}
+	for _, layer := range []Aliases{a, overrides} {
+		for kind, aliases := range layer {
+			if merged[kind] == nil {
+				merged[kind] = map[string]map[string]string{}
+			}
+			for alias, values := range aliases {
+				alias = strings.ToLower(alias)
+				if merged[kind][alias] == nil {
+					merged[kind][alias] = map[string]string{}
+				}
+				for providerName, value := range values {
+					merged[kind][alias][providerName] = value
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// Resolve returns the value an alias of a kind stands for on a provider.
+// Names that aren't aliases are provider specific already and returned unchanged.
+func (a Aliases) Resolve(kind, providerName, name string) (string, error) {
+	values, ok := a[kind][strings.ToLower(name)]
+	if !ok {
+		return name, nil
+	}
+	value, ok := values[providerName]
+	if !ok || value == "" {
+		return "", errs.Errorf(errs.Usage, "The %s alias %s isn't defined for %s -- add it under aliases.%s.%s in the config file", strings.TrimSuffix(kind, "s"), name, providerName, kind, name)
+	}
+	return value, nil
+}
+
+// Names returns the aliases of a kind in sorted order
+func (a Aliases) Names(kind string) []string {
+	names := make([]string, 0, len(a[kind]))
+	for name := range a[kind] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"maker/internal/errs"
+	"testing"
+)
+
+func TestResolveAlias(t *testing.T) {
+	aliases := DefaultAliases.Merge(Aliases{
+		CatalogSizes:  {"Small": {"do": "s-1vcpu-1gb"}},
+		CatalogImages: {"alpine": {"do": "alpine-3-x64"}},
+	})
+	tests := []struct {
+		kind, provider, name string
+		want                 string
+	}{
+		{CatalogSizes, "do", "small", "s-1vcpu-1gb"},
+		{CatalogSizes, "aws", "SMALL", "t3.small"},
+		{CatalogSizes, "gcp", "e2-highmem-2", "e2-highmem-2"},
+		{CatalogImages, "gcp", "debian-12", "debian-cloud/family/debian-12"},
+		{CatalogImages, "do", "alpine", "alpine-3-x64"},
+	}
+	for _, tt := range tests {
+		got, err := aliases.Resolve(tt.kind, tt.provider, tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%s, %s, %s) = %q, %v, want %q", tt.kind, tt.provider, tt.name, got, err, tt.want)
+		}
+	}
+
+	_, err := aliases.Resolve(CatalogImages, "aws", "alpine")
+	if errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error for an alias missing on a provider, got %v", err)
+	}
+	if DefaultAliases[CatalogSizes]["small"]["do"] != "s-1vcpu-2gb" {
+		t.Errorf("Merge changed the built-in aliases")
+	}
+}
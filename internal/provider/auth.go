@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"maker/internal/errs"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// AuthOptions are set by the auth command flags so config files can be written without prompts
+type AuthOptions struct {
+	// NonInteractive fails on missing settings instead of prompting for them
+	NonInteractive bool
+	// TokenEnv names the environment variable holding the provider secret,
+	// overriding the standard variables the provider checks
+	TokenEnv string
+	// Region is the default region, or zone for GCP
+	Region string
+	// Project is the GCP project
+	Project string
+	// CredentialChain uses the credentials the provider SDK finds on its own instead of storing
+	// keys, e.g. AWS shared config and SSO profiles or GCP Application Default Credentials
+	CredentialChain bool
+	// SharedProfile is the AWS shared config profile used by the credential chain
+	SharedProfile string
+	// RoleARN is an AWS role to assume, with its optional ExternalID and the MFASerial of
+	// the MFA device needed to assume it
+	RoleARN    string
+	ExternalID string
+	MFASerial  string
+	// Impersonate is a GCP service account to act as
+	Impersonate string
+	// In is where prompts read answers from, defaults to stdin
+	In io.Reader
+}
+
+// Env returns the value of the first environment variable in names that is set
+func Env(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Token returns the value of the --token-env variable when given, otherwise the
+// first standard variable that is set
+func (o AuthOptions) Token(standard ...string) string {
+	if o.TokenEnv != "" {
+		return os.Getenv(o.TokenEnv)
+	}
+	return Env(standard...)
+}
+
+// Ask returns value if it is set, otherwise prompts for the setting.
+// Secrets are read without echo. In non-interactive mode a missing value is an error naming how to set it.
+func (o AuthOptions) Ask(setting, hint, prompt, value string, secret bool) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	if o.NonInteractive {
+		return "", errs.Errorf(errs.Usage, "Missing %s -- set %s", setting, hint)
+	}
+
+	fmt.Print(prompt)
+	if secret && o.In == nil {
+		pass, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		println()
+		if err != nil {
+			return "", errs.Wrapf(err, "Failed to capture %s", setting)
+		}
+		return string(pass), nil
+	}
+	return o.readLine(), nil
+}
+
+// Confirm asks a yes or no question, only a yes answer returns true
+func (o AuthOptions) Confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer := strings.ToLower(o.readLine())
+	println()
+	return answer == "y"
+}
+
+// readLine reads a single answer from In without reading past the end of the line
+func (o AuthOptions) readLine() string {
+	in := o.In
+	if in == nil {
+		in = os.Stdin
+	}
+	var answer string
+	fmt.Fscanln(in, &answer)
+	return answer
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"maker/internal/errs"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAskPrefersGivenValue(t *testing.T) {
+	opts := AuthOptions{In: strings.NewReader("typed\n")}
+	if got, err := opts.Ask("region", "--region", "Region: ", "nyc3", false); err != nil || got != "nyc3" {
+		t.Errorf("Ask = %q, %v, want the given value", got, err)
+	}
+	if got, err := opts.Ask("region", "--region", "Region: ", "", false); err != nil || got != "typed" {
+		t.Errorf("Ask = %q, %v, want the typed answer", got, err)
+	}
+}
+
+func TestAskNonInteractive(t *testing.T) {
+	opts := AuthOptions{NonInteractive: true, In: strings.NewReader("typed\n")}
+	_, err := opts.Ask("PAT token", "--token-env or DIGITALOCEAN_TOKEN", "Token: ", "", true)
+	if errs.ClassOf(err) != errs.Usage {
+		t.Fatalf("expected a usage error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "DIGITALOCEAN_TOKEN") {
+		t.Errorf("error should say how to set the value: %v", err)
+	}
+}
+
+// setenv sets an environment variable for the length of a test
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestToken(t *testing.T) {
+	setenv(t, "MAKER_TEST_STANDARD", "standard")
+	setenv(t, "MAKER_TEST_CUSTOM", "custom")
+	if got := (AuthOptions{}).Token("MAKER_TEST_UNSET", "MAKER_TEST_STANDARD"); got != "standard" {
+		t.Errorf("Token = %q, want the first standard variable that is set", got)
+	}
+	if got := (AuthOptions{TokenEnv: "MAKER_TEST_CUSTOM"}).Token("MAKER_TEST_STANDARD"); got != "custom" {
+		t.Errorf("Token = %q, want the --token-env variable", got)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	if !(AuthOptions{In: strings.NewReader("Y\n")}).Confirm("ok? ") {
+		t.Errorf("expected Y to confirm")
+	}
+	if (AuthOptions{In: strings.NewReader("\n")}).Confirm("ok? ") {
+		t.Errorf("expected an empty answer not to confirm")
+	}
+}
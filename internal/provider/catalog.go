@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"maker/internal/errs"
+	"strings"
+)
+
+// Catalog kinds, each lists the values a provider accepts for a flag of the create commands
+const (
+	CatalogSizes    = "sizes"
+	CatalogImages   = "images"
+	CatalogRegions  = "regions"
+	CatalogVersions = "versions"
+)
+
+// CatalogEntry is one value a provider accepts for --size, --image, its region or --version
+type CatalogEntry struct {
+	Slug        string `json:"slug" yaml:"slug"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	VCPUs       int    `json:"vcpus,omitempty" yaml:"vcpus,omitempty"`
+	MemoryMB    int    `json:"memory_mb,omitempty" yaml:"memory_mb,omitempty"`
+	Distro      string `json:"distro,omitempty" yaml:"distro,omitempty"`
+}
+
+// CatalogFilter narrows a catalog down, zero values match everything
+type CatalogFilter struct {
+	MinVCPUs    int
+	MinMemoryMB int
+	// Distro matches part of the distribution of an image, ignoring case
+	Distro string
+}
+
+// Match reports whether an entry passes the filter
+func (f CatalogFilter) Match(entry CatalogEntry) bool {
+	if entry.VCPUs < f.MinVCPUs || entry.MemoryMB < f.MinMemoryMB {
+		return false
+	}
+	return f.Distro == "" || strings.Contains(strings.ToLower(entry.Distro), strings.ToLower(f.Distro))
+}
+
+// FilterCatalog returns the entries that pass the filter
+func FilterCatalog(entries []CatalogEntry, filter CatalogFilter) []CatalogEntry {
+	matched := []CatalogEntry{}
+	for _, entry := range entries {
+		if filter.Match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// UnknownCatalog is returned by providers asked for a catalog kind they don't have
+func UnknownCatalog(kind string) error {
+	return errs.Errorf(errs.Usage, "Unknown catalog %q -- use %s, %s, %s or %s", kind, CatalogSizes, CatalogImages, CatalogRegions, CatalogVersions)
+}
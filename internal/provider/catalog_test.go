@@ -0,0 +1,33 @@
+package provider
+
+import "testing"
+
+func TestFilterCatalog(t *testing.T) {
+	entries := []CatalogEntry{
+		{Slug: "s-1vcpu-1gb", VCPUs: 1, MemoryMB: 1024},
+		{Slug: "s-2vcpu-4gb", VCPUs: 2, MemoryMB: 4096},
+		{Slug: "ubuntu-20-04-x64", Distro: "Ubuntu"},
+		{Slug: "debian-10-x64", Distro: "Debian"},
+	}
+	tests := []struct {
+		filter CatalogFilter
+		want   []string
+	}{
+		{CatalogFilter{}, []string{"s-1vcpu-1gb", "s-2vcpu-4gb", "ubuntu-20-04-x64", "debian-10-x64"}},
+		{CatalogFilter{MinVCPUs: 2}, []string{"s-2vcpu-4gb"}},
+		{CatalogFilter{MinMemoryMB: 2048}, []string{"s-2vcpu-4gb"}},
+		{CatalogFilter{Distro: "ubuntu"}, []string{"ubuntu-20-04-x64"}},
+	}
+	for _, tt := range tests {
+		got := FilterCatalog(entries, tt.filter)
+		if len(got) != len(tt.want) {
+			t.Errorf("FilterCatalog(%+v) = %v, want %v", tt.filter, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Slug != tt.want[i] {
+				t.Errorf("FilterCatalog(%+v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"maker/internal/errs"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// ConfigVersion is the newest config file format this build writes, files without
+// a version were written by older builds and read as version 1
+const ConfigVersion = 1
+
+// ConfigSchema describes a provider config file. Every read and write uses its own
+// viper instance so loading several providers in one process can't mix their settings.
+type ConfigSchema struct {
+	// Path returns the file in the active profile
+	Path func() string
+	// Type is the file format, yaml or toml
+	Type string
+	// Keys lists the settings the file holds, nested keys use dots, e.g. default.aws_access_key_id
+	Keys []string
+	// Required lists the keys that must be set for the provider to load
+	Required []string
+}
+
+// Read parses the config file and checks it against the schema
+func (s ConfigSchema) Read() (*viper.Viper, error) {
+	path := s.Path()
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType(s.Type)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errors.Wrapf(err, "Error reading config file %s", path)
+	}
+
+	if version := v.GetInt("version"); version > ConfigVersion {
+		return nil, errs.Errorf(errs.Usage, "Config file %s has version %d, newer than the supported version %d", path, version, ConfigVersion)
+	}
+	for _, key := range s.Keys {
+		if value := v.Get(key); value != nil {
+			if _, ok := value.(string); !ok {
+				return nil, errs.Errorf(errs.AuthFailed, "Config file %s has an invalid %s -- expected a string", path, key)
+			}
+		}
+	}
+	for _, key := range s.Required {
+		if v.GetString(key) == "" {
+			return nil, errs.Errorf(errs.AuthFailed, "Config file %s is missing %s -- run 'maker auth' again", path, key)
+		}
+	}
+	return v, nil
+}
+
+// Write replaces the config file with values, keyed like Keys, and the current version.
+// The file is only readable by its owner.
+func (s ConfigSchema) Write(values map[string]string) error {
+	for key := range values {
+		if !s.hasKey(key) {
+			return errors.Errorf("Unknown config key %s", key)
+		}
+	}
+	path := s.Path()
+	v := viper.New()
+	v.SetConfigType(s.Type)
+	v.Set("version", ConfigVersion)
+	for _, key := range s.Keys {
+		v.Set(key, values[key])
+	}
+	if err := v.WriteConfigAs(path); err != nil {
+		return errors.Wrapf(err, "Failed to write config file %s", path)
+	}
+	// WriteConfigAs keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
+
+// hasKey reports whether key is part of the schema
+func (s ConfigSchema) hasKey(key string) bool {
+	for _, k := range s.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"io/ioutil"
+	"maker/internal/errs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tempSchema returns a schema for a file in a temporary directory
+func tempSchema(t *testing.T, name, format string, keys, required []string) ConfigSchema {
+	path := filepath.Join(t.TempDir(), name)
+	return ConfigSchema{Path: func() string { return path }, Type: format, Keys: keys, Required: required}
+}
+
+func TestConfigSchemaRoundTrip(t *testing.T) {
+	yamlFile := tempSchema(t, "do_config", "yaml", []string{"pat_token", "default_region"}, []string{"pat_token"})
+	tomlFile := tempSchema(t, "aws_credentials", "toml", []string{"default.aws_access_key_id", "region.default_region"}, nil)
+
+	// write both files in one process, settings must not leak from one into the other
+	if err := yamlFile.Write(map[string]string{"pat_token": "token", "default_region": "nyc3"}); err != nil {
+		t.Fatalf("Write yaml: %v", err)
+	}
+	if err := tomlFile.Write(map[string]string{"default.aws_access_key_id": "AKIA", "region.default_region": "us-east-1"}); err != nil {
+		t.Fatalf("Write toml: %v", err)
+	}
+	data, _ := ioutil.ReadFile(tomlFile.Path())
+	if strings.Contains(string(data), "pat_token") || !strings.Contains(string(data), "version = 1") {
+		t.Errorf("unexpected toml file:\n%s", data)
+	}
+	if info, err := os.Stat(yamlFile.Path()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("config file should only be readable by its owner: %v %v", info.Mode(), err)
+	}
+
+	v, err := yamlFile.Read()
+	if err != nil {
+		t.Fatalf("Read yaml: %v", err)
+	}
+	if v.GetString("pat_token") != "token" || v.GetString("default_region") != "nyc3" || v.IsSet("default.aws_access_key_id") {
+		t.Errorf("unexpected settings %v", v.AllSettings())
+	}
+	v, err = tomlFile.Read()
+	if err != nil {
+		t.Fatalf("Read toml: %v", err)
+	}
+	if v.GetString("default.aws_access_key_id") != "AKIA" || v.IsSet("pat_token") {
+		t.Errorf("unexpected settings %v", v.AllSettings())
+	}
+
+	if err := yamlFile.Write(map[string]string{"region": "nyc3"}); err == nil {
+		t.Errorf("expected unknown keys to be rejected")
+	}
+}
+
+func TestConfigSchemaValidation(t *testing.T) {
+	schema := tempSchema(t, "gcp_config", "yaml", []string{"keyfile", "gcp_project"}, []string{"keyfile"})
+	tests := []struct {
+		name, content string
+		want          errs.Class
+	}{
+		{"unversioned", "keyfile: key.json\n", -1},
+		{"newer", "version: 2\nkeyfile: key.json\n", errs.Usage},
+		{"missing", "version: 1\ngcp_project: lab\n", errs.AuthFailed},
+		{"not a string", "keyfile: key.json\ngcp_project: [a, b]\n", errs.AuthFailed},
+	}
+	for _, test := range tests {
+		ioutil.WriteFile(schema.Path(), []byte(test.content), 0600)
+		_, err := schema.Read()
+		if test.want < 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || errs.ClassOf(err) != test.want {
+			t.Errorf("%s: expected a %s error, got %v", test.name, test.want, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"maker/internal/errs"
+	"sort"
+	"strings"
+)
+
+// Taint effects, named as in Kubernetes
+const (
+	TaintNoSchedule       = "NoSchedule"
+	TaintPreferNoSchedule = "PreferNoSchedule"
+	TaintNoExecute        = "NoExecute"
+)
+
+// Taint keeps pods that don't tolerate it off the nodes of a pool
+type Taint struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  string `json:"value,omitempty" yaml:"value,omitempty"`
+	Effect string `json:"effect" yaml:"effect"`
+}
+
+// ParseTaint reads a taint written like kubectl taint does, key=value:Effect or key:Effect
+func ParseTaint(s string) (Taint, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return Taint{}, errs.Errorf(errs.Usage, "Invalid taint %q -- expected key=value:Effect", s)
+	}
+	taint := Taint{Key: s[:i], Effect: s[i+1:]}
+	if j := strings.Index(taint.Key, "="); j >= 0 {
+		taint.Key, taint.Value = taint.Key[:j], taint.Key[j+1:]
+	}
+	if taint.Key == "" {
+		return Taint{}, errs.Errorf(errs.Usage, "Invalid taint %q -- the key is empty", s)
+	}
+	switch taint.Effect {
+	case TaintNoSchedule, TaintPreferNoSchedule, TaintNoExecute:
+	default:
+		return Taint{}, errs.Errorf(errs.Usage, "Invalid taint effect %q -- use %s, %s or %s", taint.Effect, TaintNoSchedule, TaintPreferNoSchedule, TaintNoExecute)
+	}
+	return taint, nil
+}
+
+func (t Taint) String() string {
+	if t.Value == "" {
+		return t.Key + ":" + t.Effect
+	}
+	return t.Key + "=" + t.Value + ":" + t.Effect
+}
+
+// NodePool is a provider agnostic summary of a group of identical nodes in a cluster
+type NodePool struct {
+	Provider  string            `json:"provider" yaml:"provider"`
+	Cluster   string            `json:"cluster" yaml:"cluster"`
+	Name      string            `json:"name" yaml:"name"`
+	ID        string            `json:"id" yaml:"id"`
+	Size      string            `json:"size" yaml:"size"`
+	NodeCount int               `json:"node_count" yaml:"node_count"`
+	MinNodes  int               `json:"min_nodes,omitempty" yaml:"min_nodes,omitempty"`
+	MaxNodes  int               `json:"max_nodes,omitempty" yaml:"max_nodes,omitempty"`
+	Status    string            `json:"status" yaml:"status"`
+	Labels    map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Taints    []Taint           `json:"taints,omitempty" yaml:"taints,omitempty"`
+}
+
+// Autoscaled reports whether the pool is sized by the cluster autoscaler between MinNodes and MaxNodes
+func (n NodePool) Autoscaled() bool {
+	return n.MaxNodes > 0
+}
+
+// NodeScale is the size of a node pool, fixed at NodeCount or autoscaled when MaxNodes is set
+type NodeScale struct {
+	NodeCount int `yaml:"node-count"`
+	MinNodes  int `yaml:"min-nodes"`
+	MaxNodes  int `yaml:"max-nodes"`
+}
+
+// Autoscaled reports whether the scale asks for the cluster autoscaler
+func (s NodeScale) Autoscaled() bool {
+	return s.MaxNodes > 0
+}
+
+// Validate checks the counts make sense together
+func (s NodeScale) Validate() error {
+	if s.NodeCount < 0 || s.MinNodes < 0 || s.MaxNodes < 0 {
+		return errs.New(errs.Usage, "Node counts can't be negative")
+	}
+	if !s.Autoscaled() {
+		if s.MinNodes > 0 {
+			return errs.New(errs.Usage, "Autoscaling needs --max-nodes as well as --min-nodes")
+		}
+		if s.NodeCount == 0 {
+			return errs.New(errs.Usage, "Set --node-count, or --min-nodes and --max-nodes to autoscale")
+		}
+		return nil
+	}
+	if s.MinNodes > s.MaxNodes {
+		return errs.Errorf(errs.Usage, "Minimum of %d nodes is above the maximum of %d", s.MinNodes, s.MaxNodes)
+	}
+	if s.NodeCount != 0 && (s.NodeCount < s.MinNodes || s.NodeCount > s.MaxNodes) {
+		return errs.Errorf(errs.Usage, "Node count %d is outside the autoscaling range %d-%d", s.NodeCount, s.MinNodes, s.MaxNodes)
+	}
+	return nil
+}
+
+// InitialCount returns how many nodes a new pool starts with, the minimum when autoscaling without a count
+func (s NodeScale) InitialCount() int {
+	if s.NodeCount == 0 && s.Autoscaled() {
+		if s.MinNodes == 0 {
+			return 1
+		}
+		return s.MinNodes
+	}
+	return s.NodeCount
+}
+
+// NodePoolOptions holds the settings used to add a node pool to a cluster
+type NodePoolOptions struct {
+	Cluster   string `yaml:"cluster"`
+	Name      string `yaml:"name"`
+	NodeSize  string `yaml:"node-size"`
+	NodeScale `yaml:",inline"`
+	Labels    map[string]string `yaml:"labels"`
+	Taints    []Taint           `yaml:"taints"`
+}
+
+// Validate checks the options before any request is sent
+func (o NodePoolOptions) Validate() error {
+	if o.NodeSize == "" {
+		return errs.New(errs.Usage, "A node pool needs a node size (-s)")
+	}
+	return o.NodeScale.Validate()
+}
+
+// FormatLabels writes labels as sorted key=value pairs
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
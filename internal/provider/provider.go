@@ -0,0 +1,274 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maker/internal/errs"
+	"maker/internal/waiter"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Provider is implemented by every cloud platform Maker can create objects on
+type Provider interface {
+	CreateVM(opts VMOptions) (*Resource, error)
+	GetVM(name string) (*Resource, error)
+	ListVMs() ([]Resource, error)
+	DeleteVM(name string) error
+
+	CreateCluster(opts ClusterOptions) (*Resource, error)
+	GetCluster(name string) (*Resource, error)
+	ListClusters() ([]Resource, error)
+	FetchKubeconfig(name string) error
+	ClusterUpgrades(name string) (*ClusterVersions, error)
+	UpgradeCluster(name, version string) (*Resource, error)
+	DeleteCluster(name string) error
+
+	AddNodePool(opts NodePoolOptions) (*NodePool, error)
+	ListNodePools(cluster string) ([]NodePool, error)
+	ScaleNodePool(cluster, name string, scale NodeScale) (*NodePool, error)
+	DeleteNodePool(cluster, name string) error
+
+	CreateBucket(name string) (*Resource, error)
+	GetBucket(name string) (*Resource, error)
+	ListBuckets() ([]Resource, error)
+	DeleteBucket(name string) error
+
+	CreateDB(opts DBOptions) (*Resource, error)
+	GetDB(name string) (*Resource, error)
+	ListDBs() ([]Resource, error)
+	DeleteDB(name string) error
+
+	Catalog(kind string) ([]CatalogEntry, error)
+
+	ListSSHKeys() ([]SSHKey, error)
+	AddSSHKey(key PublicKey) (*SSHKey, error)
+	RemoveSSHKey(name string) error
+
+	Verify() (*Identity, error)
+	// Region is the region or zone the provider is configured with
+	Region() string
+}
+
+// Kinds of objects Maker can create
+const (
+	KindVM      = "vm"
+	KindCluster = "cluster"
+	KindBucket  = "bucket"
+	KindDB      = "db"
+)
+
+// Resource is a provider agnostic summary of an object living on a provider
+type Resource struct {
+	Name      string    `json:"name" yaml:"name"`
+	ID        string    `json:"id" yaml:"id"`
+	Provider  string    `json:"provider" yaml:"provider"`
+	Region    string    `json:"region" yaml:"region"`
+	Size      string    `json:"size" yaml:"size"`
+	Status    string    `json:"status" yaml:"status"`
+	Addresses []string  `json:"addresses" yaml:"addresses"`
+	Endpoint  string    `json:"endpoint" yaml:"endpoint"`
+	Created   time.Time `json:"created" yaml:"created"`
+	// Details holds provider specific info that doesn't fit the common fields
+	Details map[string]string `json:"details,omitempty" yaml:"details,omitempty"`
+}
+
+// Identity is who the credentials of a provider authenticate as, found with a cheap authenticated call
+type Identity struct {
+	Provider string `json:"provider" yaml:"provider"`
+	// User is the account email, IAM ARN or service account the credentials belong to
+	User string `json:"user" yaml:"user"`
+	// Account is the DO account, AWS account ID or GCP project
+	Account string `json:"account" yaml:"account"`
+	Region  string `json:"region" yaml:"region"`
+}
+
+// KubeToken is a bearer token kubectl authenticates to a cluster with
+type KubeToken struct {
+	Token  string
+	Expiry time.Time
+}
+
+// KubeTokenIssuer is implemented by providers whose kubeconfigs call 'maker kube-token'
+// rather than embedding credentials, so kubectl works without the provider's own CLI
+type KubeTokenIssuer interface {
+	KubeToken(cluster string) (*KubeToken, error)
+}
+
+// StatusPlanned is the status of objects returned by create calls in dry run mode
+const StatusPlanned = "planned"
+
+// Options changes how a loaded provider behaves
+type Options struct {
+	// DryRun makes create and delete calls print the requests they would send instead of sending them.
+	// Read only calls such as ID lookups are still made.
+	DryRun bool
+	// Plan is where requests are printed in dry run mode, defaults to stdout
+	Plan io.Writer
+	// Wait makes create and delete calls block until the object is ready or gone.
+	// Clusters are always waited on until their kubeconfig can be written.
+	Wait bool
+	// Timeout bounds every wait, zero waits forever
+	Timeout time.Duration
+	// Progress shows the state while waiting, nil keeps quiet
+	Progress io.Writer
+	// PollInterval replaces the waiter backoff with a fixed delay when set
+	PollInterval time.Duration
+	// Yes deletes the objects of non-empty buckets without asking first
+	Yes bool
+	// In is where confirmations are read from, defaults to stdin
+	In io.Reader
+}
+
+// Waiter returns a waiter using the timeout and progress output of the options
+func (o Options) Waiter() waiter.Waiter {
+	w := waiter.New(o.Timeout, o.Progress)
+	if o.PollInterval > 0 {
+		w.Interval = o.PollInterval
+		w.MaxInterval = o.PollInterval
+	}
+	return w
+}
+
+// PrintRequest writes the request an API call would have been sent with
+func (o Options) PrintRequest(call string, request interface{}) error {
+	out := o.Plan
+	if out == nil {
+		out = os.Stdout
+	}
+	data, err := json.MarshalIndent(request, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "# %s (%T)\n%s\n", call, request, data)
+	return err
+}
+
+// ConfirmEmptyBucket asks before the objects of a bucket are deleted along with it. Yes skips the
+// question, and without a terminal to ask on the delete is refused rather than left hanging.
+func (o Options) ConfirmEmptyBucket(name string, count int) error {
+	if o.Yes {
+		return nil
+	}
+	in := o.In
+	if in == nil {
+		if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return errs.Errorf(errs.Usage, "Bucket %s holds %d objects -- pass --yes to delete them with it", name, count)
+		}
+		in = os.Stdin
+	}
+	fmt.Fprintf(os.Stderr, "Bucket %s holds %d objects, they are deleted with it. Continue? (y/N): ", name, count)
+	var answer string
+	fmt.Fscanln(in, &answer)
+	if strings.ToLower(answer) != "y" {
+		return errs.Errorf(errs.Usage, "Bucket %s not deleted -- its objects must be deleted first", name)
+	}
+	return nil
+}
+
+// Planned summarizes an object a create call would have made in dry run mode
+func Planned(providerName, name, region, size string) *Resource {
+	return &Resource{
+		Name:     name,
+		Provider: providerName,
+		Region:   region,
+		Size:     size,
+		Status:   StatusPlanned,
+	}
+}
+
+// VMOptions holds the settings used to create a VM
+type VMOptions struct {
+	Name  string `yaml:"name"`
+	Size  string `yaml:"size"`
+	Image string `yaml:"image"`
+	// UserData is run by cloud-init on first boot, a cloud-config or a script
+	UserData string `yaml:"user-data,omitempty"`
+	// SSHKey is the name, ID or fingerprint of a key of the provider, or the path of a local public key
+	SSHKey string `yaml:"ssh-key,omitempty"`
+}
+
+// CloudConfigHeader starts user data that cloud-init reads as a cloud-config rather than a script
+const CloudConfigHeader = "#cloud-config"
+
+// CloudConfig reports whether the user data is a cloud-config
+func (o VMOptions) CloudConfig() bool {
+	return strings.HasPrefix(o.UserData, CloudConfigHeader)
+}
+
+// ClusterOptions holds the settings used to create a Kubernetes cluster
+type ClusterOptions struct {
+	Name      string   `yaml:"name"`
+	NodeSize  string   `yaml:"node-size"`
+	NodeCount int      `yaml:"node-count"`
+	Version   string   `yaml:"version"`
+	Subnets   []string `yaml:"subnets"`
+}
+
+// ErrClusterNotReady is returned when a cluster has no endpoint to write a kubeconfig for yet
+var ErrClusterNotReady = errors.New("cluster not ready")
+
+// DBOptions holds the settings used to create a database
+type DBOptions struct {
+	Name string `yaml:"name"`
+	Size string `yaml:"size"`
+}
+
+// Registration ties a provider name to the functions needed to configure and load it
+type Registration struct {
+	// Configure creates the config files the provider needs, prompting for
+	// anything not given by the auth options or the environment
+	Configure func(opts AuthOptions) error
+	// New loads the provider config and returns a ready to use Provider
+	New func(opts Options) (Provider, error)
+	// ConfigFile is the name of the config file Configure writes to each profile
+	ConfigFile string
+	// Secrets are the keys, under the profile name, of secrets the provider
+	// saves that its config file doesn't point at
+	Secrets []string
+}
+
+var registry = map[string]Registration{}
+
+// Register makes a provider available under the given name
+func Register(name string, reg Registration) {
+	registry[name] = reg
+}
+
+// Lookup returns the registration for the named provider
+func Lookup(name string) (Registration, error) {
+	reg, ok := registry[name]
+	if !ok {
+		return Registration{}, errs.Errorf(errs.Usage, "Unknown Provider -- %s", name)
+	}
+	return reg, nil
+}
+
+// Get loads the named provider
+func Get(name string, opts Options) (Provider, error) {
+	reg, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	p, err := reg.New(opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to load provider %s", name)
+	}
+	return p, nil
+}
+
+// Names returns the names of all registered providers in sorted order
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,199 @@
+package provider
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"maker/internal/errs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SSHFolder holds the local keys a VM is given when no --ssh-key is set and the provider has none, defaults to ~/.ssh
+var SSHFolder = defaultSSHFolder()
+
+// DefaultPublicKeys are the public keys in SSHFolder that are tried in order
+var DefaultPublicKeys = []string{"id_ed25519.pub", "id_ecdsa.pub", "id_rsa.pub"}
+
+func defaultSSHFolder() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh")
+}
+
+// SSHKey is a public key a provider adds to new VMs to log in with
+type SSHKey struct {
+	Provider    string `json:"provider" yaml:"provider"`
+	Name        string `json:"name" yaml:"name"`
+	ID          string `json:"id" yaml:"id"`
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+	// PublicKey is the OpenSSH public key, when the provider returns it
+	PublicKey string `json:"public_key,omitempty" yaml:"public_key,omitempty"`
+}
+
+// PublicKey is a local OpenSSH public key to upload to a provider
+type PublicKey struct {
+	// Name is the key comment, or the file name without .pub
+	Name string
+	// User is who logs in with the key on GCE, from a user@host comment or the local user
+	User string
+	// Key is the 'type base64 comment' line of the key file
+	Key  string
+	blob []byte
+}
+
+// ParsePublicKey reads an OpenSSH public key line
+func ParsePublicKey(line string) (*PublicKey, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, errs.New(errs.Usage, "Invalid SSH public key -- expected 'type base64 comment'")
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, errs.WrapAs(errs.Usage, err, "Invalid SSH public key")
+	}
+	key := &PublicKey{Key: strings.Join(fields, " "), blob: blob}
+	if len(fields) > 2 {
+		key.Name = strings.Join(fields[2:], " ")
+	}
+	return key, nil
+}
+
+// ReadPublicKey reads a public key file, the key is named after its comment or the file
+func ReadPublicKey(path string) (*PublicKey, error) {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errs.WrapAs(errs.Usage, err, "Failed to read SSH public key")
+	}
+	if strings.Contains(string(data), "PRIVATE KEY") {
+		return nil, errs.Errorf(errs.Usage, "%s is a private key -- pass the .pub file", path)
+	}
+	key, err := ParsePublicKey(string(data))
+	if err != nil {
+		return nil, errs.Wrapf(err, "Failed to read %s", path)
+	}
+	if key.Name == "" {
+		key.Name = strings.TrimSuffix(filepath.Base(path), ".pub")
+	}
+	key.User = loginUser(key.Name)
+	return key, nil
+}
+
+// DefaultPublicKey reads the first of DefaultPublicKeys found in SSHFolder
+func DefaultPublicKey() (*PublicKey, error) {
+	for _, name := range DefaultPublicKeys {
+		path := filepath.Join(SSHFolder, name)
+		if _, err := os.Stat(path); err == nil {
+			return ReadPublicKey(path)
+		}
+	}
+	return nil, errs.Errorf(errs.NotFound, "No SSH public key found in %s", SSHFolder)
+}
+
+// IsPublicKeyPath reports whether a --ssh-key value is a local file rather than the name or ID of a key
+func IsPublicKeyPath(ref string) bool {
+	return strings.HasSuffix(ref, ".pub") || strings.ContainsRune(ref, filepath.Separator) || strings.HasPrefix(ref, "~")
+}
+
+var invalidUserChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// loginUser picks the GCE login of a key from a user@host comment, or the local user
+func loginUser(comment string) string {
+	name := comment
+	if i := strings.Index(comment, "@"); i > 0 {
+		name = comment[:i]
+	} else if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	name = invalidUserChars.ReplaceAllString(strings.ToLower(name), "")
+	if name == "" {
+		return "maker"
+	}
+	return name
+}
+
+// FingerprintSHA256 returns the fingerprint ssh-keygen -l shows
+func (k PublicKey) FingerprintSHA256() string {
+	sum := sha256.Sum256(k.blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// FingerprintMD5 returns the colon separated MD5 fingerprint DigitalOcean identifies keys by
+func (k PublicKey) FingerprintMD5() string {
+	sum := md5.Sum(k.blob)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hex, ":")
+}
+
+// Same reports whether a key of a provider is this key, by its public key when the provider
+// returns it and by name otherwise
+func (k PublicKey) Same(key SSHKey) bool {
+	if key.PublicKey != "" {
+		fields := strings.Fields(key.PublicKey)
+		return len(fields) >= 2 && strings.HasPrefix(k.Key, fields[0]+" "+fields[1])
+	}
+	return key.Name == k.Name
+}
+
+// MatchSSHKey finds the key a name, ID or fingerprint refers to
+func MatchSSHKey(keys []SSHKey, ref string) (*SSHKey, error) {
+	for i, key := range keys {
+		if key.Name == ref || key.ID == ref || key.Fingerprint == ref {
+			return &keys[i], nil
+		}
+	}
+	return nil, errs.Errorf(errs.NotFound, "Could not find SSH key %s", ref)
+}
+
+// ChooseSSHKey picks the key a new VM is created with, without prompting. A --ssh-key value
+// names a key by name, ID or fingerprint, or is the path of a local public key. Without one the
+// only key of the account is used, or the default local key when there is none. A local key
+// that isn't registered yet is returned to be imported, with a nil SSHKey.
+func ChooseSSHKey(keys []SSHKey, ref string) (*SSHKey, *PublicKey, error) {
+	var local *PublicKey
+	var err error
+	switch {
+	case ref != "" && !IsPublicKeyPath(ref):
+		key, err := MatchSSHKey(keys, ref)
+		return key, nil, err
+	case ref != "":
+		local, err = ReadPublicKey(ref)
+	case len(keys) == 1:
+		return &keys[0], nil, nil
+	case len(keys) > 1:
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = key.Name
+		}
+		return nil, nil, errs.Errorf(errs.Usage, "Multiple SSH keys found (%s) -- pick one with --ssh-key", strings.Join(names, ", "))
+	default:
+		local, err = DefaultPublicKey()
+		if errs.ClassOf(err) == errs.NotFound {
+			return nil, nil, errs.Errorf(errs.Usage, "An SSH key is required and the account has none -- add one with 'maker ssh-key add' or pass --ssh-key PATH")
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, key := range keys {
+		if local.Same(key) {
+			return &keys[i], nil, nil
+		}
+	}
+	return nil, local, nil
+}
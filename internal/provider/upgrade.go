@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"maker/internal/errs"
+	"strconv"
+	"strings"
+)
+
+// ClusterVersions is the Kubernetes version of a cluster and the versions it can be upgraded to
+type ClusterVersions struct {
+	Provider  string   `json:"provider" yaml:"provider"`
+	Cluster   string   `json:"cluster" yaml:"cluster"`
+	Current   string   `json:"current" yaml:"current"`
+	Available []string `json:"available" yaml:"available"`
+}
+
+// Resolve finds the available version matching the one asked for, either exactly or as
+// a prefix such as 1.20 for 1.20.2-do.0, preferring the newest. The current version is
+// accepted so node pools left behind by an interrupted upgrade can catch up.
+func (v ClusterVersions) Resolve(version string) (string, error) {
+	version = strings.TrimPrefix(version, "v")
+	if version == v.Current {
+		return version, nil
+	}
+	match := ""
+	for _, available := range v.Available {
+		if available == version {
+			return available, nil
+		}
+		if strings.HasPrefix(available, version+".") || strings.HasPrefix(available, version+"-") {
+			if match == "" || CompareVersions(available, match) > 0 {
+				match = available
+			}
+		}
+	}
+	if match != "" {
+		return match, nil
+	}
+	if len(v.Available) == 0 {
+		return "", errs.Errorf(errs.Usage, "Cluster %s is on %s and has no upgrades available", v.Cluster, v.Current)
+	}
+	return "", errs.Errorf(errs.Usage, "Cluster %s can't be upgraded from %s to %s -- available: %s",
+		v.Cluster, v.Current, version, strings.Join(v.Available, ", "))
+}
+
+// CompareVersions orders Kubernetes versions such as 1.19, v1.20.2 or 1.20.2-do.0 by their
+// numeric parts, returning -1, 0 or 1. Provider suffixes only break ties.
+func CompareVersions(a, b string) int {
+	partsA, suffixA := versionParts(a)
+	partsB, suffixB := versionParts(b)
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x = partsA[i]
+		}
+		if i < len(partsB) {
+			y = partsB[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(suffixA, suffixB)
+}
+
+// versionParts splits a version into its dotted numbers and whatever follows them
+func versionParts(version string) ([]int, string) {
+	version = strings.TrimPrefix(version, "v")
+	suffix := ""
+	if i := strings.Index(version, "-"); i >= 0 {
+		version, suffix = version[:i], version[i+1:]
+	}
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts, suffix
+}
+
+// MinorVersion trims a version to its major.minor part, 1.20.2-do.0 becomes 1.20
+func MinorVersion(version string) string {
+	parts, _ := versionParts(version)
+	if len(parts) < 2 {
+		return ""
+	}
+	return strconv.Itoa(parts[0]) + "." + strconv.Itoa(parts[1])
+}
+
+// NextMinorVersion returns the minor version after the one a version belongs to, the
+// furthest most providers move a control plane in a single upgrade
+func NextMinorVersion(version string) string {
+	parts, _ := versionParts(version)
+	if len(parts) < 2 {
+		return ""
+	}
+	return strconv.Itoa(parts[0]) + "." + strconv.Itoa(parts[1]+1)
+}
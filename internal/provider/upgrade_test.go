@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"maker/internal/errs"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.19", "1.20", -1},
+		{"1.20.2-do.0", "1.20", 1},
+		{"v1.20.2", "1.20.2", 0},
+		{"1.20.8-gke.900", "1.20.8-gke.1500", 1},
+		{"1.10", "1.9", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMinorVersions(t *testing.T) {
+	if got := MinorVersion("1.20.2-do.0"); got != "1.20" {
+		t.Errorf("MinorVersion = %q, want 1.20", got)
+	}
+	if got := NextMinorVersion("v1.19.8-gke.1600"); got != "1.20" {
+		t.Errorf("NextMinorVersion = %q, want 1.20", got)
+	}
+	if got := NextMinorVersion("latest"); got != "" {
+		t.Errorf("NextMinorVersion of an unreadable version = %q, want nothing", got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	versions := ClusterVersions{
+		Cluster:   "lab",
+		Current:   "1.19.6-do.0",
+		Available: []string{"1.20.2-do.0", "1.20.5-do.0"},
+	}
+	tests := []struct {
+		version, want string
+	}{
+		{"1.20.2-do.0", "1.20.2-do.0"},
+		{"1.20", "1.20.5-do.0"},
+		{"v1.20.2", "1.20.2-do.0"},
+		{"1.19.6-do.0", "1.19.6-do.0"},
+	}
+	for _, tt := range tests {
+		got, err := versions.Resolve(tt.version)
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", tt.version, got, err, tt.want)
+		}
+	}
+
+	_, err := versions.Resolve("1.21")
+	if errs.ClassOf(err) != errs.Usage {
+		t.Errorf("Resolve(1.21) = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,193 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// PassphraseEnv holds the passphrase of the encrypted file so it isn't prompted for
+const PassphraseEnv = "MAKER_PASSPHRASE"
+
+// FileName is the name of the encrypted secrets file under .maker
+var FileName = "secrets.enc"
+
+// FileStore keeps secrets in a file encrypted with a key derived from a passphrase
+type FileStore struct {
+	Path string
+	// Passphrase returns the passphrase, it is only called when the key needs deriving
+	Passphrase func() (string, error)
+
+	key  *[32]byte
+	salt []byte
+}
+
+// encryptedFile is the layout of the secrets file on disk
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+var defaultFile *FileStore
+
+// DefaultFile returns the store kept in .maker, asking for the passphrase when it isn't set in the environment
+func DefaultFile() *FileStore {
+	if defaultFile == nil || defaultFile.Path != filepath.Join(utils.ConfigFolderPath, FileName) {
+		defaultFile = &FileStore{Path: filepath.Join(utils.ConfigFolderPath, FileName), Passphrase: askPassphrase}
+	}
+	return defaultFile
+}
+
+// askPassphrase reads the passphrase from the environment, or from the terminal when there is one
+func askPassphrase() (string, error) {
+	if pass := os.Getenv(PassphraseEnv); pass != "" {
+		return pass, nil
+	}
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errs.Errorf(errs.AuthFailed, "Missing passphrase for the encrypted secrets file -- set %s", PassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, "Passphrase for Maker secrets: ")
+	pass, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to capture passphrase")
+	}
+	if len(pass) == 0 {
+		return "", errs.New(errs.AuthFailed, "Empty passphrase for the encrypted secrets file")
+	}
+	return string(pass), nil
+}
+
+func (f *FileStore) Get(key string) (string, error) {
+	secrets, _, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (f *FileStore) Set(key, value string) error {
+	secrets, salt, err := f.load()
+	if err != nil {
+		return err
+	}
+	secrets[key] = value
+	return f.save(secrets, salt)
+}
+
+func (f *FileStore) Delete(key string) error {
+	secrets, salt, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[key]; !ok {
+		return nil
+	}
+	delete(secrets, key)
+	return f.save(secrets, salt)
+}
+
+// load decrypts the file, a missing file holds no secrets and gets a new salt
+func (f *FileStore) load() (map[string]string, []byte, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to generate salt")
+		}
+		return map[string]string{}, salt, nil
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Failed to read secrets file %s", f.Path)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil || len(file.Nonce) != 24 {
+		return nil, nil, errors.Errorf("Secrets file %s is corrupt", f.Path)
+	}
+	key, err := f.deriveKey(file.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	var nonce [24]byte
+	copy(nonce[:], file.Nonce)
+	plain, ok := secretbox.Open(nil, file.Data, &nonce, key)
+	if !ok {
+		f.key = nil
+		return nil, nil, errs.Errorf(errs.AuthFailed, "Wrong passphrase for secrets file %s", f.Path)
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, nil, errors.Errorf("Secrets file %s is corrupt", f.Path)
+	}
+	return secrets, file.Salt, nil
+}
+
+// save encrypts secrets with a fresh nonce and writes the file readable by the owner only
+func (f *FileStore) save(secrets map[string]string, salt []byte) error {
+	key, err := f.deriveKey(salt)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal secrets")
+	}
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return errors.Wrap(err, "Failed to generate nonce")
+	}
+	data, err := json.Marshal(encryptedFile{
+		Version: 1,
+		Salt:    salt,
+		Nonce:   nonce[:],
+		Data:    secretbox.Seal(nil, plain, &nonce, key),
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal secrets file")
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return errors.Wrapf(err, "Failed to create directory for %s", f.Path)
+	}
+	if err := ioutil.WriteFile(f.Path, data, 0600); err != nil {
+		return errors.Wrapf(err, "Failed to write secrets file %s", f.Path)
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(f.Path, 0600)
+}
+
+// deriveKey turns the passphrase into the encryption key, asking for the passphrase once
+func (f *FileStore) deriveKey(salt []byte) (*[32]byte, error) {
+	if f.key != nil && bytes.Equal(f.salt, salt) {
+		return f.key, nil
+	}
+	pass, err := f.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	derived, err := scrypt.Key([]byte(pass), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to derive key")
+	}
+	var key [32]byte
+	copy(key[:], derived)
+	f.key, f.salt = &key, salt
+	return f.key, nil
+}
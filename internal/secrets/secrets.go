@@ -0,0 +1,198 @@
+package secrets
+
+import (
+	"fmt"
+	"maker/internal/errs"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zalando/go-keyring"
+)
+
+// Service is the name secrets are filed under in the OS keyring
+const Service = "maker"
+
+// StoreEnv picks the secret store, "keyring" or "file", instead of using the keyring when it's reachable
+const StoreEnv = "MAKER_SECRET_STORE"
+
+// Store backends
+const (
+	Keyring = "keyring"
+	File    = "file"
+)
+
+// refPrefix marks a config value as a reference to a secret kept in a store, e.g. maker+keyring:default/do/pat_token
+const refPrefix = "maker+"
+
+var refPattern = regexp.MustCompile(`maker\+(keyring|file):[A-Za-z0-9_.\-/]+`)
+
+// ErrNotFound is returned when a store doesn't hold the requested secret
+var ErrNotFound = errors.New("secret not found")
+
+// Store keeps secrets out of the config files
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// keyringStore keeps secrets in the OS keyring, the Secret Service on Linux
+type keyringStore struct{}
+
+func (keyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(Service, key)
+	if err == keyring.ErrNotFound {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (keyringStore) Set(key, value string) error {
+	return keyring.Set(Service, key, value)
+}
+
+func (keyringStore) Delete(key string) error {
+	err := keyring.Delete(Service, key)
+	if err == keyring.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// keyringReachable caches whether the OS keyring answered, so it is only probed once
+var keyringReachable *bool
+
+// backend returns the store secrets are saved to, the keyring when it can be
+// reached and the encrypted file otherwise
+func backend() (string, error) {
+	switch name := os.Getenv(StoreEnv); name {
+	case Keyring, File:
+		return name, nil
+	case "":
+	default:
+		return "", errs.Errorf(errs.Usage, "Unknown secret store %s -- must be %s or %s", name, Keyring, File)
+	}
+	if keyringReachable == nil {
+		_, err := keyring.Get(Service, "probe")
+		reachable := err == nil || err == keyring.ErrNotFound
+		keyringReachable = &reachable
+	}
+	if *keyringReachable {
+		return Keyring, nil
+	}
+	return File, nil
+}
+
+// open returns the named store
+func open(name string) Store {
+	if name == Keyring {
+		return keyringStore{}
+	}
+	return DefaultFile()
+}
+
+// IsRef reports whether a config value points at a stored secret
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, refPrefix)
+}
+
+// parseRef splits a reference into its store and key
+func parseRef(ref string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(ref, refPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// Save stores value under key and returns the reference to write to the config file in its place
+func Save(key, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	name, err := backend()
+	if err != nil {
+		return "", err
+	}
+	if err := open(name).Set(key, value); err != nil {
+		return "", errors.Wrapf(err, "Failed to save secret to the %s store", name)
+	}
+	return fmt.Sprintf("%s%s:%s", refPrefix, name, key), nil
+}
+
+// Load returns the secret saved under key in the current store, ErrNotFound when there is none
+func Load(key string) (string, error) {
+	name, err := backend()
+	if err != nil {
+		return "", err
+	}
+	return open(name).Get(key)
+}
+
+// Delete removes the secret saved under key in the current store, a missing secret isn't an error
+func Delete(key string) error {
+	name, err := backend()
+	if err != nil {
+		return err
+	}
+	return open(name).Delete(key)
+}
+
+// Resolve returns the secret a config value points at. Values that aren't references
+// are returned as is, so config files written before secrets were stored keep working.
+func Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	name, key := parseRef(value)
+	if name != Keyring && name != File {
+		return "", errs.Errorf(errs.Usage, "Invalid secret reference %s", value)
+	}
+	secret, err := open(name).Get(key)
+	if err == ErrNotFound {
+		return "", errs.Errorf(errs.AuthFailed, "Secret %s is missing from the %s store -- run 'maker auth' again", key, name)
+	}
+	if err != nil {
+		return "", errs.WrapAs(errs.AuthFailed, err, "Failed to read secret "+key)
+	}
+	return secret, nil
+}
+
+// Remove deletes the secret a config value points at, plain values are ignored
+func Remove(value string) error {
+	if !IsRef(value) {
+		return nil
+	}
+	name, key := parseRef(value)
+	return open(name).Delete(key)
+}
+
+// RemoveAll deletes every secret referenced in the contents of a config file
+func RemoveAll(data []byte) error {
+	for _, ref := range refPattern.FindAllString(string(data), -1) {
+		if err := Remove(ref); err != nil {
+			return errors.Wrapf(err, "Failed to delete secret %s", ref)
+		}
+	}
+	return nil
+}
+
+// Redact hides a secret for display, showing where it is stored or the last few characters of a plain value
+func Redact(value string) string {
+	switch {
+	case value == "":
+		return ""
+	case IsRef(value):
+		name, _ := parseRef(value)
+		if name == Keyring {
+			return "<stored in OS keyring>"
+		}
+		return "<stored in encrypted file>"
+	case len(value) > 12:
+		return "****" + value[len(value)-4:]
+	default:
+		return "****"
+	}
+}
@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// useFileStore keeps secrets in an encrypted file in a temporary .maker folder
+func useFileStore(t *testing.T) string {
+	dir := t.TempDir()
+	oldFolder := utils.ConfigFolderPath
+	utils.ConfigFolderPath = dir
+	t.Cleanup(func() { utils.ConfigFolderPath = oldFolder })
+	setenv(t, StoreEnv, File)
+	setenv(t, PassphraseEnv, "correct horse")
+	return dir
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	store := &FileStore{Path: path, Passphrase: func() (string, error) { return "pass", nil }}
+
+	if _, err := store.Get("default/do/pat_token"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound from a missing file, got %v", err)
+	}
+	if err := store.Set("default/do/pat_token", "dop_v1_secret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), "dop_v1_secret") {
+		t.Errorf("secret written in plain text")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("secrets file mode is %v, want 0600", info.Mode().Perm())
+	}
+
+	// a fresh store has to derive the key again from the passphrase
+	reopened := &FileStore{Path: path, Passphrase: func() (string, error) { return "pass", nil }}
+	if value, err := reopened.Get("default/do/pat_token"); err != nil || value != "dop_v1_secret" {
+		t.Errorf("Get = %q, %v", value, err)
+	}
+	if err := reopened.Delete("default/do/pat_token"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := reopened.Get("default/do/pat_token"); err != ErrNotFound {
+		t.Errorf("expected the secret to be deleted, got %v", err)
+	}
+
+	wrong := &FileStore{Path: path, Passphrase: func() (string, error) { return "guess", nil }}
+	if _, err := wrong.Get("default/do/pat_token"); errs.ClassOf(err) != errs.AuthFailed {
+		t.Errorf("expected an auth error for a wrong passphrase, got %v", err)
+	}
+}
+
+func TestSaveResolveRemove(t *testing.T) {
+	useFileStore(t)
+
+	ref, err := Save("staging/aws/aws_secret_access_key", "wJalrXUtnFEMI")
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if ref != "maker+file:staging/aws/aws_secret_access_key" {
+		t.Errorf("unexpected reference %q", ref)
+	}
+	if value, err := Resolve(ref); err != nil || value != "wJalrXUtnFEMI" {
+		t.Errorf("Resolve = %q, %v", value, err)
+	}
+	// config files from before secrets were stored hold plain values
+	if value, err := Resolve("plain-token"); err != nil || value != "plain-token" {
+		t.Errorf("Resolve of a plain value = %q, %v", value, err)
+	}
+
+	if err := RemoveAll([]byte("aws_secret_access_key = '" + ref + "'\n")); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if _, err := Resolve(ref); errs.ClassOf(err) != errs.AuthFailed {
+		t.Errorf("expected a removed secret to be missing, got %v", err)
+	}
+	// secrets saved without a reference in a config file are deleted by key
+	if _, err := Save("staging/aws/role_cache", "{}"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := Delete("staging/aws/role_cache"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := Load("staging/aws/role_cache"); err != ErrNotFound {
+		t.Errorf("expected a deleted secret to be missing, got %v", err)
+	}
+	if err := Delete("staging/aws/role_cache"); err != nil {
+		t.Errorf("deleting a missing secret: %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                                   "",
+		"short":                              "****",
+		"dop_v1_0123456789abcdef":            "****cdef",
+		"maker+keyring:default/do/pat_token": "<stored in OS keyring>",
+		"maker+file:default/do/pat_token":    "<stored in encrypted file>",
+	} {
+		if got := Redact(value); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
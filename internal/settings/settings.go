@@ -0,0 +1,92 @@
+package settings
+
+import (
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// FileName is the name of the settings file under .maker
+var FileName = "config.yaml"
+
+// EnvPrefix starts the environment variables overriding the settings file, e.g. MAKER_DO_VM_SIZE
+const EnvPrefix = "MAKER"
+
+// Path is the settings file set with the --config flag, the file in .maker is used when empty
+var Path string
+
+// Settings holds the defaults for command flags, keyed by provider, object kind and flag name,
+// e.g. do.vm.size or gcp.cluster.version
+type Settings struct {
+	v *viper.Viper
+}
+
+// File returns the settings file that is read
+func File() string {
+	if Path != "" {
+		return Path
+	}
+	return filepath.Join(utils.ConfigFolderPath, FileName)
+}
+
+// Load reads the settings file and the MAKER_* environment variables.
+// A missing file in .maker holds no defaults, a missing --config file is an error.
+func Load() (*Settings, error) {
+	v := viper.New()
+	v.SetConfigFile(File())
+	v.SetConfigType("yaml")
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.AutomaticEnv()
+
+	if _, err := os.Stat(File()); os.IsNotExist(err) {
+		if Path != "" {
+			return nil, errs.Errorf(errs.NotFound, "Config file %s doesn't exist", Path)
+		}
+		return &Settings{v: v}, nil
+	}
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errs.WrapAs(errs.Usage, err, "Failed to read config file "+File())
+	}
+	return &Settings{v: v}, nil
+}
+
+// Key returns the settings key of a flag, e.g. do.cluster.node-size
+func Key(providerName, kind, flag string) string {
+	return strings.Join([]string{providerName, kind, flag}, ".")
+}
+
+// EnvName returns the environment variable overriding a settings key, e.g. MAKER_DO_CLUSTER_NODE_SIZE
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// Lookup returns the default for a flag, from the environment first and then the settings file.
+// Lists are joined with commas the way slice flags are given.
+func (s *Settings) Lookup(providerName, kind, flag string) (string, bool) {
+	key := Key(providerName, kind, flag)
+	if !s.v.IsSet(key) {
+		return "", false
+	}
+	if _, ok := s.v.Get(key).([]interface{}); ok {
+		return strings.Join(s.v.GetStringSlice(key), ","), true
+	}
+	return s.v.GetString(key), true
+}
+
+// AliasesKey holds the size and image aliases of the settings file, e.g. aliases.sizes.small.do
+const AliasesKey = "aliases"
+
+// Aliases returns the built-in size and image aliases with those of the settings file laid over them
+func (s *Settings) Aliases() (provider.Aliases, error) {
+	var overrides provider.Aliases
+	if err := s.v.UnmarshalKey(AliasesKey, &overrides); err != nil {
+		return nil, errs.WrapAs(errs.Usage, err, "Invalid aliases in config file "+File())
+	}
+	return provider.DefaultAliases.Merge(overrides), nil
+}
@@ -0,0 +1,144 @@
+package settings
+
+import (
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useTempFolder points the .maker folder at a temporary directory with no --config file
+func useTempFolder(t *testing.T) string {
+	dir := t.TempDir()
+	oldFolder, oldPath := utils.ConfigFolderPath, Path
+	utils.ConfigFolderPath, Path = dir, ""
+	t.Cleanup(func() {
+		utils.ConfigFolderPath, Path = oldFolder, oldPath
+	})
+	return dir
+}
+
+// setenv sets an environment variable for the length of a test
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+const testConfig = `
+do:
+  vm:
+    size: s-1vcpu-1gb
+    image: ubuntu-20-04-x64
+  cluster:
+    node-count: 3
+aws:
+  cluster:
+    subnets: [subnet-a, subnet-b]
+`
+
+func TestLookupPrecedence(t *testing.T) {
+	dir := useTempFolder(t)
+	if err := ioutil.WriteFile(filepath.Join(dir, FileName), []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	setenv(t, EnvName(Key("do", "vm", "size")), "s-2vcpu-2gb")
+	setenv(t, EnvName(Key("gcp", "cluster", "node-size")), "e2-medium")
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	tests := []struct {
+		provider, kind, flag string
+		want                 string
+		found                bool
+	}{
+		{"do", "vm", "size", "s-2vcpu-2gb", true},
+		{"do", "vm", "image", "ubuntu-20-04-x64", true},
+		{"do", "cluster", "node-count", "3", true},
+		{"gcp", "cluster", "node-size", "e2-medium", true},
+		{"aws", "cluster", "subnets", "subnet-a,subnet-b", true},
+		{"do", "db", "size", "", false},
+	}
+	for _, test := range tests {
+		got, found := s.Lookup(test.provider, test.kind, test.flag)
+		if got != test.want || found != test.found {
+			t.Errorf("Lookup(%s, %s, %s) = %q, %v, want %q, %v", test.provider, test.kind, test.flag, got, found, test.want, test.found)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got := EnvName(Key("do", "cluster", "node-size")); got != "MAKER_DO_CLUSTER_NODE_SIZE" {
+		t.Errorf("unexpected variable name %s", got)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	dir := useTempFolder(t)
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("a missing default file should hold no defaults: %v", err)
+	}
+	if _, found := s.Lookup("do", "vm", "size"); found {
+		t.Errorf("expected no default")
+	}
+
+	Path = filepath.Join(dir, "missing.yaml")
+	if _, err := Load(); errs.ClassOf(err) != errs.NotFound {
+		t.Errorf("expected a NotFound error for a missing --config file, got %v", err)
+	}
+
+	Path = filepath.Join(dir, "broken.yaml")
+	ioutil.WriteFile(Path, []byte("do: [unclosed"), 0600)
+	if _, err := Load(); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a Usage error for a broken config file, got %v", err)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	dir := useTempFolder(t)
+	config := `
+aliases:
+  sizes:
+    small: {do: s-1vcpu-1gb}
+  images:
+    ubuntu-22.04: {aws: ami-0123}
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, FileName), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	aliases, err := s.Aliases()
+	if err != nil {
+		t.Fatalf("Aliases: %v", err)
+	}
+	tests := []struct {
+		kind, provider, name string
+		want                 string
+	}{
+		{provider.CatalogSizes, "do", "small", "s-1vcpu-1gb"},
+		{provider.CatalogSizes, "aws", "small", "t3.small"},
+		{provider.CatalogImages, "aws", "ubuntu-22.04", "ami-0123"},
+		{provider.CatalogImages, "do", "ubuntu-22.04", "ubuntu-22-04-x64"},
+	}
+	for _, tt := range tests {
+		got, err := aliases.Resolve(tt.kind, tt.provider, tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%s, %s, %s) = %q, %v, want %q", tt.kind, tt.provider, tt.name, got, err, tt.want)
+		}
+	}
+}
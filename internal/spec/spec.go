@@ -0,0 +1,166 @@
+package spec
+
+import (
+	"io/ioutil"
+	"maker/internal/provider"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Version is the newest lab spec format this build understands
+const Version = 1
+
+// DefaultNodeCount matches the default of the create cluster --node-count flag
+const DefaultNodeCount = 2
+
+// minSubnets is how many subnets a cluster needs on providers that place clusters in subnets,
+// checked up front so a lab isn't left half built when the cluster create is refused
+var minSubnets = map[string]int{"aws": 2}
+
+// Spec describes a lab, the set of objects to create on each provider
+type Spec struct {
+	Version   int                     `yaml:"version"`
+	Providers map[string]ProviderSpec `yaml:"providers"`
+}
+
+// ProviderSpec lists the objects to create on a single provider.
+// The fields take the same parameters as the matching create commands.
+type ProviderSpec struct {
+	VMs      []provider.VMOptions      `yaml:"vms"`
+	Clusters []provider.ClusterOptions `yaml:"clusters"`
+	Buckets  []BucketOptions           `yaml:"buckets"`
+	DBs      []provider.DBOptions      `yaml:"dbs"`
+}
+
+// BucketOptions holds the settings used to create a bucket
+type BucketOptions struct {
+	Name string `yaml:"name"`
+}
+
+// Load reads and validates the lab spec at path
+func Load(path string) (*Spec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read lab spec")
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a lab spec, rejecting unknown fields so typos don't go unnoticed
+func Parse(data []byte) (*Spec, error) {
+	var s Spec
+	err := yaml.UnmarshalStrict(data, &s)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse lab spec")
+	}
+	if s.Version == 0 {
+		s.Version = Version
+	}
+	for name, ps := range s.Providers {
+		for i := range ps.Clusters {
+			if ps.Clusters[i].NodeCount == 0 {
+				ps.Clusters[i].NodeCount = DefaultNodeCount
+			}
+		}
+		s.Providers[name] = ps
+	}
+	return &s, s.Validate()
+}
+
+// Validate checks that every object has the parameters its create command requires
+func (s *Spec) Validate() error {
+	if s.Version > Version {
+		return errors.Errorf("Lab spec version %d is newer than the supported version %d", s.Version, Version)
+	}
+	if len(s.Providers) == 0 {
+		return errors.New("Lab spec doesn't list any providers")
+	}
+	for _, name := range s.ProviderNames() {
+		ps := s.Providers[name]
+		seen := map[string]bool{}
+		check := func(kind, objName string, required map[string]string) error {
+			if objName == "" {
+				return errors.Errorf("%s: every %s needs a name", name, kind)
+			}
+			if seen[kind+"/"+objName] {
+				return errors.Errorf("%s: %s %s is listed more than once", name, kind, objName)
+			}
+			seen[kind+"/"+objName] = true
+			for field, value := range required {
+				if value == "" {
+					return errors.Errorf("%s: %s %s is missing %s", name, kind, objName, field)
+				}
+			}
+			return nil
+		}
+
+		for _, vm := range ps.VMs {
+			if err := check(provider.KindVM, vm.Name, map[string]string{"size": vm.Size, "image": vm.Image}); err != nil {
+				return err
+			}
+		}
+		for _, cluster := range ps.Clusters {
+			if err := check(provider.KindCluster, cluster.Name, map[string]string{"node-size": cluster.NodeSize, "version": cluster.Version}); err != nil {
+				return err
+			}
+			if cluster.NodeCount < 0 {
+				return errors.Errorf("%s: cluster %s has a negative node-count", name, cluster.Name)
+			}
+			if min := minSubnets[name]; len(cluster.Subnets) < min {
+				return errors.Errorf("%s: cluster %s needs at least %d subnets", name, cluster.Name, min)
+			}
+		}
+		for _, bucket := range ps.Buckets {
+			if err := check(provider.KindBucket, bucket.Name, nil); err != nil {
+				return err
+			}
+		}
+		for _, db := range ps.DBs {
+			if err := check(provider.KindDB, db.Name, map[string]string{"size": db.Size}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ProviderNames returns the providers used by the lab in sorted order
+func (s *Spec) ProviderNames() []string {
+	names := make([]string, 0, len(s.Providers))
+	for name := range s.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CreateOrder is the order kinds are created in. Workloads on the clusters and VMs
+// usually depend on the bucket and database, so those come first. Destroying runs
+// in the reverse order.
+var CreateOrder = []string{provider.KindBucket, provider.KindDB, provider.KindCluster, provider.KindVM}
+
+// Names returns the names of the objects of a kind listed for a provider
+func (ps ProviderSpec) Names(kind string) []string {
+	var names []string
+	switch kind {
+	case provider.KindVM:
+		for _, vm := range ps.VMs {
+			names = append(names, vm.Name)
+		}
+	case provider.KindCluster:
+		for _, cluster := range ps.Clusters {
+			names = append(names, cluster.Name)
+		}
+	case provider.KindBucket:
+		for _, bucket := range ps.Buckets {
+			names = append(names, bucket.Name)
+		}
+	case provider.KindDB:
+		for _, db := range ps.DBs {
+			names = append(names, db.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,69 @@
+package spec
+
+import (
+	"maker/internal/provider"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const lab = `
+version: 1
+providers:
+  do:
+    vms:
+      - name: web
+        size: s-1vcpu-1gb
+        image: ubuntu-20-04-x64
+    clusters:
+      - name: k8s
+        node-size: s-2vcpu-2gb
+        version: 1.20.2-do.0
+    buckets:
+      - name: assets
+  aws:
+    dbs:
+      - name: pg
+        size: db.t3.micro
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(lab))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := s.ProviderNames(); !reflect.DeepEqual(got, []string{"aws", "do"}) {
+		t.Errorf("ProviderNames = %v", got)
+	}
+	if count := s.Providers["do"].Clusters[0].NodeCount; count != DefaultNodeCount {
+		t.Errorf("expected node-count to default to %d, got %d", DefaultNodeCount, count)
+	}
+	if names := s.Providers["do"].Names(provider.KindBucket); !reflect.DeepEqual(names, []string{"assets"}) {
+		t.Errorf("Names(bucket) = %v", names)
+	}
+	// clusters on providers without subnets don't need any
+	if subnets := s.Providers["do"].Clusters[0].Subnets; subnets != nil {
+		t.Errorf("expected no subnets for do, got %v", subnets)
+	}
+	if names := s.Providers["aws"].Names(provider.KindVM); names != nil {
+		t.Errorf("expected no VMs for aws, got %v", names)
+	}
+}
+
+func TestParseRejectsBadSpecs(t *testing.T) {
+	for name, tc := range map[string]struct{ spec, err string }{
+		"unknown field":  {"providers:\n  do:\n    vms:\n      - name: web\n        sise: s\n", "sise"},
+		"no providers":   {"version: 1\n", "doesn't list any providers"},
+		"newer version":  {"version: 9\nproviders:\n  do: {}\n", "newer than the supported"},
+		"missing name":   {"providers:\n  do:\n    buckets:\n      - {}\n", "needs a name"},
+		"missing size":   {"providers:\n  do:\n    dbs:\n      - name: pg\n", "missing size"},
+		"duplicate":      {"providers:\n  do:\n    buckets:\n      - name: a\n      - name: a\n", "more than once"},
+		"negative count": {"providers:\n  do:\n    clusters:\n      - {name: k, node-size: s, version: v, node-count: -1}\n", "negative node-count"},
+		"aws subnets":    {"providers:\n  aws:\n    clusters:\n      - {name: k, node-size: s, version: v, subnets: [subnet-a]}\n", "at least 2 subnets"},
+	} {
+		_, err := Parse([]byte(tc.spec))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"maker/internal/profile"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Version is the current schema version of the state file
+const Version = 1
+
+// Entry records a single object Maker created
+type Entry struct {
+	Profile  string            `json:"profile,omitempty"`
+	Provider string            `json:"provider"`
+	Kind     string            `json:"kind"`
+	Name     string            `json:"name"`
+	ID       string            `json:"id"`
+	Region   string            `json:"region"`
+	Created  time.Time         `json:"created"`
+	Flags    map[string]string `json:"flags,omitempty"`
+}
+
+// State is the inventory of every object Maker has created and not yet deleted
+type State struct {
+	Version   int     `json:"version"`
+	Resources []Entry `json:"resources"`
+}
+
+// StateName is the name of the state file used by Maker
+var StateName = "state.json"
+
+// StatePath is the full path to the state file
+var StatePath = filepath.Join(utils.ConfigFolderPath, StateName)
+
+// Load reads the state file, returning an empty state if none exists yet
+func Load() (*State, error) {
+	data, err := ioutil.ReadFile(StatePath)
+	if os.IsNotExist(err) {
+		return &State{Version: Version}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read state file %s", StatePath)
+	}
+
+	st := &State{}
+	err = json.Unmarshal(data, st)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse state file %s", StatePath)
+	}
+	if st.Version > Version {
+		return nil, errors.Errorf("State file %s is version %d, this Maker only understands up to %d",
+			StatePath, st.Version, Version)
+	}
+	st.Version = Version
+	return st, nil
+}
+
+// Save writes the state file, replacing the previous one in a single rename
+func (s *State) Save() error {
+	err := os.MkdirAll(utils.ConfigFolderPath, 0755)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create config directory %s", utils.ConfigFolder)
+	}
+
+	sort.Slice(s.Resources, func(i, j int) bool {
+		a, b := s.Resources[i], s.Resources[j]
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal state")
+	}
+
+	tmp := StatePath + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write state file %s", tmp)
+	}
+	return errors.Wrapf(os.Rename(tmp, StatePath), "Failed to replace state file %s", StatePath)
+}
+
+// inProfile reports whether an entry was recorded with the active profile, entries
+// written before profiles were recorded belong to the default profile
+func (e Entry) inProfile() bool {
+	name := e.Profile
+	if name == "" {
+		name = profile.Default
+	}
+	return name == profile.Current()
+}
+
+// matches reports whether an entry is the named object of the active profile
+func (e Entry) matches(provider, kind, name string) bool {
+	return e.inProfile() && e.Provider == provider && e.Kind == kind && e.Name == name
+}
+
+// Add records an entry under the active profile, replacing any existing entry for the same object
+func (s *State) Add(entry Entry) {
+	entry.Profile = profile.Current()
+	s.Remove(entry.Provider, entry.Kind, entry.Name)
+	s.Resources = append(s.Resources, entry)
+}
+
+// Remove drops the entry for an object of the active profile, reporting whether one was found
+func (s *State) Remove(provider, kind, name string) bool {
+	for i, entry := range s.Resources {
+		if entry.matches(provider, kind, name) {
+			s.Resources = append(s.Resources[:i], s.Resources[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the entry for an object of the active profile if Maker has recorded it
+func (s *State) Find(provider, kind, name string) (Entry, bool) {
+	for _, entry := range s.Resources {
+		if entry.matches(provider, kind, name) {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// List returns the entries of the active profile matching provider and kind, an empty value matches everything
+func (s *State) List(provider, kind string) []Entry {
+	var entries []Entry
+	for _, entry := range s.Resources {
+		if entry.inProfile() && (provider == "" || entry.Provider == provider) && (kind == "" || entry.Kind == kind) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// LookupID returns the cloud ID recorded for an object of the active profile, or an empty string if it isn't tracked
+func LookupID(provider, kind, name string) string {
+	st, err := Load()
+	if err != nil {
+		return ""
+	}
+	entry, _ := st.Find(provider, kind, name)
+	return entry.ID
+}
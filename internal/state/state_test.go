@@ -0,0 +1,106 @@
+package state
+
+import (
+	"io/ioutil"
+	"maker/internal/profile"
+	"maker/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func useTempState(t *testing.T) {
+	dir := t.TempDir()
+	oldState, oldFolder, oldProfile := StatePath, utils.ConfigFolderPath, profile.Name
+	StatePath = filepath.Join(dir, StateName)
+	utils.ConfigFolderPath, profile.Name = dir, profile.Default
+	t.Cleanup(func() {
+		StatePath, utils.ConfigFolderPath, profile.Name = oldState, oldFolder, oldProfile
+	})
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	useTempState(t)
+	st, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if st.Version != Version || len(st.Resources) != 0 {
+		t.Errorf("expected an empty state, got %+v", st)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	useTempState(t)
+	st, _ := Load()
+	st.Add(Entry{Provider: "do", Kind: "vm", Name: "web", ID: "1"})
+	st.Add(Entry{Provider: "aws", Kind: "db", Name: "pg", ID: "db-1"})
+	// adding the same object again replaces it
+	st.Add(Entry{Provider: "do", Kind: "vm", Name: "web", ID: "2"})
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	info, err := os.Stat(StatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("state file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if id := LookupID("do", "vm", "web"); id != "2" {
+		t.Errorf("LookupID = %q, want 2", id)
+	}
+	loaded, _ := Load()
+	if len(loaded.Resources) != 2 || loaded.Resources[0].Provider != "aws" {
+		t.Errorf("expected 2 entries sorted by provider, got %+v", loaded.Resources)
+	}
+	if entries := loaded.List("do", ""); len(entries) != 1 {
+		t.Errorf("List(do) = %+v", entries)
+	}
+	if !loaded.Remove("aws", "db", "pg") || loaded.Remove("aws", "db", "pg") {
+		t.Errorf("Remove should only find the entry once")
+	}
+}
+
+func TestLoadNewerVersion(t *testing.T) {
+	useTempState(t)
+	if err := ioutil.WriteFile(StatePath, []byte(`{"version": 99}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Fatalf("expected a version error, got %v", err)
+	}
+	if id := LookupID("do", "vm", "web"); id != "" {
+		t.Errorf("expected no ID from an unreadable state, got %q", id)
+	}
+}
+
+func TestEntriesAreScopedToProfile(t *testing.T) {
+	useTempState(t)
+	st, _ := Load()
+	// entries written before profiles were recorded belong to the default profile
+	st.Resources = append(st.Resources, Entry{Provider: "do", Kind: "vm", Name: "web", ID: "1"})
+	profile.Name = "staging"
+	st.Add(Entry{Provider: "do", Kind: "vm", Name: "web", ID: "2"})
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if id := LookupID("do", "vm", "web"); id != "2" {
+		t.Errorf("LookupID in staging = %q, want 2", id)
+	}
+	if entries := st.List("", ""); len(entries) != 1 || entries[0].Profile != "staging" {
+		t.Errorf("List in staging = %+v", entries)
+	}
+	profile.Name = profile.Default
+	if id := LookupID("do", "vm", "web"); id != "1" {
+		t.Errorf("LookupID in default = %q, want 1", id)
+	}
+	if !st.Remove("do", "vm", "web") || len(st.Resources) != 1 || st.Resources[0].ID != "2" {
+		t.Errorf("Remove should only drop the default profile's entry, left %+v", st.Resources)
+	}
+}
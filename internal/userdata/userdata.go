@@ -0,0 +1,90 @@
+package userdata
+
+import (
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"sort"
+	"strings"
+)
+
+// MaxSize is the most user data every provider accepts, EC2 takes the least
+const MaxSize = 16 * 1024
+
+// dockerHost installs Docker from its convenience script, which covers Ubuntu, Debian and CentOS,
+// and lets the default user of the image run it
+const dockerHost = provider.CloudConfigHeader + `
+package_update: true
+packages:
+  - curl
+runcmd:
+  - curl -fsSL https://get.docker.com | sh
+  - systemctl enable --now docker
+  - usermod -aG docker "$(getent passwd 1000 | cut -d: -f1)"
+`
+
+// k3sNode runs a single node k3s cluster whose kubeconfig any user on the VM can read
+const k3sNode = provider.CloudConfigHeader + `
+package_update: true
+packages:
+  - curl
+runcmd:
+  - curl -sfL https://get.k3s.io | sh -s - --write-kubeconfig-mode 644
+`
+
+// Templates are the built-in cloud-configs, picked by name instead of a file
+var Templates = map[string]string{
+	"docker": dockerHost,
+	"k3s":    k3sNode,
+}
+
+// TemplateNames returns the names of the built-in templates in sorted order
+func TemplateNames() []string {
+	names := make([]string, 0, len(Templates))
+	for name := range Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expand returns the cloud-config of the template a value names, other values are returned unchanged
+func Expand(value string) string {
+	if template, ok := Templates[value]; ok {
+		return template
+	}
+	return value
+}
+
+// ReadFile reads user data from a file, sent as it is whether it is a script or a cloud-config
+func ReadFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errs.WrapAs(errs.Usage, err, "Failed to read user data")
+	}
+	return string(data), Validate(string(data))
+}
+
+// ReadCloudConfig reads a cloud-config from a built-in template, or a file when the value
+// isn't the name of one. Use ./NAME for a file named like a template.
+func ReadCloudConfig(value string) (string, error) {
+	data := Expand(value)
+	if data == value {
+		var err error
+		if data, err = ReadFile(value); err != nil {
+			return "", err
+		}
+	}
+	if !strings.HasPrefix(data, provider.CloudConfigHeader) {
+		return "", errs.Errorf(errs.Usage, "%s isn't a cloud-config -- it must start with %s, use --user-data for scripts", value, provider.CloudConfigHeader)
+	}
+	return data, nil
+}
+
+// Validate checks user data fits every provider
+func Validate(data string) error {
+	if len(data) > MaxSize {
+		return errs.Errorf(errs.Usage, "User data is %d bytes, providers take at most %d", len(data), MaxSize)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package userdata
+
+import (
+	"io/ioutil"
+	"maker/internal/errs"
+	"maker/internal/provider"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadCloudConfig(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "lab.yaml")
+	script := filepath.Join(dir, "setup.sh")
+	ioutil.WriteFile(config, []byte(provider.CloudConfigHeader+"\npackages: [git]\n"), 0600)
+	ioutil.WriteFile(script, []byte("#!/bin/sh\napt-get install -y git\n"), 0600)
+
+	for _, name := range TemplateNames() {
+		data, err := ReadCloudConfig(name)
+		if err != nil || !strings.HasPrefix(data, provider.CloudConfigHeader) {
+			t.Errorf("ReadCloudConfig(%s) = %q, %v", name, data, err)
+		}
+	}
+	if data, err := ReadCloudConfig(config); err != nil || !strings.Contains(data, "git") {
+		t.Errorf("ReadCloudConfig(file) = %q, %v", data, err)
+	}
+	if _, err := ReadCloudConfig(script); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error for a script passed as cloud-config, got %v", err)
+	}
+	if _, err := ReadCloudConfig(filepath.Join(dir, "missing")); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error for a missing file, got %v", err)
+	}
+}
+
+func TestReadFileTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.sh")
+	ioutil.WriteFile(path, []byte("#!/bin/sh\n"+strings.Repeat("#", MaxSize)), 0600)
+	if _, err := ReadFile(path); errs.ClassOf(err) != errs.Usage {
+		t.Errorf("expected a usage error for oversized user data, got %v", err)
+	}
+}
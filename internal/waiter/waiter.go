@@ -0,0 +1,192 @@
+package waiter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ErrTimeout is the cause of errors returned when an object doesn't reach the wanted state in time
+var ErrTimeout = errors.New("timed out")
+
+// StateGone is the state Conditions report once a deleted object can no longer be found
+const StateGone = "gone"
+
+// maxErrors is how many API errors in a row are tolerated before giving up,
+// so a single throttled request doesn't end a 15 minute wait
+const maxErrors = 3
+
+// spinnerFrames are drawn in turn while waiting
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// reportEvery is how often an unchanged state is repeated when Progress isn't a terminal
+const reportEvery = time.Minute
+
+// isTTY reports whether progress goes to a terminal, only then is the spinner animated
+var isTTY = func(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	return ok && terminal.IsTerminal(int(file.Fd()))
+}
+
+// Condition fetches the current state of the object being waited on.
+// Errors wrapped with Fail end the wait at once, any other error is retried.
+type Condition func() (state string, err error)
+
+// Waiter polls a Condition with exponential backoff until it reports a wanted state
+type Waiter struct {
+	// Timeout is how long to wait before giving up, zero waits forever
+	Timeout time.Duration
+	// Interval is the delay before the first retry, doubling up to MaxInterval
+	Interval    time.Duration
+	MaxInterval time.Duration
+	// Progress shows a spinner with the current state on a terminal, and a line
+	// per state change otherwise. nil keeps quiet.
+	Progress io.Writer
+}
+
+// New returns a Waiter with the default backoff
+func New(timeout time.Duration, progress io.Writer) Waiter {
+	return Waiter{
+		Timeout:     timeout,
+		Interval:    5 * time.Second,
+		MaxInterval: time.Minute,
+		Progress:    progress,
+	}
+}
+
+// terminalError marks a failure that retrying won't fix
+type terminalError struct {
+	err error
+}
+
+func (t terminalError) Error() string { return t.err.Error() }
+
+// Fail marks err as terminal so Until returns it without retrying
+func Fail(err error) error {
+	return terminalError{err: err}
+}
+
+// Until polls cond until it reports one of the ready states. Reaching one of the
+// failed states, a terminal error or the timeout ends the wait with an error.
+func (w Waiter) Until(what string, cond Condition, ready, failed []string) error {
+	start := time.Now()
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	var deadline <-chan time.Time
+	if w.Timeout > 0 {
+		timer := time.NewTimer(w.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	animate := w.Progress != nil && isTTY(w.Progress)
+	spin := 0
+	errCount := 0
+	state := ""
+	reported, reportedAt := "", time.Time{}
+	if animate {
+		defer w.clear()
+	}
+
+	for {
+		current, err := cond()
+		switch {
+		case err == nil:
+			errCount = 0
+			state = current
+		case isTerminal(err):
+			return errors.Wrapf(errors.Cause(err).(terminalError).err, "Failed waiting for %s", what)
+		default:
+			errCount++
+			if errCount >= maxErrors {
+				return errors.Wrapf(err, "Failed waiting for %s", what)
+			}
+		}
+		if err == nil {
+			if contains(ready, state) {
+				return nil
+			}
+			if contains(failed, state) {
+				return errors.Errorf("%s reached state %s", what, state)
+			}
+		}
+
+		if !animate && (state != reported || time.Since(reportedAt) >= reportEvery) {
+			w.report(what, state, time.Since(start))
+			reported, reportedAt = state, time.Now()
+		}
+
+		retry := time.NewTimer(interval)
+		ticker := time.NewTicker(250 * time.Millisecond)
+	sleep:
+		for {
+			if animate {
+				w.draw(spinnerFrames[spin%len(spinnerFrames)], what, state, time.Since(start))
+				spin++
+			}
+			select {
+			case <-retry.C:
+				break sleep
+			case <-ticker.C:
+			case <-deadline:
+				ticker.Stop()
+				retry.Stop()
+				return errors.Wrapf(ErrTimeout, "Gave up on %s after %s, last state %q", what, w.Timeout, state)
+			}
+		}
+		ticker.Stop()
+
+		interval *= 2
+		if w.MaxInterval > 0 && interval > w.MaxInterval {
+			interval = w.MaxInterval
+		}
+	}
+}
+
+// draw redraws the spinner line in place
+func (w Waiter) draw(frame, what, state string, elapsed time.Duration) {
+	if w.Progress == nil {
+		return
+	}
+	if state == "" {
+		state = "unknown"
+	}
+	fmt.Fprintf(w.Progress, "\r%s Waiting for %s -- %s (%s)\033[K", frame, what, state, elapsed.Round(time.Second))
+}
+
+// report prints the current state on a line of its own, for logs and pipes
+func (w Waiter) report(what, state string, elapsed time.Duration) {
+	if w.Progress == nil {
+		return
+	}
+	if state == "" {
+		state = "unknown"
+	}
+	fmt.Fprintf(w.Progress, "Waiting for %s -- %s (%s)\n", what, state, elapsed.Round(time.Second))
+}
+
+// clear removes the spinner line once waiting is over
+func (w Waiter) clear() {
+	if w.Progress != nil {
+		fmt.Fprint(w.Progress, "\r\033[K")
+	}
+}
+
+func isTerminal(err error) bool {
+	_, ok := errors.Cause(err).(terminalError)
+	return ok
+}
+
+func contains(states []string, state string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
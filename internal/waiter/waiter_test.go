@@ -0,0 +1,127 @@
+package waiter
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// states returns a Condition that reports each state in turn, repeating the last one
+func states(list ...string) (Condition, *int) {
+	calls := 0
+	return func() (string, error) {
+		state := list[len(list)-1]
+		if calls < len(list) {
+			state = list[calls]
+		}
+		calls++
+		return state, nil
+	}, &calls
+}
+
+func fast(timeout time.Duration) Waiter {
+	return Waiter{Timeout: timeout, Interval: time.Millisecond, MaxInterval: 2 * time.Millisecond}
+}
+
+// useTTY makes the waiter treat every Progress writer as a terminal
+func useTTY(t *testing.T) {
+	old := isTTY
+	isTTY = func(io.Writer) bool { return true }
+	t.Cleanup(func() { isTTY = old })
+}
+
+func TestUntilReady(t *testing.T) {
+	useTTY(t)
+	cond, calls := states("pending", "pending", "running")
+	var progress bytes.Buffer
+	w := fast(time.Second)
+	w.Progress = &progress
+
+	if err := w.Until("instance web", cond, []string{"running"}, nil); err != nil {
+		t.Fatalf("Until: %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 polls, got %d", *calls)
+	}
+	if !strings.Contains(progress.String(), "Waiting for instance web -- pending") {
+		t.Errorf("progress doesn't show the state: %q", progress.String())
+	}
+	if !strings.HasSuffix(progress.String(), "\r\033[K") {
+		t.Errorf("spinner line wasn't cleared: %q", progress.String())
+	}
+}
+
+func TestUntilPlainProgress(t *testing.T) {
+	cond, _ := states("pending", "pending", "pending", "running")
+	var progress bytes.Buffer
+	w := fast(time.Second)
+	w.Progress = &progress
+
+	if err := w.Until("instance web", cond, []string{"running"}, nil); err != nil {
+		t.Fatalf("Until: %v", err)
+	}
+	// without a terminal there is no spinner, only a line per state change
+	if strings.ContainsAny(progress.String(), "\r\033") {
+		t.Errorf("progress has terminal control codes: %q", progress.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(progress.String()), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], "Waiting for instance web -- pending") {
+		t.Errorf("expected one line for the unchanged state, got %q", lines)
+	}
+}
+
+func TestUntilFailedState(t *testing.T) {
+	cond, _ := states("creating", "error")
+	err := fast(time.Second).Until("cluster k8s", cond, []string{"running"}, []string{"error"})
+	if err == nil || !strings.Contains(err.Error(), "reached state error") {
+		t.Fatalf("expected a failed state error, got %v", err)
+	}
+}
+
+func TestUntilTerminalError(t *testing.T) {
+	calls := 0
+	cond := func() (string, error) {
+		calls++
+		return "DONE", Fail(errors.New("quota exceeded"))
+	}
+	err := fast(time.Second).Until("database pg", cond, []string{"DONE"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("expected the terminal error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("terminal errors shouldn't be retried, got %d polls", calls)
+	}
+}
+
+func TestUntilRetriesErrors(t *testing.T) {
+	calls := 0
+	cond := func() (string, error) {
+		calls++
+		if calls < maxErrors {
+			return "", errors.New("throttled")
+		}
+		return "running", nil
+	}
+	if err := fast(time.Second).Until("instance web", cond, []string{"running"}, nil); err != nil {
+		t.Fatalf("expected a few errors to be tolerated, got %v", err)
+	}
+
+	err := fast(time.Second).Until("instance web", func() (string, error) { return "", errors.New("throttled") }, []string{"running"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "throttled") {
+		t.Fatalf("expected repeated errors to end the wait, got %v", err)
+	}
+}
+
+func TestUntilTimeout(t *testing.T) {
+	cond, _ := states("pending")
+	err := fast(20*time.Millisecond).Until("instance web", cond, []string{"running"}, nil)
+	if errors.Cause(err) != ErrTimeout {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `last state "pending"`) {
+		t.Errorf("timeout error should include the last state: %v", err)
+	}
+}